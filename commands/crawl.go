@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/ipfs-search/ipfs-search/components/crawler/worker"
+	"github.com/ipfs-search/ipfs-search/components/index/factory"
 	"github.com/ipfs-search/ipfs-search/config"
 	"github.com/ipfs-search/ipfs-search/instr"
 
@@ -23,15 +24,32 @@ func Crawl(ctx context.Context, cfg *config.Config) error {
 	ctx, span := i.Tracer.Start(ctx, "commands.Crawl")
 	defer span.End()
 
-	c, err := worker.NewPool(ctx, cfg, i)
+	f := getIndexFactory(ctx, cfg, true, i)
+	defer f.Close()
+
+	crawl, err := newCrawl(ctx, cfg, f, i)
 	if err != nil {
 		return err
 	}
 
-	c.Start(ctx)
+	go startIndexWorker(ctx, f)
+
+	return crawl(ctx)
+}
+
+// newCrawl initializes crawling with indexes from f, returning a function which crawls until the context is closed.
+func newCrawl(ctx context.Context, cfg *config.Config, f *factory.Factory, i *instr.Instrumentation) (func(context.Context) error, error) {
+	c, err := worker.NewPool(ctx, cfg, f, i)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context) error {
+		c.Start(ctx)
 
-	// Context closure or panic is the only way to stop crawling
-	<-ctx.Done()
+		// Context closure or panic is the only way to stop crawling
+		<-ctx.Done()
 
-	return ctx.Err()
+		return ctx.Err()
+	}, nil
 }
@@ -0,0 +1,159 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"github.com/ipfs-search/ipfs-search/components/export"
+	"github.com/ipfs-search/ipfs-search/components/index"
+	"github.com/ipfs-search/ipfs-search/components/index/cache"
+	"github.com/ipfs-search/ipfs-search/components/index/elasticsearch"
+	"github.com/ipfs-search/ipfs-search/components/index/elasticsearch/mappings"
+	"github.com/ipfs-search/ipfs-search/components/index/factory"
+	"github.com/ipfs-search/ipfs-search/config"
+	"github.com/ipfs-search/ipfs-search/instr"
+	"github.com/ipfs-search/ipfs-search/utils"
+)
+
+func getExportFactory(ctx context.Context, cfg *config.Config, i *instr.Instrumentation) *factory.Factory {
+	dialer := &utils.RetryingDialer{
+		Dialer: net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			DualStack: false,
+		},
+		Context: ctx,
+	}
+
+	return factory.New(&elasticsearch.ClientConfig{
+		URL:                      cfg.ElasticSearch.URL,
+		Transport:                utils.GetHTTPTransport(dialer.DialContext, 10),
+		BulkIndexerWorkers:       cfg.ElasticSearch.BulkIndexerWorkers,
+		BulkIndexerFlushBytes:    int(cfg.ElasticSearch.BulkIndexerFlushBytes),
+		BulkGetterBatchSize:      cfg.ElasticSearch.BulkGetterBatchSize,
+		BulkGetterBatchTimeout:   cfg.ElasticSearch.BulkGetterBatchTimeout,
+		BulkGetterRequestTimeout: cfg.ElasticSearch.BulkGetterRequestTimeout,
+		BulkGetterAliasTTL:       cfg.ElasticSearch.BulkGetterAliasTTL,
+	}, cfg.BleveConfig(), cfg.CacheConfig(), i)
+}
+
+// getKinds returns kinds, or all kinds of indexes when empty.
+func getKinds(cfg *config.Config, kinds []string) (map[string]config.Index, error) {
+	configs := indexConfigs(cfg)
+
+	if len(kinds) == 0 {
+		return configs, nil
+	}
+
+	selected := make(map[string]config.Index, len(kinds))
+
+	for _, kind := range kinds {
+		c, ok := configs[kind]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", mappings.ErrUnknownKind, kind)
+		}
+
+		selected[kind] = c
+	}
+
+	return selected, nil
+}
+
+// Export writes documents of the given kinds of indexes, or of all indexes, to a dump in dir. When car is set, the dump
+// is packed into a CAR file at that path.
+func Export(ctx context.Context, cfg *config.Config, dir string, kinds []string, opts *export.Options, car string) error {
+	configs, err := getKinds(cfg, kinds)
+	if err != nil {
+		return err
+	}
+
+	f := getExportFactory(ctx, cfg, instr.New())
+	defer f.Close()
+
+	scanners := make(map[string]index.Scanner, len(configs))
+
+	for kind, c := range configs {
+		// Read from the primary index only.
+		c.Cache = cache.None
+		c.Tee = nil
+
+		idx, err := f.Index(c.IndexConfig())
+		if err != nil {
+			return err
+		}
+
+		scanner, ok := idx.(index.Scanner)
+		if !ok {
+			return fmt.Errorf("index %s can not be exported", idx)
+		}
+
+		scanners[kind] = scanner
+	}
+
+	m, err := export.Export(ctx, scanners, dir, opts)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Exported %d shards to %s", len(m.Shards), dir)
+
+	if car == "" {
+		return nil
+	}
+
+	root, err := export.Pack(car, dir, m)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Packed %s into %s with root %s", dir, car, root)
+
+	return nil
+}
+
+// Import loads the dumps or shards at paths into the configured indexes, writing with workers concurrently.
+func Import(ctx context.Context, cfg *config.Config, paths []string, workers int) error {
+	f := getExportFactory(ctx, cfg, instr.New())
+	defer f.Close()
+
+	configs := indexConfigs(cfg)
+	indexes := make(map[string]index.Index, len(configs))
+
+	for kind, c := range configs {
+		// Documents are only written.
+		c.Cache = cache.None
+
+		idx, err := f.Index(c.IndexConfig())
+		if err != nil {
+			return err
+		}
+
+		indexes[kind] = idx
+	}
+
+	// The worker flushes writes when its context is closed.
+	workCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		if err := f.Work(workCtx); err != nil && workCtx.Err() == nil {
+			log.Printf("Error in index worker: %s", err)
+		}
+	}()
+
+	result, err := export.Import(ctx, indexes, paths, workers)
+
+	cancel()
+	<-done
+
+	if result != nil {
+		log.Printf("Imported %d documents, %d already existed", result.Imported, result.Existing)
+	}
+
+	return err
+}
@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"context"
+	"log"
+	"net"
+	"time"
+
+	"github.com/ipfs-search/ipfs-search/components/index/factory"
+	"github.com/ipfs-search/ipfs-search/config"
+	"github.com/ipfs-search/ipfs-search/instr"
+	"github.com/ipfs-search/ipfs-search/utils"
+)
+
+// getIndexFactory returns a factory for the configured indexes, spooling writes to Elasticsearch when spool is set.
+func getIndexFactory(ctx context.Context, cfg *config.Config, spool bool, i *instr.Instrumentation) *factory.Factory {
+	dialer := &utils.RetryingDialer{
+		Dialer: net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			DualStack: false,
+		},
+		Context: ctx,
+	}
+
+	esConfig := cfg.ElasticSearchClientConfig(utils.GetHTTPTransport(dialer.DialContext, 100))
+	if spool {
+		esConfig.Spool = cfg.SpoolConfig()
+	}
+
+	return factory.New(esConfig, cfg.BleveConfig(), cfg.CacheConfig(), i)
+}
+
+// startIndexWorker performs the background work of f until ctx is closed, restarting it on errors. It should be
+// started after creating indexes.
+func startIndexWorker(ctx context.Context, f *factory.Factory) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			if err := f.Work(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Error in index worker, restarting worker: %s", err)
+				// Prevent overly tight restart loop
+				time.Sleep(time.Second)
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"github.com/ipfs-search/ipfs-search/components/index/elasticsearch"
+	"github.com/ipfs-search/ipfs-search/components/index/elasticsearch/mappings"
+	"github.com/ipfs-search/ipfs-search/config"
+	"github.com/ipfs-search/ipfs-search/instr"
+	"github.com/ipfs-search/ipfs-search/utils"
+)
+
+// indexAliases returns the configured index names (aliases) by kind of index.
+func indexAliases(cfg *config.Config) map[string]string {
+	return map[string]string{
+		mappings.Files:       cfg.Indexes.Files.Name,
+		mappings.Directories: cfg.Indexes.Directories.Name,
+		mappings.Invalids:    cfg.Indexes.Invalids.Name,
+		mappings.Partials:    cfg.Indexes.Partials.Name,
+		mappings.IPNSNames:   cfg.Indexes.IPNSNames.Name,
+	}
+}
+
+// indexConfigs returns the configuration of indexes by kind of index.
+func indexConfigs(cfg *config.Config) map[string]config.Index {
+	return map[string]config.Index{
+		mappings.Files:       cfg.Indexes.Files,
+		mappings.Directories: cfg.Indexes.Directories,
+		mappings.Invalids:    cfg.Indexes.Invalids,
+		mappings.Partials:    cfg.Indexes.Partials,
+		mappings.IPNSNames:   cfg.Indexes.IPNSNames,
+	}
+}
+
+func getLifecycle(ctx context.Context, cfg *config.Config) (*elasticsearch.Lifecycle, error) {
+	dialer := &utils.RetryingDialer{
+		Dialer: net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			DualStack: false,
+		},
+		Context: ctx,
+	}
+
+	client, err := elasticsearch.NewClient(&elasticsearch.ClientConfig{
+		URL:       cfg.ElasticSearch.URL,
+		Transport: utils.GetHTTPTransport(dialer.DialContext, 10),
+	}, instr.New())
+	if err != nil {
+		return nil, err
+	}
+
+	return elasticsearch.NewLifecycle(client), nil
+}
+
+// IndexInit creates versioned indexes behind the configured index names, for those which do not exist.
+func IndexInit(ctx context.Context, cfg *config.Config) error {
+	l, err := getLifecycle(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	aliases := indexAliases(cfg)
+
+	for _, kind := range mappings.Kinds() {
+		alias := aliases[kind]
+
+		index, created, err := l.Init(ctx, alias, kind)
+		if err != nil {
+			return fmt.Errorf("initializing %s: %w", alias, err)
+		}
+
+		if created {
+			log.Printf("Created %s as %s", index, alias)
+		} else {
+			log.Printf("%s exists as %s", alias, index)
+		}
+	}
+
+	return nil
+}
+
+// IndexStatus writes the status of the configured indexes to stdout, including differences from their definitions.
+func IndexStatus(ctx context.Context, cfg *config.Config) error {
+	l, err := getLifecycle(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	aliases := indexAliases(cfg)
+
+	for _, kind := range mappings.Kinds() {
+		status, err := l.Status(ctx, aliases[kind], kind)
+		if err != nil {
+			return fmt.Errorf("getting status of %s: %w", aliases[kind], err)
+		}
+
+		fmt.Println(status)
+	}
+
+	return nil
+}
+
+// IndexMigrate migrates the index of kind to a new version according to its definition.
+func IndexMigrate(ctx context.Context, cfg *config.Config, kind string, opts *elasticsearch.MigrateOptions) error {
+	alias, ok := indexAliases(cfg)[kind]
+	if !ok {
+		return fmt.Errorf("%w: %s", mappings.ErrUnknownKind, kind)
+	}
+
+	l, err := getLifecycle(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	m, err := l.Migrate(ctx, alias, kind, opts)
+	if err != nil {
+		return fmt.Errorf("migrating %s: %w", alias, err)
+	}
+
+	log.Printf("Migrated %d documents from %s to %s, %s now refers to %s", m.Count, m.From, m.To, m.Alias, m.To)
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"context"
+	"log"
+	"net"
+	"time"
+
+	samqp "github.com/rabbitmq/amqp091-go"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/ipfs-search/ipfs-search/components/queue/amqp"
+	"github.com/ipfs-search/ipfs-search/components/sniffer/queuer"
+	"github.com/ipfs-search/ipfs-search/components/sniffer/recorder"
+	"github.com/ipfs-search/ipfs-search/config"
+	"github.com/ipfs-search/ipfs-search/instr"
+	t "github.com/ipfs-search/ipfs-search/types"
+	"github.com/ipfs-search/ipfs-search/utils"
+)
+
+// Replay re-publishes the providers recorded in the sniff log from `from` until `to` to the hashes queue,
+// at most at rate per second.
+func Replay(ctx context.Context, cfg *config.Config, from, to time.Time, rate float64) error {
+	instFlusher, err := instr.Install(cfg.InstrConfig(), "ipfs-sniffer replay")
+	if err != nil {
+		return err
+	}
+	defer instFlusher()
+
+	i := instr.New()
+
+	dialer := &utils.RetryingDialer{
+		Dialer: net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			DualStack: false,
+		},
+		Context: ctx,
+	}
+
+	f := amqp.PublisherFactory{
+		Config:          cfg.AMQPConfig(),
+		Queue:           "hashes",
+		AMQPConfig:      &samqp.Config{Dial: dialer.Dial},
+		Instrumentation: i,
+	}
+
+	publisher, err := f.NewPublisher(ctx)
+	if err != nil {
+		return err
+	}
+
+	snifferCfg := cfg.SnifferConfig()
+
+	// Replays are only rate limited; the sniffer's load shedding would defeat backfilling.
+	throttleCfg := snifferCfg.ThrottleConfig()
+	throttleCfg.Rate = rate
+	throttleCfg.SampleRatio = 1
+	throttle := queuer.NewThrottle(throttleCfg, nil, i)
+
+	providers := make(chan t.Provider, snifferCfg.BufferSize)
+	q := queuer.New(publisher, providers, throttle)
+
+	log.Printf("Replaying sniff log in %s from %s to %s at %f/s", snifferCfg.RecordDir, from, to, rate)
+
+	errg, ctx := errgroup.WithContext(ctx)
+	errg.Go(func() error {
+		// Closing providers stops the queuer once it has published them all.
+		defer close(providers)
+		return recorder.Replay(ctx, snifferCfg.RecordDir, from, to, providers)
+	})
+	errg.Go(func() error { return q.Queue(ctx) })
+
+	return errg.Wait()
+}
@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"context"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/ipfs-search/ipfs-search/config"
+	"github.com/ipfs-search/ipfs-search/instr"
+)
+
+// Run crawls, sniffs and serves the search API in a single process, sharing indexes, until the context is closed or
+// any of them fails. Embedded Bleve indexes can only be opened by a single process, so they require running this way.
+func Run(ctx context.Context, cfg *config.Config) error {
+	instFlusher, err := instr.Install(cfg.InstrConfig(), "ipfs-search")
+	if err != nil {
+		return err
+	}
+	defer instFlusher()
+
+	i := instr.New()
+
+	ctx, span := i.Tracer.Start(ctx, "commands.Run")
+	defer span.End()
+
+	f := getIndexFactory(ctx, cfg, true, i)
+	defer f.Close()
+
+	crawl, err := newCrawl(ctx, cfg, f, i)
+	if err != nil {
+		return err
+	}
+
+	sniff, err := newSniff(ctx, cfg, f, i)
+	if err != nil {
+		return err
+	}
+
+	serve, err := newServe(cfg, f, i)
+	if err != nil {
+		return err
+	}
+
+	go startIndexWorker(ctx, f)
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	for _, run := range []func(context.Context) error{crawl, sniff, serve} {
+		run := run // https://go.dev/doc/faq#closures_and_goroutines
+		g.Go(func() error { return run(ctx) })
+	}
+
+	err = g.Wait()
+	if err == context.Canceled {
+		return nil
+	}
+
+	return err
+}
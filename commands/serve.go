@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/ipfs-search/ipfs-search/components/api"
+	"github.com/ipfs-search/ipfs-search/components/index/factory"
+	"github.com/ipfs-search/ipfs-search/config"
+	"github.com/ipfs-search/ipfs-search/instr"
+)
+
+// Serve serves the search API until the context is closed.
+func Serve(ctx context.Context, cfg *config.Config) error {
+	instFlusher, err := instr.Install(cfg.InstrConfig(), "ipfs-search serve")
+	if err != nil {
+		return err
+	}
+	defer instFlusher()
+
+	i := instr.New()
+
+	f := getIndexFactory(ctx, cfg, false, i)
+	defer f.Close()
+
+	serve, err := newServe(cfg, f, i)
+	if err != nil {
+		return err
+	}
+
+	// Metadata is fetched through the bulk getter.
+	go startIndexWorker(ctx, f)
+
+	return serve(ctx)
+}
+
+// newServe initializes the search API on indexes from f, returning a function which serves it until the context is
+// closed.
+func newServe(cfg *config.Config, f *factory.Factory, i *instr.Instrumentation) (func(context.Context) error, error) {
+	searcher, err := f.Searcher(cfg.Indexes.Files.IndexConfig(), cfg.Indexes.Directories.IndexConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := f.Index(cfg.Indexes.Files.IndexConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	s := api.New(cfg.APIConfig(), searcher, files, i)
+
+	return func(ctx context.Context) error {
+		err := s.ListenAndServe(ctx)
+		if err == context.Canceled {
+			return nil
+		}
+
+		return err
+	}, nil
+}
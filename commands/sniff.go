@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"context"
+	"log"
+
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+
+	"github.com/ipfs-search/ipfs-search/components/index/factory"
+	sniffer "github.com/ipfs-search/ipfs-search/components/sniffer/factory"
+	"github.com/ipfs-search/ipfs-search/components/sniffer/node"
+	"github.com/ipfs-search/ipfs-search/config"
+	"github.com/ipfs-search/ipfs-search/instr"
+)
+
+// Sniff runs a DHT node and sniffs the provider records it receives, until the context is closed or the sniffer fails.
+func Sniff(ctx context.Context, cfg *config.Config) error {
+	instFlusher, err := instr.Install(cfg.InstrConfig(), "ipfs-sniffer")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer instFlusher()
+
+	i := instr.New()
+
+	ctx, span := i.Tracer.Start(ctx, "commands.Sniff")
+	defer span.End()
+
+	f := getIndexFactory(ctx, cfg, false, i)
+	defer f.Close()
+
+	sniff, err := newSniff(ctx, cfg, f, i)
+	if err != nil {
+		return err
+	}
+
+	go startIndexWorker(ctx, f)
+
+	return sniff(ctx)
+}
+
+// newSniff initializes a sniffer with indexes from f, returning a function which runs a DHT node and sniffs it until
+// the context is closed or the sniffer fails.
+func newSniff(ctx context.Context, cfg *config.Config, f *factory.Factory, i *instr.Instrumentation) (func(context.Context) error, error) {
+	// DHT records are ephemeral; keep them in memory.
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+
+	s, err := sniffer.New(ctx, cfg, ds, f, i)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context) error {
+		n, err := node.New(ctx, cfg.SnifferNodeConfig(), s.Batching())
+		if err != nil {
+			return err
+		}
+		defer n.Close()
+
+		if err := n.Bootstrap(ctx); err != nil {
+			return err
+		}
+
+		return s.Sniff(ctx)
+	}, nil
+}
@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net"
+	"os"
+	"time"
+
+	"github.com/ipfs-search/ipfs-search/components/index"
+	"github.com/ipfs-search/ipfs-search/components/index/cache"
+	"github.com/ipfs-search/ipfs-search/components/index/elasticsearch"
+	"github.com/ipfs-search/ipfs-search/components/index/factory"
+	"github.com/ipfs-search/ipfs-search/components/takedown"
+	"github.com/ipfs-search/ipfs-search/config"
+	"github.com/ipfs-search/ipfs-search/instr"
+	"github.com/ipfs-search/ipfs-search/utils"
+)
+
+// Takedown removes hash from all indexes and adds it to the denylist, writing the resulting record to stdout.
+func Takedown(ctx context.Context, cfg *config.Config, hash string, opts takedown.Options) error {
+	instFlusher, err := instr.Install(cfg.InstrConfig(), "ipfs-crawler takedown")
+	if err != nil {
+		return err
+	}
+	defer instFlusher()
+
+	i := instr.New()
+
+	dialer := &utils.RetryingDialer{
+		Dialer: net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			DualStack: false,
+		},
+		Context: ctx,
+	}
+
+	f := factory.New(&elasticsearch.ClientConfig{
+		URL:                      cfg.ElasticSearch.URL,
+		Transport:                utils.GetHTTPTransport(dialer.DialContext, 10),
+		BulkIndexerWorkers:       cfg.ElasticSearch.BulkIndexerWorkers,
+		BulkIndexerFlushBytes:    int(cfg.ElasticSearch.BulkIndexerFlushBytes),
+		BulkGetterBatchSize:      cfg.ElasticSearch.BulkGetterBatchSize,
+		BulkGetterBatchTimeout:   cfg.ElasticSearch.BulkGetterBatchTimeout,
+		BulkGetterRequestTimeout: cfg.ElasticSearch.BulkGetterRequestTimeout,
+		BulkGetterAliasTTL:       cfg.ElasticSearch.BulkGetterAliasTTL,
+	}, cfg.BleveConfig(), cfg.CacheConfig(), i)
+	defer f.Close()
+
+	var indexes []index.Index
+
+	for _, c := range []config.Index{
+		cfg.Indexes.Files,
+		cfg.Indexes.Directories,
+		cfg.Indexes.Invalids,
+		cfg.Indexes.Partials,
+	} {
+		// Bypass caches, as documents are deleted.
+		c.Cache = cache.None
+
+		idx, err := f.Index(c.IndexConfig())
+		if err != nil {
+			return err
+		}
+
+		indexes = append(indexes, idx)
+	}
+
+	// The worker flushes deletes and updates when its context is closed.
+	workCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		if err := f.Work(workCtx); err != nil && workCtx.Err() == nil {
+			log.Printf("Error in index worker: %s", err)
+		}
+	}()
+
+	t := takedown.New(cfg.TakedownConfig(), indexes, cfg.Denylist.File, i)
+
+	record, err := t.Takedown(ctx, hash, opts)
+
+	cancel()
+	<-done
+
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(record)
+}
@@ -0,0 +1,19 @@
+package api
+
+// Config holds configuration for a search API Server.
+type Config struct {
+	Addr        string // Address to listen on, e.g. `:9615`
+	PageSize    int    // Number of hits per page, unless requested otherwise
+	MaxPageSize int    // Maximum number of hits per page
+	MaxPage     int    // Maximum page number, as deep pagination is expensive for the search backend
+}
+
+// DefaultConfig returns the default configuration for a Server.
+func DefaultConfig() *Config {
+	return &Config{
+		Addr:        ":9615",
+		PageSize:    15,
+		MaxPageSize: 100,
+		MaxPage:     100,
+	}
+}
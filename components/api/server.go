@@ -0,0 +1,349 @@
+/*
+Package api serves a REST API for searching the indexes.
+
+Endpoints:
+
+	GET /v1/search?q=<query>   Search files and directories, with optional parameters:
+	                           type       `file`, `directory` or `any` (default)
+	                           mime       MIME type (prefix) of files, e.g. `image/`
+	                           min_size   Minimum size in bytes
+	                           max_size   Maximum size in bytes
+	                           last_seen  Only return items seen since this duration ago (e.g. `24h`) or time (RFC 3339)
+	                           page       Page number, starting at 0
+	                           page_size  Hits per page
+	GET /v1/metadata/<cid>     Metadata extracted from a file.
+*/
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/ipfs-search/ipfs-search/components/index"
+	indexTypes "github.com/ipfs-search/ipfs-search/components/index/types"
+	"github.com/ipfs-search/ipfs-search/instr"
+)
+
+// maxResultWindow is the maximum of from + size in searches, the default `index.max_result_window` of OpenSearch.
+const maxResultWindow = 10000
+
+// Server serves the search API.
+type Server struct {
+	cfg      *Config
+	searcher index.Searcher
+	files    index.Index
+	mux      *http.ServeMux
+
+	*instr.Instrumentation
+}
+
+// New returns a Server searching searcher, with metadata from the files index.
+func New(cfg *Config, searcher index.Searcher, files index.Index, i *instr.Instrumentation) *Server {
+	if cfg == nil {
+		panic("api.New Config cannot be nil.")
+	}
+
+	s := &Server{
+		cfg:             cfg,
+		searcher:        searcher,
+		files:           files,
+		mux:             http.NewServeMux(),
+		Instrumentation: i,
+	}
+
+	s.mux.HandleFunc("/v1/search", s.get("api.search", s.search))
+	s.mux.HandleFunc("/v1/metadata/", s.get("api.metadata", s.metadata))
+
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// ListenAndServe serves the API on the configured address until the context is closed.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:         s.cfg.Addr,
+		Handler:      s,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.ListenAndServe()
+	}()
+
+	log.Printf("Serving search API on %s", s.cfg.Addr)
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			return err
+		}
+
+		return ctx.Err()
+	}
+}
+
+// httpError represents an error with a status code, to be returned to the client.
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string {
+	return e.err.Error()
+}
+
+func (e *httpError) Unwrap() error {
+	return e.err
+}
+
+func badRequest(format string, a ...interface{}) error {
+	return &httpError{http.StatusBadRequest, fmt.Errorf(format, a...)}
+}
+
+// get wraps a handler returning a JSON-serializable result or an error.
+func (s *Server) get(name string, h func(ctx context.Context, r *http.Request) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := s.Tracer.Start(r.Context(), name, trace.WithNewRoot(),
+			trace.WithAttributes(label.String("url", r.URL.String())),
+		)
+		defer span.End()
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			writeJSON(w, http.StatusMethodNotAllowed, errorResponse{"method not allowed"})
+			return
+		}
+
+		result, err := h(ctx, r)
+		if err != nil {
+			status := http.StatusInternalServerError
+
+			var he *httpError
+			if errors.As(err, &he) {
+				status = he.status
+			} else if errors.Is(err, index.ErrInvalidQuery) {
+				status = http.StatusBadRequest
+			}
+
+			msg := err.Error()
+			if status == http.StatusInternalServerError {
+				span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+				log.Printf("Error serving %s: %s", r.URL, err)
+
+				// Don't expose internals.
+				msg = "internal error"
+			}
+
+			writeJSON(w, status, errorResponse{msg})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error writing response: %s", err)
+	}
+}
+
+// searchResponse is a page of search results.
+type searchResponse struct {
+	*index.SearchResult
+	Page      int `json:"page"`
+	PageSize  int `json:"page_size"`
+	PageCount int `json:"page_count"`
+}
+
+func intParam(r *http.Request, name string, def int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil || i < 0 {
+		return 0, badRequest("invalid %s: %s", name, v)
+	}
+
+	return i, nil
+}
+
+func sizeParam(r *http.Request, name string) (uint64, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return 0, nil
+	}
+
+	i, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, badRequest("invalid %s: %s", name, v)
+	}
+
+	return i, nil
+}
+
+// timeParam parses a duration before now or an RFC 3339 time.
+func timeParam(r *http.Request, name string) (time.Time, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+
+	if d, err := time.ParseDuration(v); err == nil {
+		return time.Now().Add(-d), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, badRequest("invalid %s: %s", name, v)
+	}
+
+	return t, nil
+}
+
+func (s *Server) parseQuery(r *http.Request) (*index.Query, int, error) {
+	params := r.URL.Query()
+
+	q := &index.Query{
+		Text:     params.Get("q"),
+		Type:     index.DocumentType(params.Get("type")),
+		MIMEType: params.Get("mime"),
+	}
+
+	if q.Text == "" {
+		return nil, 0, badRequest("missing query: q")
+	}
+
+	var err error
+
+	if q.MinSize, err = sizeParam(r, "min_size"); err != nil {
+		return nil, 0, err
+	}
+
+	if q.MaxSize, err = sizeParam(r, "max_size"); err != nil {
+		return nil, 0, err
+	}
+
+	if q.LastSeenAfter, err = timeParam(r, "last_seen"); err != nil {
+		return nil, 0, err
+	}
+
+	page, err := intParam(r, "page", 0)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if page > s.cfg.MaxPage {
+		return nil, 0, badRequest("page exceeds maximum of %d", s.cfg.MaxPage)
+	}
+
+	if q.Size, err = intParam(r, "page_size", s.cfg.PageSize); err != nil {
+		return nil, 0, err
+	}
+
+	if q.Size == 0 || q.Size > s.cfg.MaxPageSize {
+		return nil, 0, badRequest("page_size should be between 1 and %d", s.cfg.MaxPageSize)
+	}
+
+	q.From = page * q.Size
+
+	if q.From+q.Size > maxResultWindow {
+		return nil, 0, badRequest("page exceeds maximum of %d for page_size %d", s.pageCount(q.Size)-1, q.Size)
+	}
+
+	return q, page, nil
+}
+
+// pageCount returns the number of pages of size which can be requested.
+func (s *Server) pageCount(size int) int {
+	count := maxResultWindow / size
+	if count > s.cfg.MaxPage+1 {
+		count = s.cfg.MaxPage + 1
+	}
+
+	return count
+}
+
+func (s *Server) search(ctx context.Context, r *http.Request) (interface{}, error) {
+	q, page, err := s.parseQuery(r)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.searcher.Search(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only report pages which can be requested.
+	pageCount := int((result.Total + int64(q.Size) - 1) / int64(q.Size))
+	if max := s.pageCount(q.Size); pageCount > max {
+		pageCount = max
+	}
+
+	return &searchResponse{
+		SearchResult: result,
+		Page:         page,
+		PageSize:     q.Size,
+		PageCount:    pageCount,
+	}, nil
+}
+
+// metadataResponse holds the metadata of a file.
+type metadataResponse struct {
+	Metadata indexTypes.Metadata `json:"metadata"`
+}
+
+func (s *Server) metadata(ctx context.Context, r *http.Request) (interface{}, error) {
+	id := strings.TrimPrefix(r.URL.Path, "/v1/metadata/")
+
+	if _, err := cid.Decode(id); err != nil {
+		return nil, badRequest("invalid CID: %s", id)
+	}
+
+	dst := new(metadataResponse)
+
+	found, err := s.files.Get(ctx, id, dst, "metadata")
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, &httpError{http.StatusNotFound, fmt.Errorf("%s not found", id)}
+	}
+
+	return dst, nil
+}
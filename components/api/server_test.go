@@ -0,0 +1,193 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/ipfs-search/ipfs-search/components/index"
+	indexTypes "github.com/ipfs-search/ipfs-search/components/index/types"
+	"github.com/ipfs-search/ipfs-search/instr"
+)
+
+const testCID = "QmSKboVigcD3AY4kLsob117KJcMHvMUu6vNFqk1PQzYUpp"
+
+type ServerTestSuite struct {
+	suite.Suite
+
+	searcher *index.MockSearcher
+	files    *index.Mock
+	srv      *Server
+}
+
+func (s *ServerTestSuite) SetupTest() {
+	s.searcher = &index.MockSearcher{}
+	s.searcher.Test(s.T())
+	s.files = &index.Mock{}
+	s.files.Test(s.T())
+
+	s.srv = New(DefaultConfig(), s.searcher, s.files, instr.New())
+}
+
+func (s *ServerTestSuite) TearDownTest() {
+	s.searcher.AssertExpectations(s.T())
+	s.files.AssertExpectations(s.T())
+}
+
+// get performs a GET request on the server, decoding the response into dst.
+func (s *ServerTestSuite) get(url string, dst interface{}) int {
+	w := httptest.NewRecorder()
+	s.srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+
+	s.Equal("application/json", w.Header().Get("Content-Type"))
+	s.NoError(json.NewDecoder(w.Body).Decode(dst))
+
+	return w.Code
+}
+
+func (s *ServerTestSuite) TestSearch() {
+	s.searcher.
+		On("Search", mock.Anything, mock.MatchedBy(func(q *index.Query) bool {
+			return s.Equal("test", q.Text) &&
+				s.Equal(index.FileType, q.Type) &&
+				s.Equal("image/", q.MIMEType) &&
+				s.Equal(uint64(10), q.MinSize) &&
+				s.Equal(uint64(1000), q.MaxSize) &&
+				s.WithinDuration(time.Now().Add(-24*time.Hour), q.LastSeenAfter, time.Minute) &&
+				s.Equal(20, q.From) &&
+				s.Equal(10, q.Size)
+		})).
+		Return(&index.SearchResult{
+			Total:    21,
+			MaxScore: 2,
+			Hits: []index.Hit{
+				{ID: testCID, Type: index.FileType, Score: 2},
+			},
+		}, nil).
+		Once()
+
+	var resp struct {
+		Total     int64
+		Page      int
+		PageSize  int `json:"page_size"`
+		PageCount int `json:"page_count"`
+		Hits      []struct {
+			Hash string
+			Type string
+		}
+	}
+
+	status := s.get("/v1/search?q=test&type=file&mime=image/&min_size=10&max_size=1000&last_seen=24h&page=2&page_size=10", &resp)
+
+	s.Equal(http.StatusOK, status)
+	s.Equal(int64(21), resp.Total)
+	s.Equal(2, resp.Page)
+	s.Equal(10, resp.PageSize)
+	s.Equal(3, resp.PageCount)
+	s.Require().Len(resp.Hits, 1)
+	s.Equal(testCID, resp.Hits[0].Hash)
+	s.Equal("file", resp.Hits[0].Type)
+}
+
+func (s *ServerTestSuite) TestSearchPageCount() {
+	s.searcher.
+		On("Search", mock.Anything, mock.Anything).
+		Return(&index.SearchResult{Total: 1000000}, nil).
+		Once()
+
+	var resp struct {
+		PageCount int `json:"page_count"`
+	}
+
+	s.Equal(http.StatusOK, s.get("/v1/search?q=test&page_size=100", &resp))
+
+	// Pages beyond the result window can not be requested.
+	s.Equal(100, resp.PageCount)
+}
+
+func (s *ServerTestSuite) TestSearchInvalidParams() {
+	for _, url := range []string{
+		"/v1/search",
+		"/v1/search?q=test&page=-1",
+		"/v1/search?q=test&page=101",
+		"/v1/search?q=test&page_size=0",
+		"/v1/search?q=test&page_size=1000",
+		"/v1/search?q=test&page=100&page_size=100",
+		"/v1/search?q=test&min_size=large",
+		"/v1/search?q=test&last_seen=yesterday",
+	} {
+		var resp errorResponse
+
+		s.Equal(http.StatusBadRequest, s.get(url, &resp), url)
+		s.NotEmpty(resp.Error, url)
+	}
+}
+
+func (s *ServerTestSuite) TestSearchInvalidQuery() {
+	s.searcher.
+		On("Search", mock.Anything, mock.Anything).
+		Return((*index.SearchResult)(nil), index.ErrInvalidQuery).
+		Once()
+
+	var resp errorResponse
+	s.Equal(http.StatusBadRequest, s.get("/v1/search?q=test+AND+(", &resp))
+}
+
+func (s *ServerTestSuite) TestSearchError() {
+	s.searcher.
+		On("Search", mock.Anything, mock.Anything).
+		Return((*index.SearchResult)(nil), errors.New("secret internals")).
+		Once()
+
+	var resp errorResponse
+	s.Equal(http.StatusInternalServerError, s.get("/v1/search?q=test", &resp))
+	s.Equal("internal error", resp.Error)
+}
+
+func (s *ServerTestSuite) TestMethodNotAllowed() {
+	w := httptest.NewRecorder()
+	s.srv.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/search?q=test", nil))
+
+	s.Equal(http.StatusMethodNotAllowed, w.Code)
+}
+
+func (s *ServerTestSuite) TestMetadata() {
+	s.files.
+		On("Get", mock.Anything, testCID, mock.Anything, []string{"metadata"}).
+		Run(func(args mock.Arguments) {
+			dst := args.Get(2).(*metadataResponse)
+			dst.Metadata = indexTypes.Metadata{"title": []interface{}{"Test"}}
+		}).
+		Return(true, nil).
+		Once()
+
+	var resp metadataResponse
+
+	s.Equal(http.StatusOK, s.get("/v1/metadata/"+testCID, &resp))
+	s.Equal([]interface{}{"Test"}, resp.Metadata["title"])
+}
+
+func (s *ServerTestSuite) TestMetadataNotFound() {
+	s.files.
+		On("Get", mock.Anything, testCID, mock.Anything, []string{"metadata"}).
+		Return(false, nil).
+		Once()
+
+	var resp errorResponse
+	s.Equal(http.StatusNotFound, s.get("/v1/metadata/"+testCID, &resp))
+}
+
+func (s *ServerTestSuite) TestMetadataInvalidCID() {
+	var resp errorResponse
+	s.Equal(http.StatusBadRequest, s.get("/v1/metadata/invalid", &resp))
+}
+
+func TestServerTestSuite(t *testing.T) {
+	suite.Run(t, new(ServerTestSuite))
+}
@@ -158,6 +158,11 @@ func (c *Crawler) processDirEntries(ctx context.Context, entries <-chan *t.Annot
 }
 
 func (c *Crawler) queueDirEntry(ctx context.Context, r *t.AnnotatedResource) error {
+	if c.denylist.Denied(r) {
+		log.Printf("Not queueing denied directory entry %v", r)
+		return nil
+	}
+
 	// Generate random lower priority for items in this directory
 	// Rationale; directories might have different availability but
 	// within a directory, items are likely to have similar availability.
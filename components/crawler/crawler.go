@@ -12,7 +12,9 @@ import (
 	"net"
 	"os"
 
+	"github.com/ipfs-search/ipfs-search/components/denylist"
 	"github.com/ipfs-search/ipfs-search/components/extractor"
+	"github.com/ipfs-search/ipfs-search/components/index"
 	"github.com/ipfs-search/ipfs-search/components/protocol"
 
 	"github.com/ipfs-search/ipfs-search/instr"
@@ -26,6 +28,7 @@ type Crawler struct {
 	queues    *Queues
 	protocol  protocol.Protocol
 	extractor extractor.Extractor
+	denylist  *denylist.Denylist
 	*instr.Instrumentation
 	server *tcpServer
 }
@@ -74,6 +77,12 @@ func (c *Crawler) Crawl(ctx context.Context, r *t.AnnotatedResource) error {
 		panic("invalid type for crawler")
 	}
 
+	if c.denylist.Denied(r) {
+		log.Printf("Skipping denied resource %v", r)
+		span.AddEvent(ctx, "Skipping denied resource")
+		return nil
+	}
+
 	exists, err := c.updateMaybeExisting(ctx, r)
 	if err != nil {
 		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
@@ -103,7 +112,21 @@ func (c *Crawler) Crawl(ctx context.Context, r *t.AnnotatedResource) error {
 	}
 
 	log.Printf("Indexing new item %v", r)
-	err = c.index(ctx, r)
+	err = index.OnWritten(ctx,
+		func(ctx context.Context) error {
+			return c.index(ctx, r)
+		},
+		func(ctx context.Context, err error) error {
+			if errors.Is(err, index.ErrConflict) {
+				// Concurrently indexed, e.g. by another crawler; update the existing item instead.
+				log.Printf("Updating concurrently indexed item %v", r)
+
+				err = c.updateConflicting(ctx, r)
+			}
+
+			return err
+		},
+	)
 	if err != nil {
 		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
 	}
@@ -124,8 +147,8 @@ func establishConnection(url string) (net.Conn, net.TCPAddr) {
 	return conn, *tcpAddr
 }
 
-// New instantiates a Crawler.
-func New(config *Config, indexes *Indexes, queues *Queues, protocol protocol.Protocol, extractor extractor.Extractor, i *instr.Instrumentation) *Crawler {
+// New instantiates a Crawler. Resources on denylist are skipped, unless it is nil.
+func New(config *Config, indexes *Indexes, queues *Queues, protocol protocol.Protocol, extractor extractor.Extractor, denylist *denylist.Denylist, i *instr.Instrumentation) *Crawler {
 	c, tcpAddr := establishConnection(config.ServerURL)
 	server := &tcpServer{
 		remote: tcpAddr,
@@ -139,6 +162,7 @@ func New(config *Config, indexes *Indexes, queues *Queues, protocol protocol.Pro
 		queues,
 		protocol,
 		extractor,
+		denylist,
 		i,
 		server,
 	}
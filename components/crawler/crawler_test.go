@@ -4,12 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/ipfs-search/ipfs-search/components/denylist"
 	"github.com/ipfs-search/ipfs-search/components/extractor"
 	"github.com/ipfs-search/ipfs-search/components/index"
 	indexTypes "github.com/ipfs-search/ipfs-search/components/index/types"
@@ -32,6 +35,7 @@ type CrawlerTestSuite struct {
 
 	protocol  *protocol.Mock
 	extractor *extractor.Mock
+	denylist  *denylist.Denylist
 
 	fileIdx    *index.Mock
 	dirIdx     *index.Mock
@@ -66,11 +70,22 @@ func (s *CrawlerTestSuite) SetupTest() {
 	s.protocol = &protocol.Mock{}
 	s.extractor = &extractor.Mock{}
 
+	denylistConfig := denylist.DefaultConfig()
+	denylistConfig.File = filepath.Join(s.T().TempDir(), "denylist")
+	s.Require().NoError(ioutil.WriteFile(denylistConfig.File, []byte(
+		"QmcniBv7UQ4gGPQQW2BwbD4ZZHzN3o3tPuNLZCbBchd1zh\n"+
+			"/ipfs/QmSKboVigcD3AY4kLsob117KJcMHvMUu6vNFqk1PQzYUpp/denied.jpg\n",
+	), 0644))
+
+	var err error
+	s.denylist, err = denylist.New(denylistConfig)
+	s.Require().NoError(err)
+
 	s.instr = instr.New()
 
 	s.cfg = DefaultConfig()
 
-	s.c = New(s.cfg, s.indexes, s.queues, s.protocol, s.extractor, s.instr)
+	s.c = New(s.cfg, s.indexes, s.queues, s.protocol, s.extractor, s.denylist, s.instr)
 }
 
 func (s *CrawlerTestSuite) assertExpectations() {
@@ -302,6 +317,116 @@ func (s *CrawlerTestSuite) TestCrawlUnreferencedPartialType() {
 	s.assertExpectations()
 }
 
+// TestCrawlConflict crawls a partial which is concurrently indexed elsewhere.
+func (s *CrawlerTestSuite) TestCrawlConflict() {
+	// Prepare resource
+	r := &t.AnnotatedResource{
+		Resource: &t.Resource{
+			Protocol: t.IPFSProtocol,
+			ID:       "QmSKboVigcD3AY4kLsob117KJcMHvMUu6vNFqk1PQzYUpp",
+		},
+		Stat: t.Stat{
+			Type: t.UndefinedType,
+		},
+	}
+
+	s.protocol.
+		On("Stat", mock.Anything, r).
+		Run(func(args mock.Arguments) {
+			r := args.Get(1).(*t.AnnotatedResource)
+			r.Stat = t.Stat{
+				Type: t.PartialType,
+			}
+		}).
+		Return(nil).
+		Once()
+
+	s.assertNotExists(r.Resource.ID)
+
+	s.partialIdx.
+		On("Index", mock.Anything, r.Resource.ID, mock.Anything).
+		Return(index.ErrConflict).
+		Once()
+
+	// Existing item is read again, after the conflict.
+	s.partialIdx.
+		On("Get", mock.Anything, r.Resource.ID, mock.Anything, []string{"references", "last-seen"}).
+		Return(true, nil).
+		Once()
+
+	for _, idx := range []*index.Mock{s.fileIdx, s.dirIdx, s.invalidIdx} {
+		idx.
+			On("Get", mock.Anything, r.Resource.ID, mock.Anything, []string{"references", "last-seen"}).
+			Return(false, nil).
+			Maybe()
+	}
+
+	// Crawl
+	err := s.c.Crawl(s.ctx, r)
+
+	s.NoError(err)
+	s.assertExpectations()
+	s.partialIdx.AssertExpectations(s.T())
+}
+
+// TestCrawlConflictFlushed crawls a partial which turns out to be concurrently indexed elsewhere, once flushed.
+func (s *CrawlerTestSuite) TestCrawlConflictFlushed() {
+	// Prepare resource
+	r := &t.AnnotatedResource{
+		Resource: &t.Resource{
+			Protocol: t.IPFSProtocol,
+			ID:       "QmSKboVigcD3AY4kLsob117KJcMHvMUu6vNFqk1PQzYUpp",
+		},
+		Stat: t.Stat{
+			Type: t.UndefinedType,
+		},
+	}
+
+	s.protocol.
+		On("Stat", mock.Anything, r).
+		Run(func(args mock.Arguments) {
+			r := args.Get(1).(*t.AnnotatedResource)
+			r.Stat = t.Stat{
+				Type: t.PartialType,
+			}
+		}).
+		Return(nil).
+		Once()
+
+	s.assertNotExists(r.Resource.ID)
+
+	s.partialIdx.
+		On("Index", mock.Anything, r.Resource.ID, mock.Anything).
+		Run(func(args mock.Arguments) {
+			done := index.WritesFromContext(args.Get(0).(context.Context)).Add()
+			go done(index.ErrConflict)
+		}).
+		Return(nil).
+		Once()
+
+	// Existing item is read again, after the conflict.
+	s.partialIdx.
+		On("Get", mock.Anything, r.Resource.ID, mock.Anything, []string{"references", "last-seen"}).
+		Return(true, nil).
+		Once()
+
+	for _, idx := range []*index.Mock{s.fileIdx, s.dirIdx, s.invalidIdx} {
+		idx.
+			On("Get", mock.Anything, r.Resource.ID, mock.Anything, []string{"references", "last-seen"}).
+			Return(false, nil).
+			Maybe()
+	}
+
+	// Crawl
+	ctx, writes := index.WithWrites(s.ctx)
+	err := s.c.Crawl(ctx, r)
+
+	s.NoError(err)
+	s.NoError(writes.Wait(ctx))
+	s.assertExpectations()
+	s.partialIdx.AssertExpectations(s.T())
+}
+
 // TestCrawlReferencedPartialType crawls a cached partial, now with a reference.
 func (s *CrawlerTestSuite) TestCrawlReferencedPartialType() {
 	// Prepare resource
@@ -722,6 +847,136 @@ func (s *CrawlerTestSuite) TestCrawlDirectoryType() {
 	s.assertExpectations()
 }
 
+func (s *CrawlerTestSuite) TestCrawlDenied() {
+	// Prepare resource
+	r := &t.AnnotatedResource{
+		Resource: &t.Resource{
+			Protocol: t.IPFSProtocol,
+			ID:       "QmcniBv7UQ4gGPQQW2BwbD4ZZHzN3o3tPuNLZCbBchd1zh",
+		},
+	}
+
+	// Crawl
+	err := s.c.Crawl(s.ctx, r)
+
+	// Denied resources are neither looked up, fetched nor indexed.
+	s.NoError(err)
+	s.assertExpectations()
+}
+
+func (s *CrawlerTestSuite) TestCrawlDeniedReference() {
+	// Prepare resource
+	r := &t.AnnotatedResource{
+		Resource: &t.Resource{
+			Protocol: t.IPFSProtocol,
+			ID:       "QmafrLBfzRLV4XSH1XcaMMeaXEUhDJjmtDfsYU95TrWG87",
+		},
+		Reference: t.Reference{
+			Parent: &t.Resource{
+				Protocol: t.IPFSProtocol,
+				ID:       "QmSKboVigcD3AY4kLsob117KJcMHvMUu6vNFqk1PQzYUpp",
+			},
+			Name: "denied.jpg",
+		},
+		Stat: t.Stat{
+			Type: t.FileType,
+		},
+	}
+
+	// Crawl
+	err := s.c.Crawl(s.ctx, r)
+
+	s.NoError(err)
+	s.assertExpectations()
+}
+
+func (s *CrawlerTestSuite) TestCrawlDirectoryDeniedEntries() {
+	// Prepare resource
+	r := &t.AnnotatedResource{
+		Resource: &t.Resource{
+			Protocol: t.IPFSProtocol,
+			ID:       "QmSKboVigcD3AY4kLsob117KJcMHvMUu6vNFqk1PQzYUpp",
+		},
+		Stat: t.Stat{
+			Type: t.DirectoryType,
+		},
+	}
+
+	fileEntry := t.AnnotatedResource{
+		Resource: &t.Resource{
+			Protocol: t.IPFSProtocol,
+			ID:       "QmafrLBfzRLV4XSH1XcaMMeaXEUhDJjmtDfsYU95TrWG87",
+		},
+		Reference: t.Reference{
+			Parent: r.Resource,
+			Name:   "fileName.pdf",
+		},
+		Stat: t.Stat{
+			Type: t.FileType,
+		},
+	}
+
+	deniedEntry := t.AnnotatedResource{
+		Resource: &t.Resource{
+			Protocol: t.IPFSProtocol,
+			ID:       "QmcniBv7UQ4gGPQQW2BwbD4ZZHzN3o3tPuNLZCbBchd1zh",
+		},
+		Reference: t.Reference{
+			Parent: r.Resource,
+			Name:   "other.pdf",
+		},
+		Stat: t.Stat{
+			Type: t.FileType,
+		},
+	}
+
+	deniedNameEntry := t.AnnotatedResource{
+		Resource: &t.Resource{
+			Protocol: t.IPFSProtocol,
+			ID:       "QmS4ustL54uo8FzR9455qaxZwuMiUhyvMcX9Ba8nUH4uVv",
+		},
+		Reference: t.Reference{
+			Parent: r.Resource,
+			Name:   "denied.jpg",
+		},
+		Stat: t.Stat{
+			Type: t.UnsupportedType,
+		},
+	}
+
+	s.protocol.
+		On("Ls", mock.Anything, r, mock.AnythingOfType("chan<- *types.AnnotatedResource")).
+		Run(func(args mock.Arguments) {
+			entryChan := args.Get(2).(chan<- *t.AnnotatedResource)
+			entryChan <- &fileEntry
+			entryChan <- &deniedEntry
+			entryChan <- &deniedNameEntry
+		}).
+		Return(nil).
+		Once()
+
+	s.dirIdx.
+		On("Index", mock.Anything, r.Resource.ID, mock.IsType(&indexTypes.Directory{})).
+		Return(nil).
+		Once()
+
+	// Only the allowed entry is queued; the denied unsupported entry is not indexed as invalid either.
+	s.fileQ.
+		On("Publish", mock.Anything, mock.MatchedBy(func(f *t.AnnotatedResource) bool {
+			return s.Equal(fileEntry, *f)
+		}), mock.AnythingOfType("uint8")).
+		Return(nil).
+		Once()
+
+	s.assertNotExists(r.Resource.ID)
+
+	// Crawl
+	err := s.c.Crawl(s.ctx, r)
+
+	s.NoError(err)
+	s.assertExpectations()
+}
+
 func (s *CrawlerTestSuite) TestCrawlDirectoryUnexpectedType() {
 	// Prepare resource
 	r := &t.AnnotatedResource{
@@ -770,7 +1025,7 @@ func (s *CrawlerTestSuite) TestCrawlLargeDirectory() {
 	// Override MaxDirSize
 	s.cfg.MaxDirSize = 3
 
-	s.c = New(s.cfg, s.indexes, s.queues, s.protocol, s.extractor, s.instr)
+	s.c = New(s.cfg, s.indexes, s.queues, s.protocol, s.extractor, s.denylist, s.instr)
 
 	// Prepare resource
 	r := &t.AnnotatedResource{
@@ -847,7 +1102,7 @@ func (s *CrawlerTestSuite) TestCrawlDirEntryTimeout() {
 	// Override dir entry timeout
 	s.cfg.DirEntryTimeout = 5 * time.Millisecond
 
-	s.c = New(s.cfg, s.indexes, s.queues, s.protocol, s.extractor, s.instr)
+	s.c = New(s.cfg, s.indexes, s.queues, s.protocol, s.extractor, s.denylist, s.instr)
 
 	entryDelay := 2 * s.cfg.DirEntryTimeout
 
@@ -961,9 +1216,11 @@ func (s *CrawlerTestSuite) TestCrawlUpdateLastSeen() {
 		Maybe()
 
 	s.fileIdx.
-		On("Update", mock.Anything, r.Resource.ID, mock.MatchedBy(func(u *indexTypes.Update) bool {
-			return s.Empty(u.References) &&
-				s.WithinDuration(*u.LastSeen, time.Now(), time.Second)
+		On("Apply", mock.Anything, r.Resource.ID, mock.MatchedBy(func(ops []index.Operation) bool {
+			return len(ops) == 1 &&
+				ops[0].Type == index.MaxOperation &&
+				ops[0].Field == "last-seen" &&
+				time.Since(ops[0].Value.(time.Time)) < 2*time.Second
 		})).
 		Return(nil).
 		Once()
@@ -1064,18 +1321,12 @@ func (s *CrawlerTestSuite) TestCrawlAddReference() {
 		Maybe()
 
 	s.fileIdx.
-		On("Update", mock.Anything, r.Resource.ID, mock.MatchedBy(func(u *indexTypes.Update) bool {
-			return s.ElementsMatch(u.References, indexTypes.References{
-				indexTypes.Reference{
-					ParentHash: "Qmc8mmzycvXnzgwBHokZQd97iWAmtdFMqX4FZUAQ5AQdQi",
-					Name:       "ExistingReference.pdf",
-				},
-				indexTypes.Reference{
-					ParentHash: "QmYAqhbqNDpU7X9VW6FV5imtngQ3oBRY35zuDXduuZnyA8",
-					Name:       "NewReference.pdf",
-				},
-			}) && u.LastSeen == nil
-		})).
+		On("Apply", mock.Anything, r.Resource.ID, []index.Operation{
+			index.AppendIfAbsent("references", &indexTypes.Reference{
+				ParentHash: "QmYAqhbqNDpU7X9VW6FV5imtngQ3oBRY35zuDXduuZnyA8",
+				Name:       "NewReference.pdf",
+			}),
+		}).
 		Return(nil).
 		Once()
 
@@ -1087,6 +1338,59 @@ func (s *CrawlerTestSuite) TestCrawlAddReference() {
 	s.assertExpectations()
 }
 
+// TestCrawlAddReferenceConcreteIndex asserts that references are added in the index an item was found in, e.g. an
+// older index behind an alias, and that items which are missing by then are not considered failed writes.
+func (s *CrawlerTestSuite) TestCrawlAddReferenceConcreteIndex() {
+	r := &t.AnnotatedResource{
+		Resource: &t.Resource{
+			Protocol: t.IPFSProtocol,
+			ID:       "QmSKboVigcD3AY4kLsob117KJcMHvMUu6vNFqk1PQzYUpp",
+		},
+		Source: t.DirectorySource,
+		Reference: t.Reference{
+			Parent: &t.Resource{
+				Protocol: t.IPFSProtocol,
+				ID:       "QmYAqhbqNDpU7X9VW6FV5imtngQ3oBRY35zuDXduuZnyA8",
+			},
+			Name: "NewReference.pdf",
+		},
+	}
+
+	s.fileIdx.
+		On("Get", mock.Anything, r.Resource.ID, mock.Anything, []string{"references", "last-seen"}).
+		Run(func(args mock.Arguments) {
+			u := args.Get(2).(*indexTypes.Update)
+			u.Version = indexTypes.Version{SeqNo: 1, PrimaryTerm: 1, Index: "ipfs_files_v1"}
+		}).
+		Return(true, nil).
+		Once()
+
+	for _, idx := range []*index.Mock{s.dirIdx, s.invalidIdx, s.partialIdx} {
+		idx.
+			On("Get", mock.Anything, r.Resource.ID, mock.Anything, []string{"references", "last-seen"}).
+			Return(false, nil).
+			Maybe()
+	}
+
+	s.fileIdx.
+		On("Apply", mock.MatchedBy(func(ctx context.Context) bool {
+			return index.ConcreteIndexFromContext(ctx) == "ipfs_files_v1"
+		}), r.Resource.ID, mock.Anything).
+		Run(func(args mock.Arguments) {
+			done := index.WritesFromContext(args.Get(0).(context.Context)).Add()
+			go done(index.ErrNotFound)
+		}).
+		Return(nil).
+		Once()
+
+	ctx, writes := index.WithWrites(s.ctx)
+	err := s.c.Crawl(ctx, r)
+
+	s.NoError(err)
+	s.NoError(writes.Wait(ctx))
+	s.assertExpectations()
+}
+
 func (s *CrawlerTestSuite) TestCrawlUpdateGetError() {
 	// Prepare resource
 	r := &t.AnnotatedResource{
@@ -1186,18 +1490,12 @@ func (s *CrawlerTestSuite) TestCrawlUpdateUpdateError() {
 		Maybe()
 
 	s.fileIdx.
-		On("Update", mock.Anything, r.Resource.ID, mock.MatchedBy(func(u *indexTypes.Update) bool {
-			return s.ElementsMatch(u.References, indexTypes.References{
-				indexTypes.Reference{
-					ParentHash: "Qmc8mmzycvXnzgwBHokZQd97iWAmtdFMqX4FZUAQ5AQdQi",
-					Name:       "ExistingReference.pdf",
-				},
-				indexTypes.Reference{
-					ParentHash: "QmYAqhbqNDpU7X9VW6FV5imtngQ3oBRY35zuDXduuZnyA8",
-					Name:       "NewReference.pdf",
-				},
-			}) && u.LastSeen == nil
-		})).
+		On("Apply", mock.Anything, r.Resource.ID, []index.Operation{
+			index.AppendIfAbsent("references", &indexTypes.Reference{
+				ParentHash: "QmYAqhbqNDpU7X9VW6FV5imtngQ3oBRY35zuDXduuZnyA8",
+				Name:       "NewReference.pdf",
+			}),
+		}).
 		Return(testErr).
 		Once()
 
@@ -1316,19 +1614,12 @@ func (s *CrawlerTestSuite) TestCrawlSameReferenceDifferentName() {
 		Maybe()
 
 	s.fileIdx.
-		On("Update", mock.Anything, r.Resource.ID, mock.MatchedBy(func(u *indexTypes.Update) bool {
-			return s.ElementsMatch(u.References, indexTypes.References{
-				indexTypes.Reference{
-					ParentHash: "QmYAqhbqNDpU7X9VW6FV5imtngQ3oBRY35zuDXduuZnyA8",
-					Name:       "NewName.pdf",
-				},
-				indexTypes.Reference{
-					ParentHash: "QmYAqhbqNDpU7X9VW6FV5imtngQ3oBRY35zuDXduuZnyA8",
-					Name:       "NewReference.pdf",
-				},
-			}) &&
-				u.LastSeen == nil
-		})).
+		On("Apply", mock.Anything, r.Resource.ID, []index.Operation{
+			index.AppendIfAbsent("references", &indexTypes.Reference{
+				ParentHash: "QmYAqhbqNDpU7X9VW6FV5imtngQ3oBRY35zuDXduuZnyA8",
+				Name:       "NewReference.pdf",
+			}),
+		}).
 		Return(nil).
 		Once()
 
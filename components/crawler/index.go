@@ -29,7 +29,7 @@ func makeDocument(r *t.AnnotatedResource) indexTypes.Document {
 	now := time.Now().UTC()
 
 	// Strip milliseconds to cater to legacy ES index format.
-	// This can be safely removed after the next reindex with _nomillis removed from time format.
+	// This can be safely removed after all indexes are migrated to mappings with _nomillis removed from time format.
 	now = now.Truncate(time.Second)
 
 	var references []indexTypes.Reference
@@ -2,33 +2,56 @@ package crawler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
 	"go.opentelemetry.io/otel/label"
 
+	"github.com/ipfs-search/ipfs-search/components/index"
 	index_types "github.com/ipfs-search/ipfs-search/components/index/types"
 	t "github.com/ipfs-search/ipfs-search/types"
 )
 
-func appendReference(refs index_types.References, r *t.Reference) (index_types.References, bool) {
+// newReference returns the reference to add to the indexed references refs, if any.
+func newReference(refs index_types.References, r *t.Reference) (*index_types.Reference, bool) {
 	if r.Parent == nil {
 		// No new reference, not updating
-		return refs, false
+		return nil, false
 	}
 
 	for _, indexedRef := range refs {
 		if indexedRef.ParentHash == r.Parent.ID && indexedRef.Name == r.Name {
 			// Existing reference, not updating
-			return refs, false
+			return nil, false
 		}
 	}
 
-	return append(refs, index_types.Reference{
+	return &index_types.Reference{
 		ParentHash: r.Parent.ID,
 		Name:       r.Name,
-	}), true
+	}, true
+}
+
+// apply applies ops to an existing item in the index it was found in, e.g. an older index behind an alias. Items which
+// were removed since, e.g. when taken down, are not updated.
+func (c *Crawler) apply(ctx context.Context, i *existingItem, ops ...index.Operation) error {
+	ctx = index.WithConcreteIndex(ctx, i.Update.Version.Index)
+
+	return index.OnWritten(ctx,
+		func(ctx context.Context) error {
+			return i.Index.Apply(ctx, i.AnnotatedResource.ID, ops...)
+		},
+		func(_ context.Context, err error) error {
+			if errors.Is(err, index.ErrNotFound) {
+				log.Printf("Not updating %s, which is no longer in %s: %s", i.AnnotatedResource, i.Index, err)
+				return nil
+			}
+
+			return err
+		},
+	)
 }
 
 // updateExisting updates known existing items.
@@ -39,7 +62,7 @@ func (c *Crawler) updateExisting(ctx context.Context, i *existingItem) error {
 	switch i.Source {
 	case t.DirectorySource:
 		// Item referenced from a directory, consider updating references (but not last-seen).
-		refs, refsUpdated := appendReference(i.References, &i.AnnotatedResource.Reference)
+		ref, refsUpdated := newReference(i.References, &i.AnnotatedResource.Reference)
 
 		if refsUpdated {
 			span.AddEvent(ctx, "Updating",
@@ -47,18 +70,17 @@ func (c *Crawler) updateExisting(ctx context.Context, i *existingItem) error {
 				label.Any("new-reference", i.AnnotatedResource.Reference),
 			)
 
-			return i.Index.Update(ctx, i.AnnotatedResource.ID, &index_types.Update{
-				References: refs,
-			})
+			// Append atomically, as the same item may concurrently be crawled from other directories.
+			return c.apply(ctx, i, index.AppendIfAbsent("references", ref))
 		}
 
 	case t.SnifferSource, t.UnknownSource:
 		// TODO: Remove UnknownSource after sniffer is updated and queue is flushed.
 		// Item sniffed, conditionally update last-seen.
-		now := time.Now()
+		now := time.Now().UTC()
 
 		// Strip milliseconds to cater to legacy ES index format.
-		// This can be safely removed after the next reindex with _nomillis removed from time format.
+		// This can be safely removed after all indexes are migrated to mappings with _nomillis removed from time format.
 		now = now.Truncate(time.Second)
 
 		var isRecent bool
@@ -76,9 +98,7 @@ func (c *Crawler) updateExisting(ctx context.Context, i *existingItem) error {
 				// label.Stringer("last-seen", i.LastSeen),
 			)
 
-			return i.Index.Update(ctx, i.AnnotatedResource.ID, &index_types.Update{
-				LastSeen: &now,
-			})
+			return c.apply(ctx, i, index.Max("last-seen", now))
 		}
 
 	case t.ManualSource, t.UserSource:
@@ -94,6 +114,29 @@ func (c *Crawler) updateExisting(ctx context.Context, i *existingItem) error {
 	return nil
 }
 
+// maxConflictRetries is the number of times items are re-read and updated after conflicting with concurrent writes.
+const maxConflictRetries = 3
+
+// updateConflicting re-reads and updates an item after writing it conflicted with a concurrent write.
+func (c *Crawler) updateConflicting(ctx context.Context, r *t.AnnotatedResource) error {
+	ctx, span := c.Tracer.Start(ctx, "crawler.updateConflicting")
+	defer span.End()
+
+	err := fmt.Errorf("%w: %s", index.ErrConflict, r.ID)
+
+	for attempt := 0; attempt < maxConflictRetries && errors.Is(err, index.ErrConflict); attempt++ {
+		var exists bool
+
+		exists, err = c.updateMaybeExisting(ctx, r)
+		if err == nil && !exists {
+			// Not (yet) readable or a referenced partial; retry.
+			err = fmt.Errorf("%w: %s not found after conflicting write", index.ErrConflict, r.ID)
+		}
+	}
+
+	return err
+}
+
 // deletePartial deletes partial items.
 func (c *Crawler) deletePartial(ctx context.Context, i *existingItem) error {
 	return c.indexes.Partials.Delete(ctx, i.ID)
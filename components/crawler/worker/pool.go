@@ -3,6 +3,7 @@ package worker
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net"
@@ -15,9 +16,13 @@ import (
 	"go.opentelemetry.io/otel/codes"
 
 	"github.com/ipfs-search/ipfs-search/components/crawler"
+	"github.com/ipfs-search/ipfs-search/components/denylist"
+	"github.com/ipfs-search/ipfs-search/components/extractor/native"
 	"github.com/ipfs-search/ipfs-search/components/extractor/tika"
-	"github.com/ipfs-search/ipfs-search/components/index/elasticsearch"
+	"github.com/ipfs-search/ipfs-search/components/index"
+	"github.com/ipfs-search/ipfs-search/components/index/factory"
 	"github.com/ipfs-search/ipfs-search/components/protocol/ipfs"
+	"github.com/ipfs-search/ipfs-search/components/queue"
 	"github.com/ipfs-search/ipfs-search/components/queue/amqp"
 
 	"github.com/ipfs-search/ipfs-search/config"
@@ -35,7 +40,9 @@ type Pool struct {
 		Directories <-chan samqp.Delivery
 		Hashes      <-chan samqp.Delivery
 	}
+	factory *factory.Factory
 	crawler *crawler.Crawler
+	failed  queue.Publisher // Resources for which index writes failed after crawling.
 
 	*instr.Instrumentation
 }
@@ -66,70 +73,54 @@ func (w *Pool) makeCrawler(ctx context.Context) error {
 	// Limited Tika connections (as resources are generally known to be available by now)
 	tikaTransport := utils.GetHTTPTransport(w.dialer.DialContext, 100)
 	tikaClient := &http.Client{Transport: tikaTransport}
-	extractor := tika.New(w.config.TikaConfig(), tikaClient, protocol, w.Instrumentation)
+	tikaExtractor := tika.New(w.config.TikaConfig(), tikaClient, protocol, w.Instrumentation)
 
-	w.crawler = crawler.New(w.config.CrawlerConfig(), indexes, queues, protocol, extractor, w.Instrumentation)
+	// Common formats are extracted natively, fetching from the gateway; others are left to Tika.
+	extractor := native.New(w.config.NativeExtractorConfig(), ipfsClient, protocol, tikaExtractor, w.Instrumentation)
 
-	return nil
-}
+	denylistConfig := w.config.DenylistConfig()
+	denylist, err := denylist.New(denylistConfig)
+	if err != nil {
+		return err
+	}
 
-func (w *Pool) getSearchClient() (*elasticsearch.Client, error) {
-	clientConfig := &elasticsearch.ClientConfig{
-		URL:       w.config.ElasticSearch.URL,
-		Transport: utils.GetHTTPTransport(w.dialer.DialContext, 100),
-		Debug:     false,
+	go denylist.Watch(ctx, denylistConfig.Reload)
 
-		BulkIndexerWorkers:     w.config.ElasticSearch.BulkIndexerWorkers,
-		BulkIndexerFlushBytes:  int(w.config.ElasticSearch.BulkIndexerFlushBytes),
-		BulkGetterBatchSize:    w.config.ElasticSearch.BulkGetterBatchSize,
-		BulkGetterBatchTimeout: w.config.ElasticSearch.BulkGetterBatchTimeout,
-	}
+	w.crawler = crawler.New(w.config.CrawlerConfig(), indexes, queues, protocol, extractor, denylist, w.Instrumentation)
 
-	return elasticsearch.NewClient(clientConfig, w.Instrumentation)
+	return nil
 }
 
-func startSearchWorker(ctx context.Context, esClient *elasticsearch.Client) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			if err := esClient.Work(ctx); err != nil {
-				log.Printf("Error in ES client worker, restarting worker: %s", err)
-				// Prevent overly tight restart loop
-				time.Sleep(time.Second)
-			}
+func (w *Pool) getIndexes(ctx context.Context) (*crawler.Indexes, error) {
+	indexes := new(crawler.Indexes)
+
+	for _, i := range []struct {
+		idx *index.Index
+		cfg config.Index
+	}{
+		{&indexes.Files, w.config.Indexes.Files},
+		{&indexes.Directories, w.config.Indexes.Directories},
+		{&indexes.Invalids, w.config.Indexes.Invalids},
+		{&indexes.Partials, w.config.Indexes.Partials},
+	} {
+		var err error
+
+		if *i.idx, err = w.factory.Index(i.cfg.IndexConfig()); err != nil {
+			return nil, err
 		}
 	}
+
+	return indexes, nil
 }
 
-func (w *Pool) getIndexes(ctx context.Context) (*crawler.Indexes, error) {
-	esClient, err := w.getSearchClient()
-	if err != nil {
-		return nil, err
+// prefetch returns the number of unacknowledged deliveries for a queue with workers. When acknowledging after
+// flushing, deliveries awaiting flushes are unacknowledged while workers crawl further deliveries.
+func (w *Pool) prefetch(workers int) int {
+	if w.config.Workers.AckMode == config.AckFlushed {
+		return workers + w.config.Workers.MaxPending
 	}
 
-	// Start ES workers
-	go startSearchWorker(ctx, esClient)
-
-	return &crawler.Indexes{
-		Files: elasticsearch.New(
-			esClient,
-			&elasticsearch.Config{Name: w.config.Indexes.Files.Name},
-		),
-		Directories: elasticsearch.New(
-			esClient,
-			&elasticsearch.Config{Name: w.config.Indexes.Directories.Name},
-		),
-		Invalids: elasticsearch.New(
-			esClient,
-			&elasticsearch.Config{Name: w.config.Indexes.Invalids.Name},
-		),
-		Partials: elasticsearch.New(
-			esClient,
-			&elasticsearch.Config{Name: w.config.Indexes.Partials.Name},
-		),
-	}, nil
+	return workers
 }
 
 func (w *Pool) getQueues(ctx context.Context) (*crawler.Queues, error) {
@@ -144,21 +135,25 @@ func (w *Pool) getQueues(ctx context.Context) (*crawler.Queues, error) {
 	}
 
 	log.Println("Creating AMQP channels.")
-	fq, err := amqpConnection.NewChannelQueue(ctx, w.config.Queues.Files.Name, w.config.Workers.FileWorkers)
+	fq, err := amqpConnection.NewChannelQueue(ctx, w.config.Queues.Files.Name, w.prefetch(w.config.Workers.FileWorkers))
 	if err != nil {
 		return nil, err
 	}
 
-	dq, err := amqpConnection.NewChannelQueue(ctx, w.config.Queues.Directories.Name, w.config.Workers.DirectoryWorkers)
+	dq, err := amqpConnection.NewChannelQueue(ctx, w.config.Queues.Directories.Name, w.prefetch(w.config.Workers.DirectoryWorkers))
 	if err != nil {
 		return nil, err
 	}
 
-	hq, err := amqpConnection.NewChannelQueue(ctx, w.config.Queues.Hashes.Name, w.config.Workers.HashWorkers)
+	hq, err := amqpConnection.NewChannelQueue(ctx, w.config.Queues.Hashes.Name, w.prefetch(w.config.Workers.HashWorkers))
 	if err != nil {
 		return nil, err
 	}
 
+	if w.failed, err = amqpConnection.NewChannelQueue(ctx, w.config.Queues.Failed.Name, 1); err != nil {
+		return nil, err
+	}
+
 	return &crawler.Queues{
 		Files:       fq,
 		Directories: dq,
@@ -166,7 +161,7 @@ func (w *Pool) getQueues(ctx context.Context) (*crawler.Queues, error) {
 	}, nil
 }
 
-func (w *Pool) crawlDelivery(ctx context.Context, d samqp.Delivery) error {
+func (w *Pool) crawlDelivery(ctx context.Context, d samqp.Delivery) (*t.AnnotatedResource, *index.Writes, error) {
 	// TODO: Get SpanContext from Delivery.
 	// ctx = trace.ContextWithRemoteSpanContext(ctx, p.SpanContext)
 	ctx, span := w.Tracer.Start(ctx, "crawler.worker.crawlDelivery", trace.WithNewRoot())
@@ -178,15 +173,18 @@ func (w *Pool) crawlDelivery(ctx context.Context, d samqp.Delivery) error {
 
 	if err := json.Unmarshal(d.Body, r); err != nil {
 		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
-		return err
+		return nil, nil, err
 	}
 
 	if !r.IsValid() {
 		err := fmt.Errorf("Invalid resource: %v", r)
 		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
-		return err
+		return nil, nil, err
 	}
 
+	// Track index writes which complete after crawling, e.g. when bulk indexers flush.
+	ctx, writes := index.WithWrites(ctx)
+
 	log.Printf("Crawling '%s'", r)
 	err := w.crawler.Crawl(ctx, r)
 	log.Printf("Done crawling '%s', result: %v", r, err)
@@ -195,9 +193,108 @@ func (w *Pool) crawlDelivery(ctx context.Context, d samqp.Delivery) error {
 		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
 	}
 
+	return r, writes, err
+}
+
+// awaitWrites waits for index writes to complete, returning an error when any of them failed or when they were not
+// confirmed within the configured timeout, e.g. because a bulk request failed as a whole.
+func (w *Pool) awaitWrites(ctx context.Context, writes *index.Writes) error {
+	waitCtx, cancel := context.WithTimeout(ctx, w.config.Workers.AckTimeout)
+	defer cancel()
+
+	err := writes.Wait(waitCtx)
+	if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+		err = fmt.Errorf("index writes not confirmed within %s", w.config.Workers.AckTimeout)
+	}
+
 	return err
 }
 
+// publishFailed publishes r to the failed queue, so that it can be inspected or crawled again rather than being lost.
+func (w *Pool) publishFailed(ctx context.Context, r *t.AnnotatedResource, writeErr error) error {
+	ctx, span := w.Tracer.Start(ctx, "crawler.worker.publishFailed")
+	defer span.End()
+
+	span.RecordError(ctx, writeErr, trace.WithErrorStatus(codes.Error))
+	log.Printf("Index writes for '%s' failed, publishing to failed queue: %s", r, writeErr)
+
+	err := w.failed.Publish(ctx, r, 0)
+	if err != nil {
+		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+		log.Printf("Error publishing '%s' to failed queue: %s", r, err)
+	}
+
+	return err
+}
+
+// ackCrawled acknowledges a crawled delivery right away, publishing its resource to the failed queue when index
+// writes fail afterwards.
+func (w *Pool) ackCrawled(ctx context.Context, d samqp.Delivery, r *t.AnnotatedResource, writes *index.Writes) error {
+	go func() {
+		if err := w.awaitWrites(ctx, writes); err != nil && ctx.Err() == nil {
+			w.publishFailed(ctx, r, err)
+		}
+	}()
+
+	return d.Ack(false)
+}
+
+// ackFlushed acknowledges a crawled delivery once its index writes are confirmed. When writes fail, the delivery is
+// requeued to be crawled again; when they fail again on redelivery, its resource is published to the failed queue.
+func (w *Pool) ackFlushed(ctx context.Context, d samqp.Delivery, r *t.AnnotatedResource, writes *index.Writes) error {
+	err := w.awaitWrites(ctx, writes)
+
+	if ctx.Err() != nil {
+		// Unacknowledged deliveries are requeued by the broker when the channel closes.
+		return nil
+	}
+
+	if err == nil {
+		return d.Ack(false)
+	}
+
+	if d.Redelivered {
+		if w.publishFailed(ctx, r, err) == nil {
+			return d.Ack(false)
+		}
+	} else {
+		log.Printf("Index writes for '%s' failed, requeueing: %s", r, err)
+	}
+
+	return d.Nack(false, true)
+}
+
+func (w *Pool) handleDelivery(ctx context.Context, span trace.Span, d samqp.Delivery) {
+	r, writes, err := w.crawlDelivery(ctx, d)
+	if err != nil {
+		// By default, do not retry.
+		shouldRetry := false
+
+		span.RecordError(ctx, err)
+
+		if err := d.Reject(shouldRetry); err != nil {
+			span.RecordError(ctx, err)
+		}
+
+		return
+	}
+
+	if w.config.Workers.AckMode == config.AckFlushed {
+		// Crawl next deliveries while waiting for index writes.
+		go func() {
+			if err := w.ackFlushed(ctx, d, r, writes); err != nil {
+				span.RecordError(ctx, err)
+			}
+		}()
+
+		return
+	}
+
+	if err := w.ackCrawled(ctx, d, r, writes); err != nil {
+		span.RecordError(ctx, err)
+	}
+}
+
 func (w *Pool) startWorker(ctx context.Context, deliveries <-chan samqp.Delivery, name string) {
 	ctx, span := w.Tracer.Start(ctx, "crawler.worker.startWorker")
 	defer span.End()
@@ -211,20 +308,8 @@ func (w *Pool) startWorker(ctx context.Context, deliveries <-chan samqp.Delivery
 				// This is a fatal error; it should never happen - crash the program!
 				panic("unexpected channel close")
 			}
-			if err := w.crawlDelivery(ctx, d); err != nil {
-				// By default, do not retry.
-				shouldRetry := false
 
-				span.RecordError(ctx, err)
-
-				if err := d.Reject(shouldRetry); err != nil {
-					span.RecordError(ctx, err)
-				}
-			} else {
-				if err := d.Ack(false); err != nil {
-					span.RecordError(ctx, err)
-				}
-			}
+			w.handleDelivery(ctx, span, d)
 		}
 	}
 }
@@ -280,6 +365,12 @@ func (w *Pool) makeConsumeChans(ctx context.Context) error {
 }
 
 func (w *Pool) init(ctx context.Context) error {
+	switch w.config.Workers.AckMode {
+	case config.AckCrawled, config.AckFlushed:
+	default:
+		return fmt.Errorf("unknown ack mode: %s", w.config.Workers.AckMode)
+	}
+
 	w.dialer = &utils.RetryingDialer{
 		Dialer: net.Dialer{
 			Timeout:   30 * time.Second,
@@ -298,10 +389,16 @@ func (w *Pool) init(ctx context.Context) error {
 	return w.makeConsumeChans(ctx)
 }
 
-// NewPool initializes and returns a new worker pool.
-func NewPool(ctx context.Context, c *config.Config, i *instr.Instrumentation) (*Pool, error) {
+// NewPool initializes and returns a new worker pool, getting indexes from f. The background work of f should be
+// started after initializing the pool.
+func NewPool(ctx context.Context, c *config.Config, f *factory.Factory, i *instr.Instrumentation) (*Pool, error) {
+	if f == nil {
+		panic("NewPool Factory cannot be nil.")
+	}
+
 	w := &Pool{
 		config:          c,
+		factory:         f,
 		Instrumentation: i,
 	}
 
@@ -0,0 +1,19 @@
+package denylist
+
+import (
+	"time"
+)
+
+// Config holds configuration for a Denylist.
+type Config struct {
+	File   string        // File to read the denylist from; a missing file denies nothing
+	Reload time.Duration // Interval between checks of the file for changes
+}
+
+// DefaultConfig returns the default configuration for a Denylist.
+func DefaultConfig() *Config {
+	return &Config{
+		File:   "badbits.deny",
+		Reload: time.Minute,
+	}
+}
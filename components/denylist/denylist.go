@@ -0,0 +1,289 @@
+/*
+Package denylist keeps known-abusive content out of the index, based on lists in the format used by the IPFS "badbits"
+denylist.
+
+Lists consist of one entry per line, which can be:
+
+	//<hex sha256>       A double-hashed entry, the sha256 of `<CIDv1 base32>/<path>`, as in badbits.
+	<CID>                A plain CID, matching both CIDv0 and CIDv1 with the same multihash.
+	/ipfs/<CID>          Idem.
+	/ipfs/<CID>/<path>   A named entry in a directory, matching the directory's direct children.
+
+Empty lines and lines starting with `#` are ignored, as is a header ending with a line `---`. Other lines (e.g. /ipns/
+entries or negations) are not supported and skipped.
+*/
+package denylist
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+
+	t "github.com/ipfs-search/ipfs-search/types"
+)
+
+// entries holds the parsed entries of a denylist.
+type entries struct {
+	hashes  map[[sha256.Size]byte]struct{} // Double-hashed entries.
+	cids    map[string]struct{}            // Multihashes of CIDs.
+	paths   map[string]struct{}            // Multihashes of parent CIDs, followed by `/<name>`.
+	skipped int                            // Unsupported lines.
+}
+
+func newEntries() *entries {
+	return &entries{
+		hashes: make(map[[sha256.Size]byte]struct{}),
+		cids:   make(map[string]struct{}),
+		paths:  make(map[string]struct{}),
+	}
+}
+
+func (e *entries) len() int {
+	return len(e.hashes) + len(e.cids) + len(e.paths)
+}
+
+// add adds an entry from a single line, returning false when the line is not supported.
+func (e *entries) add(line string) bool {
+	if strings.HasPrefix(line, "//") {
+		b, err := hex.DecodeString(line[2:])
+		if err != nil || len(b) != sha256.Size {
+			return false
+		}
+
+		var h [sha256.Size]byte
+		copy(h[:], b)
+		e.hashes[h] = struct{}{}
+
+		return true
+	}
+
+	line = strings.TrimPrefix(line, "/ipfs/")
+	parts := strings.SplitN(strings.TrimRight(line, "/"), "/", 2)
+
+	c, err := cid.Decode(parts[0])
+	if err != nil {
+		return false
+	}
+
+	if len(parts) == 1 {
+		e.cids[string(c.Hash())] = struct{}{}
+	} else {
+		e.paths[string(c.Hash())+"/"+parts[1]] = struct{}{}
+	}
+
+	return true
+}
+
+// parse reads entries from a denylist.
+func parse(r io.Reader) (*entries, error) {
+	e := newEntries()
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case line == "---":
+			// Anything before is a header.
+			e = newEntries()
+		case !e.add(line):
+			e.skipped++
+		}
+	}
+
+	return e, scanner.Err()
+}
+
+// doubleHash returns the sha256 of a CID's base32 CIDv1 representation and a path, as used in double-hashed entries.
+func doubleHash(c cid.Cid, path string) [sha256.Size]byte {
+	v1 := cid.NewCidV1(c.Type(), c.Hash())
+	return sha256.Sum256([]byte(v1.String() + "/" + path))
+}
+
+// Denylist determines whether resources are denied, based on entries read from a file.
+// A missing file results in an empty list; Watch() reloads the list when the file changes.
+//
+// A nil *Denylist denies nothing.
+type Denylist struct {
+	path string
+
+	mu      sync.RWMutex
+	entries *entries
+	modTime time.Time
+	size    int64
+}
+
+// New returns a pointer to a new Denylist read from the configured file, or an error when it could not be read.
+func New(cfg *Config) (*Denylist, error) {
+	if cfg == nil {
+		panic("denylist.New Config cannot be nil.")
+	}
+
+	d := &Denylist{
+		path:    cfg.File,
+		entries: newEntries(),
+	}
+
+	if _, err := d.Reload(); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Reload reads the list when the file has changed since the last load, returning whether it did.
+func (d *Denylist) Reload() (bool, error) {
+	file, err := os.Open(d.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			d.set(newEntries(), time.Time{}, 0)
+			return false, nil
+		}
+
+		return false, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	d.mu.RLock()
+	unchanged := info.ModTime().Equal(d.modTime) && info.Size() == d.size
+	d.mu.RUnlock()
+
+	if unchanged {
+		return false, nil
+	}
+
+	e, err := parse(file)
+	if err != nil {
+		return false, fmt.Errorf("reading %s: %w", d.path, err)
+	}
+
+	d.set(e, info.ModTime(), info.Size())
+
+	log.Printf("Loaded denylist %s: %d entries, %d unsupported lines skipped", d.path, e.len(), e.skipped)
+
+	return true, nil
+}
+
+func (d *Denylist) set(e *entries, modTime time.Time, size int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.entries = e
+	d.modTime = modTime
+	d.size = size
+}
+
+// Watch reloads the list every interval until the context is closed. On errors, the previous list is retained.
+func (d *Denylist) Watch(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if _, err := d.Reload(); err != nil {
+				log.Printf("Error reloading denylist: %s", err)
+			}
+		}
+	}
+}
+
+// Denied returns true when the resource, or its name within the referring directory, is on the list.
+// Resources with an invalid CID are not denied.
+func (d *Denylist) Denied(r *t.AnnotatedResource) bool {
+	if d.DeniedResource(r.Resource) {
+		return true
+	}
+
+	if d == nil || r.Reference.Parent == nil || r.Reference.Name == "" {
+		return false
+	}
+
+	parent, err := cid.Decode(r.Reference.Parent.ID)
+	if err != nil {
+		return false
+	}
+
+	e := d.get()
+
+	if _, denied := e.paths[string(parent.Hash())+"/"+r.Reference.Name]; denied {
+		return true
+	}
+
+	_, denied := e.hashes[doubleHash(parent, r.Reference.Name)]
+	return denied
+}
+
+// DeniedResource returns true when the resource is on the list, regardless of references to it.
+func (d *Denylist) DeniedResource(r *t.Resource) bool {
+	if d == nil || r.Protocol != t.IPFSProtocol {
+		return false
+	}
+
+	c, err := cid.Decode(r.ID)
+	if err != nil {
+		return false
+	}
+
+	e := d.get()
+
+	if _, denied := e.cids[string(c.Hash())]; denied {
+		return true
+	}
+
+	_, denied := e.hashes[doubleHash(c, "")]
+	return denied
+}
+
+func (d *Denylist) get() *entries {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.entries
+}
+
+// Append adds CIDs, preceded by a comment, to the denylist file at path, creating it when it doesn't exist.
+// A running Denylist picks them up on its next reload.
+func Append(path string, comment string, cids ...string) error {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(file)
+
+	if comment != "" {
+		fmt.Fprintf(w, "# %s\n", comment)
+	}
+
+	for _, c := range cids {
+		fmt.Fprintf(w, "/ipfs/%s\n", c)
+	}
+
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,187 @@
+package denylist
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	t "github.com/ipfs-search/ipfs-search/types"
+)
+
+const (
+	testCID   = "QmSKboVigcD3AY4kLsob117KJcMHvMUu6vNFqk1PQzYUpp"
+	testCIDv1 = "bafybeib3fhqt3vu532sfyu4qnjmmpxdbjl7cyzemznkyih2vhanm6k3w5e"
+	parentCID = "QmbWqxBEKC3P8tqsKc98xmWNzrzDtRLMiMPL8wBuTGsMnR"
+	otherCID  = "QmcniBv7UQ4gGPQQW2BwbD4ZZHzN3o3tPuNLZCbBchd1zh"
+
+	// sha256 of "<testCIDv1>/"
+	testHash = "c66123c3d5bd419adb9b3e2b39a6f1cbfab6fa5ad949888d468b53d03a4bd6d5"
+	// sha256 of "<parentCIDv1>/bad.jpg"
+	testPathHash = "a5c02bc9432f9cd45dfca026cb47ecbb76a4086bd1c436033dd6729c2b4ac53d"
+)
+
+func writeList(tt *testing.T, lines ...string) *Config {
+	cfg := DefaultConfig()
+	cfg.File = filepath.Join(tt.TempDir(), "denylist")
+	require.NoError(tt, ioutil.WriteFile(cfg.File, []byte(strings.Join(lines, "\n")), 0644))
+
+	return cfg
+}
+
+func resource(id string) *t.AnnotatedResource {
+	return &t.AnnotatedResource{
+		Resource: &t.Resource{Protocol: t.IPFSProtocol, ID: id},
+	}
+}
+
+func referenced(id, parent, name string) *t.AnnotatedResource {
+	r := resource(id)
+	r.Reference = t.Reference{
+		Parent: &t.Resource{Protocol: t.IPFSProtocol, ID: parent},
+		Name:   name,
+	}
+
+	return r
+}
+
+func TestParse(tt *testing.T) {
+	assert := assert.New(tt)
+
+	e, err := parse(strings.NewReader(`
+version: 1
+name: badbits
+---
+# Comment
+//` + testHash + `
+` + testCID + `
+/ipfs/` + parentCID + `/bad.jpg
+/ipns/example.com
+!/ipfs/` + otherCID + `
+`))
+
+	assert.NoError(err)
+	assert.Len(e.hashes, 1)
+	assert.Len(e.cids, 1)
+	assert.Len(e.paths, 1)
+	assert.Equal(2, e.skipped)
+}
+
+func TestMissingFile(tt *testing.T) {
+	cfg := DefaultConfig()
+	cfg.File = filepath.Join(tt.TempDir(), "missing")
+
+	d, err := New(cfg)
+	require.NoError(tt, err)
+
+	assert.False(tt, d.Denied(resource(testCID)))
+}
+
+func TestNilDenylist(tt *testing.T) {
+	var d *Denylist
+
+	assert.False(tt, d.Denied(referenced(testCID, parentCID, "bad.jpg")))
+}
+
+func TestDeniedDoubleHash(tt *testing.T) {
+	assert := assert.New(tt)
+
+	d, err := New(writeList(tt, "//"+testHash))
+	require.NoError(tt, err)
+
+	assert.True(d.Denied(resource(testCID)))
+	assert.True(d.Denied(resource(testCIDv1)))
+	assert.False(d.Denied(resource(otherCID)))
+}
+
+func TestDeniedDoubleHashPath(tt *testing.T) {
+	assert := assert.New(tt)
+
+	d, err := New(writeList(tt, "//"+testPathHash))
+	require.NoError(tt, err)
+
+	assert.True(d.Denied(referenced(otherCID, parentCID, "bad.jpg")))
+	assert.False(d.Denied(referenced(otherCID, parentCID, "good.jpg")))
+	assert.False(d.Denied(resource(otherCID)))
+}
+
+func TestDeniedCID(tt *testing.T) {
+	assert := assert.New(tt)
+
+	d, err := New(writeList(tt, testCIDv1))
+	require.NoError(tt, err)
+
+	assert.True(d.Denied(resource(testCID)))
+	assert.True(d.DeniedResource(resource(testCID).Resource))
+	assert.False(d.Denied(resource(otherCID)))
+}
+
+func TestDeniedPath(tt *testing.T) {
+	assert := assert.New(tt)
+
+	d, err := New(writeList(tt, "/ipfs/"+parentCID+"/bad.jpg"))
+	require.NoError(tt, err)
+
+	assert.True(d.Denied(referenced(otherCID, parentCID, "bad.jpg")))
+	assert.False(d.Denied(referenced(otherCID, parentCID, "good.jpg")))
+	assert.False(d.Denied(resource(parentCID)))
+}
+
+func TestDeniedInvalidCID(tt *testing.T) {
+	d, err := New(writeList(tt, testCID))
+	require.NoError(tt, err)
+
+	assert.False(tt, d.Denied(referenced("invalid", "invalid", "bad.jpg")))
+}
+
+func TestReload(tt *testing.T) {
+	assert := assert.New(tt)
+
+	cfg := writeList(tt, testCID)
+
+	d, err := New(cfg)
+	require.NoError(tt, err)
+
+	reloaded, err := d.Reload()
+	assert.NoError(err)
+	assert.False(reloaded)
+
+	require.NoError(tt, ioutil.WriteFile(cfg.File, []byte(otherCID+"\n"), 0644))
+
+	reloaded, err = d.Reload()
+	assert.NoError(err)
+	assert.True(reloaded)
+
+	assert.False(d.Denied(resource(testCID)))
+	assert.True(d.Denied(resource(otherCID)))
+}
+
+func TestAppend(tt *testing.T) {
+	assert := assert.New(tt)
+
+	cfg := writeList(tt, testCID+"\n")
+
+	require.NoError(tt, Append(cfg.File, "Takedown", otherCID))
+
+	d, err := New(cfg)
+	require.NoError(tt, err)
+
+	assert.True(d.Denied(resource(testCID)))
+	assert.True(d.Denied(resource(otherCID)))
+	assert.False(d.Denied(resource(parentCID)))
+}
+
+func TestAppendNewFile(tt *testing.T) {
+	cfg := DefaultConfig()
+	cfg.File = filepath.Join(tt.TempDir(), "denylist")
+
+	require.NoError(tt, Append(cfg.File, "", testCID))
+
+	d, err := New(cfg)
+	require.NoError(tt, err)
+
+	assert.True(tt, d.Denied(resource(testCID)))
+}
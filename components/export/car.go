@@ -0,0 +1,262 @@
+package export
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/ipfs/go-cid"
+	ipld "github.com/ipfs/go-ipld-format"
+	merkledag "github.com/ipfs/go-merkledag"
+	unixfs "github.com/ipfs/go-unixfs"
+	unixfs_pb "github.com/ipfs/go-unixfs/pb"
+	mh "github.com/multiformats/go-multihash"
+)
+
+const (
+	chunkSize    = 256 * 1024  // Size of file chunks, like the default of `ipfs add`.
+	maxLinks     = 174         // Maximum number of links of file nodes, like `ipfs add`.
+	maxBlockSize = 1024 * 1024 // Maximum size of blocks exchanged by IPFS nodes.
+)
+
+// ErrTooManyFiles is returned when packing more files than fit into a single directory block.
+var ErrTooManyFiles = errors.New("too many files for a directory")
+
+// rawPrefix is the prefix of CID's of file chunks.
+var rawPrefix = cid.Prefix{Version: 1, Codec: cid.Raw, MhType: mh.SHA2_256, MhLength: -1}
+
+// dagNode is a node of a UnixFS DAG being built.
+type dagNode struct {
+	cid      cid.Cid
+	size     uint64 // Size of the DAG below, including the node.
+	fileSize uint64 // Size of the file data below.
+}
+
+// carWriter writes blocks to a CARv1 file, see https://ipld.io/specs/transport/car/carv1/.
+type carWriter struct {
+	w io.Writer
+}
+
+// writeBlock writes a section holding a block.
+func (c *carWriter) writeBlock(id cid.Cid, data []byte) error {
+	b := id.Bytes()
+
+	prefix := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(prefix, uint64(len(b)+len(data)))
+
+	for _, p := range [][]byte{prefix[:n], b, data} {
+		if _, err := c.w.Write(p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeHeader writes the header, with root as the only root.
+func (c *carWriter) writeHeader(root cid.Cid) error {
+	// DAG-CBOR encoding of {"roots": [root], "version": 1}. CID's are encoded as byte strings with tag 42, prefixed by
+	// a zero byte for the identity multibase.
+	id := append([]byte{0}, root.Bytes()...)
+
+	h := []byte{0xa2, 0x65}
+	h = append(h, "roots"...)
+	h = append(h, 0x81, 0xd8, 0x2a)
+
+	switch {
+	case len(id) < 24:
+		h = append(h, 0x40|byte(len(id)))
+	default:
+		h = append(h, 0x58, byte(len(id)))
+	}
+
+	h = append(h, id...)
+	h = append(h, 0x67)
+	h = append(h, "version"...)
+	h = append(h, 0x01)
+
+	prefix := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(prefix, uint64(len(h)))
+
+	if _, err := c.w.Write(prefix[:n]); err != nil {
+		return err
+	}
+
+	_, err := c.w.Write(h)
+
+	return err
+}
+
+// writeProtoNode writes a dag-pb node, returning it as a dagNode.
+func (c *carWriter) writeProtoNode(n *merkledag.ProtoNode, fileSize uint64) (*dagNode, error) {
+	n.SetCidBuilder(merkledag.V1CidPrefix())
+
+	size, err := n.Size()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.writeBlock(n.Cid(), n.RawData()); err != nil {
+		return nil, err
+	}
+
+	return &dagNode{cid: n.Cid(), size: size, fileSize: fileSize}, nil
+}
+
+// writeFile writes the file at path as a balanced UnixFS DAG with raw leaves, like `ipfs add --cid-version 1`.
+func (c *carWriter) writeFile(path string) (*dagNode, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var nodes []*dagNode
+
+	chunk := make([]byte, chunkSize)
+
+	for {
+		n, err := io.ReadFull(f, chunk)
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return nil, err
+		}
+
+		id, err := rawPrefix.Sum(chunk[:n])
+		if err != nil {
+			return nil, err
+		}
+
+		if err := c.writeBlock(id, chunk[:n]); err != nil {
+			return nil, err
+		}
+
+		nodes = append(nodes, &dagNode{cid: id, size: uint64(n), fileSize: uint64(n)})
+	}
+
+	if len(nodes) == 0 {
+		return c.writeProtoNode(merkledag.NodeWithData(unixfs.FilePBData(nil, 0)), 0)
+	}
+
+	for len(nodes) > 1 {
+		var parents []*dagNode
+
+		for start := 0; start < len(nodes); start += maxLinks {
+			end := start + maxLinks
+			if end > len(nodes) {
+				end = len(nodes)
+			}
+
+			fsn := unixfs.NewFSNode(unixfs_pb.Data_File)
+			n := new(merkledag.ProtoNode)
+
+			var fileSize uint64
+
+			for _, child := range nodes[start:end] {
+				fsn.AddBlockSize(child.fileSize)
+				fileSize += child.fileSize
+
+				if err := n.AddRawLink("", &ipld.Link{Size: child.size, Cid: child.cid}); err != nil {
+					return nil, err
+				}
+			}
+
+			data, err := fsn.GetBytes()
+			if err != nil {
+				return nil, err
+			}
+
+			n.SetData(data)
+
+			parent, err := c.writeProtoNode(n, fileSize)
+			if err != nil {
+				return nil, err
+			}
+
+			parents = append(parents, parent)
+		}
+
+		nodes = parents
+	}
+
+	return nodes[0], nil
+}
+
+// WriteCAR writes a CARv1 file to w, holding a UnixFS directory with the given files in dir, returning the CID of the
+// directory. As the directory is a single block, the number of files is limited to some ten thousands.
+func WriteCAR(w io.WriteSeeker, dir string, names []string) (cid.Cid, error) {
+	c := &carWriter{w: w}
+
+	// The header is written again once the root is known; CID's of directories have a constant length.
+	placeholder := merkledag.NodeWithData(unixfs.FolderPBData())
+	placeholder.SetCidBuilder(merkledag.V1CidPrefix())
+
+	if err := c.writeHeader(placeholder.Cid()); err != nil {
+		return cid.Undef, err
+	}
+
+	names = append([]string(nil), names...)
+	sort.Strings(names)
+
+	root := merkledag.NodeWithData(unixfs.FolderPBData())
+
+	for _, name := range names {
+		file, err := c.writeFile(filepath.Join(dir, name))
+		if err != nil {
+			return cid.Undef, fmt.Errorf("packing %s: %w", name, err)
+		}
+
+		if err := root.AddRawLink(name, &ipld.Link{Size: file.size, Cid: file.cid}); err != nil {
+			return cid.Undef, err
+		}
+	}
+
+	root.SetCidBuilder(merkledag.V1CidPrefix())
+
+	if len(root.RawData()) > maxBlockSize {
+		return cid.Undef, fmt.Errorf("%w: %d files", ErrTooManyFiles, len(names))
+	}
+
+	dirNode, err := c.writeProtoNode(root, 0)
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	if _, err := w.Seek(0, io.SeekStart); err != nil {
+		return cid.Undef, err
+	}
+
+	if err := c.writeHeader(dirNode.cid); err != nil {
+		return cid.Undef, err
+	}
+
+	return dirNode.cid, nil
+}
+
+// Pack writes the dump in dir, described by m, to a CAR file at path, returning the CID of its root directory.
+func Pack(path, dir string, m *Manifest) (cid.Cid, error) {
+	names := []string{ManifestName}
+	for _, s := range m.Shards {
+		names = append(names, s.Name)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	root, err := WriteCAR(f, dir, names)
+	if err != nil {
+		f.Close()
+		return cid.Undef, err
+	}
+
+	return root, f.Close()
+}
@@ -0,0 +1,142 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	merkledag "github.com/ipfs/go-merkledag"
+	unixfs "github.com/ipfs/go-unixfs"
+	"github.com/stretchr/testify/suite"
+)
+
+type CARTestSuite struct {
+	suite.Suite
+
+	dir    string
+	files  map[string][]byte
+	blocks map[cid.Cid][]byte
+}
+
+func (s *CARTestSuite) SetupTest() {
+	s.dir = s.T().TempDir()
+
+	large := make([]byte, 2*chunkSize+1)
+	rand.New(rand.NewSource(1)).Read(large)
+
+	s.files = map[string][]byte{
+		"empty": {},
+		"small": []byte("small file"),
+		"large": large,
+	}
+
+	for name, data := range s.files {
+		s.Require().NoError(ioutil.WriteFile(filepath.Join(s.dir, name), data, 0644))
+	}
+}
+
+// readCAR reads the CAR at path, verifying blocks, and returns its roots header.
+func (s *CARTestSuite) readCAR(path string) []byte {
+	f, err := os.Open(path)
+	s.Require().NoError(err)
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+
+	headerLen, err := binary.ReadUvarint(r)
+	s.Require().NoError(err)
+
+	header := make([]byte, headerLen)
+	_, err = io.ReadFull(r, header)
+	s.Require().NoError(err)
+
+	s.blocks = make(map[cid.Cid][]byte)
+
+	for {
+		sectionLen, err := binary.ReadUvarint(r)
+		if err == io.EOF {
+			break
+		}
+		s.Require().NoError(err)
+
+		section := make([]byte, sectionLen)
+		_, err = io.ReadFull(r, section)
+		s.Require().NoError(err)
+
+		n, id, err := cid.CidFromBytes(section)
+		s.Require().NoError(err)
+
+		// Blocks match their CID.
+		sum, err := id.Prefix().Sum(section[n:])
+		s.Require().NoError(err)
+		s.True(sum.Equals(id))
+
+		s.blocks[id] = section[n:]
+	}
+
+	return header
+}
+
+// readFile returns the contents of the UnixFS file with id.
+func (s *CARTestSuite) readFile(id cid.Cid) []byte {
+	data, ok := s.blocks[id]
+	s.Require().True(ok, "block %s missing", id)
+
+	if id.Type() == cid.Raw {
+		return data
+	}
+
+	n, err := merkledag.DecodeProtobuf(data)
+	s.Require().NoError(err)
+
+	fsn, err := unixfs.FSNodeFromBytes(n.Data())
+	s.Require().NoError(err)
+
+	contents := append([]byte(nil), fsn.Data()...)
+	for _, l := range n.Links() {
+		contents = append(contents, s.readFile(l.Cid)...)
+	}
+
+	return contents
+}
+
+func (s *CARTestSuite) TestWriteCAR() {
+	path := filepath.Join(s.T().TempDir(), "dump.car")
+
+	f, err := os.Create(path)
+	s.Require().NoError(err)
+
+	root, err := WriteCAR(f, s.dir, []string{"small", "large", "empty"})
+	s.Require().NoError(err)
+	s.NoError(f.Close())
+
+	header := s.readCAR(path)
+
+	// The header refers to the root.
+	s.True(bytes.Contains(header, root.Bytes()))
+	s.True(bytes.HasSuffix(header, []byte("version\x01")))
+
+	dir, err := merkledag.DecodeProtobuf(s.blocks[root])
+	s.Require().NoError(err)
+
+	fsn, err := unixfs.FSNodeFromBytes(dir.Data())
+	s.Require().NoError(err)
+	s.True(fsn.IsDir())
+
+	s.Require().Len(dir.Links(), 3)
+
+	for _, l := range dir.Links() {
+		s.Equal(string(s.files[l.Name]), string(s.readFile(l.Cid)), l.Name)
+	}
+}
+
+func TestCARTestSuite(t *testing.T) {
+	suite.Run(t, new(CARTestSuite))
+}
@@ -0,0 +1,107 @@
+/*
+Package export streams documents from indexes into portable dumps, and loads them back.
+
+Dumps consist of gzipped JSONL shards, each holding up to a maximum number of documents of a single kind of index, and a
+manifest describing them. Every line is a Record, holding the kind of index, the id and the source of a document, so
+that dumps do not depend on the backend or version of the index they were exported from. Dumps can be packed into a
+CAR file, holding a UnixFS directory with the shards and manifest, to be imported into IPFS.
+*/
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ipfs-search/ipfs-search/components/index"
+	"github.com/ipfs-search/ipfs-search/components/index/elasticsearch/mappings"
+)
+
+// ManifestName is the name of the manifest in a dump.
+const ManifestName = "manifest.json"
+
+// Record is a line in a shard.
+type Record struct {
+	Kind   string          `json:"kind"`
+	ID     string          `json:"id"`
+	Source json.RawMessage `json:"source"`
+}
+
+// Shard describes a shard in a dump.
+type Shard struct {
+	Name  string `json:"name"`
+	Kind  string `json:"kind"`
+	Count int    `json:"count"`
+}
+
+// Manifest describes a dump.
+type Manifest struct {
+	Created time.Time         `json:"created"`
+	Filter  *index.ScanFilter `json:"filter"`
+	Shards  []Shard           `json:"shards"`
+}
+
+// Options determine what is exported and how.
+type Options struct {
+	Filter    index.ScanFilter // Only export matching documents; MIMEType only applies to files.
+	ShardSize int              // Maximum number of documents per shard.
+}
+
+// Export writes the documents of indexes by kind, matching the filter, to shards in dir, which is created when it does
+// not exist, followed by the manifest. Kinds are exported in order of their name.
+func Export(ctx context.Context, indexes map[string]index.Scanner, dir string, opts *Options) (*Manifest, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	kinds := make([]string, 0, len(indexes))
+	for kind := range indexes {
+		kinds = append(kinds, kind)
+	}
+
+	sort.Strings(kinds)
+
+	m := &Manifest{
+		Created: time.Now().UTC(),
+		Filter:  &opts.Filter,
+	}
+
+	for _, kind := range kinds {
+		w := newShardWriter(dir, kind, opts.ShardSize)
+
+		// Only files have a MIME type.
+		filter := opts.Filter
+		if kind != mappings.Files {
+			filter.MIMEType = ""
+		}
+
+		err := indexes[kind].Scan(ctx, &filter, func(id string, source json.RawMessage) error {
+			return w.write(&Record{Kind: kind, ID: id, Source: source})
+		})
+
+		if closeErr := w.close(); err == nil {
+			err = closeErr
+		}
+
+		m.Shards = append(m.Shards, w.shards...)
+
+		if err != nil {
+			return m, fmt.Errorf("exporting %s: %w", kind, err)
+		}
+
+		log.Printf("Exported %d %s documents in %d shards", w.total, kind, len(w.shards))
+	}
+
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return m, err
+	}
+
+	return m, ioutil.WriteFile(filepath.Join(dir, ManifestName), b, 0644)
+}
@@ -0,0 +1,201 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/ipfs-search/ipfs-search/components/index"
+	"github.com/ipfs-search/ipfs-search/components/index/memory"
+	"github.com/ipfs-search/ipfs-search/components/index/types"
+)
+
+// scannerMock returns fixed documents, recording the filter scanned with.
+type scannerMock struct {
+	docs   map[string]string
+	filter *index.ScanFilter
+}
+
+func (s *scannerMock) Scan(ctx context.Context, filter *index.ScanFilter, fn func(string, json.RawMessage) error) error {
+	s.filter = filter
+
+	for id, source := range s.docs {
+		if err := fn(id, json.RawMessage(source)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type ExportTestSuite struct {
+	suite.Suite
+
+	ctx   context.Context
+	dir   string
+	files *scannerMock
+	dirs  *scannerMock
+}
+
+func (s *ExportTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.dir = s.T().TempDir()
+
+	s.files = &scannerMock{docs: map[string]string{
+		"file1": `{"size":1}`,
+		"file2": `{"size":2}`,
+		"file3": `{"size":3}`,
+	}}
+	s.dirs = &scannerMock{docs: map[string]string{
+		"dir1": `{"size":4}`,
+	}}
+}
+
+func (s *ExportTestSuite) export() *Manifest {
+	m, err := Export(s.ctx, map[string]index.Scanner{
+		"files":       s.files,
+		"directories": s.dirs,
+	}, s.dir, &Options{ShardSize: 2, Filter: index.ScanFilter{MIMEType: "text/"}})
+	s.Require().NoError(err)
+
+	return m
+}
+
+func (s *ExportTestSuite) TestExport() {
+	m := s.export()
+
+	s.Equal([]Shard{
+		{Name: "directories-00000.jsonl.gz", Kind: "directories", Count: 1},
+		{Name: "files-00000.jsonl.gz", Kind: "files", Count: 2},
+		{Name: "files-00001.jsonl.gz", Kind: "files", Count: 1},
+	}, m.Shards)
+	s.Equal("text/", s.files.filter.MIMEType)
+	s.Empty(s.dirs.filter.MIMEType)
+
+	for _, shard := range m.Shards {
+		s.FileExists(filepath.Join(s.dir, shard.Name))
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(s.dir, ManifestName))
+	s.Require().NoError(err)
+
+	manifest := new(Manifest)
+	s.NoError(json.Unmarshal(b, manifest))
+	s.Equal(m.Shards, manifest.Shards)
+}
+
+func (s *ExportTestSuite) TestImport() {
+	s.export()
+
+	files := memory.New(memory.DefaultConfig())
+	dirs := memory.New(memory.DefaultConfig())
+
+	// Existing documents are left as they are.
+	s.NoError(files.Index(s.ctx, "file1", &types.Document{Size: 5}))
+
+	result, err := Import(s.ctx, map[string]index.Index{
+		"files":       files,
+		"directories": dirs,
+	}, []string{s.dir}, 2)
+	s.Require().NoError(err)
+	s.Equal(&ImportResult{Imported: 3, Existing: 1}, result)
+
+	for id, size := range map[string]uint64{"file1": 5, "file2": 2, "file3": 3} {
+		doc := new(types.Document)
+		found, err := files.Get(s.ctx, id, doc)
+		s.NoError(err)
+		s.True(found)
+		s.Equal(size, doc.Size)
+	}
+
+	found, err := dirs.Get(s.ctx, "dir1", new(types.Document))
+	s.NoError(err)
+	s.True(found)
+}
+
+// asyncIndex writes asynchronously, reporting the result of creating documents through index.Writes.
+type asyncIndex struct {
+	backingIndex
+}
+
+type backingIndex interface {
+	index.Index
+}
+
+func (i *asyncIndex) Index(ctx context.Context, id string, properties interface{}) error {
+	done := index.WritesFromContext(ctx).Add()
+
+	go func() {
+		done(i.backingIndex.Index(context.Background(), id, properties))
+	}()
+
+	return nil
+}
+
+func (s *ExportTestSuite) TestImportFlushed() {
+	s.export()
+
+	files := memory.New(memory.DefaultConfig())
+	s.NoError(files.Index(s.ctx, "file1", &types.Document{Size: 5}))
+
+	// Existing documents are counted once conflicts are reported.
+	result, err := Import(s.ctx, map[string]index.Index{
+		"files":       &asyncIndex{files},
+		"directories": &asyncIndex{memory.New(memory.DefaultConfig())},
+	}, []string{s.dir}, 2)
+	s.Require().NoError(err)
+	s.Equal(&ImportResult{Imported: 3, Existing: 1}, result)
+}
+
+// unflushedIndex buffers writes which are never flushed.
+type unflushedIndex struct {
+	backingIndex
+}
+
+func (i *unflushedIndex) Index(ctx context.Context, id string, properties interface{}) error {
+	index.WritesFromContext(ctx).Add()
+	return nil
+}
+
+func (s *ExportTestSuite) TestImportUnconfirmed() {
+	s.export()
+
+	ctx, cancel := context.WithTimeout(s.ctx, 100*time.Millisecond)
+	defer cancel()
+
+	// Writes which are never confirmed fail the import once ctx is done.
+	_, err := Import(ctx, map[string]index.Index{
+		"files":       &unflushedIndex{memory.New(memory.DefaultConfig())},
+		"directories": &unflushedIndex{memory.New(memory.DefaultConfig())},
+	}, []string{s.dir}, 2)
+	s.ErrorIs(err, ErrUnconfirmed)
+}
+
+func (s *ExportTestSuite) TestImportUnknownKind() {
+	s.export()
+
+	_, err := Import(s.ctx, map[string]index.Index{
+		"files": memory.New(memory.DefaultConfig()),
+	}, []string{s.dir}, 2)
+	s.ErrorIs(err, ErrUnknownKind)
+}
+
+func (s *ExportTestSuite) TestImportUncompressed() {
+	path := filepath.Join(s.dir, "files.jsonl")
+	s.Require().NoError(ioutil.WriteFile(path, []byte(`{"kind":"files","id":"file1","source":{"size":1}}`+"\n"), 0644))
+
+	files := memory.New(memory.DefaultConfig())
+
+	result, err := Import(s.ctx, map[string]index.Index{"files": files}, []string{path}, 1)
+	s.Require().NoError(err)
+	s.Equal(int64(1), result.Imported)
+}
+
+func TestExportTestSuite(t *testing.T) {
+	suite.Run(t, new(ExportTestSuite))
+}
@@ -0,0 +1,188 @@
+package export
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/ipfs-search/ipfs-search/components/index"
+)
+
+// ErrUnknownKind is returned when importing records of a kind of index which is not imported into.
+var ErrUnknownKind = errors.New("unknown kind of index")
+
+// ErrUnconfirmed is returned when writes of documents are not confirmed in time, e.g. as their flush failed.
+var ErrUnconfirmed = errors.New("writes not confirmed")
+
+// flushTimeout bounds waiting for writes to be confirmed once all records are written; it exceeds the flush interval
+// of bulk indexers.
+const flushTimeout = 2 * time.Minute
+
+// ImportResult counts the documents imported.
+type ImportResult struct {
+	Imported int64 `json:"imported"`
+	Existing int64 `json:"existing"` // Documents which already existed, which are left as they are.
+}
+
+// shardPaths returns the shards in paths, which are shards or directories holding dumps.
+func shardPaths(paths []string) ([]string, error) {
+	var shards []string
+
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+
+		if !fi.IsDir() {
+			shards = append(shards, p)
+			continue
+		}
+
+		matches, err := filepath.Glob(filepath.Join(p, "*"+ShardExt))
+		if err != nil {
+			return nil, err
+		}
+
+		sort.Strings(matches)
+		shards = append(shards, matches...)
+	}
+
+	return shards, nil
+}
+
+// readShard sends the records in the shard at path to records, until ctx is done. Shards may be uncompressed.
+func readShard(ctx context.Context, path string, records chan<- *Record) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = bufio.NewReader(f)
+
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", path, err)
+		}
+		defer gz.Close()
+
+		r = gz
+	}
+
+	dec := json.NewDecoder(r)
+
+	for {
+		record := new(Record)
+
+		err := dec.Decode(record)
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", path, err)
+		}
+
+		select {
+		case records <- record:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Import loads the records in the shards at paths, or in the dumps in the directories at paths, into indexes by kind,
+// writing with workers concurrently. Documents which already exist are left as they are.
+func Import(ctx context.Context, indexes map[string]index.Index, paths []string, workers int) (*ImportResult, error) {
+	shards, err := shardPaths(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	result := new(ImportResult)
+	records := make(chan *Record, workers)
+
+	writesCtx, writes := index.WithWrites(ctx)
+	g, ctx := errgroup.WithContext(writesCtx)
+
+	g.Go(func() error {
+		defer close(records)
+
+		for _, shard := range shards {
+			if err := readShard(ctx, shard, records); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	for n := 0; n < workers; n++ {
+		g.Go(func() error {
+			for r := range records {
+				idx, ok := indexes[r.Kind]
+				if !ok {
+					return fmt.Errorf("%w: %s", ErrUnknownKind, r.Kind)
+				}
+
+				id := r.ID
+
+				// Documents are counted once written, as conflicts of writes in bulk are only known after flushing,
+				// which may be after the group's context is done.
+				err := index.OnWritten(writesCtx,
+					func(ctx context.Context) error {
+						return idx.Index(ctx, id, r.Source)
+					},
+					func(_ context.Context, err error) error {
+						switch {
+						case errors.Is(err, index.ErrConflict):
+							atomic.AddInt64(&result.Existing, 1)
+						case err != nil:
+							return fmt.Errorf("importing %s into %s: %w", id, idx, err)
+						default:
+							atomic.AddInt64(&result.Imported, 1)
+						}
+
+						return nil
+					},
+				)
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return result, err
+	}
+
+	waitCtx, cancel := context.WithTimeout(writesCtx, flushTimeout)
+	defer cancel()
+
+	if err := writes.Wait(waitCtx); err != nil {
+		if waitCtx.Err() != nil {
+			return result, fmt.Errorf("%w: %s", ErrUnconfirmed, err)
+		}
+
+		return result, err
+	}
+
+	return result, nil
+}
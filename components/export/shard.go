@@ -0,0 +1,100 @@
+package export
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ShardExt is the extension of shards.
+const ShardExt = ".jsonl.gz"
+
+// shardWriter writes records of a kind to shards of a maximum size.
+type shardWriter struct {
+	dir  string
+	kind string
+	size int
+
+	shards []Shard
+	total  int
+
+	f   *os.File
+	gz  *gzip.Writer
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func newShardWriter(dir, kind string, size int) *shardWriter {
+	return &shardWriter{
+		dir:  dir,
+		kind: kind,
+		size: size,
+	}
+}
+
+// open starts a new shard.
+func (w *shardWriter) open() error {
+	name := fmt.Sprintf("%s-%05d%s", w.kind, len(w.shards), ShardExt)
+
+	f, err := os.Create(filepath.Join(w.dir, name))
+	if err != nil {
+		return err
+	}
+
+	w.f = f
+	w.gz = gzip.NewWriter(f)
+	w.buf = bufio.NewWriter(w.gz)
+	w.enc = json.NewEncoder(w.buf)
+	w.shards = append(w.shards, Shard{Name: name, Kind: w.kind})
+
+	return nil
+}
+
+// write appends r to the current shard, starting a new one when it is full.
+func (w *shardWriter) write(r *Record) error {
+	if w.f != nil && w.shards[len(w.shards)-1].Count >= w.size {
+		if err := w.close(); err != nil {
+			return err
+		}
+	}
+
+	if w.f == nil {
+		if err := w.open(); err != nil {
+			return err
+		}
+	}
+
+	if err := w.enc.Encode(r); err != nil {
+		return err
+	}
+
+	w.shards[len(w.shards)-1].Count++
+	w.total++
+
+	return nil
+}
+
+// close finishes the current shard, if any.
+func (w *shardWriter) close() error {
+	if w.f == nil {
+		return nil
+	}
+
+	f := w.f
+	w.f = nil
+
+	if err := w.buf.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := w.gz.Close(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,21 @@
+package native
+
+import (
+	"time"
+
+	"github.com/c2h5oh/datasize"
+)
+
+// Config specifies the configuration for a native extractor.
+type Config struct {
+	RequestTimeout time.Duration     // Timeout for fetching resources from the gateway.
+	MaxFileSize    datasize.ByteSize // Leave files over this size to the fallback extractor.
+}
+
+// DefaultConfig returns the default configuration for a native extractor.
+func DefaultConfig() *Config {
+	return &Config{
+		RequestTimeout: 60 * time.Duration(time.Second),
+		MaxFileSize:    10 * 1024 * 1024, // 10MB
+	}
+}
@@ -0,0 +1,188 @@
+/*
+Package native extracts metadata from common formats in Go, leaving other formats to a fallback extractor.
+
+Resources are routed by the MIME type of their reference name's extension or, lacking one, the Content-Type of a
+HEAD request to the gateway, so that resources in other formats are not fetched before passing them on. The MIME type
+of resources which are fetched is sniffed from their first bytes. Plain text, HTML, JSON, Markdown and common images
+are parsed natively, other resources are passed on to the fallback, generally ipfs-tika. Results are written in the
+format of ipfs-tika, so that the same fields are set regardless of the extractor used; the language of documents is
+detected from their text.
+*/
+package native
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/ipfs-search/ipfs-search/components/extractor"
+	"github.com/ipfs-search/ipfs-search/components/protocol"
+
+	"github.com/ipfs-search/ipfs-search/instr"
+	t "github.com/ipfs-search/ipfs-search/types"
+)
+
+// sniffLen is the number of bytes considered when sniffing MIME types, like http.DetectContentType.
+const sniffLen = 512
+
+// Extractor extracts metadata natively, routing resources it does not support to a fallback extractor.
+type Extractor struct {
+	config   *Config
+	client   *http.Client
+	protocol protocol.Protocol
+	fallback extractor.Extractor
+	*instr.Instrumentation
+}
+
+func (e *Extractor) request(ctx context.Context, method string, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		// Errors here are programming errors.
+		panic(fmt.Sprintf("creating request: %s", err))
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", extractor.ErrRequest, err)
+	}
+
+	if resp.StatusCode != 200 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: unexpected status %s", extractor.ErrUnexpectedResponse, resp.Status)
+	}
+
+	return resp, nil
+}
+
+// contentType returns the MIME type of a resource by its reference name or, when it has no known extension, from the
+// Content-Type the gateway returns for a HEAD request. It is empty when the gateway returns none.
+func (e *Extractor) contentType(ctx context.Context, r *t.AnnotatedResource) (string, error) {
+	if contentType := typeByName(r.Reference.Name); contentType != "" {
+		return contentType, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.config.RequestTimeout)
+	defer cancel()
+
+	resp, err := e.request(ctx, http.MethodHead, e.protocol.GatewayURL(r))
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	return resp.Header.Get("Content-Type"), nil
+}
+
+// extract fetches and parses a resource, returning nil when its format turns out not to be supported or, when its
+// size is unknown, it turns out to be larger than MaxFileSize.
+func (e *Extractor) extract(ctx context.Context, r *t.AnnotatedResource) (*document, error) {
+	// Timeout if fetching hasn't fully completed within this time.
+	ctx, cancel := context.WithTimeout(ctx, e.config.RequestTimeout)
+	defer cancel()
+
+	resp, err := e.request(ctx, http.MethodGet, e.protocol.GatewayURL(r))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body := bufio.NewReaderSize(resp.Body, sniffLen)
+
+	head, err := body.Peek(sniffLen)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%w: %v", extractor.ErrRequest, err)
+	}
+
+	contentType := sniff(r.Reference.Name, head)
+
+	trace.SpanFromContext(ctx).SetAttributes(label.String("content_type", contentType))
+
+	if getFormat(contentType) == nil {
+		return nil, nil
+	}
+
+	// Read one more byte than allowed, to tell large files apart from truncated ones.
+	data, err := ioutil.ReadAll(io.LimitReader(body, int64(e.config.MaxFileSize)+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", extractor.ErrRequest, err)
+	}
+
+	if len(data) > int(e.config.MaxFileSize) {
+		return nil, nil
+	}
+
+	return parse(contentType, data)
+}
+
+// Extract metadata from a (potentially) referenced resource, updating
+// Metadata or returning an error.
+func (e *Extractor) Extract(ctx context.Context, r *t.AnnotatedResource, m interface{}) error {
+	ctx, span := e.Tracer.Start(ctx, "extractor.native.Extract")
+	defer span.End()
+
+	if r.Size > uint64(e.config.MaxFileSize) {
+		return e.fallback.Extract(ctx, r, m)
+	}
+
+	contentType, err := e.contentType(ctx, r)
+	if err != nil {
+		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+		return err
+	}
+
+	if contentType != "" && getFormat(contentType) == nil {
+		// Not fetched, as the fallback fetches it itself.
+		return e.fallback.Extract(ctx, r, m)
+	}
+
+	doc, err := e.extract(ctx, r)
+	if err != nil {
+		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+		return err
+	}
+
+	if doc == nil {
+		return e.fallback.Extract(ctx, r, m)
+	}
+
+	// Round-trip through JSON, like results from ipfs-tika.
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(b, m); err != nil {
+		return err
+	}
+
+	log.Printf("Got metadata for '%v'", r)
+
+	return nil
+}
+
+// New returns a new native extractor, passing resources it does not support on to fallback.
+func New(config *Config, client *http.Client, protocol protocol.Protocol, fallback extractor.Extractor, instr *instr.Instrumentation) extractor.Extractor {
+	if config == nil {
+		panic("native.New Config cannot be nil.")
+	}
+
+	return &Extractor{
+		config,
+		client,
+		protocol,
+		fallback,
+		instr,
+	}
+}
+
+// Compile-time assurance that implementation satisfies interface.
+var _ extractor.Extractor = &Extractor{}
@@ -0,0 +1,377 @@
+package native
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"net/http"
+	"path"
+	"testing"
+
+	"github.com/dankinder/httpmock"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/ipfs-search/ipfs-search/components/extractor"
+	indexTypes "github.com/ipfs-search/ipfs-search/components/index/types"
+	"github.com/ipfs-search/ipfs-search/components/protocol"
+	"github.com/ipfs-search/ipfs-search/instr"
+	t "github.com/ipfs-search/ipfs-search/types"
+)
+
+const testCID = "QmehHHRh1a7u66r7fugebp6f6wGNMGCa7eho9cgjwhAcm2"
+
+type NativeTestSuite struct {
+	suite.Suite
+
+	ctx context.Context
+	e   extractor.Extractor
+
+	cfg      *Config
+	protocol *protocol.Mock
+	fallback *extractor.Mock
+
+	mockGWHandler *httpmock.MockHandler
+	mockGWServer  *httpmock.Server
+}
+
+func (s *NativeTestSuite) SetupTest() {
+	s.ctx = context.Background()
+
+	s.mockGWHandler = &httpmock.MockHandler{}
+	s.mockGWServer = httpmock.NewServer(s.mockGWHandler)
+
+	s.cfg = DefaultConfig()
+	s.protocol = &protocol.Mock{}
+	s.fallback = &extractor.Mock{}
+
+	s.e = New(s.cfg, http.DefaultClient, s.protocol, s.fallback, instr.New())
+}
+
+func (s *NativeTestSuite) TearDownTest() {
+	s.mockGWServer.Close()
+}
+
+// resource returns a resource with the given name and size, which the gateway is requested for the given times.
+func (s *NativeTestSuite) resource(name string, size int, times int) *t.AnnotatedResource {
+	r := &t.AnnotatedResource{
+		Resource: &t.Resource{
+			Protocol: t.IPFSProtocol,
+			ID:       testCID,
+		},
+		Reference: t.Reference{
+			Name: name,
+		},
+		Stat: t.Stat{
+			Size: uint64(size),
+		},
+	}
+
+	if times > 0 {
+		s.protocol.
+			On("GatewayURL", r).
+			Return(s.mockGWServer.URL() + "/ipfs/" + testCID).
+			Times(times)
+	}
+
+	return r
+}
+
+// head expects a HEAD request to the gateway, returning contentType like the gateway does.
+func (s *NativeTestSuite) head(contentType string) {
+	s.mockGWHandler.
+		On("Handle", "HEAD", "/ipfs/"+testCID, mock.Anything).
+		Return(httpmock.Response{
+			Header: http.Header{"Content-Type": []string{contentType}},
+		}).
+		Once()
+}
+
+// serve returns a resource with the given name, served by the gateway with body. Without an extension, its type is
+// requested first, which the gateway sniffs from body.
+func (s *NativeTestSuite) serve(name string, body []byte) *t.AnnotatedResource {
+	var r *t.AnnotatedResource
+
+	if path.Ext(name) == "" {
+		r = s.resource(name, len(body), 2)
+		s.head(http.DetectContentType(body))
+	} else {
+		r = s.resource(name, len(body), 1)
+	}
+
+	s.mockGWHandler.
+		On("Handle", "GET", "/ipfs/"+testCID, mock.Anything).
+		Return(httpmock.Response{
+			Body: body,
+		}).
+		Once()
+
+	return r
+}
+
+func (s *NativeTestSuite) extract(r *t.AnnotatedResource) *indexTypes.File {
+	f := &indexTypes.File{
+		Document: indexTypes.Document{
+			Size: r.Size,
+		},
+	}
+
+	s.NoError(s.e.Extract(s.ctx, r, f))
+	s.mockGWHandler.AssertExpectations(s.T())
+	s.fallback.AssertExpectations(s.T())
+
+	return f
+}
+
+func (s *NativeTestSuite) TestExtractHTML() {
+	r := s.serve("index.html", []byte(`<!DOCTYPE html>
+<html>
+<head>
+	<title>How Filecoin Supports Video Storage</title>
+	<meta name="description" content="Storing video on Filecoin.">
+	<meta property="og:title" content="Video Storage">
+	<script>var hidden = true;</script>
+</head>
+<body>
+	<h1>Video <b>storage</b></h1>
+	<p>The Filecoin Space Race is now live! <a href="https://filecoin.io/">Learn More</a></p>
+	<a href="/relative">Relative</a>
+	<style>p { color: red; }</style>
+</body>
+</html>`))
+
+	f := s.extract(r)
+
+	s.Equal([]interface{}{"How Filecoin Supports Video Storage"}, f.Metadata["title"])
+	s.Equal([]interface{}{"Storing video on Filecoin."}, f.Metadata["description"])
+	s.Equal([]interface{}{"Video Storage"}, f.Metadata["og:title"])
+	s.Equal([]interface{}{"text/html; charset=utf-8"}, f.Metadata["Content-Type"])
+	s.Equal([]interface{}{"ipfs-search.native.HTMLParser"}, f.Metadata["X-Parsed-By"])
+	s.Equal("Video storage\nThe Filecoin Space Race is now live! Learn More\nRelative", f.Content)
+	s.Equal("en", f.Language.Language)
+	s.Equal([]string{"https://filecoin.io/"}, f.URLs)
+}
+
+func (s *NativeTestSuite) TestExtractText() {
+	r := s.serve("notes.txt", []byte("Some notes.\n"))
+
+	f := s.extract(r)
+
+	s.Equal([]interface{}{"text/plain; charset=utf-8"}, f.Metadata["Content-Type"])
+	s.Equal("Some notes.\n", f.Content)
+	s.Equal("NONE", f.Language.Confidence)
+	s.Nil(f.URLs)
+}
+
+func (s *NativeTestSuite) TestExtractJSON() {
+	r := s.serve("", []byte(`{"name": "ipfs-search", "links": ["https://ipfs-search.com", "not a link"]}`))
+
+	f := s.extract(r)
+
+	s.Equal([]interface{}{"application/json"}, f.Metadata["Content-Type"])
+	s.Equal([]interface{}{"ipfs-search.native.JSONParser"}, f.Metadata["X-Parsed-By"])
+	s.Contains(f.Content, `"name": "ipfs-search"`)
+	s.Equal([]string{"https://ipfs-search.com"}, f.URLs)
+}
+
+func (s *NativeTestSuite) TestExtractInvalidJSON() {
+	r := s.serve("", []byte(`{"name": `))
+
+	f := s.extract(r)
+
+	s.Equal([]interface{}{"text/plain; charset=utf-8"}, f.Metadata["Content-Type"])
+}
+
+func (s *NativeTestSuite) TestExtractMarkdown() {
+	r := s.serve("README.md", []byte("# ipfs-search\n\nSearch **IPFS**, see [the site](https://ipfs-search.com \"Site\").\n\n"+
+		"```\ncode\n```\n\n- Item ![logo](https://ipfs-search.com/logo.png)\n\n[ref]: https://github.com/ipfs-search\n"))
+
+	f := s.extract(r)
+
+	s.Equal([]interface{}{"text/markdown; charset=utf-8"}, f.Metadata["Content-Type"])
+	s.Equal([]interface{}{"ipfs-search"}, f.Metadata["title"])
+	s.Equal("ipfs-search\n\nSearch IPFS, see the site.\n\ncode\n\nItem logo", f.Content)
+	s.Equal([]string{
+		"https://ipfs-search.com",
+		"https://ipfs-search.com/logo.png",
+		"https://github.com/ipfs-search",
+	}, f.URLs)
+}
+
+func (s *NativeTestSuite) TestExtractImage() {
+	buf := new(bytes.Buffer)
+	s.Require().NoError(png.Encode(buf, image.NewGray(image.Rect(0, 0, 3, 2))))
+
+	r := s.serve("", buf.Bytes())
+
+	f := s.extract(r)
+
+	s.Equal([]interface{}{"image/png"}, f.Metadata["Content-Type"])
+	s.Equal([]interface{}{"3"}, f.Metadata["tiff:ImageWidth"])
+	s.Equal([]interface{}{"2"}, f.Metadata["tiff:ImageLength"])
+	s.Empty(f.Content)
+}
+
+// TestExtractFallback asserts that resources which are not supported by their name are not fetched.
+func (s *NativeTestSuite) TestExtractFallback() {
+	r := s.resource("document.pdf", 9, 0)
+
+	s.fallback.
+		On("Extract", mock.Anything, r, mock.Anything).
+		Return(nil).
+		Once()
+
+	f := s.extract(r)
+
+	s.Nil(f.Metadata)
+}
+
+// TestExtractFallbackHead asserts that resources which are not supported by their Content-Type are not fetched.
+func (s *NativeTestSuite) TestExtractFallbackHead() {
+	r := s.resource("", 9, 1)
+	s.head("application/pdf")
+
+	s.fallback.
+		On("Extract", mock.Anything, r, mock.Anything).
+		Return(nil).
+		Once()
+
+	f := s.extract(r)
+
+	s.Nil(f.Metadata)
+}
+
+// TestExtractFallbackSniffed asserts that resources which turn out not to be supported after fetching them are passed
+// on to the fallback.
+func (s *NativeTestSuite) TestExtractFallbackSniffed() {
+	r := s.serve("notes.txt", []byte("%PDF-1.4\n\x00\x01"))
+
+	s.fallback.
+		On("Extract", mock.Anything, r, mock.Anything).
+		Return(nil).
+		Once()
+
+	f := s.extract(r)
+
+	s.Nil(f.Metadata)
+}
+
+func (s *NativeTestSuite) TestExtractLanguage() {
+	r := s.serve("notes.txt", []byte("Het is niet de bedoeling dat de crawler een taal heeft, maar het wordt wel "+
+		"voor elk document bepaald op basis van de tekst die er in staat."))
+
+	f := s.extract(r)
+
+	s.Equal("nl", f.Language.Language)
+	s.Equal("HIGH", f.Language.Confidence)
+}
+
+func (s *NativeTestSuite) TestExtractMaxFileSize() {
+	s.cfg.MaxFileSize = 100
+
+	r := &t.AnnotatedResource{
+		Resource: &t.Resource{
+			Protocol: t.IPFSProtocol,
+			ID:       testCID,
+		},
+		Stat: t.Stat{
+			Size: uint64(s.cfg.MaxFileSize + 1),
+		},
+	}
+
+	s.fallback.
+		On("Extract", mock.Anything, r, mock.Anything).
+		Return(extractor.ErrFileTooLarge).
+		Once()
+
+	err := s.e.Extract(s.ctx, r, &indexTypes.File{})
+	s.ErrorIs(err, extractor.ErrFileTooLarge)
+
+	s.fallback.AssertExpectations(s.T())
+	s.mockGWHandler.AssertExpectations(s.T())
+}
+
+// TestExtractMaxFileSizeUnknown asserts that resources of unknown size which turn out to be larger than MaxFileSize
+// are passed on to the fallback rather than parsed truncated.
+func (s *NativeTestSuite) TestExtractMaxFileSizeUnknown() {
+	s.cfg.MaxFileSize = 100
+
+	r := s.serve("notes.txt", bytes.Repeat([]byte("a"), int(s.cfg.MaxFileSize)+1))
+	r.Size = 0
+
+	s.fallback.
+		On("Extract", mock.Anything, r, mock.Anything).
+		Return(nil).
+		Once()
+
+	f := s.extract(r)
+
+	s.Nil(f.Metadata)
+}
+
+func (s *NativeTestSuite) TestExtractUnexpectedStatus() {
+	r := &t.AnnotatedResource{
+		Resource: &t.Resource{
+			Protocol: t.IPFSProtocol,
+			ID:       testCID,
+		},
+	}
+
+	s.protocol.
+		On("GatewayURL", r).
+		Return(s.mockGWServer.URL() + "/ipfs/" + testCID).
+		Once()
+
+	s.mockGWHandler.
+		On("Handle", "HEAD", "/ipfs/"+testCID, mock.Anything).
+		Return(httpmock.Response{
+			Status: 504,
+		}).
+		Once()
+
+	err := s.e.Extract(s.ctx, r, &indexTypes.File{})
+	s.ErrorIs(err, extractor.ErrUnexpectedResponse)
+
+	s.fallback.AssertExpectations(s.T())
+}
+
+func (s *NativeTestSuite) TestExtractUnexpectedStatusGet() {
+	r := s.resource("index.html", 10, 1)
+
+	s.mockGWHandler.
+		On("Handle", "GET", "/ipfs/"+testCID, mock.Anything).
+		Return(httpmock.Response{
+			Status: 504,
+		}).
+		Once()
+
+	err := s.e.Extract(s.ctx, r, &indexTypes.File{})
+	s.ErrorIs(err, extractor.ErrUnexpectedResponse)
+
+	s.fallback.AssertExpectations(s.T())
+}
+
+func (s *NativeTestSuite) TestExtractRequestError() {
+	r := &t.AnnotatedResource{
+		Resource: &t.Resource{
+			Protocol: t.IPFSProtocol,
+			ID:       testCID,
+		},
+	}
+
+	s.protocol.
+		On("GatewayURL", r).
+		Return(s.mockGWServer.URL() + "/ipfs/" + testCID).
+		Once()
+
+	// Closing server early, generates a request error.
+	s.mockGWServer.Close()
+
+	err := s.e.Extract(s.ctx, r, &indexTypes.File{})
+	s.ErrorIs(err, extractor.ErrRequest)
+}
+
+func TestNativeTestSuite(t *testing.T) {
+	suite.Run(t, new(NativeTestSuite))
+}
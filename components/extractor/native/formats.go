@@ -0,0 +1,114 @@
+package native
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// document holds extracted metadata, in the format of ipfs-tika.
+type document struct {
+	Metadata map[string][]string `json:"metadata"`
+	Content  string              `json:"content,omitempty"`
+	Language language            `json:"language"`
+	URLs     []string            `json:"urls,omitempty"`
+}
+
+// format is a natively supported format.
+type format struct {
+	parser string // Recorded as X-Parsed-By.
+	parse  func(data []byte) (*document, error)
+}
+
+// formats are the natively supported formats, by media type.
+var formats = map[string]*format{
+	"text/plain":       {"ipfs-search.native.TextParser", parseText},
+	"text/html":        {"ipfs-search.native.HTMLParser", parseHTML},
+	"text/markdown":    {"ipfs-search.native.MarkdownParser", parseMarkdown},
+	"application/json": {"ipfs-search.native.JSONParser", parseJSON},
+	"image/png":        {"ipfs-search.native.ImageParser", parseImage},
+	"image/jpeg":       {"ipfs-search.native.ImageParser", parseImage},
+	"image/gif":        {"ipfs-search.native.ImageParser", parseImage},
+}
+
+// mediaType returns the media type of contentType, without parameters.
+func mediaType(contentType string) string {
+	t, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+
+	return t
+}
+
+// getFormat returns the format for contentType, or nil when it is not supported.
+func getFormat(contentType string) *format {
+	return formats[mediaType(contentType)]
+}
+
+// typeByName returns the MIME type of a resource with the given name by its extension, or an empty string when the
+// extension is missing or unknown.
+func typeByName(name string) string {
+	switch ext := strings.ToLower(path.Ext(name)); ext {
+	case "":
+		return ""
+	case ".md", ".markdown":
+		return "text/markdown; charset=utf-8"
+	case ".txt":
+		return "text/plain; charset=utf-8"
+	default:
+		return mime.TypeByExtension(ext)
+	}
+}
+
+// sniff returns the MIME type of a resource with the given name, based on its first bytes. As Markdown can not be told
+// from plain text, it is recognised by extension.
+func sniff(name string, head []byte) string {
+	contentType := http.DetectContentType(head)
+
+	if mediaType(contentType) != "text/plain" {
+		return contentType
+	}
+
+	switch strings.ToLower(path.Ext(name)) {
+	case ".md", ".markdown":
+		return strings.Replace(contentType, "text/plain", "text/markdown", 1)
+	}
+
+	return contentType
+}
+
+// isJSON returns whether data is a JSON object or array.
+func isJSON(data []byte) bool {
+	data = bytes.TrimSpace(data)
+
+	return len(data) > 0 && (data[0] == '{' || data[0] == '[') && json.Valid(data)
+}
+
+// parse returns the document extracted from data of contentType, which is a supported format. Plain text which turns
+// out to be JSON is parsed as such.
+func parse(contentType string, data []byte) (*document, error) {
+	if mediaType(contentType) == "text/plain" && isJSON(data) {
+		contentType = "application/json"
+	}
+
+	f := getFormat(contentType)
+
+	doc, err := f.parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if doc.Metadata == nil {
+		doc.Metadata = make(map[string][]string)
+	}
+
+	doc.Metadata["Content-Type"] = []string{contentType}
+	doc.Metadata["X-Parsed-By"] = []string{f.parser}
+	doc.Language = detectLanguage(doc.Content)
+
+	return doc, nil
+}
@@ -0,0 +1,140 @@
+package native
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// hiddenElements hold no visible text.
+var hiddenElements = map[atom.Atom]bool{
+	atom.Head:     true,
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Svg:      true,
+}
+
+// blockElements are on lines of their own.
+var blockElements = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Aside: true, atom.Blockquote: true, atom.Br: true, atom.Dd: true,
+	atom.Div: true, atom.Dl: true, atom.Dt: true, atom.Figcaption: true, atom.Footer: true, atom.H1: true,
+	atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true, atom.Header: true, atom.Hr: true,
+	atom.Li: true, atom.Main: true, atom.Nav: true, atom.Ol: true, atom.P: true, atom.Pre: true, atom.Section: true,
+	atom.Table: true, atom.Td: true, atom.Th: true, atom.Tr: true, atom.Ul: true,
+}
+
+// htmlExtractor accumulates metadata while walking an HTML tree.
+type htmlExtractor struct {
+	doc     *document
+	content strings.Builder
+}
+
+func getAttr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+
+	return ""
+}
+
+// text writes visible text, collapsing whitespace.
+func (e *htmlExtractor) text(s string) {
+	words := strings.Fields(s)
+	if len(words) == 0 {
+		return
+	}
+
+	if e.content.Len() > 0 && !strings.HasSuffix(e.content.String(), "\n") {
+		e.content.WriteByte(' ')
+	}
+
+	e.content.WriteString(strings.Join(words, " "))
+}
+
+// newline starts a new line of visible text.
+func (e *htmlExtractor) newline() {
+	if e.content.Len() > 0 && !strings.HasSuffix(e.content.String(), "\n") {
+		e.content.WriteByte('\n')
+	}
+}
+
+// meta records meta tags by their name or property, like ipfs-tika.
+func (e *htmlExtractor) meta(n *html.Node) {
+	key := getAttr(n, "name")
+	if key == "" {
+		key = getAttr(n, "property")
+	}
+
+	value := strings.TrimSpace(getAttr(n, "content"))
+
+	if key == "" || value == "" {
+		return
+	}
+
+	e.doc.Metadata[key] = append(e.doc.Metadata[key], value)
+}
+
+func (e *htmlExtractor) walk(n *html.Node, hidden bool) {
+	switch n.Type {
+	case html.TextNode:
+		if !hidden {
+			e.text(n.Data)
+		}
+
+		return
+	case html.ElementNode:
+		switch n.DataAtom {
+		case atom.Title:
+			if n.FirstChild != nil && e.doc.Metadata["title"] == nil {
+				if title := strings.Join(strings.Fields(n.FirstChild.Data), " "); title != "" {
+					e.doc.Metadata["title"] = []string{title}
+				}
+			}
+		case atom.Meta:
+			e.meta(n)
+		case atom.A:
+			if href := strings.TrimSpace(getAttr(n, "href")); isLink(href) {
+				e.doc.URLs = append(e.doc.URLs, href)
+			}
+		}
+
+		hidden = hidden || hiddenElements[n.DataAtom]
+
+		if blockElements[n.DataAtom] {
+			e.newline()
+		}
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		e.walk(c, hidden)
+	}
+
+	if n.Type == html.ElementNode && blockElements[n.DataAtom] {
+		e.newline()
+	}
+}
+
+// parseHTML extracts the title, meta tags, visible text and absolute links from HTML.
+func parseHTML(data []byte) (*document, error) {
+	root, err := html.Parse(strings.NewReader(toText(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	e := &htmlExtractor{
+		doc: &document{
+			Metadata: make(map[string][]string),
+		},
+	}
+
+	e.walk(root, false)
+
+	e.doc.Content = strings.TrimSpace(e.content.String())
+
+	return e.doc, nil
+}
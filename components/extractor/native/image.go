@@ -0,0 +1,30 @@
+package native
+
+import (
+	"bytes"
+	"image"
+	"strconv"
+
+	// Register decoders for the supported image formats.
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// parseImage extracts the dimensions of images, using the same keys as ipfs-tika. Images which can not be decoded
+// yield no dimensions.
+func parseImage(data []byte) (*document, error) {
+	doc := &document{
+		Metadata: make(map[string][]string),
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return doc, nil
+	}
+
+	doc.Metadata["tiff:ImageWidth"] = []string{strconv.Itoa(cfg.Width)}
+	doc.Metadata["tiff:ImageLength"] = []string{strconv.Itoa(cfg.Height)}
+
+	return doc, nil
+}
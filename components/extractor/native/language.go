@@ -0,0 +1,105 @@
+package native
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Confidence levels of detected languages, like ipfs-tika.
+const (
+	confidenceHigh   = "HIGH"
+	confidenceMedium = "MEDIUM"
+	confidenceLow    = "LOW"
+	confidenceNone   = "NONE"
+)
+
+// minWords is the number of words from which languages are detected with high confidence.
+const minWords = 20
+
+// stopwords are common words by language; languages are told apart by which of them occur most.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "in", "that", "it", "was", "for", "on", "are", "with", "as", "be", "this",
+		"have", "from", "or", "by", "not", "but", "what", "all", "were", "they", "which", "you"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "ein", "eine", "zu", "den", "von", "mit", "sich", "des", "auf",
+		"für", "im", "dem", "auch", "es", "an", "als", "wie", "wird", "bei"},
+	"fr": {"le", "la", "les", "et", "des", "est", "un", "une", "du", "que", "qui", "dans", "pour", "pas", "sur", "au",
+		"avec", "ce", "il", "elle", "sont", "par", "plus", "ne", "se"},
+	"es": {"el", "la", "los", "las", "y", "que", "es", "en", "un", "una", "por", "con", "para", "del", "se", "no",
+		"como", "al", "lo", "más", "pero", "sus", "le", "su"},
+	"nl": {"de", "het", "een", "en", "van", "is", "dat", "niet", "op", "te", "zijn", "met", "voor", "die", "er", "aan",
+		"ook", "als", "maar", "om", "bij", "wordt", "naar", "hij"},
+	"it": {"il", "di", "che", "e", "la", "per", "un", "una", "non", "sono", "del", "della", "le", "si", "con", "come",
+		"anche", "gli", "alla", "ma", "più", "nel", "ho"},
+	"pt": {"o", "a", "os", "as", "de", "que", "e", "do", "da", "em", "um", "uma", "para", "com", "não", "no", "na",
+		"por", "mais", "dos", "das", "se", "ao", "é"},
+}
+
+// stopwordLanguages holds the languages of stopwords, by word.
+var stopwordLanguages = func() map[string][]string {
+	m := make(map[string][]string)
+
+	for lang, words := range stopwords {
+		for _, w := range words {
+			m[w] = append(m[w], lang)
+		}
+	}
+
+	return m
+}()
+
+// language is the language of a document, in the format of ipfs-tika.
+type language struct {
+	Confidence string  `json:"confidence"`
+	Language   string  `json:"language"`
+	RawScore   float64 `json:"rawScore"`
+}
+
+// detectLanguage returns the language of text, as the language most of its stopwords belong to. The raw score is the
+// share of stopwords belonging to the language. Text without stopwords has no language, with confidence NONE.
+func detectLanguage(text string) language {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	hits := make(map[string]int)
+	total := 0
+
+	for _, w := range words {
+		if langs, ok := stopwordLanguages[w]; ok {
+			total++
+
+			for _, lang := range langs {
+				hits[lang]++
+			}
+		}
+	}
+
+	best := ""
+	for lang, n := range hits {
+		// Ties are broken by code, for deterministic results.
+		if n > hits[best] || (n == hits[best] && lang < best) {
+			best = lang
+		}
+	}
+
+	if best == "" {
+		return language{Confidence: confidenceNone}
+	}
+
+	score := float64(hits[best]) / float64(total)
+
+	confidence := confidenceLow
+
+	switch {
+	case score >= 0.8 && len(words) >= minWords:
+		confidence = confidenceHigh
+	case score >= 0.5:
+		confidence = confidenceMedium
+	}
+
+	return language{
+		Confidence: confidence,
+		Language:   best,
+		RawScore:   score,
+	}
+}
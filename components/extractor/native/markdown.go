@@ -0,0 +1,75 @@
+package native
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	mdHeading    = regexp.MustCompile(`^ {0,3}#{1,6}(?:\s+(.*?))?(?:\s+#+)?\s*$`)
+	mdFence      = regexp.MustCompile("^ {0,3}(?:```|~~~)")
+	mdDefinition = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:\s*<?([^\s>]+)>?`)
+	mdPrefix     = regexp.MustCompile(`^\s*(?:>\s*)*(?:[-*+]\s+|\d+[.)]\s+)?`)
+	mdLink       = regexp.MustCompile(`!?\[([^\]]*)\]\(\s*<?([^\s)>]*)>?(?:\s+"[^"]*")?\s*\)`)
+	mdAutolink   = regexp.MustCompile(`<([a-zA-Z][a-zA-Z0-9+.-]*:[^\s>]+)>`)
+	mdEmphasis   = strings.NewReplacer("**", "", "__", "", "~~", "", "`", "")
+)
+
+// parseMarkdown extracts text from Markdown, without markup, with the first heading as title and the targets of links
+// and images as links.
+func parseMarkdown(data []byte) (*document, error) {
+	doc := &document{
+		Metadata: make(map[string][]string),
+	}
+
+	var lines []string
+
+	addLink := func(target string) {
+		if isLink(target) {
+			doc.URLs = append(doc.URLs, target)
+		}
+	}
+
+	for _, line := range strings.Split(toText(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		if mdFence.MatchString(line) {
+			continue
+		}
+
+		if m := mdDefinition.FindStringSubmatch(line); m != nil {
+			addLink(m[1])
+			continue
+		}
+
+		if m := mdHeading.FindStringSubmatch(line); m != nil {
+			line = m[1]
+
+			if doc.Metadata["title"] == nil && line != "" {
+				doc.Metadata["title"] = []string{mdEmphasis.Replace(line)}
+			}
+		}
+
+		line = mdPrefix.ReplaceAllString(line, "")
+
+		line = mdLink.ReplaceAllStringFunc(line, func(s string) string {
+			m := mdLink.FindStringSubmatch(s)
+			addLink(m[2])
+
+			return m[1]
+		})
+
+		line = mdAutolink.ReplaceAllStringFunc(line, func(s string) string {
+			target := s[1 : len(s)-1]
+			addLink(target)
+
+			return target
+		})
+
+		lines = append(lines, mdEmphasis.Replace(line))
+	}
+
+	doc.Content = strings.TrimSpace(strings.Join(lines, "\n"))
+
+	return doc, nil
+}
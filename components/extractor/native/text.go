@@ -0,0 +1,62 @@
+package native
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+// toText returns data as valid UTF-8.
+func toText(data []byte) string {
+	return strings.ToValidUTF8(string(data), "\uFFFD")
+}
+
+// isLink returns whether s is an absolute http(s) URL.
+func isLink(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// parseText extracts plain text.
+func parseText(data []byte) (*document, error) {
+	return &document{
+		Content: toText(data),
+	}, nil
+}
+
+// collectLinks appends the links among the strings in v, a decoded JSON value, to urls.
+func collectLinks(v interface{}, urls []string) []string {
+	switch v := v.(type) {
+	case string:
+		if isLink(v) {
+			urls = append(urls, v)
+		}
+	case []interface{}:
+		for _, e := range v {
+			urls = collectLinks(e, urls)
+		}
+	case map[string]interface{}:
+		for _, e := range v {
+			urls = collectLinks(e, urls)
+		}
+	}
+
+	return urls
+}
+
+// parseJSON extracts JSON as text, with the links among its strings.
+func parseJSON(data []byte) (*document, error) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+
+	return &document{
+		Content: toText(data),
+		URLs:    collectLinks(v, nil),
+	}, nil
+}
@@ -0,0 +1,113 @@
+package bleve
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/blevesearch/bleve/v2"
+
+	"github.com/ipfs-search/ipfs-search/instr"
+)
+
+// Client opens Bleve indexes in a directory, sharing them between users within the process.
+// An index can only be opened by a single process at a time.
+type Client struct {
+	cfg *ClientConfig
+
+	mu      sync.Mutex
+	indexes map[string]*Index
+
+	*instr.Instrumentation
+}
+
+// NewClient returns a new client.
+func NewClient(cfg *ClientConfig, i *instr.Instrumentation) *Client {
+	if cfg == nil {
+		panic("NewClient ClientConfig cannot be nil.")
+	}
+
+	if i == nil {
+		panic("NewClient Instrumentation cannot be nil.")
+	}
+
+	return &Client{
+		cfg:             cfg,
+		indexes:         make(map[string]*Index),
+		Instrumentation: i,
+	}
+}
+
+// index returns the index for cfg, opening or creating it when it is not opened yet.
+func (c *Client) index(cfg *Config) (*Index, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if i, ok := c.indexes[cfg.Name]; ok {
+		return i, nil
+	}
+
+	idx, err := c.open(cfg.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	i := &Index{
+		cfg: cfg,
+		c:   c,
+		idx: idx,
+	}
+	c.indexes[cfg.Name] = i
+
+	return i, nil
+}
+
+// open opens the index with name, creating it when it does not exist.
+func (c *Client) open(name string) (bleve.Index, error) {
+
+	var (
+		idx bleve.Index
+		err error
+	)
+
+	if c.cfg.Dir == "" {
+		idx, err = bleve.NewMemOnly(newMapping())
+	} else {
+		path := filepath.Join(c.cfg.Dir, name)
+
+		idx, err = bleve.Open(path)
+		if errors.Is(err, bleve.ErrorIndexPathDoesNotExist) {
+			if err = os.MkdirAll(c.cfg.Dir, 0755); err == nil {
+				idx, err = bleve.New(path, newMapping())
+			}
+		}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("opening index %s: %w", name, err)
+	}
+
+	idx.SetName(name)
+
+	return idx, nil
+}
+
+// Close closes all opened indexes.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var err error
+
+	for name, i := range c.indexes {
+		if closeErr := i.idx.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("closing index %s: %w", name, closeErr)
+		}
+
+		delete(c.indexes, name)
+	}
+
+	return err
+}
@@ -0,0 +1,18 @@
+package bleve
+
+// Config represents the configuration for a Bleve index.
+type Config struct {
+	Name string
+}
+
+// ClientConfig configures the directory holding Bleve indexes.
+type ClientConfig struct {
+	Dir string // Directory holding an index per name; in-memory indexes are used when empty.
+}
+
+// DefaultClientConfig returns the default configuration for a Client.
+func DefaultClientConfig() *ClientConfig {
+	return &ClientConfig{
+		Dir: "indexes",
+	}
+}
@@ -0,0 +1,301 @@
+// Package bleve provides an embedded index.Index and index.Searcher on Bleve, for running without a search cluster.
+package bleve
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/blevesearch/bleve/v2"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/ipfs-search/ipfs-search/components/index"
+	"github.com/ipfs-search/ipfs-search/components/index/types"
+)
+
+// primaryTerm is the constant primary term of document versions; only sequence numbers are used.
+const primaryTerm = 1
+
+// Index wraps a Bleve index to store documents. The source of documents is stored alongside, to get fields and to
+// apply updates.
+type Index struct {
+	cfg *Config
+	c   *Client
+	idx bleve.Index
+
+	mu sync.Mutex // Serializes reading and writing sources of documents.
+}
+
+// New returns the index with the configured name, creating it when it does not exist. Indexes are shared by name
+// within a Client.
+func New(client *Client, cfg *Config) (*Index, error) {
+	if client == nil {
+		panic("Index.New Client cannot be nil.")
+	}
+
+	if cfg == nil {
+		panic("Index.New Config cannot be nil.")
+	}
+
+	return client.index(cfg)
+}
+
+// String returns the name of the index, for convenient logging.
+func (i *Index) String() string {
+	return i.cfg.Name
+}
+
+// stored represents the stored source of a document.
+type stored struct {
+	SeqNo  int64                      `json:"seq_no"`
+	Source map[string]json.RawMessage `json:"source"`
+}
+
+func sourceKey(id string) []byte {
+	return []byte("source/" + id)
+}
+
+// toSource returns the fields of properties.
+func toSource(properties interface{}) (map[string]json.RawMessage, error) {
+	b, err := json.Marshal(properties)
+	if err != nil {
+		return nil, err
+	}
+
+	source := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &source); err != nil {
+		return nil, fmt.Errorf("properties are not an object: %w", err)
+	}
+
+	return source, nil
+}
+
+// read returns the stored document with id, or nil when it does not exist.
+func (i *Index) read(id string) (*stored, error) {
+	b, err := i.idx.GetInternal(sourceKey(id))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", id, err)
+	}
+
+	if b == nil {
+		return nil, nil
+	}
+
+	s := new(stored)
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", id, err)
+	}
+
+	return s, nil
+}
+
+// write indexes document s with id and stores its source.
+func (i *Index) write(id string, s *stored) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	// Index fields as decoded from JSON, like the stored source.
+	var fields map[string]interface{}
+
+	source, _ := json.Marshal(s.Source)
+	if err := json.Unmarshal(source, &fields); err != nil {
+		return err
+	}
+
+	batch := i.idx.NewBatch()
+
+	if err := batch.Index(id, fields); err != nil {
+		return fmt.Errorf("indexing %s: %w", id, err)
+	}
+
+	batch.SetInternal(sourceKey(id), b)
+
+	if err := i.idx.Batch(batch); err != nil {
+		return fmt.Errorf("writing %s: %w", id, err)
+	}
+
+	return nil
+}
+
+// Index a document's properties, identified by id, returning index.ErrConflict when it already exists.
+func (i *Index) Index(ctx context.Context, id string, properties interface{}) error {
+	ctx, span := i.c.Tracer.Start(ctx, "index.bleve.Index")
+	defer span.End()
+
+	source, err := toSource(properties)
+	if err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	existing, err := i.read(id)
+	if err != nil {
+		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+		return err
+	}
+
+	if existing != nil {
+		return index.ErrConflict
+	}
+
+	err = i.write(id, &stored{SeqNo: 1, Source: source})
+	if err != nil {
+		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+	}
+
+	return err
+}
+
+// Update a document's properties, given id. Updating a missing document has no effect, unless properties are
+// index.Versioned with a version set, in which case index.ErrConflict is returned when the document changed or was
+// deleted since it was read.
+func (i *Index) Update(ctx context.Context, id string, properties interface{}) error {
+	ctx, span := i.c.Tracer.Start(ctx, "index.bleve.Update")
+	defer span.End()
+
+	source, err := toSource(properties)
+	if err != nil {
+		return err
+	}
+
+	var version *types.Version
+	if v, ok := properties.(index.Versioned); ok && !v.DocumentVersion().IsZero() {
+		version = v.DocumentVersion()
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	s, err := i.read(id)
+	if err != nil {
+		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+		return err
+	}
+
+	if version != nil && (s == nil || s.SeqNo != version.SeqNo) {
+		return index.ErrConflict
+	}
+
+	if s == nil {
+		return nil
+	}
+
+	for field, value := range source {
+		s.Source[field] = value
+	}
+
+	s.SeqNo++
+
+	err = i.write(id, s)
+	if err != nil {
+		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+	}
+
+	return err
+}
+
+// Apply atomic operations to a document, given id. Like Update, this has no effect on missing documents.
+func (i *Index) Apply(ctx context.Context, id string, ops ...index.Operation) error {
+	ctx, span := i.c.Tracer.Start(ctx, "index.bleve.Apply")
+	defer span.End()
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	s, err := i.read(id)
+	if err != nil || s == nil {
+		return err
+	}
+
+	for _, op := range ops {
+		if err := op.ApplyTo(s.Source); err != nil {
+			return err
+		}
+	}
+
+	s.SeqNo++
+
+	err = i.write(id, s)
+	if err != nil {
+		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+	}
+
+	return err
+}
+
+// Delete item from index
+func (i *Index) Delete(ctx context.Context, id string) error {
+	ctx, span := i.c.Tracer.Start(ctx, "index.bleve.Delete")
+	defer span.End()
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	batch := i.idx.NewBatch()
+	batch.Delete(id)
+	batch.DeleteInternal(sourceKey(id))
+
+	if err := i.idx.Batch(batch); err != nil {
+		err = fmt.Errorf("deleting %s: %w", id, err)
+		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+		return err
+	}
+
+	return nil
+}
+
+// Get retreives `fields` from document with `id` from the index, returning:
+// - (true, decoding_error) if found (decoding error set when errors in json)
+// - (false, nil) when not found
+// - (false, error) otherwise
+// Fields are top-level fields of documents; all fields are returned when none are given.
+func (i *Index) Get(ctx context.Context, id string, dst interface{}, fields ...string) (bool, error) {
+	ctx, span := i.c.Tracer.Start(ctx, "index.bleve.Get")
+	defer span.End()
+
+	s, err := i.read(id)
+	if err != nil {
+		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+		return false, err
+	}
+
+	if s == nil {
+		return false, nil
+	}
+
+	projection := s.Source
+
+	if len(fields) > 0 {
+		projection = make(map[string]json.RawMessage, len(fields))
+
+		for _, field := range fields {
+			if v, ok := s.Source[field]; ok {
+				projection[field] = v
+			}
+		}
+	}
+
+	b, err := json.Marshal(projection)
+	if err != nil {
+		return true, err
+	}
+
+	if err := json.Unmarshal(b, dst); err != nil {
+		return true, err
+	}
+
+	if v, ok := dst.(index.Versioned); ok {
+		*v.DocumentVersion() = types.Version{SeqNo: s.SeqNo, PrimaryTerm: primaryTerm}
+	}
+
+	return true, nil
+}
+
+// Compile-time assurance that implementation satisfies interface.
+var _ index.Index = &Index{}
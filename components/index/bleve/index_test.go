@@ -0,0 +1,191 @@
+package bleve
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/ipfs-search/ipfs-search/components/index"
+	"github.com/ipfs-search/ipfs-search/components/index/types"
+	"github.com/ipfs-search/ipfs-search/instr"
+)
+
+type IndexTestSuite struct {
+	suite.Suite
+
+	ctx    context.Context
+	client *Client
+	files  *Index
+	dirs   *Index
+}
+
+func (s *IndexTestSuite) SetupTest() {
+	var err error
+
+	s.ctx = context.Background()
+	s.client = NewClient(&ClientConfig{}, instr.New())
+
+	s.files, err = New(s.client, &Config{Name: "files"})
+	s.Require().NoError(err)
+
+	s.dirs, err = New(s.client, &Config{Name: "directories"})
+	s.Require().NoError(err)
+}
+
+func (s *IndexTestSuite) TearDownTest() {
+	s.NoError(s.client.Close())
+}
+
+func (s *IndexTestSuite) document() *types.File {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	return &types.File{
+		Document: types.Document{
+			FirstSeen:  now,
+			LastSeen:   now,
+			References: types.References{{ParentHash: "QmParent", Name: "test.txt"}},
+			Size:       5,
+		},
+		Content: "A test document",
+		Metadata: types.Metadata{
+			"title":        []interface{}{"Test title"},
+			"Content-Type": []interface{}{"text/plain"},
+		},
+	}
+}
+
+func (s *IndexTestSuite) TestIndexGet() {
+	s.NoError(s.files.Index(s.ctx, "id", s.document()))
+
+	dst := new(types.File)
+	found, err := s.files.Get(s.ctx, "id", dst)
+	s.NoError(err)
+	s.True(found)
+	s.Equal("A test document", dst.Content)
+	s.Equal(uint64(5), dst.Size)
+}
+
+func (s *IndexTestSuite) TestIndexConflict() {
+	s.NoError(s.files.Index(s.ctx, "id", s.document()))
+	s.ErrorIs(s.files.Index(s.ctx, "id", s.document()), index.ErrConflict)
+}
+
+func (s *IndexTestSuite) TestGetProjection() {
+	s.NoError(s.files.Index(s.ctx, "id", s.document()))
+
+	dst := new(types.File)
+	found, err := s.files.Get(s.ctx, "id", dst, "size")
+	s.NoError(err)
+	s.True(found)
+	s.Equal(uint64(5), dst.Size)
+	s.Empty(dst.Content)
+}
+
+func (s *IndexTestSuite) TestGetNotFound() {
+	found, err := s.files.Get(s.ctx, "id", new(types.File))
+	s.NoError(err)
+	s.False(found)
+}
+
+func (s *IndexTestSuite) TestUpdate() {
+	s.NoError(s.files.Index(s.ctx, "id", s.document()))
+
+	later := time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)
+	s.NoError(s.files.Update(s.ctx, "id", &types.Update{LastSeen: &later}))
+
+	dst := new(types.File)
+	_, err := s.files.Get(s.ctx, "id", dst)
+	s.NoError(err)
+	s.True(later.Equal(dst.LastSeen))
+	s.Equal("A test document", dst.Content)
+}
+
+func (s *IndexTestSuite) TestUpdateMissing() {
+	s.NoError(s.files.Update(s.ctx, "id", &types.Update{}))
+
+	found, err := s.files.Get(s.ctx, "id", new(types.File))
+	s.NoError(err)
+	s.False(found)
+}
+
+func (s *IndexTestSuite) TestUpdateVersioned() {
+	s.NoError(s.files.Index(s.ctx, "id", s.document()))
+
+	u := new(types.Update)
+	_, err := s.files.Get(s.ctx, "id", u, "references")
+	s.NoError(err)
+	s.False(u.DocumentVersion().IsZero())
+
+	stale := *u
+	s.NoError(s.files.Update(s.ctx, "id", u))
+	s.ErrorIs(s.files.Update(s.ctx, "id", &stale), index.ErrConflict)
+}
+
+func (s *IndexTestSuite) TestApply() {
+	s.NoError(s.files.Index(s.ctx, "id", s.document()))
+
+	later := time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)
+	s.NoError(s.files.Apply(s.ctx, "id",
+		index.Max("last-seen", later),
+		index.AppendIfAbsent("references", types.Reference{ParentHash: "QmOther", Name: "other.txt"}),
+	))
+
+	dst := new(types.File)
+	_, err := s.files.Get(s.ctx, "id", dst)
+	s.NoError(err)
+	s.True(later.Equal(dst.LastSeen))
+	s.Len(dst.References, 2)
+}
+
+func (s *IndexTestSuite) TestDelete() {
+	s.NoError(s.files.Index(s.ctx, "id", s.document()))
+	s.NoError(s.files.Delete(s.ctx, "id"))
+
+	found, err := s.files.Get(s.ctx, "id", new(types.File))
+	s.NoError(err)
+	s.False(found)
+
+	count, err := s.files.idx.DocCount()
+	s.NoError(err)
+	s.Zero(count)
+}
+
+func (s *IndexTestSuite) TestMultiGet() {
+	s.NoError(s.dirs.Index(s.ctx, "id", &types.Directory{Document: types.Document{Size: 7}}))
+
+	dst := new(types.Update)
+	idx, err := index.MultiGet(s.ctx, []index.Index{s.files, s.dirs}, "id", dst, "size")
+	s.NoError(err)
+	s.Equal(s.dirs, idx)
+}
+
+func (s *IndexTestSuite) TestPersistent() {
+	dir := s.T().TempDir()
+	client := NewClient(&ClientConfig{Dir: dir}, instr.New())
+
+	files, err := New(client, &Config{Name: "files"})
+	s.Require().NoError(err)
+	s.NoError(files.Index(s.ctx, "id", s.document()))
+	s.NoError(client.Close())
+
+	_, err = os.Stat(filepath.Join(dir, "files"))
+	s.NoError(err)
+
+	client = NewClient(&ClientConfig{Dir: dir}, instr.New())
+	defer client.Close()
+
+	files, err = New(client, &Config{Name: "files"})
+	s.Require().NoError(err)
+
+	found, err := files.Get(s.ctx, "id", new(types.File))
+	s.NoError(err)
+	s.True(found)
+}
+
+func TestIndexTestSuite(t *testing.T) {
+	suite.Run(t, new(IndexTestSuite))
+}
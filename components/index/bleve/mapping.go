@@ -0,0 +1,106 @@
+package bleve
+
+import (
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/mapping"
+)
+
+// Field types, equivalent to those in the Elasticsearch mappings for files and directories.
+func text() *mapping.FieldMapping {
+	// Stored with term vectors for highlighting.
+	return bleve.NewTextFieldMapping()
+}
+
+func keyword() *mapping.FieldMapping {
+	m := bleve.NewKeywordFieldMapping()
+	m.Store = false
+
+	return m
+}
+
+func number() *mapping.FieldMapping {
+	m := bleve.NewNumericFieldMapping()
+	m.Store = false
+
+	return m
+}
+
+func date() *mapping.FieldMapping {
+	m := bleve.NewDateTimeFieldMapping()
+	m.Store = false
+
+	return m
+}
+
+// static returns a mapping for an object with fields, leaving out other fields.
+func static(fields map[string]*mapping.FieldMapping) *mapping.DocumentMapping {
+	m := bleve.NewDocumentStaticMapping()
+
+	for name, f := range fields {
+		m.AddFieldMappingsAt(name, f)
+	}
+
+	return m
+}
+
+// newMapping returns the mapping for documents of all kinds of indexes. The sources of documents are stored
+// separately, so only fields which are searched are indexed.
+func newMapping() mapping.IndexMapping {
+	doc := static(map[string]*mapping.FieldMapping{
+		"first-seen":        date(),
+		"last-seen":         date(),
+		"size":              number(),
+		"content":           text(),
+		"ipfs_tika_version": keyword(),
+		"urls":              keyword(),
+	})
+
+	doc.AddSubDocumentMapping("language", static(map[string]*mapping.FieldMapping{
+		"confidence": keyword(),
+		"language":   keyword(),
+		"rawScore":   number(),
+	}))
+
+	doc.AddSubDocumentMapping("metadata", static(map[string]*mapping.FieldMapping{
+		"created":           date(),
+		"date":              date(),
+		"modified":          date(),
+		"title":             text(),
+		"name":              text(),
+		"author":            text(),
+		"description":       text(),
+		"producer":          text(),
+		"publisher":         text(),
+		"keywords":          text(),
+		"xmpDM:album":       text(),
+		"xmpDM:albumArtist": text(),
+		"xmpDM:artist":      text(),
+		"xmpDM:composer":    text(),
+		"isbn":              keyword(),
+		"language":          keyword(),
+		"resourceName":      keyword(),
+		"Content-Type":      keyword(),
+		"X-Parsed-By":       keyword(),
+	}))
+
+	doc.AddSubDocumentMapping("references", static(map[string]*mapping.FieldMapping{
+		"name":        text(),
+		"hash":        keyword(),
+		"parent_hash": keyword(),
+	}))
+
+	doc.AddSubDocumentMapping("links", static(map[string]*mapping.FieldMapping{
+		"Hash": keyword(),
+		"Name": text(),
+		"Size": number(),
+		"Type": keyword(),
+	}))
+
+	m := bleve.NewIndexMapping()
+	m.DefaultMapping = doc
+	m.IndexDynamic = false
+	m.StoreDynamic = false
+	m.DocValuesDynamic = false
+
+	return m
+}
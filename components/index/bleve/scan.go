@@ -0,0 +1,96 @@
+package bleve
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/search/query"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/ipfs-search/ipfs-search/components/index"
+)
+
+// scanSize is the number of documents per page of a scan.
+const scanSize = 1000
+
+// getScanQuery returns the query for documents matching filter.
+func getScanQuery(filter *index.ScanFilter) query.Query {
+	var conjuncts []query.Query
+
+	if !filter.LastSeenAfter.IsZero() || !filter.LastSeenBefore.IsZero() {
+		lastSeen := bleve.NewDateRangeQuery(filter.LastSeenAfter, filter.LastSeenBefore)
+		lastSeen.SetField("last-seen")
+
+		conjuncts = append(conjuncts, lastSeen)
+	}
+
+	if filter.MIMEType != "" {
+		mimeType := bleve.NewPrefixQuery(filter.MIMEType)
+		mimeType.SetField("metadata.Content-Type")
+
+		conjuncts = append(conjuncts, mimeType)
+	}
+
+	if len(conjuncts) == 0 {
+		return bleve.NewMatchAllQuery()
+	}
+
+	return bleve.NewConjunctionQuery(conjuncts...)
+}
+
+// Scan calls fn with the id and source of each document matching filter, paging through the index by id.
+func (i *Index) Scan(ctx context.Context, filter *index.ScanFilter, fn func(id string, source json.RawMessage) error) error {
+	ctx, span := i.c.Tracer.Start(ctx, "index.bleve.Scan")
+	defer span.End()
+
+	q := getScanQuery(filter)
+
+	var after []string
+
+	for {
+		req := bleve.NewSearchRequestOptions(q, scanSize, 0, false)
+		req.SortBy([]string{"_id"})
+		req.SearchAfter = after
+
+		res, err := i.idx.SearchInContext(ctx, req)
+		if err != nil {
+			err = fmt.Errorf("scanning: %w", err)
+			span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+			return err
+		}
+
+		if len(res.Hits) == 0 {
+			return nil
+		}
+
+		for _, hit := range res.Hits {
+			s, err := i.read(hit.ID)
+			if err != nil {
+				span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+				return err
+			}
+
+			if s == nil {
+				// Deleted while scanning.
+				continue
+			}
+
+			source, err := json.Marshal(s.Source)
+			if err != nil {
+				return err
+			}
+
+			if err := fn(hit.ID, source); err != nil {
+				return err
+			}
+		}
+
+		after = []string{res.Hits[len(res.Hits)-1].ID}
+	}
+}
+
+// Compile-time assurance that implementation satisfies interface.
+var _ index.Scanner = &Index{}
@@ -0,0 +1,59 @@
+package bleve
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/ipfs-search/ipfs-search/components/index"
+)
+
+// scan returns the ids of documents in files matching filter.
+func (s *IndexTestSuite) scan(filter *index.ScanFilter) []string {
+	var ids []string
+
+	err := s.files.Scan(s.ctx, filter, func(id string, source json.RawMessage) error {
+		s.True(json.Valid(source))
+		ids = append(ids, id)
+		return nil
+	})
+	s.NoError(err)
+
+	sort.Strings(ids)
+
+	return ids
+}
+
+func (s *IndexTestSuite) TestScan() {
+	old := s.document()
+	old.LastSeen = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	old.Metadata["Content-Type"] = []interface{}{"image/png"}
+
+	s.NoError(s.files.Index(s.ctx, "new", s.document()))
+	s.NoError(s.files.Index(s.ctx, "old", old))
+
+	s.Equal([]string{"new", "old"}, s.scan(&index.ScanFilter{}))
+	s.Equal([]string{"old"}, s.scan(&index.ScanFilter{MIMEType: "image/"}))
+	s.Equal([]string{"new"}, s.scan(&index.ScanFilter{LastSeenAfter: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)}))
+	s.Equal([]string{"old"}, s.scan(&index.ScanFilter{LastSeenBefore: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)}))
+}
+
+func (s *IndexTestSuite) TestScanSource() {
+	s.NoError(s.files.Index(s.ctx, "id", s.document()))
+
+	err := s.files.Scan(s.ctx, &index.ScanFilter{}, func(id string, source json.RawMessage) error {
+		var doc map[string]interface{}
+		s.NoError(json.Unmarshal(source, &doc))
+		s.Equal("A test document", doc["content"])
+		return nil
+	})
+	s.NoError(err)
+}
+
+func (s *IndexTestSuite) TestScanError() {
+	s.NoError(s.files.Index(s.ctx, "id", s.document()))
+
+	err := errors.New("stop")
+	s.ErrorIs(s.files.Scan(s.ctx, &index.ScanFilter{}, func(string, json.RawMessage) error { return err }), err)
+}
@@ -0,0 +1,227 @@
+package bleve
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/registry"
+	"github.com/blevesearch/bleve/v2/search/highlight"
+	htmlFormatter "github.com/blevesearch/bleve/v2/search/highlight/format/html"
+	simpleFragmenter "github.com/blevesearch/bleve/v2/search/highlight/fragmenter/simple"
+	simpleHighlighter "github.com/blevesearch/bleve/v2/search/highlight/highlighter/simple"
+	"github.com/blevesearch/bleve/v2/search/query"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/ipfs-search/ipfs-search/components/index"
+	"github.com/ipfs-search/ipfs-search/components/index/types"
+)
+
+// highlighterName is the name of the highlighter marking matches with <em>, like Elasticsearch.
+const highlighterName = "ipfs-search"
+
+func init() {
+	registry.RegisterHighlighter(highlighterName, func(config map[string]interface{}, cache *registry.Cache) (highlight.Highlighter, error) {
+		return simpleHighlighter.NewHighlighter(
+			simpleFragmenter.NewFragmenter(100),
+			htmlFormatter.NewFragmentFormatter("<em>", "</em>"),
+			simpleHighlighter.DefaultSeparator,
+		), nil
+	})
+}
+
+// Fields for which matching snippets are returned.
+var highlightFields = []string{
+	"content",
+	"metadata.title",
+	"metadata.description",
+	"references.name",
+}
+
+// Searcher searches files and directories in Bleve indexes.
+// Query strings use Bleve's query string syntax, in which terms are optional unless prefixed with `+`.
+type Searcher struct {
+	files       *Index
+	directories *Index
+}
+
+// NewSearcher returns a new searcher.
+func NewSearcher(files, directories *Index) index.Searcher {
+	if files == nil || directories == nil {
+		panic("Searcher.New indexes cannot be nil.")
+	}
+
+	return &Searcher{
+		files:       files,
+		directories: directories,
+	}
+}
+
+func (s *Searcher) indexes(t index.DocumentType) ([]*Index, error) {
+	switch t {
+	case index.AnyType, "":
+		return []*Index{s.files, s.directories}, nil
+	case index.FileType:
+		return []*Index{s.files}, nil
+	case index.DirectoryType:
+		return []*Index{s.directories}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown document type '%s'", index.ErrInvalidQuery, t)
+	}
+}
+
+func getQuery(q *index.Query) (query.Query, error) {
+	text, err := bleve.NewQueryStringQuery(q.Text).Parse()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", index.ErrInvalidQuery, err)
+	}
+
+	conjuncts := []query.Query{text}
+
+	if q.MinSize > 0 || q.MaxSize > 0 {
+		var min, max *float64
+
+		if q.MinSize > 0 {
+			v := float64(q.MinSize)
+			min = &v
+		}
+		if q.MaxSize > 0 {
+			v := float64(q.MaxSize)
+			max = &v
+		}
+
+		inclusive := true
+		size := bleve.NewNumericRangeInclusiveQuery(min, max, &inclusive, &inclusive)
+		size.SetField("size")
+
+		conjuncts = append(conjuncts, size)
+	}
+
+	if !q.LastSeenAfter.IsZero() {
+		lastSeen := bleve.NewDateRangeQuery(q.LastSeenAfter, time.Time{})
+		lastSeen.SetField("last-seen")
+
+		conjuncts = append(conjuncts, lastSeen)
+	}
+
+	if q.MIMEType != "" {
+		mimeType := bleve.NewPrefixQuery(q.MIMEType)
+		mimeType.SetField("metadata.Content-Type")
+
+		conjuncts = append(conjuncts, mimeType)
+	}
+
+	return bleve.NewConjunctionQuery(conjuncts...), nil
+}
+
+type hitSource struct {
+	Size       uint64                 `json:"size"`
+	FirstSeen  time.Time              `json:"first-seen"`
+	LastSeen   time.Time              `json:"last-seen"`
+	References types.References       `json:"references"`
+	Metadata   map[string]interface{} `json:"metadata"`
+}
+
+// metadataString returns the first value of a metadata field, which may be a string or a list of strings.
+func metadataString(m map[string]interface{}, field string) string {
+	switch v := m[field].(type) {
+	case string:
+		return v
+	case []interface{}:
+		if len(v) > 0 {
+			s, _ := v[0].(string)
+			return s
+		}
+	}
+
+	return ""
+}
+
+// Search returns a page of files and/or directories matching the query.
+func (s *Searcher) Search(ctx context.Context, q *index.Query) (*index.SearchResult, error) {
+	ctx, span := s.files.c.Tracer.Start(ctx, "index.bleve.Search")
+	defer span.End()
+
+	indexes, err := s.indexes(q.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	bq, err := getQuery(q)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*Index, len(indexes))
+	alias := bleve.NewIndexAlias()
+
+	for _, idx := range indexes {
+		byName[idx.idx.Name()] = idx
+		alias.Add(idx.idx)
+	}
+
+	req := bleve.NewSearchRequestOptions(bq, q.Size, q.From, false)
+	req.Highlight = bleve.NewHighlightWithStyle(highlighterName)
+
+	for _, f := range highlightFields {
+		req.Highlight.AddField(f)
+	}
+
+	res, err := alias.SearchInContext(ctx, req)
+	if err != nil {
+		err = fmt.Errorf("searching: %w", err)
+		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+		return nil, err
+	}
+
+	result := &index.SearchResult{
+		Total:    int64(res.Total),
+		MaxScore: res.MaxScore,
+		Hits:     make([]index.Hit, 0, len(res.Hits)),
+	}
+
+	for _, h := range res.Hits {
+		idx := byName[h.Index]
+
+		source := new(hitSource)
+		found, err := idx.Get(ctx, h.ID, source, "size", "first-seen", "last-seen", "references", "metadata")
+		if err != nil {
+			span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+			return nil, err
+		}
+
+		if !found {
+			// Deleted since searching.
+			continue
+		}
+
+		hit := index.Hit{
+			ID:         h.ID,
+			Type:       index.FileType,
+			Score:      h.Score,
+			Title:      metadataString(source.Metadata, "title"),
+			MIMEType:   metadataString(source.Metadata, "Content-Type"),
+			Size:       source.Size,
+			FirstSeen:  source.FirstSeen,
+			LastSeen:   source.LastSeen,
+			References: source.References,
+		}
+
+		if idx == s.directories {
+			hit.Type = index.DirectoryType
+		}
+
+		if len(h.Fragments) > 0 {
+			hit.Highlights = h.Fragments
+		}
+
+		result.Hits = append(result.Hits, hit)
+	}
+
+	return result, nil
+}
+
+// Compile-time assurance that implementation satisfies interface.
+var _ index.Searcher = &Searcher{}
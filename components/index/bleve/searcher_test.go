@@ -0,0 +1,75 @@
+package bleve
+
+import (
+	"time"
+
+	"github.com/ipfs-search/ipfs-search/components/index"
+	"github.com/ipfs-search/ipfs-search/components/index/types"
+)
+
+func (s *IndexTestSuite) searcher() index.Searcher {
+	s.NoError(s.files.Index(s.ctx, "file", s.document()))
+	s.NoError(s.dirs.Index(s.ctx, "dir", &types.Directory{
+		Document: types.Document{
+			References: types.References{{ParentHash: "QmParent", Name: "test"}},
+			Size:       23,
+		},
+	}))
+
+	return NewSearcher(s.files, s.dirs)
+}
+
+func (s *IndexTestSuite) TestSearch() {
+	r, err := s.searcher().Search(s.ctx, &index.Query{Text: "test", Size: 10})
+	s.Require().NoError(err)
+
+	s.Equal(int64(2), r.Total)
+	s.Require().Len(r.Hits, 2)
+
+	byID := map[string]index.Hit{}
+	for _, h := range r.Hits {
+		byID[h.ID] = h
+	}
+
+	file := byID["file"]
+	s.Equal(index.FileType, file.Type)
+	s.Equal("Test title", file.Title)
+	s.Equal("text/plain", file.MIMEType)
+	s.Equal(uint64(5), file.Size)
+	s.Equal(types.References{{ParentHash: "QmParent", Name: "test.txt"}}, file.References)
+	s.Equal([]string{"A <em>test</em> document"}, file.Highlights["content"])
+
+	s.Equal(index.DirectoryType, byID["dir"].Type)
+}
+
+func (s *IndexTestSuite) TestSearchFilters() {
+	searcher := s.searcher()
+
+	queries := []*index.Query{
+		{Text: "test", Type: index.FileType},
+		{Text: "test", MIMEType: "text/"},
+		{Text: "test", MinSize: 1, MaxSize: 10},
+		{Text: "test", LastSeenAfter: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, q := range queries {
+		q.Size = 10
+
+		r, err := searcher.Search(s.ctx, q)
+		s.Require().NoError(err)
+		s.Require().Len(r.Hits, 1, "%+v", q)
+		s.Equal("file", r.Hits[0].ID)
+	}
+}
+
+func (s *IndexTestSuite) TestSearchPage() {
+	r, err := s.searcher().Search(s.ctx, &index.Query{Text: "test", From: 1, Size: 10})
+	s.NoError(err)
+	s.Equal(int64(2), r.Total)
+	s.Len(r.Hits, 1)
+}
+
+func (s *IndexTestSuite) TestSearchInvalid() {
+	_, err := s.searcher().Search(s.ctx, &index.Query{Text: `"unterminated`, Size: 10})
+	s.ErrorIs(err, index.ErrInvalidQuery)
+}
@@ -1,6 +1,29 @@
 package cache
 
+import (
+	"time"
+)
+
 // Config for caching index.
 type Config struct {
-	CachingFields []string
+	CachingFields []string      // Fields to cache; gets of other fields are served from the backing index.
+	Capacity      int           // Maximum number of documents to cache in memory, and separately of documents not found.
+	TTL           time.Duration // Time to cache documents in memory; writes by other processes are not seen meanwhile.
+	NegativeTTL   time.Duration // Time to cache that documents were not found.
+}
+
+// DefaultConfig returns the default configuration for a caching index.
+func DefaultConfig() *Config {
+	return &Config{
+		CachingFields: []string{"references", "last-seen"},
+		Capacity:      100000,
+		TTL:           time.Minute,
+		NegativeTTL:   10 * time.Second,
+	}
 }
+
+// Cache modes for Wrap.
+const (
+	None   = "none"   // Do not cache.
+	Memory = "memory" // Cache in memory, with NewMemory.
+)
@@ -2,45 +2,165 @@ package cache
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/ipfs-search/ipfs-search/components/index"
+	"github.com/ipfs-search/ipfs-search/components/index/memory"
 	"github.com/ipfs-search/ipfs-search/instr"
 )
 
+// ErrUnknownMode is returned by Wrap for unknown cache modes.
+var ErrUnknownMode = errors.New("unknown cache mode")
+
 // Index wraps a backing index and caches it using another index.
+// Documents which were not found are cached in a negative index, when set, as documents without fields.
 type Index struct {
-	cfg          *Config
-	backingIndex index.Index
-	cachingIndex index.Index
+	cfg           *Config
+	backingIndex  index.Index
+	cachingIndex  index.Index
+	negativeIndex index.Index
 
 	*instr.Instrumentation
 }
 
-// New returns a new index.
-func New(backing index.Index, caching index.Index, cfg *Config) index.Index {
+// New returns a new index. The negative index is optional, nil disables caching of documents not found.
+func New(backing index.Index, caching index.Index, negative index.Index, cfg *Config, i *instr.Instrumentation) index.Index {
 
 	if cfg == nil {
 		panic("Index.New Config cannot be nil.")
 	}
 
 	index := &Index{
-		backingIndex: backing,
-		cachingIndex: caching,
-		cfg:          cfg,
+		backingIndex:    backing,
+		cachingIndex:    caching,
+		negativeIndex:   negative,
+		cfg:             cfg,
+		Instrumentation: i,
 	}
 
 	return index
 }
 
+// NewMemory returns a new index caching backing in memory.
+func NewMemory(backing index.Index, cfg *Config, i *instr.Instrumentation) index.Index {
+	if cfg == nil {
+		panic("Index.NewMemory Config cannot be nil.")
+	}
+
+	caching := memory.New(&memory.Config{Capacity: cfg.Capacity, TTL: cfg.TTL})
+	negative := memory.New(&memory.Config{Capacity: cfg.Capacity, TTL: cfg.NegativeTTL})
+
+	return New(backing, caching, negative, cfg, i)
+}
+
+// Wrap returns backing, cached according to mode.
+func Wrap(backing index.Index, mode string, cfg *Config, i *instr.Instrumentation) (index.Index, error) {
+	switch mode {
+	case None:
+		return backing, nil
+	case Memory:
+		return NewMemory(backing, cfg, i), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMode, mode)
+	}
+}
+
 // String returns the name of the index, for convenient logging.
 func (i *Index) String() string {
 	return fmt.Sprintf("cache for %s through %s", i.backingIndex, i.cachingIndex)
 }
 
-func makeCachingProperties(properties interface{}) interface{} {
-	// Take care to allocate map for caching properties on the stack.
-	return nil
+// makeCachingProperties returns the properties for the caching index, limited to the caching fields.
+func (i *Index) makeCachingProperties(properties interface{}) (interface{}, error) {
+	b, err := json.Marshal(properties)
+	if err != nil {
+		return nil, err
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(b, &all); err != nil {
+		return nil, err
+	}
+
+	cachingProperties := make(map[string]json.RawMessage, len(i.cfg.CachingFields))
+	for _, field := range i.cfg.CachingFields {
+		if v, ok := all[field]; ok {
+			cachingProperties[field] = v
+		}
+	}
+
+	return cachingProperties, nil
+}
+
+// decodeFields decodes fields from doc into dst.
+func decodeFields(doc map[string]json.RawMessage, dst interface{}, fields []string) error {
+	projection := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		if v, ok := doc[field]; ok {
+			projection[field] = v
+		}
+	}
+
+	b, err := json.Marshal(projection)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, dst)
+}
+
+// isCached returns whether all fields are caching fields.
+func (i *Index) isCached(fields []string) bool {
+	if len(fields) == 0 {
+		// All fields requested.
+		return false
+	}
+
+	for _, field := range fields {
+		found := false
+
+		for _, cachingField := range i.cfg.CachingFields {
+			if field == cachingField {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// invalidate removes document id from the caches.
+func (i *Index) invalidate(ctx context.Context, id string) error {
+	if i.negativeIndex != nil {
+		if err := i.negativeIndex.Delete(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	return i.cachingIndex.Delete(ctx, id)
+}
+
+// cache adds a document's properties, identified by id, to the caching index.
+func (i *Index) cache(ctx context.Context, id string, properties interface{}) error {
+	cachingProperties, err := i.makeCachingProperties(properties)
+	if err != nil {
+		return err
+	}
+
+	err = i.cachingIndex.Index(ctx, id, cachingProperties)
+	if errors.Is(err, index.ErrConflict) {
+		// Cached concurrently, possibly with different properties.
+		return i.cachingIndex.Delete(ctx, id)
+	}
+
+	return err
 }
 
 // Index a document's properties, identified by id
@@ -48,12 +168,30 @@ func (i *Index) Index(ctx context.Context, id string, properties interface{}) er
 	ctx, span := i.Tracer.Start(ctx, "index.cache.Index")
 	defer span.End()
 
-	if err := i.backingIndex.Index(ctx, id, properties); err != nil {
-		return err
-	}
+	// Cache once written, as conflicts of asynchronous writes are only known after flushing.
+	return index.OnWritten(ctx,
+		func(ctx context.Context) error {
+			return i.backingIndex.Index(ctx, id, properties)
+		},
+		func(ctx context.Context, err error) error {
+			if err != nil {
+				// On conflict, the document exists, but differently from the cache, if at all.
+				if err := i.invalidate(ctx, id); err != nil {
+					return err
+				}
+
+				return err
+			}
 
-	cachingProperties := makeCachingProperties(properties)
-	return i.cachingIndex.Index(ctx, id, cachingProperties)
+			if i.negativeIndex != nil {
+				if err := i.negativeIndex.Delete(ctx, id); err != nil {
+					return err
+				}
+			}
+
+			return i.cache(ctx, id, properties)
+		},
+	)
 }
 
 // Update a document's properties, given id
@@ -62,13 +200,34 @@ func (i *Index) Update(ctx context.Context, id string, properties interface{}) e
 	defer span.End()
 
 	if err := i.backingIndex.Update(ctx, id, properties); err != nil {
+		if err := i.invalidate(ctx, id); err != nil {
+			return err
+		}
+
+		return err
+	}
+
+	cachingProperties, err := i.makeCachingProperties(properties)
+	if err != nil {
 		return err
 	}
 
-	cachingProperties := makeCachingProperties(properties)
 	return i.cachingIndex.Update(ctx, id, cachingProperties)
 }
 
+// Apply atomic operations to a document, given id
+func (i *Index) Apply(ctx context.Context, id string, ops ...index.Operation) error {
+	ctx, span := i.Tracer.Start(ctx, "index.cache.Apply")
+	defer span.End()
+
+	if err := i.backingIndex.Apply(ctx, id, ops...); err != nil {
+		return err
+	}
+
+	// The result of operations is unknown without reading back the document; invalidate cache.
+	return i.invalidate(ctx, id)
+}
+
 // Delete item from index
 func (i *Index) Delete(ctx context.Context, id string) error {
 	ctx, span := i.Tracer.Start(ctx, "index.cache.Delete")
@@ -82,12 +241,32 @@ func (i *Index) Delete(ctx context.Context, id string) error {
 }
 
 // Get retreives `fields` from document with `id` from the index, returning:
+// - (true, decoding_error) if found (decoding error set when errors in json)
+// - (false, nil) when not found
+// Only gets limited to caching fields are cached. As versions are not cached, the version of index.Versioned documents
+// is not set when getting caching fields.
 func (i *Index) Get(ctx context.Context, id string, dst interface{}, fields ...string) (bool, error) {
-	ctx, span := i.Tracer.Start(ctx, "index.elasticsearch.Get")
+	ctx, span := i.Tracer.Start(ctx, "index.cache.Get")
 	defer span.End()
 
-	// First, try caching index
-	found, err := i.cachingIndex.Get(ctx, id, dst)
+	if !i.isCached(fields) {
+		return i.backingIndex.Get(ctx, id, dst, fields...)
+	}
+
+	// First, try negative index
+	if i.negativeIndex != nil {
+		found, err := i.negativeIndex.Get(ctx, id, &struct{}{})
+		if err != nil {
+			return false, err
+		}
+
+		if found {
+			return false, nil
+		}
+	}
+
+	// Secondly, try caching index
+	found, err := i.cachingIndex.Get(ctx, id, dst, fields...)
 	if err != nil {
 		return false, err
 	}
@@ -96,20 +275,147 @@ func (i *Index) Get(ctx context.Context, id string, dst interface{}, fields ...s
 		return true, nil
 	}
 
-	// Secondly, try backind index
-	found, err = i.backingIndex.Get(ctx, id, dst)
+	// Lastly, try backing index, getting all caching fields so the cached document is complete.
+	var doc map[string]json.RawMessage
+
+	found, err = i.backingIndex.Get(ctx, id, &doc, i.cfg.CachingFields...)
 	if err != nil {
 		return false, err
 	}
 
 	if found {
 		// Add to cache
-		cachingProperties := makeCachingProperties(dst)
-		return true, i.cachingIndex.Index(ctx, id, cachingProperties)
+		if err := i.cache(ctx, id, doc); err != nil {
+			return true, err
+		}
+
+		return true, decodeFields(doc, dst, fields)
+	}
+
+	return false, i.cacheNotFound(ctx, id)
+}
+
+// getCached gets a document from the caches, returning whether it was found and whether it is cached at all.
+func (i *Index) getCached(ctx context.Context, id string, dst interface{}, fields ...string) (found, cached bool, err error) {
+	if i.negativeIndex != nil {
+		found, err := i.negativeIndex.Get(ctx, id, &struct{}{})
+		if err != nil || found {
+			return false, found, err
+		}
+	}
+
+	found, err = i.cachingIndex.Get(ctx, id, dst, fields...)
+
+	return found, found, err
+}
+
+// cacheNotFound caches that document id was not found, when caching of documents not found is enabled.
+func (i *Index) cacheNotFound(ctx context.Context, id string) error {
+	if i.negativeIndex == nil {
+		return nil
+	}
+
+	err := i.negativeIndex.Index(ctx, id, struct{}{})
+	if errors.Is(err, index.ErrConflict) {
+		// Cached concurrently.
+		err = nil
+	}
+
+	return err
+}
+
+// MultiGet retrieves `fields` from the document with `id` from the first of `indexes` which has it, like
+// index.MultiGet. When all indexes are cached by backing index.MultiGetter's, gets limited to caching fields are served
+// from the caches as long as the document is known not to be in preceding indexes. Otherwise, the document is got from
+// the remaining backing indexes at once, caching it in the index it was found in and caching preceding indexes as not
+// having it. Other indexes are got from with index.ConcurrentMultiGet.
+func (i *Index) MultiGet(ctx context.Context, indexes []index.Index, id string, dst interface{}, fields ...string) (index.Index, error) {
+	ctx, span := i.Tracer.Start(ctx, "index.cache.MultiGet")
+	defer span.End()
+
+	caches := make([]*Index, len(indexes))
+	backings := make([]index.Index, len(indexes))
+
+	for n, idx := range indexes {
+		c, ok := idx.(*Index)
+		if !ok {
+			return index.ConcurrentMultiGet(ctx, indexes, id, dst, fields...)
+		}
+
+		caches[n] = c
+		backings[n] = c.backingIndex
+	}
+
+	if _, ok := backings[0].(index.MultiGetter); !ok {
+		// Without getting from backing indexes in order, it is unknown which preceding indexes lack the document.
+		return index.ConcurrentMultiGet(ctx, indexes, id, dst, fields...)
+	}
+
+	// cacheOf returns the cache of the backing index which was got from.
+	cacheOf := func(backing index.Index) index.Index {
+		for n, b := range backings {
+			if b == backing {
+				return caches[n]
+			}
+		}
+
+		return nil
+	}
+
+	for _, c := range caches {
+		if !c.isCached(fields) {
+			backing, err := index.MultiGet(ctx, backings, id, dst, fields...)
+			return cacheOf(backing), err
+		}
+	}
+
+	// Serve from caches, until the first index for which it is unknown whether it has the document.
+	first := 0
+
+	for ; first < len(caches); first++ {
+		found, cached, err := caches[first].getCached(ctx, id, dst, fields...)
+		if err != nil {
+			return nil, err
+		}
+
+		if found {
+			return caches[first], nil
+		}
+
+		if !cached {
+			break
+		}
+	}
+
+	if first == len(caches) {
+		return nil, nil
+	}
+
+	// Get all caching fields from backing indexes, so the cached document is complete.
+	var doc map[string]json.RawMessage
+
+	backing, err := index.MultiGet(ctx, backings[first:], id, &doc, i.cfg.CachingFields...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range caches[first:] {
+		if c.backingIndex == backing {
+			if err := c.cache(ctx, id, doc); err != nil {
+				return c, err
+			}
+
+			return c, decodeFields(doc, dst, fields)
+		}
+
+		if err := c.cacheNotFound(ctx, id); err != nil {
+			return nil, err
+		}
 	}
 
-	return false, nil
+	return nil, nil
 }
 
 // Compile-time assurance that implementation satisfies interface.
 var _ index.Index = &Index{}
+var _ index.MultiGetter = &Index{}
@@ -0,0 +1,339 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/ipfs-search/ipfs-search/components/index"
+	"github.com/ipfs-search/ipfs-search/components/index/types"
+	"github.com/ipfs-search/ipfs-search/instr"
+)
+
+var cachingFields = []string{"references", "last-seen"}
+
+type CacheTestSuite struct {
+	suite.Suite
+
+	ctx     context.Context
+	backing *index.Mock
+	idx     index.Index
+}
+
+func (s *CacheTestSuite) SetupTest() {
+	s.ctx = context.Background()
+
+	s.backing = &index.Mock{}
+	s.backing.Test(s.T())
+
+	s.idx = NewMemory(s.backing, DefaultConfig(), instr.New())
+}
+
+func (s *CacheTestSuite) TearDownTest() {
+	s.backing.AssertExpectations(s.T())
+}
+
+func (s *CacheTestSuite) expectGet(found bool, doc string) {
+	s.backing.
+		On("Get", mock.Anything, "id", mock.Anything, cachingFields).
+		Run(func(args mock.Arguments) {
+			if found {
+				s.NoError(json.Unmarshal([]byte(doc), args.Get(2)))
+			}
+		}).
+		Return(found, nil).
+		Once()
+}
+
+func (s *CacheTestSuite) get() (*types.Update, bool) {
+	dst := new(types.Update)
+
+	found, err := s.idx.Get(s.ctx, "id", dst, cachingFields...)
+	s.NoError(err)
+
+	return dst, found
+}
+
+func (s *CacheTestSuite) TestGetCached() {
+	s.expectGet(true, `{"references": [{"parent_hash": "QmParent", "name": "name"}], "last-seen": "2021-01-01T00:00:00Z"}`)
+
+	// Second get is served from cache.
+	for i := 0; i < 2; i++ {
+		dst, found := s.get()
+		s.True(found)
+		s.Equal(types.References{{ParentHash: "QmParent", Name: "name"}}, dst.References)
+		s.True(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC).Equal(*dst.LastSeen))
+	}
+}
+
+func (s *CacheTestSuite) TestGetProjection() {
+	s.expectGet(true, `{"references": [{"parent_hash": "QmParent", "name": "name"}], "last-seen": "2021-01-01T00:00:00Z"}`)
+
+	// Getting a subset of caching fields caches all caching fields.
+	dst := new(types.Update)
+	found, err := s.idx.Get(s.ctx, "id", dst, "references")
+	s.NoError(err)
+	s.True(found)
+	s.Nil(dst.LastSeen)
+
+	dst, found = s.get()
+	s.True(found)
+	s.NotNil(dst.LastSeen)
+}
+
+func (s *CacheTestSuite) TestGetNegative() {
+	s.expectGet(false, "")
+
+	// Second get is served from negative cache.
+	for i := 0; i < 2; i++ {
+		_, found := s.get()
+		s.False(found)
+	}
+}
+
+func (s *CacheTestSuite) TestGetOtherFields() {
+	s.backing.
+		On("Get", mock.Anything, "id", mock.Anything, []string{"metadata"}).
+		Return(true, nil).
+		Twice()
+
+	for i := 0; i < 2; i++ {
+		found, err := s.idx.Get(s.ctx, "id", new(types.File), "metadata")
+		s.NoError(err)
+		s.True(found)
+	}
+}
+
+func (s *CacheTestSuite) TestIndexClearsNegative() {
+	s.expectGet(false, "")
+	_, found := s.get()
+	s.False(found)
+
+	doc := &types.Document{References: types.References{{ParentHash: "QmParent"}}, Size: 5}
+
+	s.backing.On("Index", mock.Anything, "id", doc).Return(nil).Once()
+	s.NoError(s.idx.Index(s.ctx, "id", doc))
+
+	// Served from cache.
+	dst, found := s.get()
+	s.True(found)
+	s.Equal(doc.References, dst.References)
+}
+
+func (s *CacheTestSuite) TestUpdate() {
+	s.expectGet(true, `{"last-seen": "2021-01-01T00:00:00Z"}`)
+	s.get()
+
+	now := time.Now().UTC()
+	u := &types.Update{LastSeen: &now}
+
+	s.backing.On("Update", mock.Anything, "id", u).Return(nil).Once()
+	s.NoError(s.idx.Update(s.ctx, "id", u))
+
+	dst, found := s.get()
+	s.True(found)
+	s.True(now.Equal(*dst.LastSeen))
+}
+
+func (s *CacheTestSuite) TestUpdateConflictInvalidates() {
+	s.expectGet(true, `{"last-seen": "2021-01-01T00:00:00Z"}`)
+	s.get()
+
+	u := &types.Update{}
+	s.backing.On("Update", mock.Anything, "id", u).Return(index.ErrConflict).Once()
+	s.ErrorIs(s.idx.Update(s.ctx, "id", u), index.ErrConflict)
+
+	// Read from backing index again.
+	s.expectGet(true, `{"last-seen": "2021-01-02T00:00:00Z"}`)
+	dst, found := s.get()
+	s.True(found)
+	s.True(time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC).Equal(*dst.LastSeen))
+}
+
+func (s *CacheTestSuite) TestApplyInvalidates() {
+	s.expectGet(true, `{"last-seen": "2021-01-01T00:00:00Z"}`)
+	s.get()
+
+	ops := []index.Operation{index.Max("last-seen", time.Now())}
+	s.backing.On("Apply", mock.Anything, "id", ops).Return(nil).Once()
+	s.NoError(s.idx.Apply(s.ctx, "id", ops...))
+
+	s.expectGet(true, `{"last-seen": "2021-01-02T00:00:00Z"}`)
+	s.get()
+}
+
+func (s *CacheTestSuite) TestDelete() {
+	s.expectGet(true, `{}`)
+	s.get()
+
+	s.backing.On("Delete", mock.Anything, "id").Return(nil).Once()
+	s.NoError(s.idx.Delete(s.ctx, "id"))
+
+	s.expectGet(false, "")
+	_, found := s.get()
+	s.False(found)
+}
+
+func (s *CacheTestSuite) TestGetError() {
+	err := errors.New("backing error")
+
+	s.backing.On("Get", mock.Anything, "id", mock.Anything, cachingFields).Return(false, err).Twice()
+
+	// Errors are not cached.
+	for i := 0; i < 2; i++ {
+		_, getErr := s.idx.Get(s.ctx, "id", new(types.Update), cachingFields...)
+		s.ErrorIs(getErr, err)
+	}
+}
+
+// orderedMock is a mocked index getting from several indexes in order, as index.MultiGetter's do.
+type orderedMock struct {
+	*index.Mock
+}
+
+func (m *orderedMock) MultiGet(ctx context.Context, indexes []index.Index, id string, dst interface{}, fields ...string) (index.Index, error) {
+	for _, i := range indexes {
+		found, err := i.Get(ctx, id, dst, fields...)
+		if err != nil || found {
+			return i, err
+		}
+	}
+
+	return nil, nil
+}
+
+// multiIndexes returns two cached indexes with mocked backing index.MultiGetter's.
+func (s *CacheTestSuite) multiIndexes() ([]index.Index, []*index.Mock) {
+	indexes := make([]index.Index, 2)
+	backings := make([]*index.Mock, 2)
+
+	for n := range indexes {
+		backings[n] = &index.Mock{}
+		backings[n].Test(s.T())
+
+		indexes[n] = NewMemory(&orderedMock{backings[n]}, DefaultConfig(), instr.New())
+	}
+
+	s.T().Cleanup(func() {
+		for _, b := range backings {
+			b.AssertExpectations(s.T())
+		}
+	})
+
+	return indexes, backings
+}
+
+func expectMultiGet(m *index.Mock, found bool, doc string) {
+	m.
+		On("Get", mock.Anything, "id", mock.Anything, cachingFields).
+		Run(func(args mock.Arguments) {
+			if found {
+				if err := json.Unmarshal([]byte(doc), args.Get(2)); err != nil {
+					panic(err)
+				}
+			}
+		}).
+		Return(found, nil).
+		Once()
+}
+
+func (s *CacheTestSuite) TestMultiGet() {
+	indexes, backings := s.multiIndexes()
+
+	expectMultiGet(backings[0], false, "")
+	expectMultiGet(backings[1], true, `{"last-seen": "2021-01-01T00:00:00Z"}`)
+
+	// Second get is served from caches.
+	for i := 0; i < 2; i++ {
+		dst := new(types.Update)
+
+		found, err := index.MultiGet(s.ctx, indexes, "id", dst, cachingFields...)
+		s.NoError(err)
+		s.Equal(indexes[1], found)
+		s.True(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC).Equal(*dst.LastSeen))
+	}
+}
+
+func (s *CacheTestSuite) TestMultiGetNegative() {
+	indexes, backings := s.multiIndexes()
+
+	expectMultiGet(backings[0], false, "")
+	expectMultiGet(backings[1], false, "")
+
+	// Second get is served from negative caches.
+	for i := 0; i < 2; i++ {
+		found, err := index.MultiGet(s.ctx, indexes, "id", new(types.Update), cachingFields...)
+		s.NoError(err)
+		s.Nil(found)
+	}
+}
+
+func (s *CacheTestSuite) TestMultiGetCachedFirst() {
+	indexes, backings := s.multiIndexes()
+
+	// Cache the document in the first index.
+	expectMultiGet(backings[0], true, `{"last-seen": "2021-01-01T00:00:00Z"}`)
+	_, err := indexes[0].Get(s.ctx, "id", new(types.Update), cachingFields...)
+	s.NoError(err)
+
+	// Served from the first cache, without getting from the second index.
+	found, err := index.MultiGet(s.ctx, indexes, "id", new(types.Update), cachingFields...)
+	s.NoError(err)
+	s.Equal(indexes[0], found)
+}
+
+func (s *CacheTestSuite) TestMultiGetSkipsNegative() {
+	indexes, backings := s.multiIndexes()
+
+	// Cache the document as not found in the first index.
+	expectMultiGet(backings[0], false, "")
+	_, err := indexes[0].Get(s.ctx, "id", new(types.Update), cachingFields...)
+	s.NoError(err)
+
+	// Only got from the second index.
+	expectMultiGet(backings[1], true, `{}`)
+
+	found, err := index.MultiGet(s.ctx, indexes, "id", new(types.Update), cachingFields...)
+	s.NoError(err)
+	s.Equal(indexes[1], found)
+}
+
+func (s *CacheTestSuite) TestMultiGetOtherFields() {
+	indexes, backings := s.multiIndexes()
+
+	backings[0].On("Get", mock.Anything, "id", mock.Anything, []string{"metadata"}).Return(false, nil).Twice()
+	backings[1].On("Get", mock.Anything, "id", mock.Anything, []string{"metadata"}).Return(true, nil).Twice()
+
+	// Not cached.
+	for i := 0; i < 2; i++ {
+		found, err := index.MultiGet(s.ctx, indexes, "id", new(types.File), "metadata")
+		s.NoError(err)
+		s.Equal(indexes[1], found)
+	}
+}
+
+func (s *CacheTestSuite) TestMultiGetConcurrent() {
+	other := NewMemory(&index.Mock{}, DefaultConfig(), instr.New())
+
+	// Backings which are not index.MultiGetter's are got from through the caches.
+	s.expectGet(false, "")
+	other.(*Index).backingIndex.(*index.Mock).
+		On("Get", mock.Anything, "id", mock.Anything, cachingFields).
+		Return(false, nil).
+		Once()
+
+	for i := 0; i < 2; i++ {
+		found, err := index.MultiGet(s.ctx, []index.Index{s.idx, other}, "id", new(types.Update), cachingFields...)
+		s.NoError(err)
+		s.Nil(found)
+	}
+}
+
+func TestCacheTestSuite(t *testing.T) {
+	suite.Run(t, new(CacheTestSuite))
+}
@@ -0,0 +1,20 @@
+package index
+
+import (
+	"context"
+)
+
+type concreteIndexKey struct{}
+
+// WithConcreteIndex returns a context for applying operations to a document in the concrete index name, rather than
+// through an alias, e.g. the index it was read from as recorded in types.Version. Aliases pointing at several indexes
+// only write to one of them, missing documents in the others. Indexes without aliases ignore it.
+func WithConcreteIndex(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, concreteIndexKey{}, name)
+}
+
+// ConcreteIndexFromContext returns the concrete index to apply operations to with ctx, or an empty string when not set.
+func ConcreteIndexFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(concreteIndexKey{}).(string)
+	return name
+}
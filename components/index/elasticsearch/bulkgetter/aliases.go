@@ -0,0 +1,164 @@
+package bulkgetter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opensearch-project/opensearch-go/v2"
+	"github.com/opensearch-project/opensearch-go/v2/opensearchapi"
+)
+
+type aliasesResponse map[string]struct {
+	Aliases map[string]struct{} `json:"aliases"`
+}
+
+type resolved struct {
+	indexes []string
+	expires time.Time
+}
+
+// aliases resolves indexes or aliases to the indexes behind them, caching resolutions shared between batches. An
+// alias may point at several indexes, e.g. after rollover.
+type aliases struct {
+	client *opensearch.Client
+	ttl    time.Duration // Cached resolutions are refreshed after this time.
+
+	mu       sync.Mutex
+	resolved map[string]resolved
+}
+
+func newAliases(client *opensearch.Client, ttl time.Duration) *aliases {
+	return &aliases{
+		client:   client,
+		ttl:      ttl,
+		resolved: make(map[string]resolved),
+	}
+}
+
+func (a *aliases) getAliases(ctx context.Context, indexOrAlias string) (aliasesResponse, error) {
+	response := aliasesResponse{}
+
+	falseConst := true
+	req := opensearchapi.IndicesGetAliasRequest{
+		Index:           []string{indexOrAlias},
+		AllowNoIndices:  &falseConst,
+		ExpandWildcards: "none",
+	}
+
+	res, err := req.Do(ctx, a.client)
+	if err != nil {
+		return response, fmt.Errorf("error executing request: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return response, fmt.Errorf("%w: %s", ErrHTTP, res)
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&response)
+
+	return response, err
+}
+
+// Newer returns whether index a is newer than b, comparing the numbers in their names numerically, as indexes
+// behind an alias are versioned (e.g. ipfs_files_v2 and ipfs_files_v10) or rolled over (e.g. ipfs_files-000002).
+func Newer(a, b string) bool {
+	for a != "" && b != "" {
+		na, nb := leadingDigits(a), leadingDigits(b)
+
+		if na > 0 && nb > 0 {
+			da, db := strings.TrimLeft(a[:na], "0"), strings.TrimLeft(b[:nb], "0")
+			if len(da) != len(db) {
+				return len(da) > len(db)
+			}
+
+			if da != db {
+				return da > db
+			}
+
+			a, b = a[na:], b[nb:]
+
+			continue
+		}
+
+		if a[0] != b[0] {
+			return a[0] > b[0]
+		}
+
+		a, b = a[1:], b[1:]
+	}
+
+	return len(a) > len(b)
+}
+
+// leadingDigits returns the number of digits s starts with.
+func leadingDigits(s string) int {
+	n := 0
+	for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+		n++
+	}
+
+	return n
+}
+
+// resolve returns the indexes behind indexOrAlias, newest first.
+func (a *aliases) resolve(ctx context.Context, indexOrAlias string) ([]string, error) {
+	// GET /<index_or_alias>/_alias
+	// {
+	// 	"<index>": {
+	// 		"aliases": {
+	// 			"ipfs_directories": {}
+	// 		}
+	// 	}
+	// }
+
+	a.mu.Lock()
+	r, ok := a.resolved[indexOrAlias]
+	a.mu.Unlock()
+
+	if ok && time.Now().Before(r.expires) {
+		return r.indexes, nil
+	}
+
+	response, err := a.getAliases(ctx, indexOrAlias)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response) == 0 {
+		return nil, fmt.Errorf("index or alias %s not found", indexOrAlias)
+	}
+
+	r = resolved{
+		indexes: make([]string, 0, len(response)),
+		expires: time.Now().Add(a.ttl),
+	}
+
+	for k := range response {
+		r.indexes = append(r.indexes, k)
+	}
+
+	sort.Slice(r.indexes, func(i, j int) bool {
+		return Newer(r.indexes[i], r.indexes[j])
+	})
+
+	a.mu.Lock()
+	a.resolved[indexOrAlias] = r
+	a.mu.Unlock()
+
+	return r.indexes, nil
+}
+
+// invalidate drops the cached resolution of indexOrAlias, e.g. when an index behind it was not found.
+func (a *aliases) invalidate(indexOrAlias string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.resolved, indexOrAlias)
+}
@@ -15,10 +15,18 @@ type GetRequest struct {
 type GetResponse struct {
 	Found bool
 	Error error
+
+	// Index the document was found in, e.g. behind a requested alias.
+	Index string
+
+	// Version of found documents, for optimistic concurrency control.
+	SeqNo       int64
+	PrimaryTerm int64
 }
 
 // AsyncGetter is an interface to allow for asynchronous getting.
 type AsyncGetter interface {
 	Get(context.Context, *GetRequest, interface{}) <-chan GetResponse
+	GetAll(context.Context, []*GetRequest, []interface{}) []<-chan GetResponse
 	Work(context.Context) error
 }
@@ -11,8 +11,9 @@ import (
 
 // BulkGetter allows batching/bulk gets.
 type BulkGetter struct {
-	cfg   Config
-	queue chan reqresp
+	cfg     Config
+	queue   chan []reqresp // Requests queued together are executed in the same batch.
+	aliases *aliases
 }
 
 // New returns a new BulkGetter, setting sensible defaults for the configuration.
@@ -29,21 +30,51 @@ func New(cfg Config) *BulkGetter {
 		cfg.BatchTimeout = 100 * time.Millisecond
 	}
 
+	if cfg.AliasTTL == 0 {
+		cfg.AliasTTL = time.Minute
+	}
+
 	bg := BulkGetter{
-		cfg:   cfg,
-		queue: make(chan reqresp, 5*cfg.BatchSize),
+		cfg:     cfg,
+		queue:   make(chan []reqresp, 5*cfg.BatchSize),
+		aliases: newAliases(cfg.Client, cfg.AliasTTL),
 	}
 
 	return &bg
 }
 
-// Get queues a single Get() for a batching get.
+// Get queues a single Get() for a batching get. Concurrent gets for the same document are coalesced into a single
+// get within a batch. The response is sent when the batch is executed, or with the error of ctx when it is done
+// before, or when the configured RequestTimeout expires.
 func (bg *BulkGetter) Get(ctx context.Context, req *GetRequest, dst interface{}) <-chan GetResponse {
-	resp := make(chan GetResponse, 1)
+	return bg.GetAll(ctx, []*GetRequest{req}, []interface{}{dst})[0]
+}
+
+// GetAll queues gets of reqs into the corresponding dsts like Get, such that they are executed in the same batch.
+func (bg *BulkGetter) GetAll(ctx context.Context, reqs []*GetRequest, dsts []interface{}) []<-chan GetResponse {
+	if len(reqs) != len(dsts) {
+		panic("GetAll requires a destination for every request.")
+	}
+
+	rrs := make([]reqresp, len(reqs))
+	resps := make([]<-chan GetResponse, len(reqs))
 
-	bg.queue <- reqresp{ctx, req, resp, dst}
+	for i, req := range reqs {
+		rrs[i] = newReqResp(ctx, req, dsts[i], bg.cfg.RequestTimeout)
+		resps[i] = rrs[i].resp
+	}
+
+	select {
+	case <-ctx.Done():
+		// Don't block callers when the queue is full and nobody is working on it.
+	case bg.queue <- rrs:
+	}
 
-	return resp
+	for _, rr := range rrs {
+		go rr.expire()
+	}
+
+	return resps
 }
 
 // Work starts a single worker processing batched Get() requests. It will terminate on errors.
@@ -72,32 +103,37 @@ func (bg *BulkGetter) processBatch(ctx context.Context) error {
 		return err
 	}
 
-	if len(b.rrs) == 0 {
+	if len(b.entries) == 0 {
 		return nil
 	}
 
 	return b.execute()
 }
 
-func (bg *BulkGetter) populateBatch(ctx context.Context, queue <-chan reqresp) (*bulkRequest, error) {
+func (bg *BulkGetter) populateBatch(ctx context.Context, queue <-chan []reqresp) (*bulkRequest, error) {
 	// log.Println("Populating BulkGetter batch.")
 
-	b := newBulkRequest(ctx, bg.cfg.Client, bg.cfg.BatchSize)
+	b := newBulkRequest(ctx, bg.cfg.Client, bg.cfg.BatchSize, bg.aliases)
 
-	for i := 0; i < bg.cfg.BatchSize; i++ {
+	// Requests queued together are added together, possibly exceeding the batch size.
+	for i := 0; i < bg.cfg.BatchSize; {
 		select {
 		case <-ctx.Done():
 			return b, ctx.Err()
 		case <-time.After(bg.cfg.BatchTimeout):
-			// log.Printf("Batch timeout, %d elements", len(b.rrs))
+			// log.Printf("Batch timeout, %d elements", len(b.entries))
 
 			return b, nil
-		case rr := <-queue:
-			// log.Printf("Batch add, %d elements", len(b.rrs))
+		case rrs := <-queue:
+			// log.Printf("Batch add, %d elements", len(b.entries))
 
-			if err := b.add(rr); err != nil {
-				return b, err
+			for _, rr := range rrs {
+				if err := b.add(rr); err != nil {
+					return b, err
+				}
 			}
+
+			i += len(rrs)
 		}
 	}
 
@@ -102,6 +102,34 @@ func (s *BulkGetterSuite) TestGet() {
 	s.Empty(resp)
 }
 
+func (s *BulkGetterSuite) TestGetFullQueueContextCancel() {
+	req := GetRequest{}
+	dst := struct{}{}
+
+	// Fill up the queue, without a worker to drain it.
+	for i := 0; i < cap(s.bg.queue); i++ {
+		s.bg.Get(s.ctx, &req, &dst)
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	cancel()
+
+	resp := <-s.bg.Get(ctx, &req, &dst)
+
+	s.False(resp.Found)
+	s.ErrorIs(resp.Error, context.Canceled)
+}
+
+func (s *BulkGetterSuite) TestGetRequestTimeout() {
+	s.cfg.RequestTimeout = time.Millisecond
+	s.bg = New(s.cfg)
+
+	// Without a worker, requests expire.
+	resp := <-s.bg.Get(s.ctx, &GetRequest{}, &struct{}{})
+	s.False(resp.Found)
+	s.ErrorIs(resp.Error, context.DeadlineExceeded)
+}
+
 func (s *BulkGetterSuite) TestProcessBatchContextCancel() {
 	ctx, cancel := context.WithCancel(s.ctx)
 	cancel()
@@ -121,57 +149,80 @@ func (s *BulkGetterSuite) TestProcessBatchTimeout() {
 
 func (s *BulkGetterSuite) TestPopulateBatch() {
 	var dst interface{}
-	queue := make(chan reqresp, 4)
-	rChan := make(chan GetResponse, 1)
+	queue := make(chan []reqresp, 4)
 
-	reqresp1 := reqresp{s.ctx, &GetRequest{
+	reqresp1 := newReqResp(s.ctx, &GetRequest{
 		Index:      "index1",
 		Fields:     []string{"f1", "f2"},
 		DocumentID: "1",
-	}, rChan, dst}
+	}, dst, 0)
 
 	// Same everything, should be grouped
-	reqresp2 := reqresp{s.ctx, &GetRequest{
+	reqresp2 := newReqResp(s.ctx, &GetRequest{
 		Index:      "index1",
 		Fields:     []string{"f1", "f2"},
 		DocumentID: "2",
-	}, rChan, dst}
+	}, dst, 0)
 
 	// Different index
-	reqresp3 := reqresp{s.ctx, &GetRequest{
+	reqresp3 := newReqResp(s.ctx, &GetRequest{
 		Index:      "index2",
 		Fields:     []string{"f1", "f2"},
 		DocumentID: "3",
-	}, rChan, dst}
+	}, dst, 0)
 
 	// Different fields
-	reqresp4 := reqresp{s.ctx, &GetRequest{
+	reqresp4 := newReqResp(s.ctx, &GetRequest{
 		Index:      "index1",
 		Fields:     []string{"f1", "f3"},
 		DocumentID: "4",
-	}, rChan, dst}
+	}, dst, 0)
 
 	s.expectResolveAlias("index1")
 	s.expectResolveAlias("index2")
 
 	// Async; prevent buffer lock up.
 	go func() {
-		queue <- reqresp1
-		queue <- reqresp2
-		queue <- reqresp3
-		queue <- reqresp4
+		queue <- []reqresp{reqresp1}
+		queue <- []reqresp{reqresp2}
+		queue <- []reqresp{reqresp3}
+		queue <- []reqresp{reqresp4}
 
 		// Same everything, but outside batch range, so different request
-		queue <- reqresp1
+		queue <- []reqresp{reqresp1}
 	}()
 
 	b, err := s.bg.populateBatch(s.ctx, queue)
 
-	s.Len(b.rrs, 4)
+	s.Len(b.entries, 4)
 	s.NoError(err)
 
-	key, _ := b.keyFromRR(reqresp1)
-	s.Equal(reqresp1, b.rrs[key])
+	e := b.entries["index1/1"]
+	s.Require().NotNil(e)
+	s.Require().Len(e.waiters, 1)
+	s.Equal(reqresp1.resp, e.waiters[0].resp)
+}
+
+func (s *BulkGetterSuite) TestGetAllSameBatch() {
+	s.expectResolveAlias("index1")
+
+	var (
+		reqs []*GetRequest
+		dsts []interface{}
+	)
+
+	// More requests than the batch size.
+	for i := 0; i < s.cfg.BatchSize+1; i++ {
+		reqs = append(reqs, &GetRequest{Index: "index1", DocumentID: fmt.Sprint(i)})
+		dsts = append(dsts, new(struct{}))
+	}
+
+	resps := s.bg.GetAll(s.ctx, reqs, dsts)
+	s.Len(resps, len(reqs))
+
+	b, err := s.bg.populateBatch(s.ctx, s.bg.queue)
+	s.NoError(err)
+	s.Len(b.entries, len(reqs))
 }
 
 // TestProcessBatch is an integration test.
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"strings"
 	"sync"
 
 	"github.com/opensearch-project/opensearch-go/v2"
@@ -17,137 +18,172 @@ import (
 // ErrHTTP represents non-404 errors in HTTP requests.
 var ErrHTTP = errors.New("HTTP Error")
 
-type bulkRequest struct {
-	ctx         context.Context
-	client      *opensearch.Client
-	rrs         map[string]reqresp
-	decodeMutex sync.Mutex
-	aliases     map[string]string
-}
+// ErrMissing is returned for documents missing from the response to a bulk request.
+var ErrMissing = errors.New("document missing from bulk response")
 
-func newBulkRequest(ctx context.Context, client *opensearch.Client, size int) *bulkRequest {
-	if ctx == nil {
-		panic("required context is nil")
-	}
-	return &bulkRequest{
-		ctx:     ctx,
-		client:  client,
-		rrs:     make(map[string]reqresp, size),
-		aliases: make(map[string]string),
-	}
+// result represents the document in one of the indexes of an entry, as in the response to a bulk request.
+type result struct {
+	doc    *responseDoc
+	fields []string // Fields got for the document; all fields when empty.
 }
 
-func (r *bulkRequest) sendBulkResponse(found bool, err error) {
-	for _, rr := range r.rrs {
-		rr.resp <- GetResponse{found, err}
-		close(rr.resp)
-	}
+// entry represents a single document in a bulk request and the requests waiting for it.
+type entry struct {
+	index   string   // Index or alias, as requested.
+	indexes []string // Indexes behind index, newest first, which are all got from.
+	id      string   // Document ID.
+	fields  []string // Union of the fields of waiting requests; all fields when empty.
+	waiters []reqresp
+	results []*result // Results by position in indexes; nil until in the response.
 }
 
-type responseDoc struct {
-	Index  string          `json:"_index"`
-	ID     string          `json:"_id"`
-	Found  bool            `json:"found"`
-	Source json.RawMessage `json:"_source"`
-}
+func (e *entry) add(rr reqresp) {
+	switch {
+	case len(e.waiters) == 0:
+		e.fields = rr.req.Fields
+	case len(e.fields) == 0:
+		// All fields requested already.
+	case len(rr.req.Fields) == 0:
+		e.fields = nil
+	default:
+		e.fields = union(e.fields, rr.req.Fields)
+	}
 
-type aliasesResponse map[string]struct {
-	Aliases map[string]struct{} `json:"aliases"`
+	e.waiters = append(e.waiters, rr)
 }
 
-func (r *bulkRequest) getAliases(indexOrAlias string) (aliasesResponse, error) {
-	response := aliasesResponse{}
+// union returns the fields in a and b, without duplicates.
+func union(a, b []string) []string {
+	fields := make([]string, 0, len(a)+len(b))
+	seen := make(map[string]bool, len(a)+len(b))
 
-	falseConst := true
-	req := opensearchapi.IndicesGetAliasRequest{
-		Index:           []string{indexOrAlias},
-		AllowNoIndices:  &falseConst,
-		ExpandWildcards: "none",
+	for _, f := range append(append(fields, a...), b...) {
+		if !seen[f] {
+			seen[f] = true
+			fields = append(fields, f)
+		}
 	}
 
-	res, err := req.Do(r.ctx, r.client)
-	if err != nil {
-		return response, fmt.Errorf("error executing request: %w", err)
-	}
+	return fields
+}
 
-	defer res.Body.Close()
+// document represents a document in a single index in a bulk request, shared by the entries getting from the index.
+type document struct {
+	index   string
+	id      string
+	entries []*entry
+}
 
-	if res.IsError() {
-		return response, fmt.Errorf("%w: %s", ErrHTTP, res)
+// fields returns the union of the fields of entries; all fields when empty.
+func (d *document) fields() []string {
+	fields := d.entries[0].fields
+
+	for _, e := range d.entries[1:] {
+		if len(fields) == 0 || len(e.fields) == 0 {
+			return nil
+		}
+
+		fields = union(fields, e.fields)
 	}
 
-	err = json.NewDecoder(res.Body).Decode(&response)
+	return fields
+}
 
-	return response, err
+type bulkRequest struct {
+	ctx         context.Context
+	client      *opensearch.Client
+	entries     map[string]*entry
+	documents   map[string]*document
+	decodeMutex sync.Mutex
+	aliases     *aliases
 }
 
-func (r *bulkRequest) resolveAlias(indexOrAlias string) (string, error) {
-	// GET /<index_or_alias>/_alias
-	// {
-	// 	"<index>": {
-	// 		"aliases": {
-	// 			"ipfs_directories": {}
-	// 		}
-	// 	}
-	// }
-
-	index, ok := r.aliases[indexOrAlias]
-	if ok {
-		return index, nil
+func newBulkRequest(ctx context.Context, client *opensearch.Client, size int, aliases *aliases) *bulkRequest {
+	if ctx == nil {
+		panic("required context is nil")
 	}
-
-	response, err := r.getAliases(indexOrAlias)
-	if err != nil {
-		return "", err
+	return &bulkRequest{
+		ctx:       ctx,
+		client:    client,
+		entries:   make(map[string]*entry, size),
+		documents: make(map[string]*document, size),
+		aliases:   aliases,
 	}
+}
 
-	for k := range response {
-		r.aliases[indexOrAlias] = k
-		return k, nil
+// sendBulkResponse responds to all requests which were not responded to before.
+func (r *bulkRequest) sendBulkResponse(found bool, err error) {
+	for _, e := range r.entries {
+		for _, rr := range e.waiters {
+			rr.respond(func() GetResponse {
+				return GetResponse{Found: found, Error: err}
+			})
+		}
 	}
+}
 
-	return "", fmt.Errorf("index or alias %s not found", indexOrAlias)
+type responseDoc struct {
+	Index       string          `json:"_index"`
+	ID          string          `json:"_id"`
+	SeqNo       int64           `json:"_seq_no"`
+	PrimaryTerm int64           `json:"_primary_term"`
+	Found       bool            `json:"found"`
+	Source      json.RawMessage `json:"_source"`
+	Error       *responseError  `json:"error"`
 }
 
-func (r *bulkRequest) keyFromResponseDoc(doc *responseDoc) string {
-	return doc.Index + doc.ID
+type responseError struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
 }
 
-func (r *bulkRequest) keyFromRR(rr reqresp) (string, error) {
-	indexName, err := r.resolveAlias(rr.req.Index)
-	if err != nil {
-		return "", err
-	}
-	return indexName + rr.req.DocumentID, nil
+func (r *bulkRequest) keyFromResponseDoc(doc *responseDoc) string {
+	return doc.Index + doc.ID
 }
 
+// add adds rr to the request, sharing the entry of other requests for the same document in the same indexes.
 func (r *bulkRequest) add(rr reqresp) error {
-	key, err := r.keyFromRR(rr)
+	indexes, err := r.aliases.resolve(r.ctx, rr.req.Index)
 	if err != nil {
 		return err
 	}
 
-	r.rrs[key] = rr
+	key := strings.Join(indexes, ",") + "/" + rr.req.DocumentID
+
+	e, ok := r.entries[key]
+	if !ok {
+		e = &entry{
+			index:   rr.req.Index,
+			indexes: indexes,
+			id:      rr.req.DocumentID,
+			results: make([]*result, len(indexes)),
+		}
+		r.entries[key] = e
+
+		for _, index := range indexes {
+			r.addDocument(index, e)
+		}
+	}
+
+	e.add(rr)
 
 	return nil
 }
 
-func (r *bulkRequest) sendResponse(key string, found bool, err error) {
-	rr, keyFound := r.rrs[key]
-
-	if !keyFound {
-		panic(fmt.Sprintf("Key %s not found in reqresp %v.", key, r.rrs))
-	}
+// addDocument adds getting the document of e from index, sharing it with other entries getting from index.
+func (r *bulkRequest) addDocument(index string, e *entry) {
+	key := index + e.id
 
-	if rr.resp == nil {
-		panic(fmt.Sprintf("Invalid value for response channel for reqresp %v", rr))
+	d, ok := r.documents[key]
+	if !ok {
+		d = &document{
+			index: index,
+			id:    e.id,
+		}
+		r.documents[key] = d
 	}
 
-	// log.Printf("Sending response to %v", rr.resp)
-	// defer log.Printf("Done sending response")
-
-	rr.resp <- GetResponse{found, err}
-	close(rr.resp)
+	d.entries = append(d.entries, e)
 }
 
 func (r *bulkRequest) getReqBody() io.Reader {
@@ -161,15 +197,15 @@ func (r *bulkRequest) getReqBody() io.Reader {
 		Source source `json:"_source"`
 	}
 
-	docs := make([]doc, len(r.rrs))
+	docs := make([]doc, len(r.documents))
 
 	i := 0
-	for _, rr := range r.rrs {
+	for _, d := range r.documents {
 		docs[i] = doc{
-			Index: rr.req.Index,
-			ID:    rr.req.DocumentID,
+			Index: d.index,
+			ID:    d.id,
 			Source: source{
-				rr.req.Fields,
+				d.fields(),
 			},
 		}
 
@@ -215,6 +251,27 @@ func decodeResponse(res *opensearchapi.Response) ([]responseDoc, error) {
 	return response.Docs, nil
 }
 
+// project returns the top-level fields of src which are, or contain, any of fields.
+func project(src json.RawMessage, fields []string) (json.RawMessage, error) {
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(src, &all); err != nil {
+		return nil, err
+	}
+
+	projection := make(map[string]json.RawMessage, len(fields))
+
+	for k, v := range all {
+		for _, f := range fields {
+			if f == k || strings.HasPrefix(f, k+".") {
+				projection[k] = v
+				break
+			}
+		}
+	}
+
+	return json.Marshal(projection)
+}
+
 func (r *bulkRequest) decodeSource(src json.RawMessage, dst interface{}) error {
 	// Wrap Unmarshall in mutex to prevent race conditions as dst may be shared!
 	r.decodeMutex.Lock()
@@ -223,18 +280,101 @@ func (r *bulkRequest) decodeSource(src json.RawMessage, dst interface{}) error {
 	return json.Unmarshal(src, dst)
 }
 
-// processResponseDoc returns found, error
-func (r *bulkRequest) processResponseDoc(d *responseDoc, key string) (bool, error) {
+// processResponseDoc decodes the document into the destination of the request and returns the response.
+// When the document was shared with requests for other fields, only the requested fields are decoded.
+func (r *bulkRequest) processResponseDoc(d *responseDoc, fields []string, rr reqresp) GetResponse {
 	if d.Found {
-		if err := r.decodeSource(d.Source, r.rrs[key].dst); err != nil {
+		src := d.Source
+
+		var err error
+		if len(rr.req.Fields) > 0 && len(rr.req.Fields) != len(fields) {
+			src, err = project(src, rr.req.Fields)
+		}
+
+		if err == nil {
+			err = r.decodeSource(src, rr.dst)
+		}
+
+		if err != nil {
 			err = fmt.Errorf("error decoding source: %w", err)
-			return false, err
+			return GetResponse{Found: false, Error: err}
+		}
+
+		return GetResponse{
+			Found:       true,
+			Index:       d.Index,
+			SeqNo:       d.SeqNo,
+			PrimaryTerm: d.PrimaryTerm,
+		}
+	}
+
+	return GetResponse{Found: false}
+}
+
+// processDocument records the document d in a single index for the entries getting from it, responding to the
+// requests waiting for them when possible.
+func (r *bulkRequest) processDocument(d *responseDoc, doc *document) {
+	res := &result{
+		doc:    d,
+		fields: doc.fields(),
+	}
+
+	for _, e := range doc.entries {
+		for n, index := range e.indexes {
+			if index == d.Index {
+				e.results[n] = res
+			}
 		}
 
-		return true, nil
+		r.respondEntry(e)
 	}
+}
+
+// respondEntry responds to the requests waiting for e with the document in the newest of its indexes which has it,
+// so that requests for aliases pointing at several indexes get the same document, regardless of the order of the
+// response. Requests are responded to once the newer indexes are known not to have the document.
+func (r *bulkRequest) respondEntry(e *entry) {
+	var (
+		res *result
+		err error
+	)
+
+	for _, res = range e.results {
+		if res == nil {
+			// Wait for the document in newer indexes.
+			return
+		}
+
+		d := res.doc
+
+		if d.Error != nil {
+			if d.Error.Type == "index_not_found_exception" {
+				// The index was removed from behind the alias, e.g. after a rollover; refresh it for later batches.
+				r.aliases.invalidate(e.index)
+				continue
+			}
 
-	return false, nil
+			err = fmt.Errorf("%w getting %s from %s: %s", ErrHTTP, d.ID, d.Index, d.Error.Reason)
+			break
+		}
+
+		if d.Found {
+			break
+		}
+	}
+
+	// Each request gets its own decoded copy; requests which were responded to before are not responded to again.
+	for _, rr := range e.waiters {
+		rr := rr
+
+		rr.respond(func() GetResponse {
+			if err != nil {
+				return GetResponse{Found: false, Error: err}
+			}
+
+			return r.processResponseDoc(res.doc, res.fields, rr)
+		})
+	}
 }
 
 func (r *bulkRequest) processResponse(res *opensearchapi.Response) error {
@@ -252,21 +392,16 @@ func (r *bulkRequest) processResponse(res *opensearchapi.Response) error {
 
 		// log.Printf("Processing %d returned documents", len(docs))
 
-		for _, d := range docs {
-			key := r.keyFromResponseDoc(&d)
+		for i := range docs {
+			d := &docs[i]
+			key := r.keyFromResponseDoc(d)
 
-			// Only decode and send response when the other side is listening.
-			rr, ok := r.rrs[key]
+			doc, ok := r.documents[key]
 			if !ok {
 				return fmt.Errorf("unknown key '%s' in response to bulk request", key)
 			}
-			if rr.ctx.Err() == nil {
-				found, err := r.processResponseDoc(&d, key)
-				r.sendResponse(key, found, err)
-			} else {
-				// log.Printf("Not writing response from bulk get, request context cancelled.")
-				close(rr.resp)
-			}
+
+			r.processDocument(d, doc)
 		}
 
 		return nil
@@ -283,7 +418,7 @@ func (r *bulkRequest) processResponse(res *opensearchapi.Response) error {
 }
 
 func (r *bulkRequest) execute() error {
-	log.Printf("Performing bulk GET, %d elements", len(r.rrs))
+	log.Printf("Performing bulk GET, %d elements", len(r.entries))
 
 	res, err := r.getRequest().Do(r.ctx, r.client)
 	if err != nil {
@@ -299,5 +434,8 @@ func (r *bulkRequest) execute() error {
 		return err
 	}
 
+	// Ensure that requests for documents missing from the response are not left waiting.
+	r.sendBulkResponse(false, ErrMissing)
+
 	return nil
 }
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/dankinder/httpmock"
 	"github.com/opensearch-project/opensearch-go/v2"
@@ -86,6 +87,27 @@ func (s *BulkRequestTestSuite) expectResolveAlias(from string, to string) {
 		})
 }
 
+// expectResolveMulti expects alias from to be resolved once, to indexes.
+func (s *BulkRequestTestSuite) expectResolveMulti(from string, indexes ...string) {
+	response := map[string]interface{}{}
+	for _, index := range indexes {
+		response[index] = map[string]interface{}{
+			"aliases": map[string]interface{}{from: struct{}{}},
+		}
+	}
+
+	testJSON, _ := json.Marshal(response)
+
+	url := fmt.Sprintf("/%s/_alias?allow_no_indices=true&expand_wildcards=none", from)
+
+	s.mockAPIHandler.
+		On("Handle", "GET", url, mock.Anything).
+		Return(httpmock.Response{
+			Body: testJSON,
+		}).
+		Once()
+}
+
 func (s *BulkRequestTestSuite) SetupTest() {
 	s.ctx = context.Background()
 
@@ -106,23 +128,23 @@ func (s *BulkRequestTestSuite) SetupTest() {
 		Fields:     []string{"a1", "a2"},
 		DocumentID: "5",
 	}
-	s.rChan1 = make(chan GetResponse, 1)
-	s.reqresp1 = reqresp{s.ctx, s.req1, s.rChan1, &s.dst1}
+	s.reqresp1 = newReqResp(s.ctx, s.req1, &s.dst1, 0)
+	s.rChan1 = s.reqresp1.resp
 
 	s.req2 = &GetRequest{
 		Index:      "test2",
 		Fields:     []string{"b"},
 		DocumentID: "7",
 	}
-	s.rChan2 = make(chan GetResponse, 1)
-	s.reqresp2 = reqresp{s.ctx, s.req2, s.rChan2, &s.dst2}
+	s.reqresp2 = newReqResp(s.ctx, s.req2, &s.dst2, 0)
+	s.rChan2 = s.reqresp2.resp
 }
 
 func (s *BulkRequestTestSuite) TestGetRequest() {
 	s.expectResolveAlias("test1", "test1")
 	s.expectResolveAlias("test2", "test2")
 
-	br := newBulkRequest(s.ctx, s.client, 2)
+	br := newBulkRequest(s.ctx, s.client, 2, newAliases(s.client, time.Minute))
 
 	err := br.add(s.reqresp1)
 	s.NoError(err)
@@ -163,7 +185,7 @@ func (s *BulkRequestTestSuite) TestProcessResponseFound() {
 	s.expectResolveAlias("test1", "test1")
 	s.expectResolveAlias("test2", "test2")
 
-	br := newBulkRequest(s.ctx, s.client, 2)
+	br := newBulkRequest(s.ctx, s.client, 2, newAliases(s.client, time.Minute))
 
 	err := br.add(s.reqresp1)
 	s.NoError(err)
@@ -218,7 +240,7 @@ func (s *BulkRequestTestSuite) TestProcessResponseFound() {
 func (s *BulkRequestTestSuite) TestResolveIndex() {
 	s.expectResolveAlias("test1", "actual_index")
 
-	br := newBulkRequest(s.ctx, s.client, 1)
+	br := newBulkRequest(s.ctx, s.client, 1, newAliases(s.client, time.Minute))
 	s.NoError(br.add(s.reqresp1))
 
 	respStr := `{
@@ -248,6 +270,384 @@ func (s *BulkRequestTestSuite) TestResolveIndex() {
 	s.NotEmpty(s.reqresp1.resp)
 }
 
+func (s *BulkRequestTestSuite) TestCoalesce() {
+	s.expectResolveAlias("test1", "test1")
+
+	type testType struct {
+		Field1 string `json:"a1"`
+		Field2 int    `json:"a2"`
+	}
+
+	var dst1, dst2, dst3 testType
+
+	rr1 := newReqResp(s.ctx, &GetRequest{Index: "test1", DocumentID: "5", Fields: []string{"a1"}}, &dst1, 0)
+	rr2 := newReqResp(s.ctx, &GetRequest{Index: "test1", DocumentID: "5", Fields: []string{"a1", "a2"}}, &dst2, 0)
+	rr3 := newReqResp(s.ctx, &GetRequest{Index: "test1", DocumentID: "5", Fields: []string{"a1"}}, &dst3, 0)
+
+	br := newBulkRequest(s.ctx, s.client, 3, newAliases(s.client, time.Minute))
+	s.NoError(br.add(rr1))
+	s.NoError(br.add(rr2))
+	s.NoError(br.add(rr3))
+
+	// A single entry with the fields of all requests.
+	s.Require().Len(br.entries, 1)
+	for _, e := range br.entries {
+		s.Equal([]string{"a1", "a2"}, e.fields)
+		s.Len(e.waiters, 3)
+	}
+
+	respStr := `{
+	  "docs": [
+	    {
+	      "_index": "test1",
+	      "_id": "5",
+	      "_seq_no": 5,
+	      "_primary_term": 19,
+	      "found": true,
+	      "_source": {
+	        "a1": "kaas",
+	        "a2": 15
+	      }
+	    }
+	  ]
+	}`
+
+	resp := opensearchapi.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(strings.NewReader(respStr)),
+	}
+
+	s.NoError(br.processResponse(&resp))
+
+	// Each request is answered with its own fields.
+	for _, rr := range []reqresp{rr1, rr2, rr3} {
+		r := <-rr.resp
+		s.True(r.Found)
+		s.Equal(int64(5), r.SeqNo)
+	}
+
+	s.Equal(testType{Field1: "kaas"}, dst1)
+	s.Equal(testType{Field1: "kaas", Field2: 15}, dst2)
+	s.Equal(testType{Field1: "kaas"}, dst3)
+}
+
+func (s *BulkRequestTestSuite) TestMissing() {
+	s.expectResolveAlias("test1", "test1")
+	s.expectResolveAlias("test2", "test2")
+
+	br := newBulkRequest(s.ctx, s.client, 2, newAliases(s.client, time.Minute))
+	s.NoError(br.add(s.reqresp1))
+	s.NoError(br.add(s.reqresp2))
+
+	respStr := `{
+	  "docs": [
+	    {
+	      "_index": "test1",
+	      "_id": "5",
+	      "found": false
+	    }
+	  ]
+	}`
+
+	resp := opensearchapi.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(strings.NewReader(respStr)),
+	}
+
+	s.NoError(br.processResponse(&resp))
+	br.sendBulkResponse(false, ErrMissing)
+
+	r1 := <-s.rChan1
+	s.NoError(r1.Error)
+	s.False(r1.Found)
+
+	r2 := <-s.rChan2
+	s.ErrorIs(r2.Error, ErrMissing)
+}
+
+func (s *BulkRequestTestSuite) TestExpired() {
+	s.expectResolveAlias("test1", "test1")
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	rr := newReqResp(ctx, s.req1, &s.dst1, 0)
+	go rr.expire()
+
+	br := newBulkRequest(s.ctx, s.client, 1, newAliases(s.client, time.Minute))
+	s.NoError(br.add(rr))
+
+	cancel()
+
+	r := <-rr.resp
+	s.ErrorIs(r.Error, context.Canceled)
+
+	// Responses after expiry are dropped, leaving dst untouched.
+	br.sendBulkResponse(true, nil)
+	s.Empty(s.dst1)
+}
+
+func (s *BulkRequestTestSuite) TestMultiIndexAlias() {
+	s.expectResolveMulti("test1", "test1-000002", "test1-000001")
+
+	br := newBulkRequest(s.ctx, s.client, 1, newAliases(s.client, time.Minute))
+	s.NoError(br.add(s.reqresp1))
+
+	// Getting from each index behind the alias.
+	s.Len(br.entries, 1)
+	s.Len(br.documents, 2)
+
+	respStr := `{
+	  "docs": [
+	    {
+	      "_index": "test1-000002",
+	      "_id": "5",
+	      "found": false
+	    },
+	    {
+	      "_index": "test1-000001",
+	      "_id": "5",
+	      "_seq_no": 5,
+	      "_primary_term": 19,
+	      "found": true,
+	      "_source": {
+	        "a1": "kaas",
+	        "a2": 15
+	      }
+	    }
+	  ]
+	}`
+
+	resp := opensearchapi.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(strings.NewReader(respStr)),
+	}
+
+	s.NoError(br.processResponse(&resp))
+
+	r := <-s.rChan1
+	s.True(r.Found)
+	s.Equal("test1-000001", r.Index)
+	s.Equal(int64(5), r.SeqNo)
+	s.Equal("kaas", s.dst1.Field1)
+}
+
+// TestMultiIndexAliasNewest asserts that documents in several indexes behind an alias are got from the newest index,
+// regardless of the order of the response.
+func (s *BulkRequestTestSuite) TestMultiIndexAliasNewest() {
+	s.expectResolveMulti("test1", "test1_v2", "test1_v10")
+
+	br := newBulkRequest(s.ctx, s.client, 1, newAliases(s.client, time.Minute))
+	s.NoError(br.add(s.reqresp1))
+
+	respStr := `{
+	  "docs": [
+	    {
+	      "_index": "test1_v2",
+	      "_id": "5",
+	      "_seq_no": 3,
+	      "_primary_term": 1,
+	      "found": true,
+	      "_source": {
+	        "a1": "oud",
+	        "a2": 3
+	      }
+	    }
+	  ]
+	}`
+
+	resp := opensearchapi.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(strings.NewReader(respStr)),
+	}
+
+	s.NoError(br.processResponse(&resp))
+
+	// Not responded to until the document in the newer index is known.
+	s.Empty(s.rChan1)
+
+	respStr = `{
+	  "docs": [
+	    {
+	      "_index": "test1_v10",
+	      "_id": "5",
+	      "_seq_no": 8,
+	      "_primary_term": 2,
+	      "found": true,
+	      "_source": {
+	        "a1": "nieuw",
+	        "a2": 10
+	      }
+	    }
+	  ]
+	}`
+
+	resp.Body = ioutil.NopCloser(strings.NewReader(respStr))
+
+	s.NoError(br.processResponse(&resp))
+
+	r := <-s.rChan1
+	s.True(r.Found)
+	s.Equal("test1_v10", r.Index)
+	s.Equal(int64(8), r.SeqNo)
+	s.Equal("nieuw", s.dst1.Field1)
+}
+
+func (s *BulkRequestTestSuite) TestMultiIndexAliasNotFound() {
+	s.expectResolveMulti("test1", "test1-000001", "test1-000002")
+
+	br := newBulkRequest(s.ctx, s.client, 1, newAliases(s.client, time.Minute))
+	s.NoError(br.add(s.reqresp1))
+
+	respStr := `{
+	  "docs": [
+	    {
+	      "_index": "test1-000001",
+	      "_id": "5",
+	      "found": false
+	    }
+	  ]
+	}`
+
+	resp := opensearchapi.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(strings.NewReader(respStr)),
+	}
+
+	s.NoError(br.processResponse(&resp))
+
+	// Not responded to until the document was not found in all indexes.
+	s.Empty(s.rChan1)
+
+	respStr = `{
+	  "docs": [
+	    {
+	      "_index": "test1-000002",
+	      "_id": "5",
+	      "found": false
+	    }
+	  ]
+	}`
+
+	resp.Body = ioutil.NopCloser(strings.NewReader(respStr))
+
+	s.NoError(br.processResponse(&resp))
+
+	r := <-s.rChan1
+	s.NoError(r.Error)
+	s.False(r.Found)
+}
+
+func (s *BulkRequestTestSuite) TestAliasCache() {
+	aliases := newAliases(s.client, time.Minute)
+
+	// Resolved once for several bulk requests.
+	s.expectResolveMulti("test1", "test1-000001")
+
+	for i := 0; i < 2; i++ {
+		br := newBulkRequest(s.ctx, s.client, 1, aliases)
+		s.NoError(br.add(newReqResp(s.ctx, s.req1, &s.dst1, 0)))
+	}
+
+	s.mockAPIHandler.AssertExpectations(s.T())
+}
+
+func (s *BulkRequestTestSuite) TestAliasCacheExpires() {
+	aliases := newAliases(s.client, time.Nanosecond)
+
+	s.expectResolveMulti("test1", "test1-000001")
+	s.expectResolveMulti("test1", "test1-000001", "test1-000002")
+
+	br := newBulkRequest(s.ctx, s.client, 1, aliases)
+	s.NoError(br.add(s.reqresp1))
+	s.Len(br.documents, 1)
+
+	time.Sleep(time.Millisecond)
+
+	// Resolved again after rollover.
+	br = newBulkRequest(s.ctx, s.client, 1, aliases)
+	s.NoError(br.add(s.reqresp1))
+	s.Len(br.documents, 2)
+
+	s.mockAPIHandler.AssertExpectations(s.T())
+}
+
+func (s *BulkRequestTestSuite) TestIndexNotFound() {
+	aliases := newAliases(s.client, time.Minute)
+
+	s.expectResolveMulti("test1", "test1-000001", "test1-000002")
+
+	br := newBulkRequest(s.ctx, s.client, 1, aliases)
+	s.NoError(br.add(s.reqresp1))
+
+	respStr := `{
+	  "docs": [
+	    {
+	      "_index": "test1-000001",
+	      "_id": "5",
+	      "error": {
+	        "type": "index_not_found_exception",
+	        "reason": "no such index [test1-000001]"
+	      }
+	    },
+	    {
+	      "_index": "test1-000002",
+	      "_id": "5",
+	      "found": false
+	    }
+	  ]
+	}`
+
+	resp := opensearchapi.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(strings.NewReader(respStr)),
+	}
+
+	s.NoError(br.processResponse(&resp))
+
+	// A removed index does not have the document.
+	r := <-s.rChan1
+	s.NoError(r.Error)
+	s.False(r.Found)
+
+	// The alias is resolved again.
+	s.expectResolveMulti("test1", "test1-000002")
+
+	br = newBulkRequest(s.ctx, s.client, 1, aliases)
+	s.NoError(br.add(newReqResp(s.ctx, s.req1, &s.dst1, 0)))
+	s.Len(br.documents, 1)
+
+	s.mockAPIHandler.AssertExpectations(s.T())
+}
+
+func (s *BulkRequestTestSuite) TestDocumentError() {
+	s.expectResolveAlias("test1", "test1")
+
+	br := newBulkRequest(s.ctx, s.client, 1, newAliases(s.client, time.Minute))
+	s.NoError(br.add(s.reqresp1))
+
+	respStr := `{
+	  "docs": [
+	    {
+	      "_index": "test1",
+	      "_id": "5",
+	      "error": {
+	        "type": "illegal_argument_exception",
+	        "reason": "invalid"
+	      }
+	    }
+	  ]
+	}`
+
+	resp := opensearchapi.Response{
+		StatusCode: 200,
+		Body:       ioutil.NopCloser(strings.NewReader(respStr)),
+	}
+
+	s.NoError(br.processResponse(&resp))
+
+	r := <-s.rChan1
+	s.ErrorIs(r.Error, ErrHTTP)
+}
+
 func TestBulkRequestTestSuite(t *testing.T) {
 	suite.Run(t, new(BulkRequestTestSuite))
 }
@@ -8,7 +8,9 @@ import (
 
 // Config provides configuration for a BatchingGetter.
 type Config struct {
-	Client       *opensearch.Client
-	BatchSize    int
-	BatchTimeout time.Duration
+	Client         *opensearch.Client
+	BatchSize      int
+	BatchTimeout   time.Duration
+	RequestTimeout time.Duration // Maximum time to wait for a response to a Get, regardless of batches; none when 0.
+	AliasTTL       time.Duration // Time after which the indexes behind aliases are resolved again.
 }
@@ -19,6 +19,22 @@ func (m *Mock) Get(ctx context.Context, req *GetRequest, dst interface{}) <-chan
 	return r
 }
 
+// GetAll mocks a get of multiple requests of an AsyncGetter, returning a slice of GetResponse.
+func (m *Mock) GetAll(ctx context.Context, reqs []*GetRequest, dsts []interface{}) []<-chan GetResponse {
+	args := m.Called(ctx, reqs, dsts)
+
+	responses := args.Get(0).([]GetResponse)
+	resps := make([]<-chan GetResponse, len(responses))
+
+	for i, resp := range responses {
+		r := make(chan GetResponse, 1)
+		r <- resp
+		resps[i] = r
+	}
+
+	return resps
+}
+
 // Work mocks the start of an AsyncGetter worker.
 func (m *Mock) Work(ctx context.Context) error {
 	args := m.Called(ctx)
@@ -2,11 +2,57 @@ package bulkgetter
 
 import (
 	"context"
+	"sync"
+	"time"
 )
 
 type reqresp struct {
-	ctx  context.Context
-	req  *GetRequest
-	resp chan GetResponse
-	dst  interface{}
+	ctx    context.Context
+	req    *GetRequest
+	resp   chan GetResponse
+	dst    interface{}
+	once   *sync.Once         // Ensures a single response, either from a batch or when ctx is done.
+	cancel context.CancelFunc // Cancels ctx after responding.
+}
+
+// newReqResp returns a request for dst with a context derived from ctx, expiring after timeout when non-zero.
+func newReqResp(ctx context.Context, req *GetRequest, dst interface{}, timeout time.Duration) reqresp {
+	var cancel context.CancelFunc
+
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+
+	return reqresp{
+		ctx:    ctx,
+		req:    req,
+		resp:   make(chan GetResponse, 1),
+		dst:    dst,
+		once:   new(sync.Once),
+		cancel: cancel,
+	}
+}
+
+// respond sends the response returned by f, unless a response was sent before. As f is only called when responding,
+// dst is not written to after the request was answered otherwise, e.g. when it expired.
+func (rr reqresp) respond(f func() GetResponse) {
+	rr.once.Do(func() {
+		rr.resp <- f()
+		close(rr.resp)
+
+		if rr.cancel != nil {
+			rr.cancel()
+		}
+	})
+}
+
+// expire responds with the error of the context when it is done before a response was sent.
+func (rr reqresp) expire() {
+	<-rr.ctx.Done()
+
+	rr.respond(func() GetResponse {
+		return GetResponse{Found: false, Error: rr.ctx.Err()}
+	})
 }
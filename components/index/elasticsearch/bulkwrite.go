@@ -0,0 +1,165 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	opensearchutil "github.com/opensearch-project/opensearch-go/v2/opensearchutil"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/ipfs-search/ipfs-search/components/index"
+	"github.com/ipfs-search/ipfs-search/components/index/types"
+)
+
+// bulkWrite represents a write through the bulk indexer, in a form which can be spooled.
+type bulkWrite struct {
+	Index  string          `json:"index"`
+	Action string          `json:"action"`
+	ID     string          `json:"id"`
+	Script bool            `json:"script,omitempty"` // Body is a scripted update.
+	Body   json.RawMessage `json:"body,omitempty"`
+
+	Version *types.Version `json:"version,omitempty"` // Only written when the document has this version.
+}
+
+// item returns a bulk indexer item for w, calling done with its result once flushed.
+func (c *Client) item(w *bulkWrite, done func(error)) opensearchutil.BulkIndexerItem {
+	var body io.ReadSeeker
+	if w.Body != nil {
+		body = bytes.NewReader(w.Body)
+	}
+
+	return opensearchutil.BulkIndexerItem{
+		Index:      w.Index,
+		Action:     w.Action,
+		Body:       body,
+		DocumentID: w.ID,
+		Version:    nil,
+		OnSuccess: func(
+			ctx context.Context,
+			item opensearchutil.BulkIndexerItem,
+			res opensearchutil.BulkIndexerResponseItem,
+		) {
+			done(nil)
+		},
+		OnFailure: func(
+			ctx context.Context,
+			item opensearchutil.BulkIndexerItem,
+			res opensearchutil.BulkIndexerResponseItem, err error,
+		) {
+			if w.Script && err == nil && res.Status == http.StatusConflict {
+				// Scripts run on the document as read by the backend, conflicting when it was concurrently
+				// modified. As the bulk indexer does not support retry_on_conflict, retry outside of it.
+				i := &Index{c: c, cfg: &Config{Name: w.Index}}
+
+				go func() {
+					done(i.retryScript(w.ID, item.Body))
+				}()
+				return
+			}
+
+			if w.Action == "create" && err == nil && res.Status == http.StatusConflict {
+				// The document already exists.
+				done(fmt.Errorf("%w: %s in %s", index.ErrConflict, w.ID, w.Index))
+				return
+			}
+
+			if w.Action == "update" && err == nil && res.Error.Type == "document_missing_exception" {
+				done(fmt.Errorf("%w: %s in %s", index.ErrNotFound, w.ID, w.Index))
+				return
+			}
+
+			if err == nil {
+				err = fmt.Errorf("%w flushing %s in %s: %+v", ErrWrite, w.ID, w.Index, res)
+			}
+
+			span := trace.SpanFromContext(ctx)
+			span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+			log.Println(err)
+
+			done(err)
+		},
+	}
+}
+
+// perform performs versioned write w synchronously, as the bulk indexer does not support versions, returning
+// index.ErrConflict when the document changed.
+func (c *Client) perform(ctx context.Context, w *bulkWrite) error {
+	i := &Index{c: c, cfg: &Config{Name: w.Index}}
+
+	params := url.Values{
+		"if_seq_no":       {strconv.FormatInt(w.Version.SeqNo, 10)},
+		"if_primary_term": {strconv.FormatInt(w.Version.PrimaryTerm, 10)},
+	}
+
+	return i.write(ctx, http.MethodPost, "_"+w.Action, w.ID, params, bytes.NewReader(w.Body))
+}
+
+// addVersioned performs versioned write w or, when the cluster is unavailable, adds it to the spool.
+func (c *Client) addVersioned(ctx context.Context, w *bulkWrite) error {
+	if c.spool != nil {
+		return c.spool.addVersioned(ctx, w)
+	}
+
+	return c.perform(ctx, w)
+}
+
+// add adds w to the bulk indexer or, when the cluster is unavailable, to the spool.
+func (c *Client) add(ctx context.Context, w *bulkWrite, done func(error)) error {
+	if c.spool != nil {
+		return c.spool.add(ctx, w, done)
+	}
+
+	return c.submit(ctx, w, done)
+}
+
+// submit adds w to the bulk indexer, tracking it until it is flushed, so that it fails when its flush fails.
+func (c *Client) submit(ctx context.Context, w *bulkWrite, done func(error)) error {
+	p := &pendingWrite{w: w, done: done}
+
+	c.mu.Lock()
+	key := c.next
+	c.next++
+	c.pending[key] = p
+	c.mu.Unlock()
+
+	item := c.item(w, func(err error) {
+		c.mu.Lock()
+		delete(c.pending, key)
+		c.mu.Unlock()
+
+		p.complete(err)
+	})
+
+	if err := c.bulkIndexer.Add(ctx, item); err != nil {
+		c.mu.Lock()
+		delete(c.pending, key)
+		c.mu.Unlock()
+
+		return err
+	}
+
+	return nil
+}
+
+// flushFailed fails all writes pending in the bulk indexer with err, as writes of a failed flush are dropped without
+// reporting them. As flushes are not told apart, writes pending in other flushes fail as well, even when they are
+// flushed later on.
+func (c *Client) flushFailed(err error) {
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = make(map[uint64]*pendingWrite)
+	c.mu.Unlock()
+
+	for _, p := range pending {
+		p.complete(fmt.Errorf("%w flushing %s in %s: %s", ErrWrite, p.w.ID, p.w.Index, err))
+	}
+}
@@ -2,8 +2,10 @@ package elasticsearch
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/jpillora/backoff"
@@ -12,6 +14,7 @@ import (
 	opensearchutil "github.com/opensearch-project/opensearch-go/v2/opensearchutil"
 	"go.opentelemetry.io/otel/api/trace"
 	"go.opentelemetry.io/otel/codes"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/ipfs-search/ipfs-search/components/index/elasticsearch/bulkgetter"
 	"github.com/ipfs-search/ipfs-search/instr"
@@ -22,6 +25,12 @@ type Client struct {
 	searchClient *opensearch.Client
 	bulkIndexer  opensearchutil.BulkIndexer
 	bulkGetter   bulkgetter.AsyncGetter
+	spool        *spool // Nil when writes are not spooled.
+
+	// Writes added to the bulk indexer until flushed, when not spooled, as writes of failed flushes are not reported.
+	mu      sync.Mutex
+	next    uint64
+	pending map[uint64]*pendingWrite
 
 	*instr.Instrumentation
 }
@@ -35,8 +44,12 @@ type ClientConfig struct {
 	BulkIndexerWorkers    int
 	BulkIndexerFlushBytes int
 
-	BulkGetterBatchSize    int
-	BulkGetterBatchTimeout time.Duration
+	BulkGetterBatchSize      int
+	BulkGetterBatchTimeout   time.Duration
+	BulkGetterRequestTimeout time.Duration
+	BulkGetterAliasTTL       time.Duration
+
+	Spool *SpoolConfig // Spooling of writes while the cluster is unavailable; disabled when nil.
 }
 
 // NewClient returns a configured search index, or an error.
@@ -45,6 +58,7 @@ func NewClient(cfg *ClientConfig, i *instr.Instrumentation) (*Client, error) {
 		c   *opensearch.Client
 		bi  opensearchutil.BulkIndexer
 		bg  bulkgetter.AsyncGetter
+		s   *spool
 		err error
 	)
 
@@ -60,7 +74,18 @@ func NewClient(cfg *ClientConfig, i *instr.Instrumentation) (*Client, error) {
 		return nil, err
 	}
 
-	if bi, err = getBulkIndexer(c, cfg, i); err != nil {
+	var client *Client
+
+	// Writes of failed flushes are spooled, when enabled, or fail otherwise.
+	onFlushError := func(err error) {
+		if s != nil {
+			s.flushFailed()
+		} else {
+			client.flushFailed(err)
+		}
+	}
+
+	if bi, err = getBulkIndexer(c, cfg, i, onFlushError); err != nil {
 		return nil, err
 	}
 
@@ -68,21 +93,62 @@ func NewClient(cfg *ClientConfig, i *instr.Instrumentation) (*Client, error) {
 		return nil, err
 	}
 
-	return &Client{
+	client = &Client{
 		searchClient:    c,
 		bulkIndexer:     bi,
 		bulkGetter:      bg,
+		pending:         make(map[uint64]*pendingWrite),
 		Instrumentation: i,
-	}, nil
+	}
+
+	if s, err = getSpool(client, cfg, i); err != nil {
+		return nil, err
+	}
+
+	client.spool = s
+
+	return client, nil
 }
 
 // Work starts (and closes) a client worker.
 func (c *Client) Work(ctx context.Context) error {
+	if c.spool != nil {
+		defer c.spool.close()
+	}
+
 	// Flush indexing buffers on context close.
 	// Use background context because current context is already closed.
 	defer c.bulkIndexer.Close(context.Background())
 
-	return c.bulkGetter.Work(ctx)
+	if c.spool == nil {
+		return c.bulkGetter.Work(ctx)
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	g.Go(func() error { return c.spool.work(ctx) })
+	g.Go(func() error { return c.bulkGetter.Work(ctx) })
+
+	return g.Wait()
+}
+
+// ping returns an error unless the cluster is available for writes.
+func (c *Client) ping(ctx context.Context) error {
+	res, err := c.searchClient.Cluster.Health(
+		c.searchClient.Cluster.Health.WithContext(ctx),
+		c.searchClient.Cluster.Health.WithWaitForStatus("yellow"),
+	)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("cluster unavailable: %s", res)
+	}
+
+	return nil
 }
 
 func getSearchClient(cfg *ClientConfig, i *instr.Instrumentation) (*opensearch.Client, error) {
@@ -118,7 +184,7 @@ func getSearchClient(cfg *ClientConfig, i *instr.Instrumentation) (*opensearch.C
 	return opensearch.NewClient(clientConfig)
 }
 
-func getBulkIndexer(client *opensearch.Client, cfg *ClientConfig, i *instr.Instrumentation) (opensearchutil.BulkIndexer, error) {
+func getBulkIndexer(client *opensearch.Client, cfg *ClientConfig, i *instr.Instrumentation, onError func(error)) (opensearchutil.BulkIndexer, error) {
 	iCfg := opensearchutil.BulkIndexerConfig{
 		Client:     client,
 		NumWorkers: cfg.BulkIndexerWorkers,
@@ -131,6 +197,8 @@ func getBulkIndexer(client *opensearch.Client, cfg *ClientConfig, i *instr.Instr
 			span := trace.SpanFromContext(ctx)
 			span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
 			log.Printf("Error flushing index buffer: %s", err)
+
+			onError(err)
 		},
 		OnFlushEnd: func(ctx context.Context) {
 			span := trace.SpanFromContext(ctx)
@@ -151,10 +219,27 @@ func getBulkIndexer(client *opensearch.Client, cfg *ClientConfig, i *instr.Instr
 
 func getBulkGetter(client *opensearch.Client, cfg *ClientConfig, i *instr.Instrumentation) (bulkgetter.AsyncGetter, error) {
 	bgCfg := bulkgetter.Config{
-		Client:       client,
-		BatchSize:    cfg.BulkGetterBatchSize,
-		BatchTimeout: cfg.BulkGetterBatchTimeout,
+		Client:         client,
+		BatchSize:      cfg.BulkGetterBatchSize,
+		BatchTimeout:   cfg.BulkGetterBatchTimeout,
+		RequestTimeout: cfg.BulkGetterRequestTimeout,
+		AliasTTL:       cfg.BulkGetterAliasTTL,
 	}
 
 	return bulkgetter.New(bgCfg), nil
 }
+
+func getSpool(c *Client, cfg *ClientConfig, i *instr.Instrumentation) (*spool, error) {
+	if cfg.Spool == nil {
+		return nil, nil
+	}
+
+	switch cfg.Spool.Mode {
+	case SpoolNone:
+		return nil, nil
+	case SpoolDisk:
+		return newSpool(cfg.Spool, c.bulkIndexer, c.item, c.perform, c.ping, i)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSpoolMode, cfg.Spool.Mode)
+	}
+}
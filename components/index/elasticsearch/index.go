@@ -4,19 +4,31 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
+	"net/http"
+	"net/url"
+	"strconv"
 
-	opensearchutil "github.com/opensearch-project/opensearch-go/v2/opensearchutil"
+	"github.com/opensearch-project/opensearch-go/v2/opensearchapi"
 
 	"go.opentelemetry.io/otel/api/trace"
 	"go.opentelemetry.io/otel/codes"
 
 	"github.com/ipfs-search/ipfs-search/components/index"
 	"github.com/ipfs-search/ipfs-search/components/index/elasticsearch/bulkgetter"
+	"github.com/ipfs-search/ipfs-search/components/index/types"
 )
 
+// ErrWrite is returned when the search backend returns an error on writing a document.
+var ErrWrite = errors.New("write error")
+
+// maxConflictRetries is the number of times scripted updates are retried when the document was concurrently modified.
+const maxConflictRetries = 3
+
 // Index wraps an Elasticsearch index to store documents
 type Index struct {
 	cfg *Config
@@ -65,53 +77,119 @@ func (i *Index) index(
 	ctx, span := i.c.Tracer.Start(ctx, "index.elasticsearch.index")
 	defer span.End()
 
-	var (
-		body io.ReadSeeker
-		err  error
-	)
+	w := &bulkWrite{
+		Index:  i.cfg.Name,
+		Action: action,
+		ID:     id,
+	}
 
 	if properties != nil {
-		if action == "update" {
+		var (
+			body interface{}
+			err  error
+		)
+
+		if s, ok := properties.(*script); ok {
+			w.Script = true
+			body = struct {
+				Script *script `json:"script"`
+			}{s}
+		} else if action == "update" {
 			// For updates, the updated fields need to be wrapped in a `doc` field
-			body, err = getBody(struct {
+			body = struct {
 				Doc interface{} `json:"doc"`
-			}{properties})
+			}{properties}
 		} else {
-			body, err = getBody(properties)
+			body = properties
 		}
-		if err != nil {
+
+		if w.Body, err = json.Marshal(body); err != nil {
 			panic(err)
 		}
 	}
 
-	item := opensearchutil.BulkIndexerItem{
-		Index:      i.cfg.Name,
-		Action:     action,
-		Body:       body,
-		DocumentID: id,
-		Version:    nil,
-		OnFailure: func(
-			ctx context.Context,
-			item opensearchutil.BulkIndexerItem,
-			res opensearchutil.BulkIndexerResponseItem, err error,
-		) {
-			if err == nil {
-				err = fmt.Errorf("Error flushing: %+v (%s)", res, id)
-			}
+	// Report the result of the write once flushed, for callers tracking writes.
+	done := index.WritesFromContext(ctx).Add()
+
+	ctx, span = i.c.Tracer.Start(ctx, "index.elasticsearch.bulkIndexer.Add")
+	defer span.End()
 
-			span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
-			log.Println(err)
+	if err := i.c.add(ctx, w, done); err != nil {
+		done(err)
+		return err
+	}
 
-		},
+	return nil
+}
+
+// retryScript retries a conflicting scripted update, letting the backend retry on further conflicts.
+func (i *Index) retryScript(id string, body io.ReadSeeker) error {
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		panic(err)
 	}
 
-	ctx, span = i.c.Tracer.Start(ctx, "index.elasticsearch.bulkIndexer.Add")
-	defer span.End()
+	params := url.Values{"retry_on_conflict": {strconv.Itoa(maxConflictRetries)}}
+
+	err := i.write(context.Background(), http.MethodPost, "_update", id, params, body)
+	if err != nil {
+		log.Printf("Error retrying update of %s in %s: %s", id, i, err)
+	}
+
+	return err
+}
+
+// write performs a single document request on the `/<index>/<endpoint>/<id>` API, bypassing the bulk indexer so that
+// conflicts can be returned.
+// Note: the client's request builders are not used as they generate legacy paths with document types.
+func (i *Index) write(ctx context.Context, method, endpoint, id string, params url.Values, body io.Reader) error {
+	path := "/" + url.PathEscape(i.cfg.Name) + "/" + endpoint + "/" + url.PathEscape(id)
+	if len(params) > 0 {
+		path += "?" + params.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	httpRes, err := i.c.searchClient.Perform(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("error executing request: %w", err)
+		}
+
+		return fmt.Errorf("%w: error executing request: %s", errUnavailable, err)
+	}
+
+	res := &opensearchapi.Response{
+		StatusCode: httpRes.StatusCode,
+		Body:       httpRes.Body,
+		Header:     httpRes.Header,
+	}
+	defer res.Body.Close()
 
-	return i.c.bulkIndexer.Add(ctx, item)
+	if res.StatusCode == http.StatusConflict {
+		return fmt.Errorf("%w: %s", index.ErrConflict, res)
+	}
+
+	if res.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("%w: %s", errUnavailable, res)
+	}
+
+	if res.IsError() {
+		return fmt.Errorf("%w: %s", ErrWrite, res)
+	}
+
+	// Drain body to allow for connection reuse.
+	_, err = io.Copy(ioutil.Discard, res.Body)
+
+	return err
 }
 
-// Index a document's properties, identified by id
+// Index a document's properties, identified by id. As documents are created in bulk, index.ErrConflict is reported
+// through index.Writes when the document already exists.
 func (i *Index) Index(ctx context.Context, id string, properties interface{}) error {
 	ctx, span := i.c.Tracer.Start(ctx, "index.elasticsearch.Index")
 	defer span.End()
@@ -124,12 +202,70 @@ func (i *Index) Index(ctx context.Context, id string, properties interface{}) er
 	return nil
 }
 
-// Update a document's properties, given id
+// updateVersioned synchronously updates a document, given its version. The update targets the index the version was
+// read from, as sequence numbers are specific to an index and an alias may point at several.
+func (i *Index) updateVersioned(ctx context.Context, id string, properties interface{}, v *types.Version) error {
+	body, err := json.Marshal(struct {
+		Doc interface{} `json:"doc"`
+	}{properties})
+	if err != nil {
+		panic(err)
+	}
+
+	name := i.cfg.Name
+	if v.Index != "" {
+		name = v.Index
+	}
+
+	return i.c.addVersioned(ctx, &bulkWrite{
+		Index:   name,
+		Action:  "update",
+		ID:      id,
+		Body:    body,
+		Version: v,
+	})
+}
+
+// Update a document's properties, given id. When properties are index.Versioned with a version set, the update is
+// performed synchronously and index.ErrConflict is returned when the document changed since it was read, unless the
+// update is spooled as the cluster is unavailable.
 func (i *Index) Update(ctx context.Context, id string, properties interface{}) error {
 	ctx, span := i.c.Tracer.Start(ctx, "index.elasticsearch.Update")
 	defer span.End()
 
-	if err := i.index(ctx, "update", id, properties); err != nil {
+	var err error
+
+	if v, ok := properties.(index.Versioned); ok && !v.DocumentVersion().IsZero() {
+		err = i.updateVersioned(ctx, id, properties, v.DocumentVersion())
+	} else {
+		err = i.index(ctx, "update", id, properties)
+	}
+
+	if err != nil {
+		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+		return err
+	}
+
+	return nil
+}
+
+// Apply atomic operations to a document, given id, using a script executed by the backend. The operations are applied
+// in the concrete index set with index.WithConcreteIndex, if any, instead of through the alias.
+func (i *Index) Apply(ctx context.Context, id string, ops ...index.Operation) error {
+	ctx, span := i.c.Tracer.Start(ctx, "index.elasticsearch.Apply")
+	defer span.End()
+
+	s, err := makeScript(ops)
+	if err != nil {
+		panic(err)
+	}
+
+	target := i
+	if name := index.ConcreteIndexFromContext(ctx); name != "" {
+		target = &Index{c: i.c, cfg: &Config{Name: name}}
+	}
+
+	if err := target.index(ctx, "update", id, s); err != nil {
 		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
 		return err
 	}
@@ -166,6 +302,14 @@ func (i *Index) Get(ctx context.Context, id string, dst interface{}, fields ...s
 
 	resp := <-i.c.bulkGetter.Get(ctx, &req, dst)
 
+	if v, ok := dst.(index.Versioned); ok && resp.Found {
+		*v.DocumentVersion() = types.Version{
+			SeqNo:       resp.SeqNo,
+			PrimaryTerm: resp.PrimaryTerm,
+			Index:       resp.Index,
+		}
+	}
+
 	// Turn on for increased verbosity.
 	// if resp.Found {
 	// 	log.Printf("Found %s in %s", id, i)
@@ -178,5 +322,72 @@ func (i *Index) Get(ctx context.Context, id string, dst interface{}, fields ...s
 	return resp.Found, resp.Error
 }
 
+// MultiGet retrieves `fields` from the document with `id` from the first of `indexes` which has it, like
+// index.MultiGet, getting from all indexes in a single bulk request. Indexes other than Elasticsearch indexes with the
+// same client are got from with index.ConcurrentMultiGet instead, which does not respect the order of indexes.
+func (i *Index) MultiGet(ctx context.Context, indexes []index.Index, id string, dst interface{}, fields ...string) (index.Index, error) {
+	ctx, span := i.c.Tracer.Start(ctx, "index.elasticsearch.MultiGet")
+	defer span.End()
+
+	reqs := make([]*bulkgetter.GetRequest, len(indexes))
+	dsts := make([]interface{}, len(indexes))
+	sources := make([]json.RawMessage, len(indexes))
+
+	for n, idx := range indexes {
+		esIdx, ok := idx.(*Index)
+		if !ok || esIdx.c != i.c {
+			return index.ConcurrentMultiGet(ctx, indexes, id, dst, fields...)
+		}
+
+		reqs[n] = &bulkgetter.GetRequest{
+			Index:      esIdx.cfg.Name,
+			DocumentID: id,
+			Fields:     fields,
+		}
+		dsts[n] = &sources[n]
+	}
+
+	var (
+		found index.Index
+		err   error
+	)
+
+	// Responses arrive together, as requests are executed in the same batch.
+	for n, respChan := range i.c.bulkGetter.GetAll(ctx, reqs, dsts) {
+		resp := <-respChan
+
+		if resp.Error != nil {
+			if err == nil {
+				err = resp.Error
+			}
+
+			continue
+		}
+
+		if resp.Found && found == nil {
+			found = indexes[n]
+
+			if decodeErr := json.Unmarshal(sources[n], dst); decodeErr != nil {
+				err = fmt.Errorf("error decoding source: %w", decodeErr)
+			}
+
+			if v, ok := dst.(index.Versioned); ok {
+				*v.DocumentVersion() = types.Version{
+					SeqNo:       resp.SeqNo,
+					PrimaryTerm: resp.PrimaryTerm,
+					Index:       resp.Index,
+				}
+			}
+		}
+	}
+
+	if err != nil {
+		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+	}
+
+	return found, err
+}
+
 // Compile-time assurance that implementation satisfies interface.
 var _ index.Index = &Index{}
+var _ index.MultiGetter = &Index{}
@@ -3,7 +3,10 @@ package elasticsearch
 // TODO: Test whether indexed items with omitempty are actually left out - otherwise
 // non-updating references will overwrite the existing!
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"testing"
@@ -14,7 +17,9 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/ipfs-search/ipfs-search/components/index"
 	"github.com/ipfs-search/ipfs-search/components/index/elasticsearch/bulkgetter"
+	"github.com/ipfs-search/ipfs-search/components/index/types"
 )
 
 type IndexTestSuite struct {
@@ -89,6 +94,12 @@ func (s *IndexTestSuite) TestNewClient() {
 	s.NotNil(client)
 }
 
+func (s *IndexTestSuite) TestNewClientSpoolMode() {
+	config := &ClientConfig{Spool: &SpoolConfig{Mode: "invalid"}}
+	_, err := NewClient(config, s.instr)
+	s.ErrorIs(err, ErrUnknownSpoolMode)
+}
+
 func (s *IndexTestSuite) TestNew() {
 	client, _ := NewClient(&ClientConfig{}, s.instr)
 	idx := New(client, &Config{Name: "test"})
@@ -101,62 +112,123 @@ func (s *IndexTestSuite) TestString() {
 	s.Equal(fmt.Sprintf("%s", idx), "test")
 }
 
-func (s *IndexTestSuite) TestIndex() {
-	idx := New(s.mockClient, &Config{Name: "test"})
-
+func (s *IndexTestSuite) expectCreate(status int, result string) {
 	// Note whitespace here! This is NDJSON
 	request := []byte(`{"create":{"_index":"test","_id":"objId"}}
 {"field1":"hoi","field2":4}
 `)
-
-	response := []byte(`{
+	response := []byte(fmt.Sprintf(`{
 	   "took": 30,
-	   "errors": false,
+	   "errors": %t,
 	   "items": [
 	      {
 	         "create": {
 	            "_index": "test",
-	            "_type": "_doc",
 	            "_id": "objId",
-	            "_version": 1,
-	            "result": "created",
-	            "_shards": {
-	               "total": 2,
-	               "successful": 1,
-	               "failed": 0
-	            },
-	            "status": 201,
-	            "_seq_no" : 1,
-	            "_primary_term" : 2
+	            %s,
+	            "status": %d
 	         }
 	      }
 	   ]
-	}`)
-
-	type testType struct {
-		Field1 string `json:"field1"`
-		Field2 int    `json:"field2"`
-	}
+	}`, status >= 300, result, status))
 
-	dst := testType{
-		Field1: "hoi",
-		Field2: 4,
-	}
-
-	testURL := "/_bulk"
 	s.mockAPIHandler.
-		On("Handle", "POST", testURL, request).
+		On("Handle", "POST", "/_bulk", request).
 		Return(httpmock.Response{
 			Body: response,
 		}).
 		Once()
+}
 
-	err := idx.Index(s.ctx, "objId", &dst)
-	s.NoError(err)
+type testDocument struct {
+	Field1 string `json:"field1"`
+	Field2 int    `json:"field2"`
+}
+
+func (s *IndexTestSuite) TestIndex() {
+	idx := New(s.mockClient, &Config{Name: "test"})
+
+	s.expectCreate(201, `"result": "created", "_seq_no": 1, "_primary_term": 2`)
+
+	ctx, writes := index.WithWrites(context.Background())
+	s.NoError(idx.Index(ctx, "objId", &testDocument{Field1: "hoi", Field2: 4}))
 
 	// Ensure flushing
 	s.ctxCancel()
-	time.Sleep(100 * time.Millisecond)
+
+	s.NoError(writes.Wait(ctx))
+
+	s.mockAPIHandler.AssertExpectations(s.T())
+}
+
+func (s *IndexTestSuite) TestIndexConflict() {
+	idx := New(s.mockClient, &Config{Name: "test"})
+
+	s.expectCreate(409, `"error": {
+	              "type": "version_conflict_engine_exception",
+	              "reason": "[objId]: version conflict, document already exists (current version [1])"
+	            }`)
+
+	ctx, writes := index.WithWrites(context.Background())
+	s.NoError(idx.Index(ctx, "objId", &testDocument{Field1: "hoi", Field2: 4}))
+
+	// Ensure flushing
+	s.ctxCancel()
+
+	s.ErrorIs(writes.Wait(ctx), index.ErrConflict)
+
+	s.mockAPIHandler.AssertExpectations(s.T())
+}
+
+func (s *IndexTestSuite) TestUpdateVersioned() {
+	idx := New(s.mockClient, &Config{Name: "test"})
+
+	request := []byte(`{"doc":{"references":[{"parent_hash":"QmParent","name":"name"}]}}`)
+
+	u := &types.Update{
+		Version:    types.Version{SeqNo: 5, PrimaryTerm: 19},
+		References: types.References{{ParentHash: "QmParent", Name: "name"}},
+	}
+
+	s.mockAPIHandler.
+		On("Handle", "POST", "/test/_update/objId?if_primary_term=19&if_seq_no=5", request).
+		Return(httpmock.Response{
+			Body: []byte(`{"_index": "test", "_id": "objId", "result": "updated", "_seq_no": 6, "_primary_term": 19}`),
+		}).
+		Once()
+
+	s.mockAPIHandler.
+		On("Handle", "POST", "/test/_update/objId?if_primary_term=19&if_seq_no=5", request).
+		Return(httpmock.Response{
+			Status: 409,
+			Body:   []byte(`{"error": {"type": "version_conflict_engine_exception"}, "status": 409}`),
+		}).
+		Once()
+
+	s.NoError(idx.Update(s.ctx, "objId", u))
+	s.ErrorIs(idx.Update(s.ctx, "objId", u), index.ErrConflict)
+
+	s.mockAPIHandler.AssertExpectations(s.T())
+}
+
+// TestUpdateVersionedIndex asserts that versioned updates target the index the version was read from, e.g. behind
+// an alias.
+func (s *IndexTestSuite) TestUpdateVersionedIndex() {
+	idx := New(s.mockClient, &Config{Name: "test"})
+
+	u := &types.Update{
+		Version:    types.Version{SeqNo: 5, PrimaryTerm: 19, Index: "test_v2"},
+		References: types.References{{ParentHash: "QmParent", Name: "name"}},
+	}
+
+	s.mockAPIHandler.
+		On("Handle", "POST", "/test_v2/_update/objId?if_primary_term=19&if_seq_no=5", mock.Anything).
+		Return(httpmock.Response{
+			Body: []byte(`{"_index": "test_v2", "_id": "objId", "result": "updated", "_seq_no": 6, "_primary_term": 19}`),
+		}).
+		Once()
+
+	s.NoError(idx.Update(s.ctx, "objId", u))
 
 	s.mockAPIHandler.AssertExpectations(s.T())
 }
@@ -220,6 +292,87 @@ func (s *IndexTestSuite) TestUpdate() {
 	s.mockAPIHandler.AssertExpectations(s.T())
 }
 
+func (s *IndexTestSuite) TestUpdateMissing() {
+	idx := New(s.mockClient, &Config{Name: "test"})
+
+	request := []byte(`{"update":{"_index":"test","_id":"objId"}}
+{"doc":{"field1":"hoi","field2":4}}
+`)
+	response := []byte(`{
+	   "took": 30,
+	   "errors": true,
+	   "items": [
+	      {
+	         "update": {
+	            "_index": "test",
+	            "_id": "objId",
+	            "status": 404,
+	            "error": {
+	               "type": "document_missing_exception",
+	               "reason": "[_doc][objId]: document missing"
+	            }
+	         }
+	      }
+	   ]
+	}`)
+
+	s.mockAPIHandler.
+		On("Handle", "POST", "/_bulk", request).
+		Return(httpmock.Response{
+			Body: response,
+		}).
+		Once()
+
+	ctx, writes := index.WithWrites(context.Background())
+	s.NoError(idx.Update(ctx, "objId", &testDocument{Field1: "hoi", Field2: 4}))
+
+	// Ensure flushing
+	s.ctxCancel()
+
+	s.ErrorIs(writes.Wait(ctx), index.ErrNotFound)
+
+	s.mockAPIHandler.AssertExpectations(s.T())
+}
+
+// TestApplyConcreteIndex asserts that operations are applied in the concrete index set in the context.
+func (s *IndexTestSuite) TestApplyConcreteIndex() {
+	idx := New(s.mockClient, &Config{Name: "test"})
+
+	response := []byte(`{
+	   "took": 30,
+	   "errors": false,
+	   "items": [
+	      {
+	         "update": {
+	            "_index": "test_v1",
+	            "_id": "objId",
+	            "result": "updated",
+	            "status": 200
+	         }
+	      }
+	   ]
+	}`)
+
+	s.mockAPIHandler.
+		On("Handle", "POST", "/_bulk", mock.MatchedBy(func(body []byte) bool {
+			return bytes.HasPrefix(body, []byte(`{"update":{"_index":"test_v1","_id":"objId"}}`))
+		})).
+		Return(httpmock.Response{
+			Body: response,
+		}).
+		Once()
+
+	ctx, writes := index.WithWrites(index.WithConcreteIndex(context.Background(), "test_v1"))
+	s.NoError(idx.Apply(ctx, "objId", index.Increment("field2", 1)))
+
+	// Ensure flushing
+	s.ctxCancel()
+
+	s.NoError(writes.Wait(ctx))
+
+	s.mockAPIHandler.AssertExpectations(s.T())
+}
+
 func (s *IndexTestSuite) TestUpdateOmitEmpty() {
 	idx := New(s.mockClient, &Config{Name: "test"})
 
@@ -327,6 +480,90 @@ func (s *IndexTestSuite) TestDelete() {
 	s.mockAPIHandler.AssertExpectations(s.T())
 }
 
+func (s *IndexTestSuite) expectDelete(status int, result string) {
+	request := []byte(`{"delete":{"_index":"test","_id":"objId"}}
+`)
+	response := []byte(fmt.Sprintf(`{
+	   "took": 30,
+	   "errors": %t,
+	   "items": [
+	      {
+	         "delete": {
+	            "_index": "test",
+	            "_id": "objId",
+	            %s,
+	            "status": %d
+	         }
+	      }
+	   ]
+	}`, status >= 300, result, status))
+
+	s.mockAPIHandler.
+		On("Handle", "POST", "/_bulk", request).
+		Return(httpmock.Response{
+			Body: response,
+		}).
+		Once()
+}
+
+func (s *IndexTestSuite) TestWrites() {
+	idx := New(s.mockClient, &Config{Name: "test"})
+
+	s.expectDelete(200, `"result": "deleted"`)
+
+	ctx, writes := index.WithWrites(context.Background())
+	s.NoError(idx.Delete(ctx, "objId"))
+
+	// Ensure flushing
+	s.ctxCancel()
+
+	s.NoError(writes.Wait(ctx))
+
+	s.mockAPIHandler.AssertExpectations(s.T())
+}
+
+func (s *IndexTestSuite) TestWritesFailure() {
+	idx := New(s.mockClient, &Config{Name: "test"})
+
+	s.expectDelete(400, `"error": {"type": "mapper_parsing_exception", "reason": "failed to parse"}`)
+
+	ctx, writes := index.WithWrites(context.Background())
+	s.NoError(idx.Delete(ctx, "objId"))
+
+	// Ensure flushing
+	s.ctxCancel()
+
+	s.ErrorIs(writes.Wait(ctx), ErrWrite)
+
+	s.mockAPIHandler.AssertExpectations(s.T())
+}
+
+// TestWritesFlushFailed asserts that writes fail when their flush fails as a whole, without a spool.
+func (s *IndexTestSuite) TestWritesFlushFailed() {
+	idx := New(s.mockClient, &Config{Name: "test"})
+
+	s.mockAPIHandler.
+		On("Handle", "POST", "/_bulk", mock.Anything).
+		Return(httpmock.Response{
+			Status: 500,
+			Body:   []byte(`{"error": {"type": "internal_server_error"}, "status": 500}`),
+		}).
+		Once()
+
+	ctx, writes := index.WithWrites(context.Background())
+	s.NoError(idx.Delete(ctx, "objId"))
+
+	// Ensure flushing
+	s.ctxCancel()
+
+	waitCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	s.ErrorIs(writes.Wait(waitCtx), ErrWrite)
+
+	s.mockAPIHandler.AssertExpectations(s.T())
+}
+
 func (s *IndexTestSuite) TestGetFound() {
 	idx := New(s.mockClient, &Config{Name: "test"})
 
@@ -342,7 +579,7 @@ func (s *IndexTestSuite) TestGetFound() {
 		mock.Anything,
 		&bulkgetter.GetRequest{Index: "test", DocumentID: "objId", Fields: []string{"field1", "field2"}},
 		&dst,
-	).Return(bulkgetter.GetResponse{true, nil})
+	).Return(bulkgetter.GetResponse{Found: true, SeqNo: 5, PrimaryTerm: 19})
 
 	result, err := idx.Get(s.ctx, "objId", &dst, "field1", "field2")
 	s.NoError(err)
@@ -351,6 +588,26 @@ func (s *IndexTestSuite) TestGetFound() {
 	s.mockAsyncGetter.AssertExpectations(s.T())
 }
 
+func (s *IndexTestSuite) TestGetVersioned() {
+	idx := New(s.mockClient, &Config{Name: "test"})
+
+	dst := new(types.Update)
+
+	s.mockAsyncGetter.On(
+		"Get",
+		mock.Anything,
+		&bulkgetter.GetRequest{Index: "test", DocumentID: "objId", Fields: []string{"references"}},
+		dst,
+	).Return(bulkgetter.GetResponse{Found: true, Index: "test_v2", SeqNo: 5, PrimaryTerm: 19})
+
+	result, err := idx.Get(s.ctx, "objId", dst, "references")
+	s.NoError(err)
+	s.True(result)
+	s.Equal(types.Version{SeqNo: 5, PrimaryTerm: 19, Index: "test_v2"}, dst.Version)
+
+	s.mockAsyncGetter.AssertExpectations(s.T())
+}
+
 func (s *IndexTestSuite) TestGetNotFound() {
 	idx := New(s.mockClient, &Config{Name: "test"})
 
@@ -366,7 +623,7 @@ func (s *IndexTestSuite) TestGetNotFound() {
 		mock.Anything,
 		&bulkgetter.GetRequest{Index: "test", DocumentID: "objId", Fields: []string{"field1", "field2"}},
 		&dst,
-	).Return(bulkgetter.GetResponse{false, nil})
+	).Return(bulkgetter.GetResponse{Found: false})
 
 	result, err := idx.Get(s.ctx, "objId", &dst, "field1", "field2")
 	s.NoError(err)
@@ -375,6 +632,66 @@ func (s *IndexTestSuite) TestGetNotFound() {
 	s.mockAsyncGetter.AssertExpectations(s.T())
 }
 
+func (s *IndexTestSuite) TestMultiGet() {
+	indexes := []index.Index{
+		New(s.mockClient, &Config{Name: "files"}),
+		New(s.mockClient, &Config{Name: "directories"}),
+		New(s.mockClient, &Config{Name: "invalids"}),
+	}
+
+	fields := []string{"references"}
+
+	s.mockAsyncGetter.On(
+		"GetAll",
+		mock.Anything,
+		[]*bulkgetter.GetRequest{
+			{Index: "files", DocumentID: "objId", Fields: fields},
+			{Index: "directories", DocumentID: "objId", Fields: fields},
+			{Index: "invalids", DocumentID: "objId", Fields: fields},
+		},
+		mock.Anything,
+	).Run(func(args mock.Arguments) {
+		dsts := args.Get(2).([]interface{})
+		*dsts[1].(*json.RawMessage) = json.RawMessage(`{"references": [{"name": "directory"}]}`)
+		*dsts[2].(*json.RawMessage) = json.RawMessage(`{"references": [{"name": "invalid"}]}`)
+	}).Return([]bulkgetter.GetResponse{
+		{Found: false},
+		{Found: true, SeqNo: 5, PrimaryTerm: 19},
+		{Found: true, SeqNo: 6, PrimaryTerm: 19},
+	}).Once()
+
+	dst := new(types.Update)
+
+	// The first index having the document, in order, is returned.
+	found, err := index.MultiGet(s.ctx, indexes, "objId", dst, fields...)
+	s.NoError(err)
+	s.Equal(indexes[1], found)
+	s.Equal(types.References{{Name: "directory"}}, dst.References)
+	s.Equal(types.Version{SeqNo: 5, PrimaryTerm: 19}, dst.Version)
+
+	s.mockAsyncGetter.AssertExpectations(s.T())
+}
+
+func (s *IndexTestSuite) TestMultiGetError() {
+	indexes := []index.Index{
+		New(s.mockClient, &Config{Name: "files"}),
+		New(s.mockClient, &Config{Name: "directories"}),
+	}
+
+	err := errors.New("get error")
+
+	s.mockAsyncGetter.On("GetAll", mock.Anything, mock.Anything, mock.Anything).Return([]bulkgetter.GetResponse{
+		{Found: false},
+		{Error: err},
+	}).Once()
+
+	found, getErr := index.MultiGet(s.ctx, indexes, "objId", new(types.Update), "references")
+	s.ErrorIs(getErr, err)
+	s.Nil(found)
+
+	s.mockAsyncGetter.AssertExpectations(s.T())
+}
+
 func TestIndexTestSuite(t *testing.T) {
 	suite.Run(t, new(IndexTestSuite))
 }
@@ -0,0 +1,515 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opensearch-project/opensearch-go/v2/opensearchapi"
+
+	"github.com/ipfs-search/ipfs-search/components/index/elasticsearch/bulkgetter"
+	"github.com/ipfs-search/ipfs-search/components/index/elasticsearch/mappings"
+)
+
+var (
+	// ErrRequest is returned when the search backend returns an error on managing indexes.
+	ErrRequest = errors.New("request error")
+
+	// ErrNotAliased is returned when migrating an index which is not behind an alias.
+	ErrNotAliased = errors.New("index is not aliased")
+
+	// ErrCountMismatch is returned when a migrated index does not hold as many documents as the original.
+	ErrCountMismatch = errors.New("document count mismatch")
+)
+
+// MigrateOptions represents options for migrating an index.
+type MigrateOptions struct {
+	Transform    string // Painless script applied to documents while reindexing, e.g. `ctx._source.remove('urls')`.
+	ReplaceIndex bool   // Delete the original index after migrating when it is not behind an alias, replacing it by one.
+}
+
+// Migration represents the result of migrating an index.
+type Migration struct {
+	Alias string `json:"alias"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Count int64  `json:"count"`
+}
+
+// IndexStatus represents the state of an index, compared to its definition.
+type IndexStatus struct {
+	Alias string   `json:"alias"`
+	Index string   `json:"index,omitempty"` // Newest index behind the alias, empty when it does not exist.
+	Older []string `json:"older,omitempty"` // Older indexes behind the alias, e.g. rolled over ones, newest first.
+	Count int64    `json:"count"`
+	Drift []string `json:"drift,omitempty"` // Differences of the mapping from the definition.
+}
+
+// Lifecycle manages versioned indexes behind aliases, e.g. the alias `ipfs_files` pointing to `ipfs_files_v2`, based
+// on the definitions in the mappings package.
+type Lifecycle struct {
+	c *Client
+
+	ProgressInterval time.Duration // Interval for reporting progress of migrations.
+}
+
+// NewLifecycle returns a new Lifecycle.
+func NewLifecycle(client *Client) *Lifecycle {
+	if client == nil {
+		panic("NewLifecycle Client cannot be nil.")
+	}
+
+	return &Lifecycle{
+		c:                client,
+		ProgressInterval: 10 * time.Second,
+	}
+}
+
+// do executes req, decoding the response into dst unless it is nil. Returns the status code.
+func (l *Lifecycle) do(ctx context.Context, req opensearchapi.Request, dst interface{}) (int, error) {
+	res, err := req.Do(ctx, l.c.searchClient)
+	if err != nil {
+		return 0, fmt.Errorf("error executing request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return res.StatusCode, fmt.Errorf("%w: %s", ErrRequest, res)
+	}
+
+	if dst != nil {
+		if err := json.NewDecoder(res.Body).Decode(dst); err != nil {
+			return res.StatusCode, fmt.Errorf("error decoding body: %w", err)
+		}
+	}
+
+	return res.StatusCode, nil
+}
+
+// resolve returns the indexes behind alias, newest first, which is alias itself when it is not an alias. Returns
+// none when it does not exist.
+func (l *Lifecycle) resolve(ctx context.Context, alias string) ([]string, error) {
+	response := map[string]struct {
+		Aliases map[string]struct{} `json:"aliases"`
+	}{}
+
+	req := opensearchapi.IndicesGetAliasRequest{
+		Index: []string{alias},
+	}
+
+	status, err := l.do(ctx, req, &response)
+	if status == 404 {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	indexes := make([]string, 0, len(response))
+	for index := range response {
+		indexes = append(indexes, index)
+	}
+
+	// Like with getting documents, the newest index is the one written to, e.g. after rolling over.
+	sort.Slice(indexes, func(i, j int) bool {
+		return bulkgetter.Newer(indexes[i], indexes[j])
+	})
+
+	return indexes, nil
+}
+
+// nextVersion returns the name for the next version of the index behind alias.
+func nextVersion(alias, current string) string {
+	version := 1
+
+	re := regexp.MustCompile("^" + regexp.QuoteMeta(alias) + `_v(\d+)$`)
+	if m := re.FindStringSubmatch(current); m != nil {
+		v, _ := strconv.Atoi(m[1])
+		version = v + 1
+	}
+
+	return fmt.Sprintf("%s_v%d", alias, version)
+}
+
+// create creates index for the kind of index, adding aliases.
+func (l *Lifecycle) create(ctx context.Context, index, kind string, aliases ...string) error {
+	d, err := mappings.Get(kind)
+	if err != nil {
+		return err
+	}
+
+	a := make(map[string]struct{}, len(aliases))
+	for _, alias := range aliases {
+		a[alias] = struct{}{}
+	}
+
+	body, err := getBody(struct {
+		*mappings.Definition
+		Aliases map[string]struct{} `json:"aliases,omitempty"`
+	}{d, a})
+	if err != nil {
+		panic(err)
+	}
+
+	req := opensearchapi.IndicesCreateRequest{
+		Index: index,
+		Body:  body,
+	}
+
+	_, err = l.do(ctx, req, nil)
+
+	return err
+}
+
+// Init creates the first version of the index behind alias, unless it exists. Returns the name of the index and
+// whether it was created.
+func (l *Lifecycle) Init(ctx context.Context, alias, kind string) (string, bool, error) {
+	indexes, err := l.resolve(ctx, alias)
+	if err != nil {
+		return "", false, err
+	}
+
+	if len(indexes) > 0 {
+		return indexes[0], false, nil
+	}
+
+	index := nextVersion(alias, "")
+
+	if err := l.create(ctx, index, kind, alias); err != nil {
+		return "", false, err
+	}
+
+	return index, true, nil
+}
+
+func (l *Lifecycle) count(ctx context.Context, index string) (int64, error) {
+	response := struct {
+		Count int64 `json:"count"`
+	}{}
+
+	req := opensearchapi.CountRequest{
+		Index: []string{index},
+	}
+
+	_, err := l.do(ctx, req, &response)
+
+	return response.Count, err
+}
+
+// Parameters which are left out of mappings returned by the backend when set to their default.
+var defaultParams = map[string]string{
+	"index":   "true",
+	"dynamic": "true",
+}
+
+// flatten adds the leaves of mapping m to dst, keyed by their path.
+func flatten(prefix string, m map[string]interface{}, dst map[string]string) {
+	for k, v := range m {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+
+		switch v := v.(type) {
+		case map[string]interface{}:
+			flatten(path, v, dst)
+		case []interface{}:
+			b, _ := json.Marshal(v)
+			dst[path] = string(b)
+		default:
+			s := fmt.Sprint(v)
+			if defaultParams[k] != s {
+				dst[path] = s
+			}
+		}
+	}
+}
+
+// diffMappings returns the differences between the defined and actual mappings; additions are prefixed with `+`,
+// removals with `-` and changes with `~`.
+func diffMappings(defined, actual map[string]interface{}) []string {
+	d, a := map[string]string{}, map[string]string{}
+	flatten("", defined, d)
+	flatten("", actual, a)
+
+	var diff []string
+
+	for path, dv := range d {
+		av, ok := a[path]
+		switch {
+		case !ok:
+			diff = append(diff, fmt.Sprintf("- %s: %s", path, dv))
+		case av != dv:
+			diff = append(diff, fmt.Sprintf("~ %s: %s (defined: %s)", path, av, dv))
+		}
+	}
+
+	for path, av := range a {
+		if _, ok := d[path]; !ok {
+			diff = append(diff, fmt.Sprintf("+ %s: %s", path, av))
+		}
+	}
+
+	// Sort by path.
+	sort.Slice(diff, func(i, j int) bool {
+		return diff[i][2:] < diff[j][2:]
+	})
+
+	return diff
+}
+
+// Status returns the status of the index behind alias, including differences of its mapping from the definition.
+// When alias points at more than one index, the status is that of the newest, listing the older ones.
+func (l *Lifecycle) Status(ctx context.Context, alias, kind string) (*IndexStatus, error) {
+	d, err := mappings.Get(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &IndexStatus{
+		Alias: alias,
+	}
+
+	indexes, err := l.resolve(ctx, alias)
+	if err != nil || len(indexes) == 0 {
+		return status, err
+	}
+
+	index := indexes[0]
+	status.Index, status.Older = index, indexes[1:]
+
+	if status.Count, err = l.count(ctx, index); err != nil {
+		return nil, err
+	}
+
+	response := map[string]struct {
+		Mappings map[string]interface{} `json:"mappings"`
+	}{}
+
+	req := opensearchapi.IndicesGetMappingRequest{
+		Index: []string{index},
+	}
+
+	if _, err := l.do(ctx, req, &response); err != nil {
+		return nil, err
+	}
+
+	status.Drift = diffMappings(d.Mappings, response[index].Mappings)
+
+	return status, nil
+}
+
+type taskStatus struct {
+	Total            int64 `json:"total"`
+	Created          int64 `json:"created"`
+	Updated          int64 `json:"updated"`
+	Deleted          int64 `json:"deleted"`
+	VersionConflicts int64 `json:"version_conflicts"`
+}
+
+type taskResponse struct {
+	Completed bool `json:"completed"`
+	Task      struct {
+		Status taskStatus `json:"status"`
+	} `json:"task"`
+	Response struct {
+		Failures []json.RawMessage `json:"failures"`
+	} `json:"response"`
+	Error json.RawMessage `json:"error"`
+}
+
+// waitForTask waits for the task with id to complete, logging progress.
+func (l *Lifecycle) waitForTask(ctx context.Context, id string) error {
+	ticker := time.NewTicker(l.ProgressInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("Cancelling task %s", id)
+
+			req := opensearchapi.TasksCancelRequest{TaskID: id}
+			if _, err := l.do(context.Background(), req, nil); err != nil {
+				log.Printf("Error cancelling task %s: %s", id, err)
+			}
+
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		task := new(taskResponse)
+
+		if _, err := l.do(ctx, opensearchapi.TasksGetRequest{TaskID: id}, task); err != nil {
+			return err
+		}
+
+		s := task.Task.Status
+		log.Printf("Reindexed %d of %d documents (%d version conflicts)", s.Created+s.Updated, s.Total, s.VersionConflicts)
+
+		if task.Completed {
+			if len(task.Error) > 0 {
+				return fmt.Errorf("%w: task %s failed: %s", ErrRequest, id, task.Error)
+			}
+
+			if len(task.Response.Failures) > 0 {
+				return fmt.Errorf("%w: task %s failed for %d documents, first: %s",
+					ErrRequest, id, len(task.Response.Failures), task.Response.Failures[0])
+			}
+
+			return nil
+		}
+	}
+}
+
+// reindex copies documents from one index to another on the backend, optionally transforming them.
+func (l *Lifecycle) reindex(ctx context.Context, from, to, transform string) error {
+	type object map[string]interface{}
+
+	reindex := object{
+		"source": object{"index": from},
+		"dest":   object{"index": to, "op_type": "create"},
+	}
+
+	if transform != "" {
+		reindex["script"] = object{"source": transform, "lang": "painless"}
+	}
+
+	body, err := getBody(reindex)
+	if err != nil {
+		panic(err)
+	}
+
+	f, t := false, true
+
+	req := opensearchapi.ReindexRequest{
+		Body:              body,
+		Refresh:           &t,
+		WaitForCompletion: &f,
+	}
+
+	response := struct {
+		Task string `json:"task"`
+	}{}
+
+	if _, err := l.do(ctx, req, &response); err != nil {
+		return err
+	}
+
+	log.Printf("Reindexing %s to %s in task %s", from, to, response.Task)
+
+	return l.waitForTask(ctx, response.Task)
+}
+
+// Migrate creates a new version of the index behind alias from its definition, reindexes the current version into
+// it and, after verifying the number of documents, atomically points the alias to the new version.
+// The original index is kept, unless it is replaced by an alias. Writes during migration might be lost, hence
+// writers should be stopped. When alias points at more than one index, e.g. after rolling over, only the newest is
+// migrated, and the new version is made the index written to; older indexes are left behind the alias.
+func (l *Lifecycle) Migrate(ctx context.Context, alias, kind string, opts *MigrateOptions) (*Migration, error) {
+	indexes, err := l.resolve(ctx, alias)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(indexes) == 0 {
+		return nil, fmt.Errorf("index %s does not exist", alias)
+	}
+
+	from := indexes[0]
+
+	aliased := from != alias
+	if !aliased && !opts.ReplaceIndex {
+		return nil, fmt.Errorf("%w: %s", ErrNotAliased, alias)
+	}
+
+	m := &Migration{
+		Alias: alias,
+		From:  from,
+		To:    nextVersion(alias, from),
+	}
+
+	if err := l.create(ctx, m.To, kind); err != nil {
+		return nil, err
+	}
+
+	// Make all documents visible for reindexing and counting.
+	if _, err := l.do(ctx, opensearchapi.IndicesRefreshRequest{Index: []string{from}}, nil); err != nil {
+		return nil, err
+	}
+
+	if err := l.reindex(ctx, from, m.To, opts.Transform); err != nil {
+		return nil, err
+	}
+
+	fromCount, err := l.count(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.Count, err = l.count(ctx, m.To); err != nil {
+		return nil, err
+	}
+
+	if fromCount != m.Count {
+		return nil, fmt.Errorf("%w: %d documents in %s, %d in %s", ErrCountMismatch, fromCount, from, m.Count, m.To)
+	}
+
+	type action map[string]map[string]interface{}
+
+	add := action{"add": {"index": m.To, "alias": alias}}
+	if len(indexes) > 1 {
+		add["add"]["is_write_index"] = true
+	}
+
+	actions := []action{add}
+
+	if aliased {
+		actions = append(actions, action{"remove": {"index": from, "alias": alias}})
+	} else {
+		actions = append(actions, action{"remove_index": {"index": from}})
+	}
+
+	body, err := getBody(map[string]interface{}{"actions": actions})
+	if err != nil {
+		panic(err)
+	}
+
+	if _, err := l.do(ctx, opensearchapi.IndicesUpdateAliasesRequest{Body: body}, nil); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// String returns a human-readable representation of the status.
+func (s *IndexStatus) String() string {
+	if s.Index == "" {
+		return fmt.Sprintf("%s: does not exist", s.Alias)
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s: %s, %d documents", s.Alias, s.Index, s.Count)
+
+	if len(s.Older) > 0 {
+		fmt.Fprintf(&b, " (older indexes: %s)", strings.Join(s.Older, ", "))
+	}
+
+	if len(s.Drift) == 0 {
+		b.WriteString(", mapping matches definition")
+	}
+
+	for _, d := range s.Drift {
+		fmt.Fprintf(&b, "\n  %s", d)
+	}
+
+	return b.String()
+}
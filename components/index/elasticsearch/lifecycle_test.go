@@ -0,0 +1,317 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/dankinder/httpmock"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/ipfs-search/ipfs-search/components/index/elasticsearch/mappings"
+)
+
+func (s *IndexTestSuite) lifecycle() *Lifecycle {
+	l := NewLifecycle(s.mockClient)
+	l.ProgressInterval = time.Millisecond
+
+	return l
+}
+
+// expectJSON returns a matcher for request bodies decoding into dst.
+func expectJSON(dst interface{}) interface{} {
+	return mock.MatchedBy(func(b []byte) bool {
+		return json.Unmarshal(b, dst) == nil
+	})
+}
+
+func (s *IndexTestSuite) expectAlias(alias string, indexes ...string) {
+	response := make(map[string]interface{}, len(indexes))
+	for _, index := range indexes {
+		response[index] = map[string]interface{}{"aliases": map[string]interface{}{alias: struct{}{}}}
+	}
+
+	body, err := json.Marshal(response)
+	s.Require().NoError(err)
+
+	s.mockAPIHandler.
+		On("Handle", "GET", "/"+alias+"/_alias", mock.Anything).
+		Return(httpmock.Response{
+			Body: body,
+		}).
+		Once()
+}
+
+func (s *IndexTestSuite) expectCount(index string, count int) {
+	s.mockAPIHandler.
+		On("Handle", "POST", "/"+index+"/_count", mock.Anything).
+		Return(httpmock.Response{
+			Body: []byte(fmt.Sprintf(`{"count": %d}`, count)),
+		}).
+		Once()
+}
+
+func (s *IndexTestSuite) TestNextVersion() {
+	s.Equal("files_v1", nextVersion("files", ""))
+	s.Equal("files_v1", nextVersion("files", "files"))
+	s.Equal("files_v3", nextVersion("files", "files_v2"))
+	s.Equal("files_v1", nextVersion("files", "other_v2"))
+}
+
+func (s *IndexTestSuite) TestDiffMappings() {
+	defined := map[string]interface{}{
+		"dynamic": "strict",
+		"properties": map[string]interface{}{
+			"size":    map[string]interface{}{"type": "long"},
+			"content": map[string]interface{}{"type": "text", "index": true},
+			"removed": map[string]interface{}{"type": "keyword"},
+		},
+	}
+
+	actual := map[string]interface{}{
+		"dynamic": "strict",
+		"properties": map[string]interface{}{
+			"size":    map[string]interface{}{"type": "integer"},
+			"content": map[string]interface{}{"type": "text"},
+			"added":   map[string]interface{}{"type": "keyword"},
+		},
+	}
+
+	s.Equal([]string{
+		"+ properties.added.type: keyword",
+		"- properties.removed.type: keyword",
+		"~ properties.size.type: integer (defined: long)",
+	}, diffMappings(defined, actual))
+}
+
+func (s *IndexTestSuite) TestLifecycleInit() {
+	s.mockAPIHandler.
+		On("Handle", "GET", "/ipfs_files/_alias", mock.Anything).
+		Return(httpmock.Response{
+			Status: 404,
+			Body:   []byte(`{"error": "alias [ipfs_files] missing", "status": 404}`),
+		}).
+		Once()
+
+	var body struct {
+		Mappings map[string]interface{}
+		Aliases  map[string]interface{}
+	}
+
+	s.mockAPIHandler.
+		On("Handle", "PUT", "/ipfs_files_v1", expectJSON(&body)).
+		Return(httpmock.Response{
+			Body: []byte(`{"acknowledged": true, "index": "ipfs_files_v1"}`),
+		}).
+		Once()
+
+	index, created, err := s.lifecycle().Init(s.ctx, "ipfs_files", mappings.Files)
+	s.NoError(err)
+	s.True(created)
+	s.Equal("ipfs_files_v1", index)
+	s.Contains(body.Aliases, "ipfs_files")
+	s.NotEmpty(body.Mappings)
+
+	s.mockAPIHandler.AssertExpectations(s.T())
+}
+
+func (s *IndexTestSuite) TestLifecycleInitExisting() {
+	s.expectAlias("ipfs_files", "ipfs_files_v2")
+
+	index, created, err := s.lifecycle().Init(s.ctx, "ipfs_files", mappings.Files)
+	s.NoError(err)
+	s.False(created)
+	s.Equal("ipfs_files_v2", index)
+
+	s.mockAPIHandler.AssertExpectations(s.T())
+}
+
+func (s *IndexTestSuite) TestLifecycleInitMultiIndex() {
+	s.expectAlias("ipfs_files", "ipfs_files-000002", "ipfs_files-000010", "ipfs_files-000001")
+
+	// The newest index is returned, like with getting documents.
+	index, created, err := s.lifecycle().Init(s.ctx, "ipfs_files", mappings.Files)
+	s.NoError(err)
+	s.False(created)
+	s.Equal("ipfs_files-000010", index)
+
+	s.mockAPIHandler.AssertExpectations(s.T())
+}
+
+func (s *IndexTestSuite) TestLifecycleMigrate() {
+	s.expectAlias("ipfs_files", "ipfs_files_v1")
+
+	s.mockAPIHandler.
+		On("Handle", "PUT", "/ipfs_files_v2", mock.Anything).
+		Return(httpmock.Response{
+			Body: []byte(`{"acknowledged": true, "index": "ipfs_files_v2"}`),
+		}).
+		Once()
+
+	s.mockAPIHandler.
+		On("Handle", "POST", "/ipfs_files_v1/_refresh", mock.Anything).
+		Return(httpmock.Response{
+			Body: []byte(`{"_shards": {"total": 1, "successful": 1, "failed": 0}}`),
+		}).
+		Once()
+
+	var reindex struct {
+		Source struct{ Index string }
+		Dest   struct{ Index string }
+		Script struct{ Source string }
+	}
+
+	s.mockAPIHandler.
+		On("Handle", "POST", "/_reindex?refresh=true&wait_for_completion=false", expectJSON(&reindex)).
+		Return(httpmock.Response{
+			Body: []byte(`{"task": "node:42"}`),
+		}).
+		Once()
+
+	s.mockAPIHandler.
+		On("Handle", "GET", "/_tasks/node:42", mock.Anything).
+		Return(httpmock.Response{
+			Body: []byte(`{"completed": false, "task": {"status": {"total": 3, "created": 1}}}`),
+		}).
+		Once()
+
+	s.mockAPIHandler.
+		On("Handle", "GET", "/_tasks/node:42", mock.Anything).
+		Return(httpmock.Response{
+			Body: []byte(`{"completed": true, "task": {"status": {"total": 3, "created": 3}}, "response": {"failures": []}}`),
+		}).
+		Once()
+
+	s.expectCount("ipfs_files_v1", 3)
+	s.expectCount("ipfs_files_v2", 3)
+
+	var aliases struct {
+		Actions []map[string]map[string]string
+	}
+
+	s.mockAPIHandler.
+		On("Handle", "POST", "/_aliases", expectJSON(&aliases)).
+		Return(httpmock.Response{
+			Body: []byte(`{"acknowledged": true}`),
+		}).
+		Once()
+
+	opts := &MigrateOptions{
+		Transform: "ctx._source.remove('urls')",
+	}
+
+	m, err := s.lifecycle().Migrate(s.ctx, "ipfs_files", mappings.Files, opts)
+	s.NoError(err)
+	s.Equal(&Migration{
+		Alias: "ipfs_files",
+		From:  "ipfs_files_v1",
+		To:    "ipfs_files_v2",
+		Count: 3,
+	}, m)
+
+	s.Equal("ipfs_files_v1", reindex.Source.Index)
+	s.Equal("ipfs_files_v2", reindex.Dest.Index)
+	s.Equal(opts.Transform, reindex.Script.Source)
+
+	s.Equal([]map[string]map[string]string{
+		{"add": {"index": "ipfs_files_v2", "alias": "ipfs_files"}},
+		{"remove": {"index": "ipfs_files_v1", "alias": "ipfs_files"}},
+	}, aliases.Actions)
+
+	s.mockAPIHandler.AssertExpectations(s.T())
+}
+
+func (s *IndexTestSuite) TestLifecycleMigrateCountMismatch() {
+	s.expectAlias("ipfs_files", "ipfs_files_v1")
+
+	s.mockAPIHandler.
+		On("Handle", "PUT", "/ipfs_files_v2", mock.Anything).
+		Return(httpmock.Response{Body: []byte(`{"acknowledged": true}`)}).
+		Once()
+
+	s.mockAPIHandler.
+		On("Handle", "POST", "/ipfs_files_v1/_refresh", mock.Anything).
+		Return(httpmock.Response{Body: []byte(`{}`)}).
+		Once()
+
+	s.mockAPIHandler.
+		On("Handle", "POST", "/_reindex?refresh=true&wait_for_completion=false", mock.Anything).
+		Return(httpmock.Response{Body: []byte(`{"task": "node:42"}`)}).
+		Once()
+
+	s.mockAPIHandler.
+		On("Handle", "GET", "/_tasks/node:42", mock.Anything).
+		Return(httpmock.Response{Body: []byte(`{"completed": true}`)}).
+		Once()
+
+	s.expectCount("ipfs_files_v1", 3)
+	s.expectCount("ipfs_files_v2", 2)
+
+	_, err := s.lifecycle().Migrate(s.ctx, "ipfs_files", mappings.Files, &MigrateOptions{})
+	s.ErrorIs(err, ErrCountMismatch)
+
+	// Alias is not swapped.
+	s.mockAPIHandler.AssertExpectations(s.T())
+}
+
+func (s *IndexTestSuite) TestLifecycleMigrateNotAliased() {
+	s.expectAlias("ipfs_files", "ipfs_files")
+
+	_, err := s.lifecycle().Migrate(s.ctx, "ipfs_files", mappings.Files, &MigrateOptions{})
+	s.ErrorIs(err, ErrNotAliased)
+
+	s.mockAPIHandler.AssertExpectations(s.T())
+}
+
+func (s *IndexTestSuite) TestLifecycleMigrateMultiIndex() {
+	s.expectAlias("ipfs_files", "ipfs_files-000001", "ipfs_files-000002")
+
+	s.mockAPIHandler.
+		On("Handle", "PUT", "/ipfs_files_v1", mock.Anything).
+		Return(httpmock.Response{Body: []byte(`{"acknowledged": true}`)}).
+		Once()
+
+	s.mockAPIHandler.
+		On("Handle", "POST", "/ipfs_files-000002/_refresh", mock.Anything).
+		Return(httpmock.Response{Body: []byte(`{}`)}).
+		Once()
+
+	var reindex struct {
+		Source struct{ Index string }
+	}
+
+	s.mockAPIHandler.
+		On("Handle", "POST", "/_reindex?refresh=true&wait_for_completion=false", expectJSON(&reindex)).
+		Return(httpmock.Response{Body: []byte(`{"task": "node:42"}`)}).
+		Once()
+
+	s.mockAPIHandler.
+		On("Handle", "GET", "/_tasks/node:42", mock.Anything).
+		Return(httpmock.Response{Body: []byte(`{"completed": true}`)}).
+		Once()
+
+	s.expectCount("ipfs_files-000002", 3)
+	s.expectCount("ipfs_files_v1", 3)
+
+	var aliases struct {
+		Actions []map[string]map[string]interface{}
+	}
+
+	s.mockAPIHandler.
+		On("Handle", "POST", "/_aliases", expectJSON(&aliases)).
+		Return(httpmock.Response{Body: []byte(`{"acknowledged": true}`)}).
+		Once()
+
+	m, err := s.lifecycle().Migrate(s.ctx, "ipfs_files", mappings.Files, &MigrateOptions{})
+	s.NoError(err)
+	s.Equal("ipfs_files-000002", m.From)
+
+	// Only the newest index is migrated, which is replaced as the index written to.
+	s.Equal("ipfs_files-000002", reindex.Source.Index)
+	s.Equal([]map[string]map[string]interface{}{
+		{"add": {"index": "ipfs_files_v1", "alias": "ipfs_files", "is_write_index": true}},
+		{"remove": {"index": "ipfs_files-000002", "alias": "ipfs_files"}},
+	}, aliases.Actions)
+
+	s.mockAPIHandler.AssertExpectations(s.T())
+}
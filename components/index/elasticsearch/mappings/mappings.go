@@ -0,0 +1,93 @@
+// Package mappings embeds the settings and mappings of the indexes, as used in the Create index API.
+package mappings
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+//go:embed *.json
+var definitions embed.FS
+
+// ErrUnknownKind is returned for kinds of indexes without definition.
+var ErrUnknownKind = errors.New("unknown kind of index")
+
+// Kinds of indexes with embedded definitions.
+const (
+	Files       = "files"
+	Directories = "directories"
+	Invalids    = "invalids"
+	Partials    = "partials"
+	IPNSNames   = "ipns_names"
+)
+
+// Kinds returns all kinds of indexes with embedded definitions.
+func Kinds() []string {
+	return []string{Files, Directories, Invalids, Partials, IPNSNames}
+}
+
+// Definition represents the settings and mappings for a kind of index.
+type Definition struct {
+	Settings map[string]interface{} `json:"settings,omitempty"`
+	Mappings map[string]interface{} `json:"mappings,omitempty"`
+}
+
+// stripComments removes `//` line comments, which are used to annotate definitions, from JSON.
+func stripComments(b []byte) []byte {
+	var (
+		out      bytes.Buffer
+		inString bool
+		escaped  bool
+	)
+
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+
+		switch {
+		case inString:
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '/' && i+1 < len(b) && b[i+1] == '/':
+			// Skip until end of line.
+			for i < len(b) && b[i] != '\n' {
+				i++
+			}
+
+			if i < len(b) {
+				out.WriteByte('\n')
+			}
+
+			continue
+		}
+
+		out.WriteByte(c)
+	}
+
+	return out.Bytes()
+}
+
+// Get returns the embedded definition for a kind of index.
+func Get(kind string) (*Definition, error) {
+	b, err := definitions.ReadFile(kind + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKind, kind)
+	}
+
+	d := new(Definition)
+	if err := json.Unmarshal(stripComments(b), d); err != nil {
+		return nil, fmt.Errorf("error decoding definition for %s: %w", kind, err)
+	}
+
+	return d, nil
+}
@@ -0,0 +1,31 @@
+package mappings
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGet(t *testing.T) {
+	for _, kind := range Kinds() {
+		d, err := Get(kind)
+		if assert.NoError(t, err, kind) {
+			assert.NotEmpty(t, d.Settings, kind)
+		}
+	}
+}
+
+func TestGetUnknown(t *testing.T) {
+	_, err := Get("unknown")
+	assert.ErrorIs(t, err, ErrUnknownKind)
+}
+
+func TestStripComments(t *testing.T) {
+	in := []byte(`{
+	"a": "http://example.com", // comment
+	// "b": 1,
+	"c": "\"//"
+}`)
+
+	assert.JSONEq(t, `{"a": "http://example.com", "c": "\"//"}`, string(stripComments(in)))
+}
@@ -0,0 +1,148 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/opensearch-project/opensearch-go/v2/opensearchapi"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/ipfs-search/ipfs-search/components/index"
+)
+
+const (
+	scrollSize      = 1000            // Number of documents per page of a scan.
+	scrollKeepAlive = 5 * time.Minute // Time to keep the scroll context between pages.
+)
+
+type scrollResponse struct {
+	ScrollID string `json:"_scroll_id"`
+	Hits     struct {
+		Hits []struct {
+			ID     string          `json:"_id"`
+			Source json.RawMessage `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+// getScanBody returns the body of a scan request for filter.
+func getScanBody(filter *index.ScanFilter) (*strings.Reader, error) {
+	var filters []interface{}
+
+	lastSeen := object{}
+	if !filter.LastSeenAfter.IsZero() {
+		lastSeen["gte"] = filter.LastSeenAfter.Format(time.RFC3339)
+	}
+	if !filter.LastSeenBefore.IsZero() {
+		lastSeen["lt"] = filter.LastSeenBefore.Format(time.RFC3339)
+	}
+	if len(lastSeen) > 0 {
+		filters = append(filters, object{"range": object{"last-seen": lastSeen}})
+	}
+
+	if filter.MIMEType != "" {
+		filters = append(filters, object{"prefix": object{"metadata.Content-Type": filter.MIMEType}})
+	}
+
+	query := object{"match_all": object{}}
+	if len(filters) > 0 {
+		query = object{"bool": object{"filter": filters}}
+	}
+
+	b, err := json.Marshal(object{
+		"size":  scrollSize,
+		"sort":  []string{"_doc"},
+		"query": query,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.NewReader(string(b)), nil
+}
+
+// scroll executes req, returning the decoded page of documents.
+func (i *Index) scroll(ctx context.Context, req opensearchapi.Request) (*scrollResponse, error) {
+	res, err := req.Do(ctx, i.c.searchClient)
+	if err != nil {
+		return nil, fmt.Errorf("error executing request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, fmt.Errorf("%w: %s", ErrSearch, res)
+	}
+
+	page := new(scrollResponse)
+	if err := json.NewDecoder(res.Body).Decode(page); err != nil {
+		return nil, fmt.Errorf("error decoding body: %w", err)
+	}
+
+	return page, nil
+}
+
+// Scan calls fn with the id and source of each document matching filter, scrolling through the index in pages.
+func (i *Index) Scan(ctx context.Context, filter *index.ScanFilter, fn func(id string, source json.RawMessage) error) error {
+	ctx, span := i.c.Tracer.Start(ctx, "index.elasticsearch.Scan")
+	defer span.End()
+
+	body, err := getScanBody(filter)
+	if err != nil {
+		panic(err)
+	}
+
+	page, err := i.scroll(ctx, opensearchapi.SearchRequest{
+		Index:  []string{i.cfg.Name},
+		Body:   body,
+		Scroll: scrollKeepAlive,
+	})
+
+	for err == nil && len(page.Hits.Hits) > 0 {
+		for _, hit := range page.Hits.Hits {
+			if err := fn(hit.ID, hit.Source); err != nil {
+				i.clearScroll(page.ScrollID)
+				return err
+			}
+		}
+
+		scrollID := page.ScrollID
+
+		page, err = i.scroll(ctx, opensearchapi.ScrollRequest{
+			ScrollID: scrollID,
+			Scroll:   scrollKeepAlive,
+		})
+		if err != nil {
+			i.clearScroll(scrollID)
+		}
+	}
+
+	if err != nil {
+		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+		return err
+	}
+
+	i.clearScroll(page.ScrollID)
+
+	return nil
+}
+
+// clearScroll releases the scroll context of a scan, ignoring errors as it expires anyhow.
+func (i *Index) clearScroll(scrollID string) {
+	if scrollID == "" {
+		return
+	}
+
+	req := opensearchapi.ClearScrollRequest{ScrollID: []string{scrollID}}
+
+	res, err := req.Do(context.Background(), i.c.searchClient)
+	if err == nil {
+		res.Body.Close()
+	}
+}
+
+// Compile-time assurance that implementation satisfies interface.
+var _ index.Scanner = &Index{}
@@ -0,0 +1,76 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dankinder/httpmock"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/ipfs-search/ipfs-search/components/index"
+)
+
+func (s *IndexTestSuite) expectScroll(method, path string, body interface{}, response string) {
+	s.mockAPIHandler.
+		On("Handle", method, path, body).
+		Return(httpmock.Response{
+			Body: []byte(response),
+		}).
+		Once()
+}
+
+func (s *IndexTestSuite) TestScan() {
+	idx := New(s.mockClient, &Config{Name: "test"}).(index.Scanner)
+
+	var body map[string]interface{}
+
+	s.expectScroll("POST", "/test/_search?scroll=300000ms", mock.MatchedBy(func(b []byte) bool {
+		return json.Unmarshal(b, &body) == nil
+	}), `{"_scroll_id": "scroll1", "hits": {"hits": [
+		{"_id": "a", "_source": {"size": 1}},
+		{"_id": "b", "_source": {"size": 2}}
+	]}}`)
+	s.expectScroll("POST", "/_search/scroll?scroll=300000ms&scroll_id=scroll1", mock.Anything, `{"_scroll_id": "scroll2", "hits": {"hits": [
+		{"_id": "c", "_source": {"size": 3}}
+	]}}`)
+	s.expectScroll("POST", "/_search/scroll?scroll=300000ms&scroll_id=scroll2", mock.Anything, `{"_scroll_id": "scroll2", "hits": {"hits": []}}`)
+	s.expectScroll("DELETE", "/_search/scroll/scroll2", mock.Anything, `{"succeeded": true}`)
+
+	var ids []string
+
+	err := idx.Scan(s.ctx, &index.ScanFilter{
+		LastSeenAfter: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		MIMEType:      "text/",
+	}, func(id string, source json.RawMessage) error {
+		ids = append(ids, id)
+		s.JSONEq(fmt.Sprintf(`{"size": %d}`, len(ids)), string(source))
+		return nil
+	})
+	s.NoError(err)
+	s.Equal([]string{"a", "b", "c"}, ids)
+
+	filters := body["query"].(map[string]interface{})["bool"].(map[string]interface{})["filter"]
+	s.Equal([]interface{}{
+		map[string]interface{}{"range": map[string]interface{}{"last-seen": map[string]interface{}{"gte": "2021-01-01T00:00:00Z"}}},
+		map[string]interface{}{"prefix": map[string]interface{}{"metadata.Content-Type": "text/"}},
+	}, filters)
+
+	s.mockAPIHandler.AssertExpectations(s.T())
+}
+
+func (s *IndexTestSuite) TestScanError() {
+	idx := New(s.mockClient, &Config{Name: "test"}).(index.Scanner)
+
+	s.expectScroll("POST", "/test/_search?scroll=300000ms", mock.Anything, `{"_scroll_id": "scroll1", "hits": {"hits": [
+		{"_id": "a", "_source": {}}
+	]}}`)
+	s.expectScroll("DELETE", "/_search/scroll/scroll1", mock.Anything, `{"succeeded": true}`)
+
+	// The scroll is cleared when fn fails.
+	err := errors.New("stop")
+	s.ErrorIs(idx.Scan(s.ctx, &index.ScanFilter{}, func(string, json.RawMessage) error { return err }), err)
+
+	s.mockAPIHandler.AssertExpectations(s.T())
+}
@@ -0,0 +1,68 @@
+package elasticsearch
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ipfs-search/ipfs-search/components/index"
+)
+
+// script represents a painless script in an update request.
+type script struct {
+	Source string                 `json:"source"`
+	Lang   string                 `json:"lang"`
+	Params map[string]interface{} `json:"params"`
+}
+
+// Painless snippets for operations, formatted with the index of the operation in the script. Field names and values
+// are passed as parameters so that the script source only depends on the types of operations, allowing the
+// compiled script to be cached.
+var operationSources = map[index.OperationType]string{
+	index.AppendIfAbsentOperation: `if (ctx._source[params.f%[1]d] == null) { ctx._source[params.f%[1]d] = []; } ` +
+		`if (!ctx._source[params.f%[1]d].contains(params.v%[1]d)) { ctx._source[params.f%[1]d].add(params.v%[1]d); changed = true; } `,
+	index.MaxOperation: `def c%[1]d = ctx._source[params.f%[1]d]; ` +
+		`if (c%[1]d == null || (c%[1]d instanceof Number ? c%[1]d.doubleValue() < params.v%[1]d.doubleValue() : c%[1]d.compareTo(params.v%[1]d) < 0)) ` +
+		`{ ctx._source[params.f%[1]d] = params.v%[1]d; changed = true; } `,
+	index.IncrementOperation: `if (params.v%[1]d != 0) { ` +
+		`ctx._source[params.f%[1]d] = (ctx._source[params.f%[1]d] == null ? 0 : ctx._source[params.f%[1]d]) + params.v%[1]d; changed = true; } `,
+}
+
+// maxTimeSource is used instead of the MaxOperation snippet for time values, which are compared as instants as their
+// string representation varies in length.
+const maxTimeSource = `def c%[1]d = ctx._source[params.f%[1]d]; ` +
+	`if (c%[1]d == null || ZonedDateTime.parse(c%[1]d).isBefore(ZonedDateTime.parse(params.v%[1]d))) ` +
+	`{ ctx._source[params.f%[1]d] = params.v%[1]d; changed = true; } `
+
+// makeScript returns a script applying ops to a document, without reindexing it when nothing changed.
+func makeScript(ops []index.Operation) (*script, error) {
+	var src strings.Builder
+
+	params := make(map[string]interface{}, 2*len(ops))
+
+	src.WriteString("boolean changed = false; ")
+
+	for i, op := range ops {
+		s, ok := operationSources[op.Type]
+		if !ok {
+			return nil, fmt.Errorf("unknown operation type '%s'", op.Type)
+		}
+
+		if _, isTime := op.Value.(time.Time); isTime && op.Type == index.MaxOperation {
+			s = maxTimeSource
+		}
+
+		fmt.Fprintf(&src, s, i)
+
+		params[fmt.Sprintf("f%d", i)] = op.Field
+		params[fmt.Sprintf("v%d", i)] = op.Value
+	}
+
+	src.WriteString("if (!changed) { ctx.op = 'none'; }")
+
+	return &script{
+		Source: src.String(),
+		Lang:   "painless",
+		Params: params,
+	}, nil
+}
@@ -0,0 +1,145 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/dankinder/httpmock"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/ipfs-search/ipfs-search/components/index"
+)
+
+func (s *IndexTestSuite) TestApply() {
+	idx := New(s.mockClient, &Config{Name: "test"})
+
+	response := []byte(`{
+	   "took": 30,
+	   "errors": false,
+	   "items": [
+	      {
+	         "update": {
+	            "_index": "test",
+	            "_id": "objId",
+	            "result": "noop",
+	            "status": 200
+	         }
+	      }
+	   ]
+	}`)
+
+	var body struct {
+		Script script `json:"script"`
+	}
+
+	s.mockAPIHandler.
+		On("Handle", "POST", "/_bulk", mock.MatchedBy(func(b []byte) bool {
+			// NDJSON; action on the first line, script on the second.
+			lines := bytes.Split(b, []byte("\n"))
+			return len(lines) == 3 &&
+				bytes.Equal(lines[0], []byte(`{"update":{"_index":"test","_id":"objId"}}`)) &&
+				json.Unmarshal(lines[1], &body) == nil
+		})).
+		Return(httpmock.Response{
+			Body: response,
+		}).
+		Once()
+
+	lastSeen := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	err := idx.Apply(s.ctx, "objId",
+		index.AppendIfAbsent("references", map[string]string{"parent_hash": "QmParent", "name": "name"}),
+		index.Max("last-seen", lastSeen),
+		index.Increment("count", 2),
+	)
+	s.NoError(err)
+
+	// Ensure flushing
+	s.ctxCancel()
+	time.Sleep(100 * time.Millisecond)
+
+	s.mockAPIHandler.AssertExpectations(s.T())
+
+	s.Equal("painless", body.Script.Lang)
+	s.Contains(body.Script.Source, "ctx.op = 'none'")
+	s.Equal(map[string]interface{}{
+		"f0": "references",
+		"v0": map[string]interface{}{"parent_hash": "QmParent", "name": "name"},
+		"f1": "last-seen",
+		"v1": "2021-01-01T00:00:00Z",
+		"f2": "count",
+		"v2": float64(2),
+	}, body.Script.Params)
+}
+
+func (s *IndexTestSuite) TestMakeScriptSourceIndependentOfValues() {
+	s1, err := makeScript([]index.Operation{index.Max("a", 1)})
+	s.NoError(err)
+
+	s2, err := makeScript([]index.Operation{index.Max("b", 2)})
+	s.NoError(err)
+
+	s.Equal(s1.Source, s2.Source)
+}
+
+func (s *IndexTestSuite) TestMakeScriptMaxTime() {
+	// Times with and without fractional seconds differ in length, hence they are not compared as strings.
+	sc, err := makeScript([]index.Operation{index.Max("last-seen", time.Now())})
+	s.NoError(err)
+	s.Contains(sc.Source, "ZonedDateTime.parse")
+
+	sc, err = makeScript([]index.Operation{index.Max("size", 1)})
+	s.NoError(err)
+	s.NotContains(sc.Source, "ZonedDateTime.parse")
+}
+
+func (s *IndexTestSuite) TestMakeScriptUnknownOperation() {
+	_, err := makeScript([]index.Operation{{Type: "invalid", Field: "a"}})
+	s.Error(err)
+}
+
+func (s *IndexTestSuite) TestApplyConflict() {
+	idx := New(s.mockClient, &Config{Name: "test"})
+
+	response := []byte(`{
+	   "took": 30,
+	   "errors": true,
+	   "items": [
+	      {
+	         "update": {
+	            "_index": "test",
+	            "_id": "objId",
+	            "status": 409,
+	            "error": {"type": "version_conflict_engine_exception"}
+	         }
+	      }
+	   ]
+	}`)
+
+	s.mockAPIHandler.
+		On("Handle", "POST", "/_bulk", mock.Anything).
+		Return(httpmock.Response{
+			Body: response,
+		}).
+		Once()
+
+	// Conflicting scripts are retried, letting the backend retry on further conflicts.
+	s.mockAPIHandler.
+		On("Handle", "POST", "/test/_update/objId?retry_on_conflict=3", mock.MatchedBy(func(b []byte) bool {
+			return bytes.Contains(b, []byte(`"script"`))
+		})).
+		Return(httpmock.Response{
+			Body: []byte(`{"_index": "test", "_id": "objId", "result": "updated"}`),
+		}).
+		Once()
+
+	err := idx.Apply(s.ctx, "objId", index.Increment("count", 1))
+	s.NoError(err)
+
+	// Ensure flushing
+	s.ctxCancel()
+	time.Sleep(100 * time.Millisecond)
+
+	s.mockAPIHandler.AssertExpectations(s.T())
+}
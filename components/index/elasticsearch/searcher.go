@@ -0,0 +1,266 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/opensearch-project/opensearch-go/v2/opensearchapi"
+
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/ipfs-search/ipfs-search/components/index"
+	"github.com/ipfs-search/ipfs-search/components/index/types"
+)
+
+// ErrSearch is returned when the search backend returns an error.
+var ErrSearch = errors.New("search error")
+
+// SearcherConfig represents the configuration for searching files and directories.
+type SearcherConfig struct {
+	Files       string // Name of the files index
+	Directories string // Name of the directories index
+}
+
+// Searcher searches files and directories in Elasticsearch indexes.
+type Searcher struct {
+	cfg *SearcherConfig
+	c   *Client
+}
+
+// NewSearcher returns a new searcher.
+func NewSearcher(client *Client, cfg *SearcherConfig) index.Searcher {
+	if client == nil {
+		panic("Searcher.New Client cannot be nil.")
+	}
+
+	if cfg == nil {
+		panic("Searcher.New Config cannot be nil.")
+	}
+
+	return &Searcher{
+		cfg: cfg,
+		c:   client,
+	}
+}
+
+// Fields included in hits; as contents can be huge, only what is needed for rendering hits.
+var sourceFields = []string{
+	"size",
+	"first-seen",
+	"last-seen",
+	"references",
+	"metadata.title",
+	"metadata.Content-Type",
+}
+
+// Fields for which matching snippets are returned.
+var highlightFields = []string{
+	"content",
+	"metadata.title",
+	"metadata.description",
+	"references.name",
+}
+
+type object map[string]interface{}
+
+// typeQuery matches documents from an index, naming the query after the document type so that the type of hits can be
+// told from their matched queries (the hits' index names can not be used when indexes are aliased).
+func typeQuery(name string, t index.DocumentType) object {
+	return object{"term": object{"_index": object{"value": name, "_name": string(t)}}}
+}
+
+func (s *Searcher) indexes(t index.DocumentType) ([]string, []interface{}, error) {
+	files := typeQuery(s.cfg.Files, index.FileType)
+	dirs := typeQuery(s.cfg.Directories, index.DirectoryType)
+
+	switch t {
+	case index.AnyType, "":
+		return []string{s.cfg.Files, s.cfg.Directories}, []interface{}{files, dirs}, nil
+	case index.FileType:
+		return []string{s.cfg.Files}, []interface{}{files}, nil
+	case index.DirectoryType:
+		return []string{s.cfg.Directories}, []interface{}{dirs}, nil
+	default:
+		return nil, nil, fmt.Errorf("%w: unknown document type '%s'", index.ErrInvalidQuery, t)
+	}
+}
+
+func getSearchBody(q *index.Query, typeQueries []interface{}) (io.ReadSeeker, error) {
+	filters := []interface{}{
+		object{"bool": object{"should": typeQueries}},
+	}
+
+	if q.MinSize > 0 || q.MaxSize > 0 {
+		size := object{}
+		if q.MinSize > 0 {
+			size["gte"] = q.MinSize
+		}
+		if q.MaxSize > 0 {
+			size["lte"] = q.MaxSize
+		}
+
+		filters = append(filters, object{"range": object{"size": size}})
+	}
+
+	if !q.LastSeenAfter.IsZero() {
+		filters = append(filters, object{"range": object{"last-seen": object{"gte": q.LastSeenAfter.Format(time.RFC3339)}}})
+	}
+
+	if q.MIMEType != "" {
+		filters = append(filters, object{"prefix": object{"metadata.Content-Type": q.MIMEType}})
+	}
+
+	highlight := object{}
+	for _, f := range highlightFields {
+		highlight[f] = object{}
+	}
+
+	return getBody(object{
+		"from": q.From,
+		"size": q.Size,
+		"query": object{
+			"bool": object{
+				"must": object{
+					"query_string": object{
+						"query":            q.Text,
+						"default_operator": "AND",
+					},
+				},
+				"filter": filters,
+			},
+		},
+		"_source":   sourceFields,
+		"highlight": object{"fields": highlight},
+	})
+}
+
+type searchResponse struct {
+	Hits struct {
+		Total struct {
+			Value int64 `json:"value"`
+		} `json:"total"`
+		MaxScore float64 `json:"max_score"`
+		Hits     []struct {
+			ID             string              `json:"_id"`
+			Score          float64             `json:"_score"`
+			Source         hitSource           `json:"_source"`
+			Highlight      map[string][]string `json:"highlight"`
+			MatchedQueries []string            `json:"matched_queries"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+type hitSource struct {
+	Size       uint64                 `json:"size"`
+	FirstSeen  time.Time              `json:"first-seen"`
+	LastSeen   time.Time              `json:"last-seen"`
+	References types.References       `json:"references"`
+	Metadata   map[string]interface{} `json:"metadata"`
+}
+
+// metadataString returns the first value of a metadata field, which may be a string or a list of strings.
+func metadataString(m map[string]interface{}, field string) string {
+	switch v := m[field].(type) {
+	case string:
+		return v
+	case []interface{}:
+		if len(v) > 0 {
+			s, _ := v[0].(string)
+			return s
+		}
+	}
+
+	return ""
+}
+
+func decodeSearchResponse(res *opensearchapi.Response) (*index.SearchResult, error) {
+	response := searchResponse{}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("error decoding body: %w", err)
+	}
+
+	result := &index.SearchResult{
+		Total:    response.Hits.Total.Value,
+		MaxScore: response.Hits.MaxScore,
+		Hits:     make([]index.Hit, len(response.Hits.Hits)),
+	}
+
+	for i, h := range response.Hits.Hits {
+		hit := index.Hit{
+			ID:         h.ID,
+			Score:      h.Score,
+			Title:      metadataString(h.Source.Metadata, "title"),
+			MIMEType:   metadataString(h.Source.Metadata, "Content-Type"),
+			Size:       h.Source.Size,
+			FirstSeen:  h.Source.FirstSeen,
+			LastSeen:   h.Source.LastSeen,
+			References: h.Source.References,
+			Highlights: h.Highlight,
+		}
+
+		if len(h.MatchedQueries) > 0 {
+			hit.Type = index.DocumentType(h.MatchedQueries[0])
+		}
+
+		result.Hits[i] = hit
+	}
+
+	return result, nil
+}
+
+// Search returns a page of files and/or directories matching the query.
+func (s *Searcher) Search(ctx context.Context, q *index.Query) (*index.SearchResult, error) {
+	ctx, span := s.c.Tracer.Start(ctx, "index.elasticsearch.Search")
+	defer span.End()
+
+	indexes, typeQueries, err := s.indexes(q.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := getSearchBody(q, typeQueries)
+	if err != nil {
+		panic(err)
+	}
+
+	req := opensearchapi.SearchRequest{
+		Index: indexes,
+		Body:  body,
+	}
+
+	res, err := req.Do(ctx, s.c.searchClient)
+	if err != nil {
+		err = fmt.Errorf("error executing request: %w", err)
+		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 400 {
+		// Bad requests are the result of the query, e.g. query string syntax errors.
+		err = fmt.Errorf("%w: %s", index.ErrInvalidQuery, res)
+		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+		return nil, err
+	}
+
+	if res.IsError() {
+		err = fmt.Errorf("%w: %s", ErrSearch, res)
+		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+		return nil, err
+	}
+
+	result, err := decodeSearchResponse(res)
+	if err != nil {
+		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+	}
+
+	return result, err
+}
+
+// Compile-time assurance that implementation satisfies interface.
+var _ index.Searcher = &Searcher{}
@@ -0,0 +1,149 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/dankinder/httpmock"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/ipfs-search/ipfs-search/components/index"
+)
+
+func (s *IndexTestSuite) searcher() index.Searcher {
+	return NewSearcher(s.mockClient, &SearcherConfig{
+		Files:       "files",
+		Directories: "dirs",
+	})
+}
+
+func (s *IndexTestSuite) TestSearch() {
+	response := []byte(`{
+	  "took": 5,
+	  "timed_out": false,
+	  "hits": {
+	    "total": {"value": 12, "relation": "eq"},
+	    "max_score": 3.5,
+	    "hits": [
+	      {
+	        "_index": "files_v9",
+	        "_id": "QmafrLBfzRLV4XSH1XcaMMeaXEUhDJjmtDfsYU95TrWG87",
+	        "_score": 3.5,
+	        "_source": {
+	          "size": 3431,
+	          "first-seen": "2021-01-01T00:00:00Z",
+	          "last-seen": "2021-02-01T00:00:00Z",
+	          "references": [{"parent_hash": "QmSKboVigcD3AY4kLsob117KJcMHvMUu6vNFqk1PQzYUpp", "name": "test.pdf"}],
+	          "metadata": {"title": ["Test title"], "Content-Type": ["application/pdf"]}
+	        },
+	        "highlight": {"content": ["A <em>test</em> document"]},
+	        "matched_queries": ["file"]
+	      },
+	      {
+	        "_index": "dirs_v9",
+	        "_id": "QmSKboVigcD3AY4kLsob117KJcMHvMUu6vNFqk1PQzYUpp",
+	        "_score": 1.5,
+	        "_source": {"size": 23},
+	        "matched_queries": ["directory"]
+	      }
+	    ]
+	  }
+	}`)
+
+	var body map[string]interface{}
+
+	s.mockAPIHandler.
+		On("Handle", "POST", "/files,dirs/_search", mock.MatchedBy(func(b []byte) bool {
+			return json.Unmarshal(b, &body) == nil
+		})).
+		Return(httpmock.Response{
+			Body: response,
+		}).
+		Once()
+
+	lastSeen := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	result, err := s.searcher().Search(s.ctx, &index.Query{
+		Text:          "test",
+		MIMEType:      "application/",
+		MinSize:       10,
+		LastSeenAfter: lastSeen,
+		From:          15,
+		Size:          15,
+	})
+	s.NoError(err)
+
+	s.Equal(int64(12), result.Total)
+	s.Equal(3.5, result.MaxScore)
+	s.Require().Len(result.Hits, 2)
+
+	file := result.Hits[0]
+	s.Equal("QmafrLBfzRLV4XSH1XcaMMeaXEUhDJjmtDfsYU95TrWG87", file.ID)
+	s.Equal(index.FileType, file.Type)
+	s.Equal("Test title", file.Title)
+	s.Equal("application/pdf", file.MIMEType)
+	s.Equal(uint64(3431), file.Size)
+	s.Equal(lastSeen, file.FirstSeen)
+	s.Equal("test.pdf", file.References[0].Name)
+	s.Equal([]string{"A <em>test</em> document"}, file.Highlights["content"])
+
+	s.Equal(index.DirectoryType, result.Hits[1].Type)
+
+	// Filters are applied in the request.
+	s.EqualValues(15, body["from"])
+	filters := body["query"].(map[string]interface{})["bool"].(map[string]interface{})["filter"].([]interface{})
+	s.Len(filters, 4)
+	s.Contains(filters, map[string]interface{}{
+		"range": map[string]interface{}{"size": map[string]interface{}{"gte": float64(10)}},
+	})
+	s.Contains(filters, map[string]interface{}{
+		"prefix": map[string]interface{}{"metadata.Content-Type": "application/"},
+	})
+
+	s.mockAPIHandler.AssertExpectations(s.T())
+}
+
+func (s *IndexTestSuite) TestSearchType() {
+	s.mockAPIHandler.
+		On("Handle", "POST", "/dirs/_search", mock.Anything).
+		Return(httpmock.Response{
+			Body: []byte(`{"hits": {"total": {"value": 0}, "hits": []}}`),
+		}).
+		Once()
+
+	result, err := s.searcher().Search(s.ctx, &index.Query{
+		Text: "test",
+		Type: index.DirectoryType,
+		Size: 15,
+	})
+	s.NoError(err)
+	s.Empty(result.Hits)
+
+	s.mockAPIHandler.AssertExpectations(s.T())
+}
+
+func (s *IndexTestSuite) TestSearchInvalidType() {
+	_, err := s.searcher().Search(s.ctx, &index.Query{
+		Text: "test",
+		Type: "invalid",
+	})
+	s.ErrorIs(err, index.ErrInvalidQuery)
+}
+
+func (s *IndexTestSuite) TestSearchInvalidQuery() {
+	s.mockAPIHandler.
+		On("Handle", "POST", "/files,dirs/_search", mock.Anything).
+		Return(httpmock.Response{
+			Status: 400,
+			Body:   []byte(`{"error": {"type": "query_shard_exception"}, "status": 400}`),
+		}).
+		Once()
+
+	_, err := s.searcher().Search(s.ctx, &index.Query{
+		Text: "test AND (",
+		Size: 15,
+	})
+	s.ErrorIs(err, index.ErrInvalidQuery)
+
+	s.mockAPIHandler.AssertExpectations(s.T())
+}
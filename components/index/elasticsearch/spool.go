@@ -0,0 +1,599 @@
+package elasticsearch
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/c2h5oh/datasize"
+	opensearchutil "github.com/opensearch-project/opensearch-go/v2/opensearchutil"
+	"go.opentelemetry.io/otel/api/metric"
+
+	"github.com/ipfs-search/ipfs-search/instr"
+)
+
+// Spool modes.
+const (
+	SpoolNone = "none" // Writes are dropped when flushing fails.
+	SpoolDisk = "disk" // Writes are spooled to disk when flushing fails.
+)
+
+// ErrUnknownSpoolMode is returned by NewClient for unknown spool modes.
+var ErrUnknownSpoolMode = errors.New("unknown spool mode")
+
+// ErrSpoolFull is returned for writes which can not be spooled as the spool reached its maximum size.
+var ErrSpoolFull = errors.New("spool full")
+
+// errUnavailable is returned when replaying is aborted as the cluster became unavailable again.
+var errUnavailable = errors.New("cluster unavailable")
+
+// segmentExt is the extension of spool segments, named by their sequence number.
+const segmentExt = ".jsonl"
+
+// failedExt is appended to the names of segments which are set aside after failing to be replayed.
+const failedExt = ".failed"
+
+// SpoolConfig configures spooling of bulk writes to disk while the cluster is unavailable.
+type SpoolConfig struct {
+	Mode          string            // SpoolNone or SpoolDisk.
+	Dir           string            // Directory for spooled writes, which should not be shared between processes.
+	MaxSize       datasize.ByteSize // Maximum size of spooled writes.
+	RetryInterval time.Duration     // Interval for checking whether the cluster is available again.
+	MaxReplays    int               // Failed replays after which a segment is set aside; unlimited when 0.
+}
+
+// DefaultSpoolConfig returns the default configuration for spooling, which is disabled.
+func DefaultSpoolConfig() *SpoolConfig {
+	return &SpoolConfig{
+		Mode:          SpoolNone,
+		Dir:           "spool",
+		MaxSize:       1024 * 1024 * 1024, // 1GB
+		RetryInterval: 10 * time.Second,
+		MaxReplays:    10,
+	}
+}
+
+// pendingWrite is a write added to the bulk indexer, which has not been flushed yet.
+type pendingWrite struct {
+	w        *bulkWrite
+	replayed bool // Replayed from a segment, which is kept until replaying it succeeds.
+	once     sync.Once
+	done     func(error)
+}
+
+// complete reports the result of the write, once; writes which were spooled may still be flushed afterwards.
+func (p *pendingWrite) complete(err error) {
+	p.once.Do(func() {
+		p.done(err)
+	})
+}
+
+// spool is a write-ahead log for bulk writes. When flushing fails, e.g. when the cluster is down, writes pending in
+// the bulk indexer are spooled to disk, as are subsequent writes. Once the cluster is available, spooled writes are
+// replayed in order. As writes pending in the bulk indexer may have been flushed otherwise, and as segments are
+// replayed from the start after failing, writes may be replayed more than once. Segments which fail to be replayed
+// MaxReplays times, e.g. because they can not be flushed at all, are set aside.
+// Versioned writes are spooled likewise, but performed synchronously as the bulk indexer does not support versions.
+type spool struct {
+	cfg         *SpoolConfig
+	bulkIndexer opensearchutil.BulkIndexer
+	item        func(*bulkWrite, func(error)) opensearchutil.BulkIndexerItem
+	perform     func(context.Context, *bulkWrite) error // Performs versioned writes.
+	ping        func(context.Context) error             // Returns an error while the cluster is unavailable.
+
+	mu        sync.Mutex
+	available bool
+	failures  int // Number of failed flushes.
+	next      uint64
+	pending   map[uint64]*pendingWrite
+	segment   *os.File // Segment being appended to, nil when not opened yet.
+	segmentNo int
+	size      int64
+	replays   map[int]int // Failed replays by segment.
+
+	spooled   metric.Int64Counter
+	replayed  metric.Int64Counter
+	rejected  metric.Int64Counter
+	setAsides metric.Int64Counter
+}
+
+func newSpool(
+	cfg *SpoolConfig,
+	bulkIndexer opensearchutil.BulkIndexer,
+	item func(*bulkWrite, func(error)) opensearchutil.BulkIndexerItem,
+	perform func(context.Context, *bulkWrite) error,
+	ping func(context.Context) error,
+	i *instr.Instrumentation,
+) (*spool, error) {
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, err
+	}
+
+	s := &spool{
+		cfg:         cfg,
+		bulkIndexer: bulkIndexer,
+		item:        item,
+		perform:     perform,
+		ping:        ping,
+		pending:     make(map[uint64]*pendingWrite),
+		replays:     make(map[int]int),
+	}
+
+	segments, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, no := range segments {
+		fi, err := os.Stat(s.segmentPath(no))
+		if err != nil {
+			return nil, err
+		}
+
+		s.size += fi.Size()
+		s.segmentNo = no + 1
+	}
+
+	// Replay writes spooled before, e.g. before a restart.
+	s.available = len(segments) == 0
+
+	if !s.available {
+		log.Printf("Found %d bytes of spooled writes in %s", s.size, cfg.Dir)
+	}
+
+	m := metric.Must(i.Meter)
+	s.spooled = m.NewInt64Counter("index.elasticsearch.spool.spooled",
+		metric.WithDescription("Writes spooled to disk."))
+	s.replayed = m.NewInt64Counter("index.elasticsearch.spool.replayed",
+		metric.WithDescription("Spooled writes replayed to the bulk indexer."))
+	s.rejected = m.NewInt64Counter("index.elasticsearch.spool.rejected",
+		metric.WithDescription("Writes rejected as the spool was full."))
+	s.setAsides = m.NewInt64Counter("index.elasticsearch.spool.set_aside",
+		metric.WithDescription("Spool segments set aside after failing to be replayed."))
+	m.NewInt64ValueObserver("index.elasticsearch.spool.size", func(_ context.Context, r metric.Int64ObserverResult) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		r.Observe(s.size)
+	}, metric.WithDescription("Size of spooled writes in bytes."))
+
+	return s, nil
+}
+
+func (s *spool) segmentPath(no int) string {
+	return filepath.Join(s.cfg.Dir, fmt.Sprintf("%020d%s", no, segmentExt))
+}
+
+// segments returns the numbers of segments on disk, in order.
+func (s *spool) segments() ([]int, error) {
+	files, err := ioutil.ReadDir(s.cfg.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []int
+
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+
+		no, err := strconv.Atoi(strings.TrimSuffix(name, segmentExt))
+		if err != nil {
+			continue
+		}
+
+		segments = append(segments, no)
+	}
+
+	sort.Ints(segments)
+
+	return segments, nil
+}
+
+// write appends w to the current segment. The caller must hold the lock.
+func (s *spool) write(w *bulkWrite) error {
+	b, err := json.Marshal(w)
+	if err != nil {
+		return err
+	}
+
+	b = append(b, '\n')
+
+	if s.size+int64(len(b)) > int64(s.cfg.MaxSize) {
+		s.rejected.Add(context.Background(), 1)
+		return fmt.Errorf("%w: not spooling %s in %s", ErrSpoolFull, w.ID, w.Index)
+	}
+
+	if s.segment == nil {
+		s.segment, err = os.OpenFile(s.segmentPath(s.segmentNo), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.segment.Write(b); err != nil {
+		return err
+	}
+
+	s.size += int64(len(b))
+	s.spooled.Add(context.Background(), 1)
+
+	return nil
+}
+
+// sync commits the current segment to disk. The caller must hold the lock.
+func (s *spool) sync() error {
+	if s.segment == nil {
+		return nil
+	}
+
+	return s.segment.Sync()
+}
+
+// commit appends w to the current segment and commits it to disk. The caller must hold the lock.
+func (s *spool) commit(w *bulkWrite) error {
+	if err := s.write(w); err != nil {
+		return err
+	}
+
+	return s.sync()
+}
+
+// submit adds w to the bulk indexer, tracking it until it is flushed.
+func (s *spool) submit(ctx context.Context, w *bulkWrite, replayed bool, done func(error)) error {
+	p := &pendingWrite{w: w, replayed: replayed, done: done}
+
+	s.mu.Lock()
+	key := s.next
+	s.next++
+	s.pending[key] = p
+	s.mu.Unlock()
+
+	item := s.item(w, func(err error) {
+		s.mu.Lock()
+		delete(s.pending, key)
+		s.mu.Unlock()
+
+		p.complete(err)
+	})
+
+	if err := s.bulkIndexer.Add(ctx, item); err != nil {
+		s.mu.Lock()
+		delete(s.pending, key)
+		s.mu.Unlock()
+
+		return err
+	}
+
+	return nil
+}
+
+// add adds w to the bulk indexer, or spools it while the cluster is unavailable, in which case done is called once
+// it is committed to disk.
+func (s *spool) add(ctx context.Context, w *bulkWrite, done func(error)) error {
+	s.mu.Lock()
+
+	if !s.available {
+		defer s.mu.Unlock()
+
+		if err := s.commit(w); err != nil {
+			return err
+		}
+
+		done(nil)
+
+		return nil
+	}
+
+	s.mu.Unlock()
+
+	return s.submit(ctx, w, false, done)
+}
+
+// addVersioned performs versioned write w, or spools it while the cluster is unavailable, or when it turns out to be
+// unavailable performing it.
+func (s *spool) addVersioned(ctx context.Context, w *bulkWrite) error {
+	s.mu.Lock()
+	available := s.available
+	s.mu.Unlock()
+
+	if available {
+		err := s.perform(ctx, w)
+		if !errors.Is(err, errUnavailable) || ctx.Err() != nil {
+			return err
+		}
+
+		log.Printf("Error performing versioned write of %s in %s: %s", w.ID, w.Index, err)
+
+		s.flushFailed()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.commit(w)
+}
+
+// flushFailed spools all writes pending in the bulk indexer, as writes of a failed flush are dropped, and spools
+// subsequent writes until the cluster is available. Pending writes which are being replayed are not spooled again,
+// as their segment is replayed again.
+func (s *spool) flushFailed() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.available {
+		log.Printf("Spooling index writes to %s until the cluster is available", s.cfg.Dir)
+	}
+
+	s.available = false
+	s.failures++
+
+	keys := make([]uint64, 0, len(s.pending))
+	for key := range s.pending {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	errs := make([]error, len(keys))
+
+	for n, key := range keys {
+		if p := s.pending[key]; !p.replayed {
+			errs[n] = s.write(p.w)
+		}
+	}
+
+	// Writes are only reported to be spooled once committed to disk.
+	syncErr := s.sync()
+
+	for n, key := range keys {
+		p := s.pending[key]
+
+		switch {
+		case p.replayed:
+			p.complete(errUnavailable)
+		case errs[n] != nil:
+			p.complete(errs[n])
+		default:
+			p.complete(syncErr)
+		}
+	}
+
+	s.pending = make(map[uint64]*pendingWrite)
+}
+
+// replaySegment adds the writes in segment to the bulk indexer, in order, waiting until they are flushed or their
+// flush failed. Writes which fail otherwise are dropped. Replaying is aborted when a flush fails after failures.
+func (s *spool) replaySegment(ctx context.Context, no int, failures int) error {
+	f, err := os.Open(s.segmentPath(no))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var wg sync.WaitGroup
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, int(s.cfg.MaxSize))
+
+	for scanner.Scan() {
+		s.mu.Lock()
+		failed := s.failures != failures
+		s.mu.Unlock()
+
+		if failed {
+			wg.Wait()
+			return errUnavailable
+		}
+
+		w := new(bulkWrite)
+		if err := json.Unmarshal(scanner.Bytes(), w); err != nil {
+			log.Printf("Skipping corrupt write in spool segment %d: %s", no, err)
+			continue
+		}
+
+		if w.Version != nil {
+			// Perform versioned writes after the writes before them.
+			wg.Wait()
+
+			s.replayVersioned(ctx, w)
+			s.replayed.Add(ctx, 1)
+
+			continue
+		}
+
+		wg.Add(1)
+
+		if err := s.submit(ctx, w, true, func(error) { wg.Done() }); err != nil {
+			wg.Done()
+			wg.Wait()
+
+			return err
+		}
+
+		s.replayed.Add(ctx, 1)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failures != failures {
+		return errUnavailable
+	}
+
+	return nil
+}
+
+// replayVersioned performs a spooled versioned write. Writes which conflict are dropped, as the document changed since
+// the write was spooled.
+func (s *spool) replayVersioned(ctx context.Context, w *bulkWrite) {
+	err := s.perform(ctx, w)
+
+	switch {
+	case errors.Is(err, errUnavailable):
+		s.flushFailed()
+	case err != nil:
+		log.Printf("Dropping spooled versioned write of %s in %s: %s", w.ID, w.Index, err)
+	}
+}
+
+// setAside sets aside a segment which failed to be replayed too often, keeping it for inspection.
+func (s *spool) setAside(no int) error {
+	path := s.segmentPath(no)
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Rename(path, path+failedExt); err != nil {
+		return err
+	}
+
+	log.Printf("Set aside spool segment %s after %d failed replays", path, s.cfg.MaxReplays)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.size -= fi.Size()
+	delete(s.replays, no)
+	s.setAsides.Add(context.Background(), 1)
+
+	return nil
+}
+
+// replay replays spooled writes until none are left, after which writes are added to the bulk indexer again.
+func (s *spool) replay(ctx context.Context) error {
+	for {
+		s.mu.Lock()
+
+		// Continue spooling to a new segment, while replaying those before.
+		if s.segment != nil {
+			if err := s.segment.Close(); err != nil {
+				s.mu.Unlock()
+				return err
+			}
+
+			s.segment = nil
+			s.segmentNo++
+		}
+
+		failures := s.failures
+
+		segments, err := s.segments()
+		if err != nil {
+			s.mu.Unlock()
+			return err
+		}
+
+		if len(segments) == 0 {
+			s.available = true
+			s.mu.Unlock()
+
+			log.Printf("Replayed spooled index writes")
+
+			return nil
+		}
+
+		s.mu.Unlock()
+
+		for _, no := range segments {
+			err := s.replaySegment(ctx, no, failures)
+			if errors.Is(err, errUnavailable) {
+				// The segment is replayed again later, unless it failed too often.
+				s.mu.Lock()
+				s.replays[no]++
+				replays := s.replays[no]
+				s.mu.Unlock()
+
+				if s.cfg.MaxReplays > 0 && replays >= s.cfg.MaxReplays {
+					return s.setAside(no)
+				}
+
+				return nil
+			}
+
+			if err != nil {
+				return err
+			}
+
+			fi, err := os.Stat(s.segmentPath(no))
+			if err != nil {
+				return err
+			}
+
+			if err := os.Remove(s.segmentPath(no)); err != nil {
+				return err
+			}
+
+			s.mu.Lock()
+			s.size -= fi.Size()
+			delete(s.replays, no)
+			s.mu.Unlock()
+		}
+	}
+}
+
+// work replays spooled writes whenever the cluster is available, until ctx is done.
+func (s *spool) work(ctx context.Context) error {
+	ticker := time.NewTicker(s.cfg.RetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		s.mu.Lock()
+		available := s.available
+		s.mu.Unlock()
+
+		if available {
+			continue
+		}
+
+		if err := s.ping(ctx); err != nil {
+			log.Printf("Cluster unavailable, not replaying spooled writes: %s", err)
+			continue
+		}
+
+		if err := s.replay(ctx); err != nil {
+			log.Printf("Error replaying spooled writes: %s", err)
+		}
+	}
+}
+
+// close closes the segment being appended to.
+func (s *spool) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.segment == nil {
+		return nil
+	}
+
+	err := s.segment.Close()
+	s.segment = nil
+
+	return err
+}
@@ -0,0 +1,351 @@
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	opensearchutil "github.com/opensearch-project/opensearch-go/v2/opensearchutil"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/ipfs-search/ipfs-search/components/index"
+	"github.com/ipfs-search/ipfs-search/components/index/types"
+	"github.com/ipfs-search/ipfs-search/instr"
+)
+
+// fakeBulkIndexer records added items, flushing them immediately when flush is set.
+type fakeBulkIndexer struct {
+	mu    sync.Mutex
+	items []opensearchutil.BulkIndexerItem
+	flush bool
+}
+
+func (b *fakeBulkIndexer) Add(ctx context.Context, item opensearchutil.BulkIndexerItem) error {
+	b.mu.Lock()
+	b.items = append(b.items, item)
+	flush := b.flush
+	b.mu.Unlock()
+
+	if flush {
+		item.OnSuccess(ctx, item, opensearchutil.BulkIndexerResponseItem{})
+	}
+
+	return nil
+}
+
+func (b *fakeBulkIndexer) Close(ctx context.Context) error {
+	return nil
+}
+
+func (b *fakeBulkIndexer) Stats() opensearchutil.BulkIndexerStats {
+	return opensearchutil.BulkIndexerStats{}
+}
+
+func (b *fakeBulkIndexer) ids() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ids := make([]string, len(b.items))
+	for i, item := range b.items {
+		ids[i] = item.DocumentID
+	}
+
+	return ids
+}
+
+type SpoolTestSuite struct {
+	suite.Suite
+
+	ctx         context.Context
+	cfg         *SpoolConfig
+	bulkIndexer *fakeBulkIndexer
+	pingErr     error
+	performed   []string
+	performErr  error
+	spool       *spool
+}
+
+func (s *SpoolTestSuite) SetupTest() {
+	s.ctx = context.Background()
+
+	s.cfg = DefaultSpoolConfig()
+	s.cfg.Mode = SpoolDisk
+	s.cfg.Dir = s.T().TempDir()
+
+	s.bulkIndexer = &fakeBulkIndexer{}
+	s.pingErr = nil
+	s.performed = nil
+	s.performErr = nil
+
+	s.spool = s.newSpool()
+}
+
+func (s *SpoolTestSuite) newSpool() *spool {
+	ping := func(context.Context) error { return s.pingErr }
+	perform := func(_ context.Context, w *bulkWrite) error {
+		s.performed = append(s.performed, w.ID)
+		return s.performErr
+	}
+
+	spool, err := newSpool(s.cfg, s.bulkIndexer, (&Client{}).item, perform, ping, instr.New())
+	s.Require().NoError(err)
+
+	return spool
+}
+
+// add adds a write of id, returning the result reported for it.
+func (s *SpoolTestSuite) add(id string) <-chan error {
+	result := make(chan error, 1)
+
+	w := &bulkWrite{Index: "test", Action: "update", ID: id, Body: []byte(`{"doc":{}}`)}
+	s.NoError(s.spool.add(s.ctx, w, func(err error) { result <- err }))
+
+	return result
+}
+
+// spooled returns the IDs of spooled writes.
+func (s *SpoolTestSuite) spooled() []string {
+	s.NoError(s.spool.close())
+
+	files, err := filepath.Glob(filepath.Join(s.cfg.Dir, "*"+segmentExt))
+	s.NoError(err)
+
+	var ids []string
+
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		s.NoError(err)
+
+		for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+			for _, id := range []string{"1", "2", "3"} {
+				if strings.Contains(line, `"id":"`+id+`"`) {
+					ids = append(ids, id)
+				}
+			}
+		}
+	}
+
+	return ids
+}
+
+func (s *SpoolTestSuite) TestAvailable() {
+	s.bulkIndexer.flush = true
+
+	s.NoError(<-s.add("1"))
+
+	s.Equal([]string{"1"}, s.bulkIndexer.ids())
+	s.Empty(s.spooled())
+}
+
+func (s *SpoolTestSuite) TestFlushFailed() {
+	r1 := s.add("1")
+	r2 := s.add("2")
+
+	// Writes pending in the failed flush are spooled.
+	s.spool.flushFailed()
+	s.NoError(<-r1)
+	s.NoError(<-r2)
+
+	// Subsequent writes are spooled, without adding them to the bulk indexer.
+	s.NoError(<-s.add("3"))
+
+	s.Equal([]string{"1", "2"}, s.bulkIndexer.ids())
+	s.Equal([]string{"1", "2", "3"}, s.spooled())
+}
+
+func (s *SpoolTestSuite) TestReplay() {
+	s.add("1")
+	s.spool.flushFailed()
+	s.add("2")
+
+	s.bulkIndexer.items = nil
+	s.bulkIndexer.flush = true
+
+	s.NoError(s.spool.replay(s.ctx))
+
+	// Replayed in order, after which writes are added to the bulk indexer.
+	s.Equal([]string{"1", "2"}, s.bulkIndexer.ids())
+	s.NoError(<-s.add("3"))
+	s.Equal([]string{"1", "2", "3"}, s.bulkIndexer.ids())
+
+	s.Empty(s.spooled())
+	s.Zero(s.spool.size)
+}
+
+func (s *SpoolTestSuite) TestReplayFailed() {
+	s.spool.flushFailed()
+	s.add("1")
+
+	// Flushing fails again while replaying.
+	s.bulkIndexer.items = nil
+	s.spool.bulkIndexer = &failingBulkIndexer{s.spool}
+
+	s.NoError(s.spool.replay(s.ctx))
+
+	// The segment is kept for replaying, without spooling its writes again.
+	s.False(s.spool.available)
+	s.Equal([]string{"1"}, s.spooled())
+}
+
+func (s *SpoolTestSuite) TestReplaySetAside() {
+	s.cfg.MaxReplays = 2
+
+	s.spool.flushFailed()
+	s.add("1")
+
+	// Flushing always fails.
+	s.spool.bulkIndexer = &failingBulkIndexer{s.spool}
+
+	s.NoError(s.spool.replay(s.ctx))
+	s.Equal([]string{"1"}, s.spooled())
+
+	// The segment is set aside after failing to be replayed too often.
+	s.NoError(s.spool.replay(s.ctx))
+	s.Empty(s.spooled())
+	s.Zero(s.spool.size)
+
+	failed, err := filepath.Glob(filepath.Join(s.cfg.Dir, "*"+failedExt))
+	s.NoError(err)
+	s.Len(failed, 1)
+
+	s.NoError(s.spool.replay(s.ctx))
+	s.True(s.spool.available)
+}
+
+// versioned returns a versioned write of id.
+func versioned(id string) *bulkWrite {
+	return &bulkWrite{
+		Index: "test", Action: "update", ID: id, Body: []byte(`{"doc":{}}`),
+		Version: &types.Version{SeqNo: 1, PrimaryTerm: 1},
+	}
+}
+
+func (s *SpoolTestSuite) TestAddVersioned() {
+	s.NoError(s.spool.addVersioned(s.ctx, versioned("1")))
+
+	s.Equal([]string{"1"}, s.performed)
+	s.Empty(s.spooled())
+}
+
+func (s *SpoolTestSuite) TestAddVersionedConflict() {
+	s.performErr = index.ErrConflict
+
+	s.ErrorIs(s.spool.addVersioned(s.ctx, versioned("1")), index.ErrConflict)
+	s.True(s.spool.available)
+	s.Empty(s.spooled())
+}
+
+func (s *SpoolTestSuite) TestAddVersionedUnavailable() {
+	s.performErr = errUnavailable
+
+	// Spooled when the cluster turns out to be unavailable.
+	s.NoError(s.spool.addVersioned(s.ctx, versioned("1")))
+	s.False(s.spool.available)
+
+	// Spooled right away afterwards.
+	s.NoError(s.spool.addVersioned(s.ctx, versioned("2")))
+
+	s.Equal([]string{"1"}, s.performed)
+	s.Equal([]string{"1", "2"}, s.spooled())
+}
+
+func (s *SpoolTestSuite) TestReplayVersioned() {
+	s.spool.flushFailed()
+	s.add("1")
+	s.NoError(s.spool.addVersioned(s.ctx, versioned("2")))
+	s.add("3")
+
+	s.bulkIndexer.flush = true
+
+	// Versioned writes are performed in order with writes in bulk, and dropped when conflicting.
+	s.performErr = index.ErrConflict
+	s.NoError(s.spool.replay(s.ctx))
+
+	s.Equal([]string{"1", "3"}, s.bulkIndexer.ids())
+	s.Equal([]string{"2"}, s.performed)
+	s.True(s.spool.available)
+	s.Empty(s.spooled())
+}
+
+func (s *SpoolTestSuite) TestFull() {
+	s.cfg.MaxSize = 10
+
+	s.spool.flushFailed()
+
+	w := &bulkWrite{Index: "test", Action: "update", ID: "1", Body: []byte(`{"doc":{}}`)}
+	s.ErrorIs(s.spool.add(s.ctx, w, func(error) {}), ErrSpoolFull)
+}
+
+func (s *SpoolTestSuite) TestRestart() {
+	s.spool.flushFailed()
+	s.add("1")
+	s.NoError(s.spool.close())
+
+	// Spooled writes are replayed after restarting.
+	s.spool = s.newSpool()
+	s.False(s.spool.available)
+	s.NotZero(s.spool.size)
+
+	s.bulkIndexer.flush = true
+
+	s.NoError(s.spool.replay(s.ctx))
+	s.Equal([]string{"1"}, s.bulkIndexer.ids())
+}
+
+func (s *SpoolTestSuite) TestWorkUnavailable() {
+	s.spool.flushFailed()
+	s.add("1")
+
+	s.pingErr = errors.New("unavailable")
+	s.cfg.RetryInterval = time.Millisecond
+
+	ctx, cancel := context.WithTimeout(s.ctx, 50*time.Millisecond)
+	defer cancel()
+
+	// Not replayed while the cluster is unavailable.
+	s.ErrorIs(s.spool.work(ctx), context.DeadlineExceeded)
+	s.Empty(s.bulkIndexer.ids())
+	s.Equal([]string{"1"}, s.spooled())
+}
+
+func (s *SpoolTestSuite) TestWork() {
+	s.spool.flushFailed()
+	s.add("1")
+
+	s.bulkIndexer.flush = true
+	s.cfg.RetryInterval = time.Millisecond
+
+	ctx, cancel := context.WithTimeout(s.ctx, 50*time.Millisecond)
+	defer cancel()
+
+	s.ErrorIs(s.spool.work(ctx), context.DeadlineExceeded)
+	s.Equal([]string{"1"}, s.bulkIndexer.ids())
+	s.True(s.spool.available)
+}
+
+// failingBulkIndexer fails flushing every write added to it.
+type failingBulkIndexer struct {
+	spool *spool
+}
+
+func (b *failingBulkIndexer) Add(ctx context.Context, item opensearchutil.BulkIndexerItem) error {
+	go b.spool.flushFailed()
+	return nil
+}
+
+func (b *failingBulkIndexer) Close(ctx context.Context) error {
+	return nil
+}
+
+func (b *failingBulkIndexer) Stats() opensearchutil.BulkIndexerStats {
+	return opensearchutil.BulkIndexerStats{}
+}
+
+func TestSpoolTestSuite(t *testing.T) {
+	suite.Run(t, new(SpoolTestSuite))
+}
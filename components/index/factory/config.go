@@ -0,0 +1,35 @@
+package factory
+
+// Values for Backend.
+const (
+	Elasticsearch = "elasticsearch"
+	Bleve         = "bleve"
+)
+
+// Config represents the configuration of an index created by a Factory.
+type Config struct {
+	Name    string
+	Backend string     // Store the index in "elasticsearch" or in an embedded "bleve" index.
+	Cache   string     // Cache documents in "memory" or "none".
+	Tee     *TeeConfig // Write to secondary indexes as well; optional.
+}
+
+// Values for TeeConfig.Read.
+const (
+	ReadPrimary  = "primary"
+	ReadFallback = "fallback"
+)
+
+// TeeConfig configures writing to secondary indexes besides the index, e.g. while migrating to another cluster.
+type TeeConfig struct {
+	Read        string // Read from the "primary" index only, or "fallback" to secondary indexes.
+	Secondaries []SecondaryConfig
+}
+
+// SecondaryConfig represents the configuration of a secondary index.
+type SecondaryConfig struct {
+	Name    string
+	Backend string
+	URL     string // URL of the Elasticsearch cluster, when other than the configured one.
+	OnError string // Policy for failed writes: "fail", "log" or "spool", the latter only with "elasticsearch".
+}
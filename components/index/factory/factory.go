@@ -0,0 +1,276 @@
+// Package factory creates indexes on their configured backend, so that components need not be aware of backends.
+package factory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/ipfs-search/ipfs-search/components/index"
+	"github.com/ipfs-search/ipfs-search/components/index/bleve"
+	"github.com/ipfs-search/ipfs-search/components/index/cache"
+	"github.com/ipfs-search/ipfs-search/components/index/elasticsearch"
+	"github.com/ipfs-search/ipfs-search/components/index/tee"
+	"github.com/ipfs-search/ipfs-search/instr"
+)
+
+// ErrUnknownBackend is returned for indexes with an unknown backend.
+var ErrUnknownBackend = errors.New("unknown index backend")
+
+// ErrUnknownRead is returned for tees with an unknown read mode.
+var ErrUnknownRead = errors.New("unknown tee read mode")
+
+// ErrUnsupportedPolicy is returned for secondary indexes with a policy their backend does not support.
+var ErrUnsupportedPolicy = errors.New("tee policy not supported by backend")
+
+// ErrMixedBackends is returned when searching indexes on different backends.
+var ErrMixedBackends = errors.New("indexes are on different backends")
+
+// esKey identifies an Elasticsearch client by cluster and spool directory, if any.
+type esKey struct {
+	url   string
+	spool string
+}
+
+// Factory creates indexes, sharing a client per backend and, for Elasticsearch, per cluster and spool. Clients are
+// created when first used.
+type Factory struct {
+	esConfig    *elasticsearch.ClientConfig
+	bleveConfig *bleve.ClientConfig
+	cacheConfig *cache.Config
+
+	es    map[esKey]*elasticsearch.Client
+	bleve *bleve.Client
+
+	*instr.Instrumentation
+}
+
+// New returns a new factory.
+func New(esConfig *elasticsearch.ClientConfig, bleveConfig *bleve.ClientConfig, cacheConfig *cache.Config, i *instr.Instrumentation) *Factory {
+	if esConfig == nil || bleveConfig == nil || cacheConfig == nil {
+		panic("factory.New configuration cannot be nil.")
+	}
+
+	return &Factory{
+		esConfig:        esConfig,
+		bleveConfig:     bleveConfig,
+		cacheConfig:     cacheConfig,
+		es:              make(map[esKey]*elasticsearch.Client),
+		Instrumentation: i,
+	}
+}
+
+func (f *Factory) esClient() (*elasticsearch.Client, error) {
+	return f.esClientFor(f.esConfig.URL, false)
+}
+
+// spoolsToDisk returns whether cfg spools writes to disk.
+func spoolsToDisk(cfg *elasticsearch.ClientConfig) bool {
+	return cfg.Spool != nil && cfg.Spool.Mode == elasticsearch.SpoolDisk
+}
+
+// secondarySpool returns the configuration for spooling writes to secondary indexes on the cluster at clusterURL to
+// disk, in a directory of their own within the configured spool directory.
+func (f *Factory) secondarySpool(clusterURL string) (*elasticsearch.SpoolConfig, error) {
+	u, err := url.Parse(clusterURL)
+	if err != nil {
+		return nil, err
+	}
+
+	name := u.Host
+	if name == "" {
+		name = url.PathEscape(clusterURL)
+	}
+
+	cfg := *f.esConfig.Spool
+	cfg.Mode = elasticsearch.SpoolDisk
+	cfg.Dir = filepath.Join(cfg.Dir, "secondaries", name)
+
+	return &cfg, nil
+}
+
+// esClientFor returns the client for the cluster at url. Writes to the configured cluster are spooled as configured,
+// writes to other clusters are not, as the spool is specific to a cluster. With spool set, writes are spooled to disk
+// nonetheless when the factory spools writes at all, in a directory of their own unless spooled as configured.
+func (f *Factory) esClientFor(url string, spool bool) (*elasticsearch.Client, error) {
+	if url == "" {
+		url = f.esConfig.URL
+	}
+
+	cfg := *f.esConfig
+	if url != f.esConfig.URL {
+		cfg.URL = url
+		cfg.Spool = nil
+	}
+
+	if spool && f.esConfig.Spool != nil && !spoolsToDisk(&cfg) {
+		spoolCfg, err := f.secondarySpool(url)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.Spool = spoolCfg
+	}
+
+	key := esKey{url: url}
+	if spoolsToDisk(&cfg) {
+		key.spool = cfg.Spool.Dir
+	}
+
+	if client, ok := f.es[key]; ok {
+		return client, nil
+	}
+
+	client, err := elasticsearch.NewClient(&cfg, f.Instrumentation)
+	if err != nil {
+		return nil, err
+	}
+
+	f.es[key] = client
+
+	return client, nil
+}
+
+func (f *Factory) bleveClient() *bleve.Client {
+	if f.bleve == nil {
+		f.bleve = bleve.NewClient(f.bleveConfig, f.Instrumentation)
+	}
+
+	return f.bleve
+}
+
+// backing returns the index name on backend, on the cluster at url for Elasticsearch, spooling writes to disk when
+// spool is set, as with esClientFor.
+func (f *Factory) backing(name, backend, url string, spool bool) (index.Index, error) {
+	switch backend {
+	case Elasticsearch:
+		client, err := f.esClientFor(url, spool)
+		if err != nil {
+			return nil, err
+		}
+
+		return elasticsearch.New(client, &elasticsearch.Config{Name: name}), nil
+	case Bleve:
+		return bleve.New(f.bleveClient(), &bleve.Config{Name: name})
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownBackend, backend)
+	}
+}
+
+// tee returns primary, writing to the secondary indexes of cfg as well.
+func (f *Factory) tee(primary index.Index, cfg *TeeConfig) (index.Index, error) {
+	teeConfig := tee.DefaultConfig()
+
+	switch cfg.Read {
+	case ReadPrimary:
+	case ReadFallback:
+		teeConfig.Fallback = true
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownRead, cfg.Read)
+	}
+
+	targets := make([]tee.Target, len(cfg.Secondaries))
+
+	for n, secondary := range cfg.Secondaries {
+		spool := secondary.OnError == tee.Spool
+		if spool && secondary.Backend != Elasticsearch {
+			return nil, fmt.Errorf("secondary %s: %w: %s on %s", secondary.Name, ErrUnsupportedPolicy, secondary.OnError, secondary.Backend)
+		}
+
+		idx, err := f.backing(secondary.Name, secondary.Backend, secondary.URL, spool)
+		if err != nil {
+			return nil, fmt.Errorf("secondary %s: %w", secondary.Name, err)
+		}
+
+		targets[n] = tee.Target{Index: idx, Policy: secondary.OnError}
+	}
+
+	return tee.New(primary, targets, teeConfig, f.Instrumentation)
+}
+
+// Index returns the index for cfg, writing to secondary indexes and cached as configured.
+func (f *Factory) Index(cfg *Config) (index.Index, error) {
+	idx, err := f.backing(cfg.Name, cfg.Backend, "", false)
+	if err != nil {
+		return nil, fmt.Errorf("index %s: %w", cfg.Name, err)
+	}
+
+	if cfg.Tee != nil {
+		if idx, err = f.tee(idx, cfg.Tee); err != nil {
+			return nil, fmt.Errorf("index %s: %w", cfg.Name, err)
+		}
+	}
+
+	idx, err = cache.Wrap(idx, cfg.Cache, f.cacheConfig, f.Instrumentation)
+	if err != nil {
+		return nil, fmt.Errorf("index %s: %w", cfg.Name, err)
+	}
+
+	return idx, nil
+}
+
+// Searcher returns a searcher for files and directories, which should be on the same backend.
+func (f *Factory) Searcher(files, directories *Config) (index.Searcher, error) {
+	if files.Backend != directories.Backend {
+		return nil, fmt.Errorf("%w: %s and %s", ErrMixedBackends, files.Backend, directories.Backend)
+	}
+
+	switch files.Backend {
+	case Elasticsearch:
+		client, err := f.esClient()
+		if err != nil {
+			return nil, err
+		}
+
+		return elasticsearch.NewSearcher(client, &elasticsearch.SearcherConfig{
+			Files:       files.Name,
+			Directories: directories.Name,
+		}), nil
+	case Bleve:
+		filesIndex, err := bleve.New(f.bleveClient(), &bleve.Config{Name: files.Name})
+		if err != nil {
+			return nil, err
+		}
+
+		directoriesIndex, err := bleve.New(f.bleveClient(), &bleve.Config{Name: directories.Name})
+		if err != nil {
+			return nil, err
+		}
+
+		return bleve.NewSearcher(filesIndex, directoriesIndex), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownBackend, files.Backend)
+	}
+}
+
+// Work performs background work for the created indexes until ctx is closed, like getting and flushing batches of
+// documents with Elasticsearch. It should be started after creating indexes.
+func (f *Factory) Work(ctx context.Context) error {
+	if len(f.es) == 0 {
+		<-ctx.Done()
+
+		return ctx.Err()
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	for _, client := range f.es {
+		client := client // https://go.dev/doc/faq#closures_and_goroutines
+		g.Go(func() error { return client.Work(ctx) })
+	}
+
+	return g.Wait()
+}
+
+// Close closes embedded indexes, after which they cannot be used.
+func (f *Factory) Close() error {
+	if f.bleve != nil {
+		return f.bleve.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package factory
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/ipfs-search/ipfs-search/components/index/bleve"
+	"github.com/ipfs-search/ipfs-search/components/index/cache"
+	"github.com/ipfs-search/ipfs-search/components/index/elasticsearch"
+	"github.com/ipfs-search/ipfs-search/components/index/tee"
+	"github.com/ipfs-search/ipfs-search/components/index/types"
+	"github.com/ipfs-search/ipfs-search/instr"
+)
+
+type FactoryTestSuite struct {
+	suite.Suite
+
+	f *Factory
+}
+
+func (s *FactoryTestSuite) SetupTest() {
+	s.f = New(&elasticsearch.ClientConfig{}, &bleve.ClientConfig{}, cache.DefaultConfig(), instr.New())
+}
+
+func (s *FactoryTestSuite) TearDownTest() {
+	s.NoError(s.f.Close())
+}
+
+func (s *FactoryTestSuite) TestIndexShared() {
+	cfg := &Config{Name: "files", Backend: Bleve, Cache: cache.None}
+
+	a, err := s.f.Index(cfg)
+	s.Require().NoError(err)
+
+	b, err := s.f.Index(cfg)
+	s.Require().NoError(err)
+
+	ctx := context.Background()
+	s.NoError(a.Index(ctx, "id", &types.Document{Size: 5}))
+
+	dst := new(types.Document)
+	found, err := b.Get(ctx, "id", dst)
+	s.NoError(err)
+	s.True(found)
+	s.Equal(uint64(5), dst.Size)
+}
+
+func (s *FactoryTestSuite) TestIndexUnknownBackend() {
+	_, err := s.f.Index(&Config{Name: "files", Backend: "unknown", Cache: cache.None})
+	s.ErrorIs(err, ErrUnknownBackend)
+}
+
+func (s *FactoryTestSuite) TestIndexUnknownCache() {
+	_, err := s.f.Index(&Config{Name: "files", Backend: Bleve, Cache: "unknown"})
+	s.ErrorIs(err, cache.ErrUnknownMode)
+}
+
+func (s *FactoryTestSuite) TestIndexTee() {
+	cfg := &Config{Name: "files", Backend: Bleve, Cache: cache.None, Tee: &TeeConfig{
+		Read:        ReadFallback,
+		Secondaries: []SecondaryConfig{{Name: "files_v2", Backend: Bleve, OnError: tee.Fail}},
+	}}
+
+	idx, err := s.f.Index(cfg)
+	s.Require().NoError(err)
+
+	ctx := context.Background()
+	s.NoError(idx.Index(ctx, "id", &types.Document{Size: 5}))
+
+	// Written to the secondary index as well.
+	secondary, err := s.f.Index(&Config{Name: "files_v2", Backend: Bleve, Cache: cache.None})
+	s.Require().NoError(err)
+
+	dst := new(types.Document)
+	found, err := secondary.Get(ctx, "id", dst)
+	s.NoError(err)
+	s.True(found)
+	s.Equal(uint64(5), dst.Size)
+}
+
+func (s *FactoryTestSuite) TestIndexTeeUnknownRead() {
+	_, err := s.f.Index(&Config{Name: "files", Backend: Bleve, Cache: cache.None, Tee: &TeeConfig{Read: "unknown"}})
+	s.ErrorIs(err, ErrUnknownRead)
+}
+
+func (s *FactoryTestSuite) TestIndexTeeUnknownPolicy() {
+	_, err := s.f.Index(&Config{Name: "files", Backend: Bleve, Cache: cache.None, Tee: &TeeConfig{
+		Read:        ReadPrimary,
+		Secondaries: []SecondaryConfig{{Name: "files_v2", Backend: Bleve, OnError: "unknown"}},
+	}})
+	s.ErrorIs(err, tee.ErrUnknownPolicy)
+}
+
+func (s *FactoryTestSuite) TestIndexTeeSpool() {
+	spool := elasticsearch.DefaultSpoolConfig()
+	spool.Dir = s.T().TempDir()
+
+	s.f = New(&elasticsearch.ClientConfig{URL: "http://localhost:9200", Spool: spool},
+		&bleve.ClientConfig{}, cache.DefaultConfig(), instr.New())
+
+	_, err := s.f.Index(&Config{Name: "files", Backend: Elasticsearch, Cache: cache.None, Tee: &TeeConfig{
+		Read: ReadPrimary,
+		Secondaries: []SecondaryConfig{
+			{Name: "files_v2", Backend: Elasticsearch, URL: "http://other:9200", OnError: tee.Spool},
+			{Name: "files_v3", Backend: Elasticsearch, URL: "http://other:9200", OnError: tee.Log},
+		},
+	}})
+	s.Require().NoError(err)
+
+	// Secondary indexes are spooled to a directory of their own, even when not spooling the primary index.
+	s.DirExists(filepath.Join(spool.Dir, "secondaries", "other:9200"))
+	s.Len(s.f.es, 3)
+}
+
+func (s *FactoryTestSuite) TestIndexTeeSpoolUnsupported() {
+	_, err := s.f.Index(&Config{Name: "files", Backend: Bleve, Cache: cache.None, Tee: &TeeConfig{
+		Read:        ReadPrimary,
+		Secondaries: []SecondaryConfig{{Name: "files_v2", Backend: Bleve, OnError: tee.Spool}},
+	}})
+	s.ErrorIs(err, ErrUnsupportedPolicy)
+}
+
+func (s *FactoryTestSuite) TestSearcher() {
+	searcher, err := s.f.Searcher(&Config{Name: "files", Backend: Bleve}, &Config{Name: "directories", Backend: Bleve})
+	s.NoError(err)
+	s.IsType(&bleve.Searcher{}, searcher)
+}
+
+func (s *FactoryTestSuite) TestSearcherMixed() {
+	_, err := s.f.Searcher(&Config{Name: "files", Backend: Bleve}, &Config{Name: "directories", Backend: Elasticsearch})
+	s.ErrorIs(err, ErrMixedBackends)
+}
+
+func TestFactoryTestSuite(t *testing.T) {
+	suite.Run(t, new(FactoryTestSuite))
+}
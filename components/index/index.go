@@ -3,12 +3,32 @@ package index
 
 import (
 	"context"
+	"errors"
+
+	"github.com/ipfs-search/ipfs-search/components/index/types"
 )
 
+// ErrConflict is returned when writing a document conflicts with a concurrent write, i.e. when creating a document
+// which already exists or when updating a document which changed since it was read.
+var ErrConflict = errors.New("version conflict")
+
+// ErrNotFound is returned when updating a document which does not exist, by indexes which do not ignore such updates.
+var ErrNotFound = errors.New("document not found")
+
+// Versioned is implemented by documents tracking the version they were read at, e.g. by embedding types.Version.
+// Get sets the version of Versioned documents and Update only succeeds when the version is unchanged, returning
+// ErrConflict otherwise.
+type Versioned interface {
+	DocumentVersion() *types.Version
+}
+
 // Index represents an index which stores and retrieves document properties.
+// Index returns ErrConflict when a document with the same id already exists; indexes writing asynchronously report it
+// as the result of the write, tracked with Writes, instead.
 type Index interface {
 	Index(ctx context.Context, id string, properties interface{}) error
 	Update(ctx context.Context, id string, properties interface{}) error
+	Apply(ctx context.Context, id string, ops ...Operation) error
 	Get(ctx context.Context, id string, dst interface{}, fields ...string) (bool, error)
 	Delete(ctx context.Context, id string) error
 }
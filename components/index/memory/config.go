@@ -0,0 +1,19 @@
+package memory
+
+import (
+	"time"
+)
+
+// Config holds configuration for an in-memory Index.
+type Config struct {
+	Capacity int           // Maximum number of documents; the least recently used are evicted first.
+	TTL      time.Duration // Documents expire after this time.
+}
+
+// DefaultConfig returns the default configuration for an in-memory Index.
+func DefaultConfig() *Config {
+	return &Config{
+		Capacity: 100000,
+		TTL:      time.Hour,
+	}
+}
@@ -0,0 +1,219 @@
+// Package memory provides an in-memory Index, bounded in size and with expiring documents, e.g. for caching.
+package memory
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ipfs-search/ipfs-search/components/index"
+)
+
+// document holds the fields of a document as JSON.
+type document map[string]json.RawMessage
+
+type entry struct {
+	id      string
+	doc     document
+	expires time.Time
+}
+
+// Index is an LRU of documents with a TTL, safe for concurrent use.
+// Versions are not tracked; Get leaves the version of index.Versioned documents unset.
+type Index struct {
+	cfg *Config
+
+	mu       sync.Mutex
+	elements map[string]*list.Element
+	order    *list.List // Most recently used at the front.
+}
+
+// New returns a new in-memory Index.
+func New(cfg *Config) *Index {
+	if cfg == nil {
+		panic("memory.New Config cannot be nil.")
+	}
+
+	return &Index{
+		cfg:      cfg,
+		elements: make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// String returns the name of the index, for convenient logging.
+func (i *Index) String() string {
+	return "memory"
+}
+
+// Len returns the number of documents in the index, including expired ones which have not been evicted yet.
+func (i *Index) Len() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.order.Len()
+}
+
+// toDocument returns the fields of properties.
+func toDocument(properties interface{}) (document, error) {
+	b, err := json.Marshal(properties)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := document{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("properties are not an object: %w", err)
+	}
+
+	return doc, nil
+}
+
+// get returns the unexpired entry for id, marking it as most recently used. Must be called with the lock held.
+func (i *Index) get(id string) *entry {
+	el, ok := i.elements[id]
+	if !ok {
+		return nil
+	}
+
+	e := el.Value.(*entry)
+	if time.Now().After(e.expires) {
+		i.remove(el)
+		return nil
+	}
+
+	i.order.MoveToFront(el)
+
+	return e
+}
+
+// remove removes element el. Must be called with the lock held.
+func (i *Index) remove(el *list.Element) {
+	i.order.Remove(el)
+	delete(i.elements, el.Value.(*entry).id)
+}
+
+// Index a document's properties, identified by id, returning index.ErrConflict when it already exists.
+func (i *Index) Index(ctx context.Context, id string, properties interface{}) error {
+	doc, err := toDocument(properties)
+	if err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.get(id) != nil {
+		return index.ErrConflict
+	}
+
+	if el, ok := i.elements[id]; ok {
+		// Expired, but not removed by get.
+		i.remove(el)
+	}
+
+	i.elements[id] = i.order.PushFront(&entry{id, doc, time.Now().Add(i.cfg.TTL)})
+
+	if i.order.Len() > i.cfg.Capacity {
+		i.remove(i.order.Back())
+	}
+
+	return nil
+}
+
+// Update a document's properties, given id. Like with a backing index, updating a missing document has no effect.
+// The TTL is not extended, so that updates do not keep documents from being refreshed.
+func (i *Index) Update(ctx context.Context, id string, properties interface{}) error {
+	doc, err := toDocument(properties)
+	if err != nil {
+		return err
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	e := i.get(id)
+	if e == nil {
+		return nil
+	}
+
+	for field, value := range doc {
+		e.doc[field] = value
+	}
+
+	return nil
+}
+
+// Apply atomic operations to a document, given id. Like Update, this has no effect on missing documents.
+func (i *Index) Apply(ctx context.Context, id string, ops ...index.Operation) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	e := i.get(id)
+	if e == nil {
+		return nil
+	}
+
+	for _, op := range ops {
+		if err := op.ApplyTo(e.doc); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get retreives `fields` from document with `id` from the index, returning:
+// - (true, decoding_error) if found (decoding error set when errors in json)
+// - (false, nil) when not found
+func (i *Index) Get(ctx context.Context, id string, dst interface{}, fields ...string) (bool, error) {
+	i.mu.Lock()
+
+	e := i.get(id)
+	if e == nil {
+		i.mu.Unlock()
+		return false, nil
+	}
+
+	if len(fields) == 0 {
+		fields = make([]string, 0, len(e.doc))
+		for field := range e.doc {
+			fields = append(fields, field)
+		}
+	}
+
+	projection := make(document, len(fields))
+	for _, field := range fields {
+		if v, ok := e.doc[field]; ok {
+			projection[field] = v
+		}
+	}
+
+	i.mu.Unlock()
+
+	// RawMessage values are replaced, never modified, so the projection can be used without lock.
+	b, err := json.Marshal(projection)
+	if err != nil {
+		return true, err
+	}
+
+	return true, json.Unmarshal(b, dst)
+}
+
+// Delete item from index
+func (i *Index) Delete(ctx context.Context, id string) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if el, ok := i.elements[id]; ok {
+		i.remove(el)
+	}
+
+	return nil
+}
+
+// Compile-time assurance that implementation satisfies interface.
+var _ index.Index = &Index{}
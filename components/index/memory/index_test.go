@@ -0,0 +1,165 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/ipfs-search/ipfs-search/components/index"
+	"github.com/ipfs-search/ipfs-search/components/index/types"
+)
+
+type MemoryTestSuite struct {
+	suite.Suite
+
+	ctx context.Context
+	idx *Index
+}
+
+func (s *MemoryTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.idx = New(&Config{
+		Capacity: 2,
+		TTL:      time.Hour,
+	})
+}
+
+func (s *MemoryTestSuite) TestIndexGet() {
+	now := time.Now().UTC()
+
+	doc := &types.Document{
+		LastSeen:   now,
+		References: types.References{{ParentHash: "QmParent", Name: "name"}},
+		Size:       5,
+	}
+
+	s.NoError(s.idx.Index(s.ctx, "a", doc))
+
+	dst := new(types.Update)
+	found, err := s.idx.Get(s.ctx, "a", dst, "references", "last-seen")
+	s.NoError(err)
+	s.True(found)
+	s.Equal(doc.References, dst.References)
+	s.True(now.Equal(*dst.LastSeen))
+
+	// Projection
+	var projected map[string]interface{}
+	found, err = s.idx.Get(s.ctx, "a", &projected, "size")
+	s.NoError(err)
+	s.True(found)
+	s.Equal(map[string]interface{}{"size": float64(5)}, projected)
+}
+
+func (s *MemoryTestSuite) TestGetNotFound() {
+	found, err := s.idx.Get(s.ctx, "a", new(types.Update))
+	s.NoError(err)
+	s.False(found)
+}
+
+func (s *MemoryTestSuite) TestIndexConflict() {
+	s.NoError(s.idx.Index(s.ctx, "a", &types.Document{}))
+	s.ErrorIs(s.idx.Index(s.ctx, "a", &types.Document{}), index.ErrConflict)
+}
+
+func (s *MemoryTestSuite) TestEviction() {
+	s.NoError(s.idx.Index(s.ctx, "a", &types.Document{}))
+	s.NoError(s.idx.Index(s.ctx, "b", &types.Document{}))
+
+	// Use a, making b least recently used.
+	found, _ := s.idx.Get(s.ctx, "a", new(types.Update))
+	s.True(found)
+
+	s.NoError(s.idx.Index(s.ctx, "c", &types.Document{}))
+	s.Equal(2, s.idx.Len())
+
+	found, _ = s.idx.Get(s.ctx, "b", new(types.Update))
+	s.False(found)
+
+	found, _ = s.idx.Get(s.ctx, "a", new(types.Update))
+	s.True(found)
+}
+
+func (s *MemoryTestSuite) TestExpiry() {
+	s.idx.cfg.TTL = time.Millisecond
+
+	s.NoError(s.idx.Index(s.ctx, "a", &types.Document{}))
+	time.Sleep(2 * time.Millisecond)
+
+	found, err := s.idx.Get(s.ctx, "a", new(types.Update))
+	s.NoError(err)
+	s.False(found)
+
+	// Expired documents can be indexed again.
+	s.NoError(s.idx.Index(s.ctx, "a", &types.Document{}))
+}
+
+func (s *MemoryTestSuite) TestUpdate() {
+	s.NoError(s.idx.Index(s.ctx, "a", &types.Document{Size: 5}))
+
+	now := time.Now().UTC()
+	s.NoError(s.idx.Update(s.ctx, "a", &types.Update{LastSeen: &now}))
+
+	dst := new(types.Document)
+	found, err := s.idx.Get(s.ctx, "a", dst)
+	s.NoError(err)
+	s.True(found)
+	s.Equal(uint64(5), dst.Size)
+	s.True(now.Equal(dst.LastSeen))
+
+	// Missing documents are not created.
+	s.NoError(s.idx.Update(s.ctx, "b", &types.Update{LastSeen: &now}))
+	found, _ = s.idx.Get(s.ctx, "b", dst)
+	s.False(found)
+}
+
+func (s *MemoryTestSuite) TestDelete() {
+	s.NoError(s.idx.Index(s.ctx, "a", &types.Document{}))
+	s.NoError(s.idx.Delete(s.ctx, "a"))
+
+	found, _ := s.idx.Get(s.ctx, "a", new(types.Document))
+	s.False(found)
+	s.Equal(0, s.idx.Len())
+}
+
+func (s *MemoryTestSuite) TestApply() {
+	// Milliseconds make the second time longer, yet earlier.
+	earlier := time.Date(2021, 1, 1, 0, 0, 0, 5e6, time.UTC)
+	later := time.Date(2021, 1, 1, 0, 0, 1, 0, time.UTC)
+	ref := types.Reference{ParentHash: "QmParent", Name: "name"}
+
+	s.NoError(s.idx.Index(s.ctx, "a", &types.Document{LastSeen: later, Size: 5}))
+
+	s.NoError(s.idx.Apply(s.ctx, "a",
+		index.AppendIfAbsent("references", ref),
+		index.AppendIfAbsent("references", ref),
+		index.Max("last-seen", earlier),
+		index.Max("size", 3),
+		index.Increment("count", 2),
+	))
+
+	var dst struct {
+		types.Document
+		Count int `json:"count"`
+	}
+
+	found, err := s.idx.Get(s.ctx, "a", &dst)
+	s.NoError(err)
+	s.True(found)
+	s.Equal(types.References{ref}, dst.References)
+	s.True(later.Equal(dst.LastSeen))
+	s.Equal(uint64(5), dst.Size)
+	s.Equal(2, dst.Count)
+
+	s.NoError(s.idx.Apply(s.ctx, "a", index.Max("last-seen", later.Add(time.Millisecond))))
+
+	found, err = s.idx.Get(s.ctx, "a", &dst)
+	s.NoError(err)
+	s.True(found)
+	s.True(later.Add(time.Millisecond).Equal(dst.LastSeen))
+}
+
+func TestMemoryTestSuite(t *testing.T) {
+	suite.Run(t, new(MemoryTestSuite))
+}
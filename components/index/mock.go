@@ -22,6 +22,12 @@ func (m *Mock) Update(ctx context.Context, id string, properties interface{}) er
 	return args.Error(0)
 }
 
+// Apply mocks the Apply method on the Index interface.
+func (m *Mock) Apply(ctx context.Context, id string, ops ...Operation) error {
+	args := m.Called(ctx, id, ops)
+	return args.Error(0)
+}
+
 // Get mocks the Get method on the Index interface.
 func (m *Mock) Get(ctx context.Context, id string, dst interface{}, fields ...string) (bool, error) {
 	args := m.Called(ctx, id, dst, fields)
@@ -36,3 +42,17 @@ func (m *Mock) Delete(ctx context.Context, id string) error {
 
 // Compile-time assurance that implementation satisfies interface.
 var _ Index = &Mock{}
+
+// MockSearcher mocks the Searcher interface.
+type MockSearcher struct {
+	mock.Mock
+}
+
+// Search mocks the Search method on the Searcher interface.
+func (m *MockSearcher) Search(ctx context.Context, q *Query) (*SearchResult, error) {
+	args := m.Called(ctx, q)
+	return args.Get(0).(*SearchResult), args.Error(1)
+}
+
+// Compile-time assurance that implementation satisfies interface.
+var _ Searcher = &MockSearcher{}
@@ -7,9 +7,30 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
+// MultiGetter is implemented by indexes which can get a document from several indexes at once, e.g. with a single
+// request to their backend. Unlike ConcurrentMultiGet, it returns the first of indexes, in order, which has the
+// document. MultiGet uses it when implemented by the first of indexes.
+type MultiGetter interface {
+	MultiGet(ctx context.Context, indexes []Index, id string, dst interface{}, fields ...string) (Index, error)
+}
+
 // MultiGet returns `fields` for the first document with `id` from given `indexes`.
 // When the document is not found (nil, nil) is returned.
 func MultiGet(ctx context.Context, indexes []Index, id string, dst interface{}, fields ...string) (Index, error) {
+	if len(indexes) == 0 {
+		return nil, nil
+	}
+
+	if m, ok := indexes[0].(MultiGetter); ok {
+		return m.MultiGet(ctx, indexes, id, dst, fields...)
+	}
+
+	return ConcurrentMultiGet(ctx, indexes, id, dst, fields...)
+}
+
+// ConcurrentMultiGet is MultiGet getting from each of indexes concurrently, for indexes which are not a MultiGetter.
+// It returns whichever of indexes having the document responds first.
+func ConcurrentMultiGet(ctx context.Context, indexes []Index, id string, dst interface{}, fields ...string) (Index, error) {
 	foundIdx := make(chan Index, 1)
 
 	ctx, cancel := context.WithCancel(ctx)
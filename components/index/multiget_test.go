@@ -100,6 +100,30 @@ func (s *MultiGetTestSuite) TestMultiFound() {
 	s.True(index == s.mock1 || index == s.mock2)
 }
 
+type multiGetterMock struct {
+	*Mock
+}
+
+func (m *multiGetterMock) MultiGet(ctx context.Context, indexes []Index, id string, dst interface{}, fields ...string) (Index, error) {
+	args := m.Called(ctx, indexes, id, dst, fields)
+	return args.Get(0).(Index), args.Error(1)
+}
+
+// TestMultiGetter tests dispatching to the first index, when it is a MultiGetter.
+func (s *MultiGetTestSuite) TestMultiGetter() {
+	dst := new(struct{})
+
+	m := &multiGetterMock{s.mock1}
+	indexes := []Index{m, s.mock2}
+
+	m.On("MultiGet", mock.Anything, indexes, "objId", dst, []string{"testField"}).Return(s.mock2, nil).Once()
+
+	index, err := MultiGet(s.ctx, indexes, "objId", dst, "testField")
+
+	s.Equal(s.mock2, index)
+	s.NoError(err)
+}
+
 func TestMultiGetTestSuite(t *testing.T) {
 	suite.Run(t, new(MultiGetTestSuite))
 }
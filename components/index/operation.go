@@ -0,0 +1,136 @@
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// OperationType represents the type of an atomic Operation.
+type OperationType string
+
+// Values for OperationType.
+const (
+	AppendIfAbsentOperation OperationType = "append-if-absent"
+	MaxOperation            OperationType = "max"
+	IncrementOperation      OperationType = "increment"
+)
+
+// Operation represents an atomic modification of a single field of a document, applied by the index itself rather
+// than through read-modify-write, so that concurrent operations on the same document do not overwrite each other.
+type Operation struct {
+	Type  OperationType
+	Field string
+	Value interface{}
+}
+
+// AppendIfAbsent appends value to the list in field, unless an equal value is already in it.
+func AppendIfAbsent(field string, value interface{}) Operation {
+	return Operation{AppendIfAbsentOperation, field, value}
+}
+
+// Max sets field to value, unless the field is already greater or equal. Values should be numbers, times or strings
+// with lexicographic ordering. Times are stored in UTC, like other times in documents.
+func Max(field string, value interface{}) Operation {
+	if t, ok := value.(time.Time); ok {
+		value = t.UTC()
+	}
+
+	return Operation{MaxOperation, field, value}
+}
+
+// Increment adds delta to field, treating a missing field as 0.
+func Increment(field string, delta int64) Operation {
+	return Operation{IncrementOperation, field, delta}
+}
+
+// decode returns v as it would be decoded from JSON, for comparison with decoded fields.
+func decode(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var d interface{}
+	err = json.Unmarshal(b, &d)
+
+	return d, err
+}
+
+// less returns whether a is less than b, comparing strings as times when both are valid times.
+func less(a, b interface{}) (bool, error) {
+	switch a := a.(type) {
+	case float64:
+		if b, ok := b.(float64); ok {
+			return a < b, nil
+		}
+	case string:
+		if b, ok := b.(string); ok {
+			ta, errA := time.Parse(time.RFC3339Nano, a)
+			tb, errB := time.Parse(time.RFC3339Nano, b)
+
+			if errA == nil && errB == nil {
+				return ta.Before(tb), nil
+			}
+
+			return a < b, nil
+		}
+	}
+
+	return false, fmt.Errorf("cannot compare %v to %v", a, b)
+}
+
+// ApplyTo applies the operation to a document, represented by its fields as JSON.
+func (op Operation) ApplyTo(doc map[string]json.RawMessage) error {
+	var current interface{}
+
+	if raw, ok := doc[op.Field]; ok {
+		if err := json.Unmarshal(raw, &current); err != nil {
+			return err
+		}
+	}
+
+	value, err := decode(op.Value)
+	if err != nil {
+		return err
+	}
+
+	switch op.Type {
+	case AppendIfAbsentOperation:
+		list, _ := current.([]interface{})
+
+		for _, v := range list {
+			if reflect.DeepEqual(v, value) {
+				return nil
+			}
+		}
+
+		value = append(list, value)
+	case MaxOperation:
+		if current != nil {
+			isLess, err := less(current, value)
+			if err != nil {
+				return err
+			}
+
+			if !isLess {
+				return nil
+			}
+		}
+	case IncrementOperation:
+		c, _ := current.(float64)
+		value = c + value.(float64)
+	default:
+		return fmt.Errorf("unknown operation type '%s'", op.Type)
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	doc[op.Field] = raw
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package index
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type OperationTestSuite struct {
+	suite.Suite
+}
+
+func (s *OperationTestSuite) TestMaxUTC() {
+	local := time.Date(2021, 1, 1, 12, 0, 0, 0, time.FixedZone("CET", 3600))
+
+	op := Max("last-seen", local)
+
+	s.Equal(time.UTC, op.Value.(time.Time).Location())
+	s.True(local.Equal(op.Value.(time.Time)))
+}
+
+func (s *OperationTestSuite) TestMaxTime() {
+	doc := map[string]json.RawMessage{
+		"last-seen": json.RawMessage(`"2021-01-01T11:30:00Z"`),
+	}
+
+	// Later, but lexicographically smaller in local time.
+	later := time.Date(2021, 1, 1, 10, 0, 0, 0, time.FixedZone("EST", -5*3600))
+
+	s.NoError(Max("last-seen", later).ApplyTo(doc))
+	s.JSONEq(`"2021-01-01T15:00:00Z"`, string(doc["last-seen"]))
+}
+
+func TestOperationTestSuite(t *testing.T) {
+	suite.Run(t, new(OperationTestSuite))
+}
@@ -0,0 +1,21 @@
+package index
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// ScanFilter limits the documents returned by a Scanner. Zero values are ignored.
+type ScanFilter struct {
+	LastSeenAfter  time.Time // Only documents seen at or after this time.
+	LastSeenBefore time.Time // Only documents seen before this time.
+	MIMEType       string    // Prefix of the MIME type of files, e.g. `image/` or `text/html`.
+}
+
+// Scanner is implemented by indexes which can stream all of their documents, e.g. for exporting them.
+type Scanner interface {
+	// Scan calls fn with the id and source of each document matching filter, in no particular order, returning the
+	// first error returned by fn.
+	Scan(ctx context.Context, filter *ScanFilter, fn func(id string, source json.RawMessage) error) error
+}
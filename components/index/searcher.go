@@ -0,0 +1,60 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ipfs-search/ipfs-search/components/index/types"
+)
+
+// ErrInvalidQuery is returned by a Searcher for queries it can't execute, e.g. due to query string syntax errors.
+var ErrInvalidQuery = errors.New("invalid query")
+
+// DocumentType represents the type of documents to search for.
+type DocumentType string
+
+// Values for DocumentType.
+const (
+	AnyType       DocumentType = "any"
+	FileType      DocumentType = "file"
+	DirectoryType DocumentType = "directory"
+)
+
+// Query represents a search query.
+type Query struct {
+	Text          string       // Query string, in Lucene query string syntax.
+	Type          DocumentType // Type of documents to search, AnyType when empty.
+	MIMEType      string       // Prefix of the MIME type of files, e.g. `image/` or `text/html`.
+	MinSize       uint64       // Minimum size in bytes, ignored when 0.
+	MaxSize       uint64       // Maximum size in bytes, ignored when 0.
+	LastSeenAfter time.Time    // Only documents seen after this time, ignored when zero.
+	From          int          // Offset of the first hit.
+	Size          int          // Number of hits to return.
+}
+
+// Hit represents a document matching a Query.
+type Hit struct {
+	ID         string              `json:"hash"`
+	Type       DocumentType        `json:"type"`
+	Score      float64             `json:"score"`
+	Title      string              `json:"title,omitempty"`
+	MIMEType   string              `json:"mimetype,omitempty"`
+	Size       uint64              `json:"size"`
+	FirstSeen  time.Time           `json:"first-seen"`
+	LastSeen   time.Time           `json:"last-seen"`
+	References types.References    `json:"references"`
+	Highlights map[string][]string `json:"highlights,omitempty"` // Snippets of matching text, by field.
+}
+
+// SearchResult represents a page of hits for a Query.
+type SearchResult struct {
+	Total    int64   `json:"total"`
+	MaxScore float64 `json:"max_score"`
+	Hits     []Hit   `json:"hits"`
+}
+
+// Searcher represents an index which can be queried for documents.
+type Searcher interface {
+	Search(ctx context.Context, q *Query) (*SearchResult, error)
+}
@@ -0,0 +1,26 @@
+package tee
+
+import (
+	"time"
+)
+
+// Policies for handling failed writes to a secondary index.
+const (
+	Fail  = "fail"  // Return the error to the caller, waiting for writes to the secondary index to be made.
+	Log   = "log"   // Log the error and drop the write.
+	Spool = "spool" // Log the error; the secondary index spools writes which fail to be flushed, e.g. to disk.
+)
+
+// Config for a tee index.
+type Config struct {
+	Fallback     bool          // Get documents not found in the primary index from secondary indexes, in order.
+	FlushTimeout time.Duration // Maximum time to wait for asynchronous writes to secondary indexes to be flushed.
+}
+
+// DefaultConfig returns the default configuration for a tee index.
+func DefaultConfig() *Config {
+	return &Config{
+		Fallback:     false,
+		FlushTimeout: 5 * time.Minute,
+	}
+}
@@ -0,0 +1,294 @@
+// Package tee provides an index writing to a primary and to secondary indexes, e.g. for migrating to another cluster
+// or for testing new mappings with real traffic.
+package tee
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/api/metric"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/ipfs-search/ipfs-search/components/index"
+	"github.com/ipfs-search/ipfs-search/components/index/types"
+	"github.com/ipfs-search/ipfs-search/instr"
+)
+
+// ErrUnknownPolicy is returned by New for targets with an unknown policy.
+var ErrUnknownPolicy = errors.New("unknown tee policy")
+
+// Target is a secondary index, with the policy for writes to it which fail.
+type Target struct {
+	Index  index.Index
+	Policy string // Fail, Log or Spool; with Spool, Index should spool writes which fail to be flushed.
+}
+
+// Index writes to a primary index and to secondary indexes, reading from the primary index.
+//
+// Writes are made to the primary index first; when that fails, the error is returned without writing to secondary
+// indexes. Secondary indexes with the Fail policy are written to with the context of the caller, so their
+// asynchronous writes are tracked by its index.Writes. Failures of other secondary indexes, including those of
+// asynchronous writes, are logged in the background. Secondary indexes with the Spool policy spool writes which fail
+// to be flushed themselves, e.g. Elasticsearch indexes with a disk spool, which replay them in order.
+type Index struct {
+	cfg     *Config
+	primary index.Index
+	targets []*target
+
+	failed metric.Int64Counter
+
+	*instr.Instrumentation
+}
+
+// New returns a new tee index writing to primary and targets.
+func New(primary index.Index, targets []Target, cfg *Config, i *instr.Instrumentation) (index.Index, error) {
+	if cfg == nil {
+		panic("tee.New Config cannot be nil.")
+	}
+
+	t := &Index{
+		cfg:             cfg,
+		primary:         primary,
+		targets:         make([]*target, len(targets)),
+		Instrumentation: i,
+	}
+
+	m := metric.Must(i.Meter)
+	t.failed = m.NewInt64Counter("index.tee.failed",
+		metric.WithDescription("Failed writes to secondary indexes which were dropped."))
+
+	for n, target := range targets {
+		switch target.Policy {
+		case Fail, Log, Spool:
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrUnknownPolicy, target.Policy)
+		}
+
+		t.targets[n] = newTarget(target, t)
+	}
+
+	return t, nil
+}
+
+// String returns the name of the index, for convenient logging.
+func (i *Index) String() string {
+	secondaries := make([]string, len(i.targets))
+	for n, t := range i.targets {
+		secondaries[n] = fmt.Sprint(t.Index)
+	}
+
+	return fmt.Sprintf("%s teed to %s", i.primary, strings.Join(secondaries, ", "))
+}
+
+// write applies primary to the primary index and, when successful, secondary to the targets.
+func (i *Index) write(ctx context.Context, desc string, primary, secondary func(context.Context, index.Index) error) error {
+	span := trace.SpanFromContext(ctx)
+
+	if err := primary(ctx, i.primary); err != nil {
+		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+		return err
+	}
+
+	w := &write{desc: desc, apply: secondary}
+
+	for _, t := range i.targets {
+		if err := t.write(ctx, w); err != nil {
+			span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ignoring returns apply, succeeding when it fails with target, either right away or once flushed.
+func ignoring(target error, apply func(context.Context, index.Index) error) func(context.Context, index.Index) error {
+	return func(ctx context.Context, idx index.Index) error {
+		return index.OnWritten(ctx,
+			func(ctx context.Context) error {
+				return apply(ctx, idx)
+			},
+			func(_ context.Context, err error) error {
+				if errors.Is(err, target) {
+					return nil
+				}
+
+				return err
+			},
+		)
+	}
+}
+
+// Index a document's properties, identified by id. Documents which already exist in secondary indexes, e.g. when
+// they were copied to it before, are not considered to conflict.
+func (i *Index) Index(ctx context.Context, id string, properties interface{}) error {
+	ctx, span := i.Tracer.Start(ctx, "index.tee.Index")
+	defer span.End()
+
+	create := func(ctx context.Context, idx index.Index) error {
+		return idx.Index(ctx, id, properties)
+	}
+
+	return i.write(ctx, "index "+id, create, ignoring(index.ErrConflict, create))
+}
+
+// unversioned returns properties without their version, which only applies to the primary index.
+func unversioned(properties interface{}) (interface{}, error) {
+	if v, ok := properties.(index.Versioned); !ok || v.DocumentVersion().IsZero() {
+		return properties, nil
+	}
+
+	// Versions are not encoded.
+	b, err := json.Marshal(properties)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.RawMessage(b), nil
+}
+
+// Update a document's properties, given id. Versions of index.Versioned properties are only checked against the
+// primary index. Documents missing from secondary indexes, e.g. as they were indexed before adding them, are skipped.
+func (i *Index) Update(ctx context.Context, id string, properties interface{}) error {
+	ctx, span := i.Tracer.Start(ctx, "index.tee.Update")
+	defer span.End()
+
+	secondaryProperties, err := unversioned(properties)
+	if err != nil {
+		return err
+	}
+
+	return i.write(ctx, "update "+id,
+		func(ctx context.Context, idx index.Index) error {
+			return idx.Update(ctx, id, properties)
+		},
+		ignoring(index.ErrNotFound, func(ctx context.Context, idx index.Index) error {
+			return idx.Update(ctx, id, secondaryProperties)
+		}),
+	)
+}
+
+// Apply atomic operations to a document, given id. Concrete indexes set with index.WithConcreteIndex are only applied
+// to in the primary index. Documents missing from secondary indexes are skipped.
+func (i *Index) Apply(ctx context.Context, id string, ops ...index.Operation) error {
+	ctx, span := i.Tracer.Start(ctx, "index.tee.Apply")
+	defer span.End()
+
+	return i.write(ctx, "apply to "+id,
+		func(ctx context.Context, idx index.Index) error {
+			return idx.Apply(ctx, id, ops...)
+		},
+		ignoring(index.ErrNotFound, func(ctx context.Context, idx index.Index) error {
+			// Concrete indexes only apply to the primary index.
+			return idx.Apply(index.WithConcreteIndex(ctx, ""), id, ops...)
+		}),
+	)
+}
+
+// Delete item from index.
+func (i *Index) Delete(ctx context.Context, id string) error {
+	ctx, span := i.Tracer.Start(ctx, "index.tee.Delete")
+	defer span.End()
+
+	del := func(ctx context.Context, idx index.Index) error {
+		return idx.Delete(ctx, id)
+	}
+
+	return i.write(ctx, "delete "+id, del, del)
+}
+
+// getSecondaries gets a document from the first secondary index which has it. As the version of a document in a
+// secondary index does not apply to the primary index, it is not set.
+func (i *Index) getSecondaries(ctx context.Context, id string, dst interface{}, fields ...string) (bool, error) {
+	for _, t := range i.targets {
+		found, err := t.Index.Get(ctx, id, dst, fields...)
+		if err != nil {
+			return false, err
+		}
+
+		if found {
+			if v, ok := dst.(index.Versioned); ok {
+				*v.DocumentVersion() = types.Version{}
+			}
+
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Get retreives `fields` from document with `id` from the primary index, returning:
+// - (true, decoding_error) if found (decoding error set when errors in json)
+// - (false, nil) when not found
+// With Fallback, documents not found in the primary index are got from secondary indexes, in order. Updates of
+// those documents only apply to the indexes having them.
+func (i *Index) Get(ctx context.Context, id string, dst interface{}, fields ...string) (bool, error) {
+	ctx, span := i.Tracer.Start(ctx, "index.tee.Get")
+	defer span.End()
+
+	found, err := i.primary.Get(ctx, id, dst, fields...)
+	if err != nil || found || !i.cfg.Fallback {
+		return found, err
+	}
+
+	return i.getSecondaries(ctx, id, dst, fields...)
+}
+
+// MultiGet retrieves `fields` from the document with `id` from the first of `indexes` which has it, like
+// index.MultiGet, getting from their primary indexes at once. With Fallback, documents not found in any of the
+// primary indexes are got from their secondary indexes, in order of indexes.
+// Other indexes are got from with index.ConcurrentMultiGet.
+func (i *Index) MultiGet(ctx context.Context, indexes []index.Index, id string, dst interface{}, fields ...string) (index.Index, error) {
+	ctx, span := i.Tracer.Start(ctx, "index.tee.MultiGet")
+	defer span.End()
+
+	tees := make([]*Index, len(indexes))
+	primaries := make([]index.Index, len(indexes))
+
+	for n, idx := range indexes {
+		t, ok := idx.(*Index)
+		if !ok {
+			return index.ConcurrentMultiGet(ctx, indexes, id, dst, fields...)
+		}
+
+		tees[n] = t
+		primaries[n] = t.primary
+	}
+
+	primary, err := index.MultiGet(ctx, primaries, id, dst, fields...)
+	if err != nil {
+		return nil, err
+	}
+
+	for n, p := range primaries {
+		if p == primary {
+			return tees[n], nil
+		}
+	}
+
+	for _, t := range tees {
+		if !t.cfg.Fallback {
+			continue
+		}
+
+		found, err := t.getSecondaries(ctx, id, dst, fields...)
+		if err != nil {
+			return nil, err
+		}
+
+		if found {
+			return t, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// Compile-time assurance that implementation satisfies interface.
+var _ index.Index = &Index{}
+var _ index.MultiGetter = &Index{}
@@ -0,0 +1,279 @@
+package tee
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/ipfs-search/ipfs-search/components/index"
+	"github.com/ipfs-search/ipfs-search/components/index/memory"
+	"github.com/ipfs-search/ipfs-search/components/index/types"
+	"github.com/ipfs-search/ipfs-search/instr"
+)
+
+type TeeTestSuite struct {
+	suite.Suite
+
+	ctx       context.Context
+	cfg       *Config
+	primary   *index.Mock
+	secondary *index.Mock
+}
+
+func (s *TeeTestSuite) SetupTest() {
+	s.ctx = context.Background()
+
+	s.cfg = DefaultConfig()
+
+	s.primary = &index.Mock{}
+	s.primary.Test(s.T())
+
+	s.secondary = &index.Mock{}
+	s.secondary.Test(s.T())
+}
+
+func (s *TeeTestSuite) TearDownTest() {
+	s.primary.AssertExpectations(s.T())
+	s.secondary.AssertExpectations(s.T())
+}
+
+func (s *TeeTestSuite) tee(policy string) index.Index {
+	idx, err := New(s.primary, []Target{{s.secondary, policy}}, s.cfg, instr.New())
+	s.Require().NoError(err)
+
+	return idx
+}
+
+func (s *TeeTestSuite) TestIndex() {
+	props := &types.Document{Size: 5}
+
+	s.primary.On("Index", mock.Anything, "id", props).Return(nil).Once()
+	s.secondary.On("Index", mock.Anything, "id", props).Return(nil).Once()
+
+	s.NoError(s.tee(Fail).Index(s.ctx, "id", props))
+}
+
+func (s *TeeTestSuite) TestIndexSecondaryConflict() {
+	props := &types.Document{Size: 5}
+
+	s.primary.On("Index", mock.Anything, "id", props).Return(nil).Once()
+	s.secondary.On("Index", mock.Anything, "id", props).Return(index.ErrConflict).Once()
+
+	s.NoError(s.tee(Fail).Index(s.ctx, "id", props))
+}
+
+func (s *TeeTestSuite) TestApplySecondaryMissing() {
+	op := index.Increment("size", 1)
+
+	s.primary.On("Apply", mock.Anything, "id", []index.Operation{op}).Return(nil).Once()
+	s.secondary.On("Apply", mock.Anything, "id", []index.Operation{op}).
+		Run(func(args mock.Arguments) {
+			done := index.WritesFromContext(args.Get(0).(context.Context)).Add()
+			go done(index.ErrNotFound)
+		}).
+		Return(nil).Once()
+
+	// Documents missing from the secondary index, once flushed, are skipped.
+	ctx, writes := index.WithWrites(s.ctx)
+
+	s.NoError(s.tee(Fail).Apply(ctx, "id", op))
+	s.NoError(writes.Wait(ctx))
+}
+
+func (s *TeeTestSuite) TestUpdateSecondaryMissing() {
+	props := &types.Update{References: types.References{{Name: "name"}}}
+
+	s.primary.On("Update", mock.Anything, "id", props).Return(nil).Once()
+	s.secondary.On("Update", mock.Anything, "id", props).Return(index.ErrNotFound).Once()
+
+	s.NoError(s.tee(Fail).Update(s.ctx, "id", props))
+}
+
+func (s *TeeTestSuite) TestPrimaryError() {
+	s.primary.On("Delete", mock.Anything, "id").Return(index.ErrConflict).Once()
+
+	s.ErrorIs(s.tee(Fail).Delete(s.ctx, "id"), index.ErrConflict)
+}
+
+func (s *TeeTestSuite) TestFail() {
+	err := errors.New("secondary")
+
+	s.primary.On("Delete", mock.Anything, "id").Return(nil).Once()
+	s.secondary.On("Delete", mock.Anything, "id").Return(err).Once()
+
+	s.ErrorIs(s.tee(Fail).Delete(s.ctx, "id"), err)
+}
+
+func (s *TeeTestSuite) TestFailWrites() {
+	err := errors.New("secondary")
+
+	s.primary.On("Delete", mock.Anything, "id").Return(nil).Once()
+	s.secondary.On("Delete", mock.Anything, "id").
+		Run(func(args mock.Arguments) {
+			index.WritesFromContext(args.Get(0).(context.Context)).Add()(err)
+		}).
+		Return(nil).Once()
+
+	// Asynchronous failures are tracked by the caller.
+	ctx, writes := index.WithWrites(s.ctx)
+
+	s.NoError(s.tee(Fail).Delete(ctx, "id"))
+	s.ErrorIs(writes.Wait(ctx), err)
+}
+
+func (s *TeeTestSuite) TestLog() {
+	s.primary.On("Delete", mock.Anything, "id").Return(nil).Once()
+	s.secondary.On("Delete", mock.Anything, "id").Return(errors.New("secondary")).Once()
+
+	s.NoError(s.tee(Log).Delete(s.ctx, "id"))
+}
+
+func (s *TeeTestSuite) TestLogWrites() {
+	s.primary.On("Delete", mock.Anything, "id").Return(nil).Once()
+	s.secondary.On("Delete", mock.Anything, "id").
+		Run(func(args mock.Arguments) {
+			index.WritesFromContext(args.Get(0).(context.Context)).Add()(errors.New("secondary"))
+		}).
+		Return(nil).Once()
+
+	// Asynchronous failures are not tracked by the caller.
+	ctx, writes := index.WithWrites(s.ctx)
+
+	s.NoError(s.tee(Log).Delete(ctx, "id"))
+	s.NoError(writes.Wait(ctx))
+}
+
+func (s *TeeTestSuite) TestSpool() {
+	s.primary.On("Delete", mock.Anything, "id").Return(nil).Once()
+	s.secondary.On("Delete", mock.Anything, "id").
+		Run(func(args mock.Arguments) {
+			// Spooled by the secondary index.
+			index.WritesFromContext(args.Get(0).(context.Context)).Add()(nil)
+		}).
+		Return(nil).Once()
+
+	ctx, writes := index.WithWrites(s.ctx)
+
+	s.NoError(s.tee(Spool).Delete(ctx, "id"))
+	s.NoError(writes.Wait(ctx))
+}
+
+func (s *TeeTestSuite) TestUpdateUnversioned() {
+	props := &types.Update{Version: types.Version{SeqNo: 1, PrimaryTerm: 1}, References: types.References{{Name: "name"}}}
+
+	s.primary.On("Update", mock.Anything, "id", props).Return(nil).Once()
+	s.secondary.On("Update", mock.Anything, "id", json.RawMessage(`{"references":[{"parent_hash":"","name":"name"}]}`)).
+		Return(nil).Once()
+
+	s.NoError(s.tee(Fail).Update(s.ctx, "id", props))
+}
+
+func (s *TeeTestSuite) TestUnknownPolicy() {
+	_, err := New(s.primary, []Target{{s.secondary, "unknown"}}, s.cfg, instr.New())
+	s.ErrorIs(err, ErrUnknownPolicy)
+}
+
+func TestTeeTestSuite(t *testing.T) {
+	suite.Run(t, new(TeeTestSuite))
+}
+
+// GetTestSuite tests getting from in-memory indexes.
+type GetTestSuite struct {
+	suite.Suite
+
+	ctx         context.Context
+	cfg         *Config
+	primaries   []index.Index
+	secondaries []index.Index
+	tees        []index.Index
+}
+
+func (s *GetTestSuite) SetupTest() {
+	s.ctx = context.Background()
+
+	s.cfg = DefaultConfig()
+	s.cfg.Fallback = true
+
+	s.primaries = nil
+	s.secondaries = nil
+	s.tees = nil
+
+	for n := 0; n < 2; n++ {
+		primary := memory.New(memory.DefaultConfig())
+		secondary := memory.New(memory.DefaultConfig())
+
+		tee, err := New(primary, []Target{{secondary, Log}}, s.cfg, instr.New())
+		s.Require().NoError(err)
+
+		s.primaries = append(s.primaries, primary)
+		s.secondaries = append(s.secondaries, secondary)
+		s.tees = append(s.tees, tee)
+	}
+}
+
+func (s *GetTestSuite) TestGet() {
+	s.NoError(s.primaries[0].Index(s.ctx, "id", &types.Document{Size: 1}))
+	s.NoError(s.secondaries[0].Index(s.ctx, "id", &types.Document{Size: 2}))
+
+	dst := new(types.Document)
+	found, err := s.tees[0].Get(s.ctx, "id", dst)
+	s.NoError(err)
+	s.True(found)
+	s.Equal(uint64(1), dst.Size)
+}
+
+func (s *GetTestSuite) TestGetFallback() {
+	s.NoError(s.secondaries[0].Index(s.ctx, "id", &types.Document{Size: 2}))
+
+	dst := new(types.Document)
+	found, err := s.tees[0].Get(s.ctx, "id", dst)
+	s.NoError(err)
+	s.True(found)
+	s.Equal(uint64(2), dst.Size)
+}
+
+func (s *GetTestSuite) TestGetNoFallback() {
+	s.cfg.Fallback = false
+
+	s.NoError(s.secondaries[0].Index(s.ctx, "id", &types.Document{Size: 2}))
+
+	found, err := s.tees[0].Get(s.ctx, "id", new(types.Document))
+	s.NoError(err)
+	s.False(found)
+}
+
+func (s *GetTestSuite) TestMultiGet() {
+	// Primary indexes take precedence over secondary indexes.
+	s.NoError(s.secondaries[0].Index(s.ctx, "id", &types.Document{Size: 1}))
+	s.NoError(s.primaries[1].Index(s.ctx, "id", &types.Document{Size: 2}))
+
+	dst := new(types.Document)
+	idx, err := index.MultiGet(s.ctx, s.tees, "id", dst)
+	s.NoError(err)
+	s.Equal(s.tees[1], idx)
+	s.Equal(uint64(2), dst.Size)
+}
+
+func (s *GetTestSuite) TestMultiGetFallback() {
+	s.NoError(s.secondaries[1].Index(s.ctx, "id", &types.Document{Size: 2}))
+
+	dst := new(types.Document)
+	idx, err := index.MultiGet(s.ctx, s.tees, "id", dst)
+	s.NoError(err)
+	s.Equal(s.tees[1], idx)
+	s.Equal(uint64(2), dst.Size)
+}
+
+func (s *GetTestSuite) TestMultiGetNotFound() {
+	idx, err := index.MultiGet(s.ctx, s.tees, "id", new(types.Document))
+	s.NoError(err)
+	s.Nil(idx)
+}
+
+func TestGetTestSuite(t *testing.T) {
+	suite.Run(t, new(GetTestSuite))
+}
@@ -0,0 +1,65 @@
+package tee
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/ipfs-search/ipfs-search/components/index"
+)
+
+// write is a write to secondary indexes.
+type write struct {
+	desc  string
+	apply func(context.Context, index.Index) error
+}
+
+// target writes to a secondary index, handling failed writes according to its policy.
+type target struct {
+	Target
+	tee *Index
+}
+
+func newTarget(t Target, tee *Index) *target {
+	return &target{
+		Target: t,
+		tee:    tee,
+	}
+}
+
+// write writes w to the secondary index. Errors are only returned with the Fail policy.
+func (t *target) write(ctx context.Context, w *write) error {
+	if t.Policy == Fail {
+		if err := w.apply(ctx, t.Index); err != nil {
+			return fmt.Errorf("writing to secondary index %s: %w", t.Index, err)
+		}
+
+		return nil
+	}
+
+	writesCtx, writes := index.WithWrites(ctx)
+
+	if err := w.apply(writesCtx, t.Index); err != nil {
+		t.failed(w, err)
+		return nil
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), t.tee.cfg.FlushTimeout)
+		defer cancel()
+
+		if err := writes.Wait(ctx); err != nil {
+			t.failed(w, err)
+		}
+	}()
+
+	return nil
+}
+
+// failed logs and drops a failed write. With the Spool policy, writes which failed to be flushed were spooled by
+// the secondary index instead of failing.
+func (t *target) failed(w *write, err error) {
+	log.Printf("Error writing %s to secondary index %s: %s", w.desc, t.Index, err)
+
+	t.tee.failed.Add(context.Background(), 1)
+}
@@ -0,0 +1,24 @@
+package types
+
+import (
+	"time"
+)
+
+// IPNSName represents an IPNS name and the value it resolves to in an Index.
+type IPNSName struct {
+	FirstSeen time.Time `json:"first-seen"`
+	LastSeen  time.Time `json:"last-seen"`
+	Value     string    `json:"value"`         // Path the name resolves to, e.g. /ipfs/<cid>.
+	CID       string    `json:"cid,omitempty"` // CID the name resolves to, if any.
+	Sequence  uint64    `json:"sequence"`
+	Validity  time.Time `json:"validity"` // End of validity of the record.
+}
+
+// IPNSNameUpdate represents the updatable part of an IPNSName.
+type IPNSNameUpdate struct {
+	LastSeen *time.Time `json:"last-seen,omitempty"`
+	Value    string     `json:"value,omitempty"`
+	CID      string     `json:"cid,omitempty"`
+	Sequence *uint64    `json:"sequence,omitempty"`
+	Validity *time.Time `json:"validity,omitempty"`
+}
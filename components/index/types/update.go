@@ -6,6 +6,8 @@ import (
 
 // Update represents the updatable part of a Document.
 type Update struct {
+	Version `json:"-"`
+
 	LastSeen   *time.Time `json:"last-seen,omitempty"`
 	References References `json:"references,omitempty"`
 }
@@ -0,0 +1,19 @@
+package types
+
+// Version identifies the revision of a document in an index, for optimistic concurrency control.
+type Version struct {
+	SeqNo       int64
+	PrimaryTerm int64
+	Index       string // Concrete index holding the revision, when read through an alias; optional.
+}
+
+// DocumentVersion returns the Version itself, allowing indexes to read and set the version of documents embedding it.
+func (v *Version) DocumentVersion() *Version {
+	return v
+}
+
+// IsZero returns true when no version is set.
+func (v *Version) IsZero() bool {
+	// Primary terms start at 1.
+	return v.PrimaryTerm == 0
+}
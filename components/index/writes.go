@@ -0,0 +1,117 @@
+package index
+
+import (
+	"context"
+	"sync"
+)
+
+type writesKey struct{}
+
+// Writes tracks the completion of asynchronous writes, e.g. those buffered by a bulk indexer, so that callers can
+// wait for writes to be durable and learn about writes which failed after the write method returned.
+// Indexes writing asynchronously register writes with the Writes in the context they are called with.
+type Writes struct {
+	mu      sync.Mutex
+	pending int
+	err     error
+	idle    chan struct{} // Closed when no writes are pending.
+}
+
+// WithWrites returns a context tracking asynchronous writes made with it, and the Writes tracking them.
+func WithWrites(ctx context.Context) (context.Context, *Writes) {
+	w := &Writes{}
+	return context.WithValue(ctx, writesKey{}, w), w
+}
+
+// WritesFromContext returns the Writes tracking writes for ctx, or nil when writes are not tracked.
+func WritesFromContext(ctx context.Context) *Writes {
+	w, _ := ctx.Value(writesKey{}).(*Writes)
+	return w
+}
+
+// Add registers an asynchronous write, returning a function to be called exactly once with its result.
+// It is safe to call on nil, in which case results are ignored.
+func (w *Writes) Add() func(error) {
+	if w == nil {
+		return func(error) {}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.pending == 0 {
+		w.idle = make(chan struct{})
+	}
+	w.pending++
+
+	return w.done
+}
+
+func (w *Writes) done(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+
+	w.pending--
+	if w.pending == 0 {
+		close(w.idle)
+	}
+}
+
+// Wait waits until all writes added so far completed, returning the first error of a failed write, or the error of
+// ctx when it is closed first.
+func (w *Writes) Wait(ctx context.Context) error {
+	w.mu.Lock()
+	idle, pending := w.idle, w.pending
+	w.mu.Unlock()
+
+	if pending > 0 {
+		select {
+		case <-idle:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return w.Err()
+}
+
+// Err returns the error of the first failed write, if any.
+func (w *Writes) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.err
+}
+
+// OnWritten calls write with a context tracking its asynchronous writes and handles their outcome with result: right
+// away when write fails or only wrote synchronously, otherwise in the background once its writes complete or ctx is
+// done. In the latter case, the handling is tracked as a write with the Writes of ctx, if any, failing with the error
+// returned by result. This allows handling failures which are only known once asynchronous writes are flushed, e.g.
+// ErrConflict when creating documents in bulk.
+func OnWritten(ctx context.Context, write func(context.Context) error, result func(context.Context, error) error) error {
+	writesCtx, writes := WithWrites(ctx)
+
+	if err := write(writesCtx); err != nil || !writes.isPending() {
+		return result(ctx, err)
+	}
+
+	done := WritesFromContext(ctx).Add()
+
+	go func() {
+		done(result(ctx, writes.Wait(ctx)))
+	}()
+
+	return nil
+}
+
+// isPending returns whether any writes are pending.
+func (w *Writes) isPending() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.pending > 0
+}
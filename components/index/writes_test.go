@@ -0,0 +1,116 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type WritesTestSuite struct {
+	suite.Suite
+
+	ctx    context.Context
+	writes *Writes
+}
+
+func (s *WritesTestSuite) SetupTest() {
+	s.ctx, s.writes = WithWrites(context.Background())
+}
+
+func (s *WritesTestSuite) TestFromContext() {
+	s.Equal(s.writes, WritesFromContext(s.ctx))
+	s.Nil(WritesFromContext(context.Background()))
+}
+
+func (s *WritesTestSuite) TestNil() {
+	var w *Writes
+
+	// Untracked writes are ignored.
+	w.Add()(errors.New("error"))
+}
+
+func (s *WritesTestSuite) TestWaitNone() {
+	s.NoError(s.writes.Wait(s.ctx))
+}
+
+func (s *WritesTestSuite) TestWait() {
+	done1 := s.writes.Add()
+	done2 := s.writes.Add()
+
+	go func() {
+		done1(nil)
+		done2(nil)
+	}()
+
+	s.NoError(s.writes.Wait(s.ctx))
+}
+
+func (s *WritesTestSuite) TestWaitError() {
+	err := errors.New("error")
+
+	done1 := s.writes.Add()
+	done2 := s.writes.Add()
+
+	done1(err)
+	done2(errors.New("other error"))
+
+	s.ErrorIs(s.writes.Wait(s.ctx), err)
+	s.ErrorIs(s.writes.Err(), err)
+}
+
+func (s *WritesTestSuite) TestWaitContext() {
+	s.writes.Add()
+
+	ctx, cancel := context.WithTimeout(s.ctx, time.Millisecond)
+	defer cancel()
+
+	s.ErrorIs(s.writes.Wait(ctx), context.DeadlineExceeded)
+}
+
+func (s *WritesTestSuite) TestOnWrittenSync() {
+	err := errors.New("error")
+
+	var result error
+
+	s.NoError(OnWritten(s.ctx,
+		func(context.Context) error { return err },
+		func(_ context.Context, err error) error {
+			result = err
+			return nil
+		},
+	))
+	s.ErrorIs(result, err)
+	s.NoError(s.writes.Wait(s.ctx))
+}
+
+func (s *WritesTestSuite) TestOnWrittenAsync() {
+	var (
+		flush  func(error)
+		result = make(chan error, 1)
+	)
+
+	err := OnWritten(s.ctx,
+		func(ctx context.Context) error {
+			flush = WritesFromContext(ctx).Add()
+			return nil
+		},
+		func(_ context.Context, err error) error {
+			result <- err
+			return ErrConflict
+		},
+	)
+	s.NoError(err)
+	s.Empty(result)
+
+	flush(ErrConflict)
+
+	s.ErrorIs(<-result, ErrConflict)
+	s.ErrorIs(s.writes.Wait(s.ctx), ErrConflict)
+}
+
+func TestWritesTestSuite(t *testing.T) {
+	suite.Run(t, new(WritesTestSuite))
+}
@@ -61,6 +61,22 @@ func (q *Queue) Publish(ctx context.Context, params interface{}, priority uint8)
 	return err
 }
 
+// Size returns the amount of messages ready for delivery in the queue.
+func (q *Queue) Size(ctx context.Context) (int, error) {
+	ctx, span := q.Tracer.Start(ctx, "queue.amqp.Size",
+		trace.WithAttributes(label.String("queue", q.name)),
+	)
+	defer span.End()
+
+	state, err := q.channel.ch.QueueInspect(q.name)
+	if err != nil {
+		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+		return 0, err
+	}
+
+	return state.Messages, nil
+}
+
 // Consume consumes messages from a queue
 func (q *Queue) Consume(ctx context.Context) (<-chan amqp.Delivery, error) {
 	ctx, span := q.Tracer.Start(ctx, "queue.amqp.Consume")
@@ -86,3 +102,4 @@ func (q *Queue) Consume(ctx context.Context) (<-chan amqp.Delivery, error) {
 
 // Compile-time assurance that implementation satisfies interface.
 var _ queue.Queue = &Queue{}
+var _ queue.Sizer = &Queue{}
@@ -17,6 +17,11 @@ type Consumer interface {
 	Consume(context.Context) (<-chan amqp.Delivery, error)
 }
 
+// Sizer allows probing the amount of messages waiting in a queue.
+type Sizer interface {
+	Size(context.Context) (int, error)
+}
+
 // PublisherFactory creates Publishers.
 type PublisherFactory interface {
 	NewPublisher(context.Context) (Publisher, error)
@@ -1,13 +1,53 @@
 package sniffer
 
-import "time"
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ipfs-search/ipfs-search/components/sniffer/queuer"
+	"github.com/ipfs-search/ipfs-search/components/sniffer/recorder"
+)
+
+// RecordMode determines which resources are recorded to the sniff log.
+type RecordMode string
+
+const (
+	// RecordNone disables the sniff log.
+	RecordNone RecordMode = "none"
+	// RecordRaw records resources as they are sniffed, before filtering.
+	RecordRaw RecordMode = "raw"
+	// RecordFiltered records resources after filtering, as they are queued.
+	RecordFiltered RecordMode = "filtered"
+)
 
 // Config holds configuration for a Sniffer.
 type Config struct {
 	LastSeenExpiration time.Duration // Expiration time for the last-seen resources
-	LastSeenPruneLen   int           // Cleanup expired resources from the last-seen
+	LastSeenPruneLen   int           // Maximum amount of resources remembered in the last-seen
+	LastSeenSnapshot   string        // Snapshot last-seen to this file on exit and reload on start; disabled when empty
 	LoggerTimeout      time.Duration // Throw timeout error when no log messages arrive
 	BufferSize         uint          // Size of the channels buffering between yielder, filter and adder
+	FilterWorkers      int           // Number of concurrent filter workers
+	IndexFilterTimeout time.Duration // Include resources without checking the index when it fails to respond within this time
+	PublishRate        float64       // Maximum sustained rate of publishing to the queue, per second
+	PublishBurst       int           // Maximum burst of publishing to the queue
+	QueueHighWater     int           // Pause publishing when the queue holds more messages than this
+	QueueProbeInterval time.Duration // Interval between probes of the queue's depth
+	OverloadWindow     time.Duration // Start sampling after sustained overload for this long
+	OverloadSampling   float64       // Fraction of resources to publish while sampling
+	RestartMinBackoff  time.Duration // Minimum wait before restarting after an error
+	RestartMaxBackoff  time.Duration // Maximum wait before restarting after an error
+	BreakerThreshold   int           // Give up after this many consecutive failures
+	BreakerResetAfter  time.Duration // Failures are no longer consecutive after running this long without error
+	RecordMode         RecordMode    // Record raw or filtered resources to the sniff log, or none
+	RecordDir          string        // Directory to write the sniff log to
+	RecordSegment      time.Duration // Start a new sniff log segment after this long
+	RecordRetention    time.Duration // Remove sniff log segments older than this
+	PeerListFile       string        // File with peers to allow or deny, see providerfilters.PeerListFilter
+	PeerListReload     time.Duration // Interval for checking the peer list file for changes
+	PeerRateWindow     time.Duration // Window for per-peer rate limiting
+	PeerRateLimit      int           // Maximum number of resources per peer per window
 }
 
 // DefaultConfig returns the default configuration for a Sniffer.
@@ -17,5 +57,46 @@ func DefaultConfig() *Config {
 		LastSeenPruneLen:   32768,
 		LoggerTimeout:      60 * time.Duration(time.Second),
 		BufferSize:         512,
+		FilterWorkers:      32,
+		IndexFilterTimeout: 2 * time.Second,
+		PublishRate:        1000,
+		PublishBurst:       100,
+		QueueHighWater:     1000000,
+		QueueProbeInterval: 5 * time.Second,
+		OverloadWindow:     time.Minute,
+		OverloadSampling:   0.1,
+		RestartMinBackoff:  time.Second,
+		RestartMaxBackoff:  5 * time.Minute,
+		BreakerThreshold:   10,
+		BreakerResetAfter:  10 * time.Minute,
+		RecordMode:         RecordNone,
+		RecordDir:          filepath.Join(os.TempDir(), "ipfs-sniffer-log"),
+		RecordSegment:      time.Hour,
+		RecordRetention:    7 * 24 * time.Hour,
+		PeerListFile:       "sniffer_peers.txt",
+		PeerListReload:     10 * time.Second,
+		PeerRateWindow:     time.Minute,
+		PeerRateLimit:      1000,
+	}
+}
+
+// ThrottleConfig returns the configuration for throttling publishing to the queue.
+func (c *Config) ThrottleConfig() *queuer.ThrottleConfig {
+	return &queuer.ThrottleConfig{
+		Rate:           c.PublishRate,
+		Burst:          c.PublishBurst,
+		HighWater:      c.QueueHighWater,
+		ProbeInterval:  c.QueueProbeInterval,
+		OverloadWindow: c.OverloadWindow,
+		SampleRatio:    c.OverloadSampling,
+	}
+}
+
+// RecorderConfig returns the configuration for recording the sniff log.
+func (c *Config) RecorderConfig() *recorder.Config {
+	return &recorder.Config{
+		Dir:             c.RecordDir,
+		SegmentDuration: c.RecordSegment,
+		Retention:       c.RecordRetention,
 	}
 }
@@ -1,6 +1,8 @@
 package eventsource
 
 import (
+	"time"
+
 	"github.com/ipfs/go-cid"
 	"github.com/libp2p/go-libp2p-core/peer"
 	"go.opentelemetry.io/otel/api/trace"
@@ -12,3 +14,13 @@ type EvtProviderPut struct {
 	PeerID      peer.ID
 	SpanContext trace.SpanContext // SpanContext allows a Resource' processing to be traceable across the program
 }
+
+// EvtIPNSPut should be emitted on every datastore Put() of a valid IPNS record.
+type EvtIPNSPut struct {
+	Name        peer.ID
+	Value       string    // Path the name resolves to, e.g. /ipfs/<cid>.
+	CID         cid.Cid   // CID the name resolves to, cid.Undef when Value is not an IPFS path.
+	Sequence    uint64    // Sequence number of the record.
+	Validity    time.Time // End of validity of the record.
+	SpanContext trace.SpanContext
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/ipfs/go-datastore"
 	"github.com/libp2p/go-eventbus"
 	"github.com/libp2p/go-libp2p-core/event"
+	"github.com/libp2p/go-libp2p-core/peer"
 
 	"go.opentelemetry.io/otel/api/trace"
 	"go.opentelemetry.io/otel/codes"
@@ -22,12 +23,14 @@ const bufSize = 512
 var handleTimeout = time.Second
 
 type handleFunc func(context.Context, EvtProviderPut) error
+type ipnsHandleFunc func(context.Context, EvtIPNSPut) error
 
 // EventSource generates events on a Bus after Put operations by proxying a Batching datastore.
 type EventSource struct {
-	bus     event.Bus
-	emitter event.Emitter
-	ds      datastore.Batching
+	bus         event.Bus
+	emitter     event.Emitter
+	ipnsEmitter event.Emitter
+	ds          datastore.Batching
 	*instr.Instrumentation
 }
 
@@ -38,9 +41,15 @@ func New(b event.Bus, ds datastore.Batching) (EventSource, error) {
 		return EventSource{}, err
 	}
 
+	ie, err := b.Emitter(new(EvtIPNSPut))
+	if err != nil {
+		return EventSource{}, err
+	}
+
 	s := EventSource{
 		bus:             b,
 		emitter:         e,
+		ipnsEmitter:     ie,
 		Instrumentation: instr.New(),
 	}
 
@@ -59,6 +68,12 @@ func (s *EventSource) afterPut(k datastore.Key, v []byte, err error) error {
 		return err
 	}
 
+	// IPNS records are stored in the same datastore
+	if name, err := keyToName(k); err == nil {
+		s.afterIPNSPut(ctx, name, v)
+		return nil
+	}
+
 	// Ignore non-provider keys
 	if !isProviderKey(k) {
 		span.RecordError(ctx, fmt.Errorf("Non-provider key"), trace.WithErrorStatus(codes.Ok))
@@ -98,12 +113,32 @@ func (s *EventSource) afterPut(k datastore.Key, v []byte, err error) error {
 	return err
 }
 
+func (s *EventSource) afterIPNSPut(ctx context.Context, name peer.ID, v []byte) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(label.Stringer("name", name))
+
+	e, err := decodeIPNSRecord(name, v)
+	if err != nil {
+		// Invalid records are not emitted
+		span.RecordError(ctx, fmt.Errorf("IPNS record for '%s': %w", name, err), trace.WithErrorStatus(codes.Error))
+		return
+	}
+
+	e.SpanContext = span.SpanContext()
+
+	if err := s.ipnsEmitter.Emit(*e); err != nil {
+		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+	} else {
+		span.SetStatus(codes.Ok, "emitted")
+	}
+}
+
 // Batching returns the proxied Batching datastore.
 func (s *EventSource) Batching() datastore.Batching {
 	return s.ds
 }
 
-func (s *EventSource) iterate(ctx context.Context, c <-chan interface{}, h handleFunc) error {
+func (s *EventSource) iterate(ctx context.Context, c <-chan interface{}, h func(context.Context, interface{}) error) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
@@ -112,22 +147,16 @@ func (s *EventSource) iterate(ctx context.Context, c <-chan interface{}, h handl
 			return fmt.Errorf("reading from event bus")
 		}
 
-		evt, ok := e.(EvtProviderPut)
-		if !ok {
-			return fmt.Errorf("casting event: %v", evt)
-		}
-
 		// Timeout handler to expose issues on the handler side
 		ctx, cancel := context.WithTimeout(ctx, handleTimeout)
 		defer cancel()
 
-		return h(ctx, evt)
+		return h(ctx, e)
 	}
 }
 
-// Subscribe handleFunc to EvtProviderPut events.
-func (s *EventSource) Subscribe(ctx context.Context, h handleFunc) error {
-	sub, err := s.bus.Subscribe(new(EvtProviderPut), eventbus.BufSize(bufSize))
+func (s *EventSource) subscribe(ctx context.Context, evtType interface{}, h func(context.Context, interface{}) error) error {
+	sub, err := s.bus.Subscribe(evtType, eventbus.BufSize(bufSize))
 	if err != nil {
 		return fmt.Errorf("subscribing: %w", err)
 	}
@@ -141,3 +170,27 @@ func (s *EventSource) Subscribe(ctx context.Context, h handleFunc) error {
 		}
 	}
 }
+
+// Subscribe handleFunc to EvtProviderPut events.
+func (s *EventSource) Subscribe(ctx context.Context, h handleFunc) error {
+	return s.subscribe(ctx, new(EvtProviderPut), func(ctx context.Context, e interface{}) error {
+		evt, ok := e.(EvtProviderPut)
+		if !ok {
+			return fmt.Errorf("casting event: %v", e)
+		}
+
+		return h(ctx, evt)
+	})
+}
+
+// SubscribeIPNS subscribes handleFunc to EvtIPNSPut events.
+func (s *EventSource) SubscribeIPNS(ctx context.Context, h ipnsHandleFunc) error {
+	return s.subscribe(ctx, new(EvtIPNSPut), func(ctx context.Context, e interface{}) error {
+		evt, ok := e.(EvtIPNSPut)
+		if !ok {
+			return fmt.Errorf("casting event: %v", e)
+		}
+
+		return h(ctx, evt)
+	})
+}
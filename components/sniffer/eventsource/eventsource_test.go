@@ -83,6 +83,57 @@ func (s *EventSourceTestSuite) TestSubscribePut() {
 	wg.Wait()
 }
 
+func (s *EventSourceTestSuite) TestSubscribeIPNSPut() {
+	es, _ := New(s.bus, s.ds)
+
+	name, k, v := mockIPNSRecord(s.T(), testIPNSValue, 1, time.Now().Add(time.Hour))
+
+	events := make(chan EvtIPNSPut, 1)
+	go es.SubscribeIPNS(s.ctx, func(ctx context.Context, e EvtIPNSPut) error {
+		events <- e
+		return nil
+	})
+
+	// Give the goroutine some time to start
+	time.Sleep(10 * time.Millisecond)
+
+	err := es.Batching().Put(k, v)
+	s.NoError(err)
+
+	select {
+	case e := <-events:
+		s.Equal(name, e.Name)
+		s.Equal("QmSKboVigcD3AY4kLsob117KJcMHvMUu6vNFqk1PQzYUpp", e.CID.String())
+	case <-time.After(time.Second):
+		s.Fail("IPNS event not received")
+	}
+}
+
+func (s *EventSourceTestSuite) TestSubscribeIPNSPutInvalid() {
+	es, _ := New(s.bus, s.ds)
+
+	// Expired record
+	_, k, v := mockIPNSRecord(s.T(), testIPNSValue, 1, time.Now().Add(-time.Hour))
+
+	events := make(chan EvtIPNSPut, 1)
+	go es.SubscribeIPNS(s.ctx, func(ctx context.Context, e EvtIPNSPut) error {
+		events <- e
+		return nil
+	})
+
+	time.Sleep(10 * time.Millisecond)
+
+	// Invalid records are stored but not emitted
+	err := es.Batching().Put(k, v)
+	s.NoError(err)
+
+	select {
+	case <-events:
+		s.Fail("invalid IPNS record emitted")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
 func TestEventSourceTestSuite(t *testing.T) {
 	suite.Run(t, new(EventSourceTestSuite))
 }
@@ -0,0 +1,94 @@
+package eventsource
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-ipns"
+	ipnspb "github.com/ipfs/go-ipns/pb"
+	"github.com/libp2p/go-libp2p-core/peer"
+	recpb "github.com/libp2p/go-libp2p-record/pb"
+	"github.com/multiformats/go-base32"
+)
+
+var (
+	errNotIPNSKey = errors.New("not an IPNS record key")
+	ipnsPrefix    = []byte("/ipns/")
+)
+
+const ipfsPathPrefix = "/ipfs/"
+
+// keyToName returns the name for an IPNS record key, or errNotIPNSKey.
+// The DHT stores records under the base32 encoding of their key, i.e. /ipns/<peer ID bytes>.
+// Ref: https://github.com/libp2p/go-libp2p-kad-dht/blob/v0.10.0/handlers.go#L374
+func keyToName(k datastore.Key) (peer.ID, error) {
+	nss := k.Namespaces()
+	if len(nss) != 1 {
+		return "", errNotIPNSKey
+	}
+
+	b, err := base32.RawStdEncoding.DecodeString(nss[0])
+	if err != nil || !bytes.HasPrefix(b, ipnsPrefix) {
+		return "", errNotIPNSKey
+	}
+
+	return peer.IDFromBytes(b[len(ipnsPrefix):])
+}
+
+// pathToCID returns the CID an IPFS path refers to, or cid.Undef for other paths.
+func pathToCID(p string) cid.Cid {
+	if !strings.HasPrefix(p, ipfsPathPrefix) {
+		return cid.Undef
+	}
+
+	c, err := cid.Decode(strings.SplitN(p[len(ipfsPathPrefix):], "/", 2)[0])
+	if err != nil {
+		return cid.Undef
+	}
+
+	return c
+}
+
+// decodeIPNSRecord decodes and validates a DHT record for name, returning an event for it.
+func decodeIPNSRecord(name peer.ID, v []byte) (*EvtIPNSPut, error) {
+	rec := new(recpb.Record)
+	if err := proto.Unmarshal(v, rec); err != nil {
+		return nil, fmt.Errorf("unmarshaling record: %w", err)
+	}
+
+	entry := new(ipnspb.IpnsEntry)
+	if err := proto.Unmarshal(rec.GetValue(), entry); err != nil {
+		return nil, fmt.Errorf("unmarshaling IPNS entry: %w", err)
+	}
+
+	// Names without embedded public keys are identity-hashed keys, which are extracted from the name itself.
+	pk, err := ipns.ExtractPublicKey(name, entry)
+	if err != nil {
+		return nil, fmt.Errorf("extracting public key: %w", err)
+	}
+
+	// Validates signature and EOL.
+	if err := ipns.Validate(pk, entry); err != nil {
+		return nil, err
+	}
+
+	eol, err := ipns.GetEOL(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	value := string(entry.GetValue())
+
+	return &EvtIPNSPut{
+		Name:     name,
+		Value:    value,
+		CID:      pathToCID(value),
+		Sequence: entry.GetSequence(),
+		Validity: eol,
+	}, nil
+}
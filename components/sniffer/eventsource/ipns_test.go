@@ -0,0 +1,111 @@
+package eventsource
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-ipns"
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/peer"
+	recpb "github.com/libp2p/go-libp2p-record/pb"
+	"github.com/multiformats/go-base32"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testIPNSValue = "/ipfs/QmSKboVigcD3AY4kLsob117KJcMHvMUu6vNFqk1PQzYUpp/file.txt"
+
+// mockIPNSRecord returns the datastore key and value of an IPNS record as stored by the DHT.
+func mockIPNSRecord(t *testing.T, value string, seq uint64, eol time.Time) (peer.ID, datastore.Key, []byte) {
+	sk, pk, err := crypto.GenerateEd25519Key(nil)
+	require.NoError(t, err)
+
+	name, err := peer.IDFromPublicKey(pk)
+	require.NoError(t, err)
+
+	entry, err := ipns.Create(sk, []byte(value), seq, eol)
+	require.NoError(t, err)
+
+	entryBytes, err := proto.Marshal(entry)
+	require.NoError(t, err)
+
+	recordKey := ipns.RecordKey(name)
+	v, err := proto.Marshal(&recpb.Record{
+		Key:   []byte(recordKey),
+		Value: entryBytes,
+	})
+	require.NoError(t, err)
+
+	k := datastore.NewKey(base32.RawStdEncoding.EncodeToString([]byte(recordKey)))
+
+	return name, k, v
+}
+
+func TestKeyToName(t *testing.T) {
+	name, k, _ := mockIPNSRecord(t, testIPNSValue, 1, time.Now().Add(time.Hour))
+
+	n, err := keyToName(k)
+	assert.NoError(t, err)
+	assert.Equal(t, name, n)
+}
+
+func TestKeyToNameNonIPNS(t *testing.T) {
+	keys := []string{
+		"/providers/CIQDWKPBHXLJ3XVELRJZA2SYY7OGCSX6FRSIZS2VQQPVKOA2Z4VXN2I/CIQO7FK6IWMEVZU2QU6QRJKMCLW4DXQGSVSVB3V56Y272TB3IPSBGFQ",
+		"/" + base32.RawStdEncoding.EncodeToString([]byte("/pk/whatever")),
+		"/invalid-base32",
+	}
+
+	for _, k := range keys {
+		_, err := keyToName(datastore.NewKey(k))
+		assert.ErrorIs(t, err, errNotIPNSKey, k)
+	}
+}
+
+func TestDecodeIPNSRecord(t *testing.T) {
+	eol := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	name, _, v := mockIPNSRecord(t, testIPNSValue, 5, eol)
+
+	e, err := decodeIPNSRecord(name, v)
+	require.NoError(t, err)
+
+	assert.Equal(t, name, e.Name)
+	assert.Equal(t, testIPNSValue, e.Value)
+	assert.Equal(t, "QmSKboVigcD3AY4kLsob117KJcMHvMUu6vNFqk1PQzYUpp", e.CID.String())
+	assert.Equal(t, uint64(5), e.Sequence)
+	assert.True(t, eol.Equal(e.Validity))
+}
+
+func TestDecodeIPNSRecordIPNSPath(t *testing.T) {
+	name, _, v := mockIPNSRecord(t, "/ipns/example.com", 1, time.Now().Add(time.Hour))
+
+	e, err := decodeIPNSRecord(name, v)
+	require.NoError(t, err)
+
+	assert.False(t, e.CID.Defined())
+}
+
+func TestDecodeIPNSRecordExpired(t *testing.T) {
+	name, _, v := mockIPNSRecord(t, testIPNSValue, 1, time.Now().Add(-time.Hour))
+
+	_, err := decodeIPNSRecord(name, v)
+	assert.ErrorIs(t, err, ipns.ErrExpiredRecord)
+}
+
+func TestDecodeIPNSRecordWrongName(t *testing.T) {
+	// Record signed by a key other than the name's.
+	_, _, v := mockIPNSRecord(t, testIPNSValue, 1, time.Now().Add(time.Hour))
+	otherName, _, _ := mockIPNSRecord(t, testIPNSValue, 1, time.Now().Add(time.Hour))
+
+	_, err := decodeIPNSRecord(otherName, v)
+	assert.ErrorIs(t, err, ipns.ErrSignature)
+}
+
+func TestDecodeIPNSRecordInvalid(t *testing.T) {
+	name, _, _ := mockIPNSRecord(t, testIPNSValue, 1, time.Now().Add(time.Hour))
+
+	_, err := decodeIPNSRecord(name, []byte("invalid"))
+	assert.Error(t, err)
+}
@@ -3,12 +3,17 @@ package factory
 import (
 	"context"
 	"fmt"
+	"log"
 	"time"
 
 	"net"
 
+	"github.com/ipfs-search/ipfs-search/components/denylist"
+	"github.com/ipfs-search/ipfs-search/components/index"
+	index_factory "github.com/ipfs-search/ipfs-search/components/index/factory"
 	"github.com/ipfs-search/ipfs-search/components/queue/amqp"
 	"github.com/ipfs-search/ipfs-search/components/sniffer"
+	filters "github.com/ipfs-search/ipfs-search/components/sniffer/providerfilters"
 	"github.com/ipfs-search/ipfs-search/config"
 	"github.com/ipfs-search/ipfs-search/instr"
 	"github.com/ipfs-search/ipfs-search/utils"
@@ -38,9 +43,9 @@ func getInstr(cfg *instr.Config) (*instr.Instrumentation, func(), error) {
 	return instr.New(), instFlusher, nil
 }
 
-func getQueue(ctx context.Context, cfg *amqp.Config, i *instr.Instrumentation) amqp.PublisherFactory {
+func getDialer(ctx context.Context) *utils.RetryingDialer {
 	// Retrying dialer for connecting
-	dialer := &utils.RetryingDialer{
+	return &utils.RetryingDialer{
 		Dialer: net.Dialer{
 			Timeout:   30 * time.Second,
 			KeepAlive: 30 * time.Second,
@@ -48,6 +53,10 @@ func getQueue(ctx context.Context, cfg *amqp.Config, i *instr.Instrumentation) a
 		},
 		Context: ctx,
 	}
+}
+
+func getQueue(ctx context.Context, cfg *amqp.Config, i *instr.Instrumentation) amqp.PublisherFactory {
+	dialer := getDialer(ctx)
 	samqpConfig := &samqp.Config{
 		Dial: dialer.Dial,
 	}
@@ -60,46 +69,129 @@ func getQueue(ctx context.Context, cfg *amqp.Config, i *instr.Instrumentation) a
 	}
 }
 
-func getSniffer(cfg *sniffer.Config, ds datastore.Batching, q amqp.PublisherFactory, i *instr.Instrumentation) (*sniffer.Sniffer, error) {
-	return sniffer.New(cfg, ds, q, i)
+func getIndexFilter(cfg *config.Config, f *index_factory.Factory) (*filters.IndexFilter, error) {
+	var indexes []index.Index
+
+	for _, c := range []config.Index{
+		cfg.Indexes.Files,
+		cfg.Indexes.Directories,
+	} {
+		idx, err := f.Index(c.IndexConfig())
+		if err != nil {
+			return nil, err
+		}
+
+		indexes = append(indexes, idx)
+	}
+
+	return filters.NewIndexFilter(indexes, cfg.Crawler.MinUpdateAge, cfg.Sniffer.IndexFilterTimeout), nil
+}
+
+func startIndexWorker(ctx context.Context, f *index_factory.Factory) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			if err := f.Work(ctx); err != nil {
+				log.Printf("Error in index worker, restarting worker: %s", err)
+				// Prevent overly tight restart loop
+				time.Sleep(time.Second)
+			}
+		}
+	}
+}
+
+// getIndexFactory returns a factory for the indexes of a sniffer running on its own.
+func getIndexFactory(ctx context.Context, cfg *config.Config, i *instr.Instrumentation) *index_factory.Factory {
+	esConfig := cfg.ElasticSearchClientConfig(utils.GetHTTPTransport(getDialer(ctx).DialContext, 100))
+
+	return index_factory.New(esConfig, cfg.BleveConfig(), cfg.CacheConfig(), i)
+}
+
+func getDenylistFilter(ctx context.Context, cfg *config.Config, i *instr.Instrumentation) (*filters.DenylistFilter, error) {
+	dCfg := cfg.DenylistConfig()
+
+	d, err := denylist.New(dCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	go d.Watch(ctx, dCfg.Reload)
+
+	return filters.NewDenylistFilter(d, i), nil
+}
+
+// New initialises a sniffer and all its dependencies from cfg, sniffing ds and getting indexes from f. Background
+// workers are bound to ctx; the background work of f should be started after initialising the sniffer.
+func New(ctx context.Context, cfg *config.Config, ds datastore.Batching, f *index_factory.Factory, i *instr.Instrumentation) (*sniffer.Sniffer, error) {
+	q := getQueue(ctx, cfg.AMQPConfig(), i)
+
+	indexFilter, err := getIndexFilter(cfg, f)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := f.Index(cfg.Indexes.IPNSNames.IndexConfig())
+	if err != nil {
+		return nil, err
+	}
+
+	denylistFilter, err := getDenylistFilter(ctx, cfg, i)
+	if err != nil {
+		return nil, err
+	}
+
+	// Denied items are dropped before looking them up in the index.
+	return sniffer.New(cfg.SnifferConfig(), ds, q, names, i, denylistFilter, indexFilter)
 }
 
 // Start initialises a sniffer and all its dependencies and launches it in a goroutine, returning a wrapped context
 // and datastore, which should replace the original ones, or an error from initialisation.
-func Start(ctx context.Context, ds datastore.Batching) (context.Context, datastore.Batching, error) {
+// When the sniffer exits, the returned context is canceled and the error it exited with (e.g. sniffer.ErrCircuitOpen)
+// is sent on the returned channel.
+func Start(ctx context.Context, ds datastore.Batching) (context.Context, datastore.Batching, <-chan error, error) {
 	cfg, err := getConfig()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	i, instFlusher, err := getInstr(cfg.InstrConfig())
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	// Create context which can be canceled by sniffer so as to propagate failure from sniffer goroutine.
 	ctx, cancel := context.WithCancel(ctx)
 
-	q := getQueue(ctx, cfg.AMQPConfig(), i)
+	f := getIndexFactory(ctx, cfg, i)
 
-	s, err := getSniffer(cfg.SnifferConfig(), ds, q, i)
+	s, err := New(ctx, cfg, ds, f, i)
 	if err != nil {
 		cancel()
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
+	go startIndexWorker(ctx, f)
+
 	// Use batched datastore
 	ds = s.Batching()
 
+	errc := make(chan error, 1)
+
 	// Start sniffer
 	go func() {
 		// Cancel parent context when done
 		defer cancel()
 		defer instFlusher()
+		defer f.Close()
+		defer close(errc)
+
+		err := s.Sniff(ctx)
+		fmt.Printf("Sniffer exited: %s, status: %+v\n", err, s.Status())
 
-		err = s.Sniff(ctx)
-		fmt.Printf("Sniffer exited: %s\n", err)
+		errc <- err
 	}()
 
-	return ctx, ds, nil
+	return ctx, ds, errc, nil
 }
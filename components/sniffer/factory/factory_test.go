@@ -43,11 +43,12 @@ func (s *FactoryTestSuite) TearDownTest() {
 
 // TestStartBurn performs a burn test for Start().
 func (s *FactoryTestSuite) TestStartBurn() {
-	ctx, ds, err := Start(s.ctx, s.ds)
+	ctx, ds, errc, err := Start(s.ctx, s.ds)
 	s.NoError(err)
 
 	s.NotEqual(s.ds, ds)
 	s.NotEqual(s.ctx, ctx)
+	s.NotNil(errc)
 }
 
 func TestFactoryTestSuite(t *testing.T) {
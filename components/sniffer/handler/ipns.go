@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/ipfs-search/ipfs-search/components/index"
+	indexTypes "github.com/ipfs-search/ipfs-search/components/index/types"
+	"github.com/ipfs-search/ipfs-search/components/sniffer/eventsource"
+
+	"github.com/ipfs-search/ipfs-search/instr"
+	t "github.com/ipfs-search/ipfs-search/types"
+)
+
+const (
+	pendingNames = 1024             // Names awaiting indexing, beyond which names are not recorded.
+	nameTimeout  = 10 * time.Second // Timeout for recording a single name.
+	flushTimeout = time.Minute      // Timeout for writes of a name to be flushed, after recording it.
+)
+
+// IPNSHandler handles EvtIPNSPut events by recording names in an index and writing Provider's for the CID's they
+// resolve to to a channel.
+type IPNSHandler struct {
+	providers chan<- t.Provider
+	names     index.Index
+	pending   chan eventsource.EvtIPNSPut
+	*instr.Instrumentation
+}
+
+// NewIPNS returns a new IPNSHandler, writing Provider's to providers and names to the names index.
+// When names is nil, names are not recorded.
+func NewIPNS(providers chan<- t.Provider, names index.Index) IPNSHandler {
+	return IPNSHandler{
+		providers:       providers,
+		names:           names,
+		pending:         make(chan eventsource.EvtIPNSPut, pendingNames),
+		Instrumentation: instr.New(),
+	}
+}
+
+// indexName creates or updates the name in the index. Values from records with a lower sequence number than the
+// indexed one are ignored, as records are not necessarily received in order.
+func (h *IPNSHandler) indexName(ctx context.Context, e eventsource.EvtIPNSPut) error {
+	id := e.Name.String()
+	now := time.Now().UTC()
+
+	var cid string
+	if e.CID.Defined() {
+		cid = e.CID.String()
+	}
+
+	existing := new(indexTypes.IPNSName)
+	found, err := h.names.Get(ctx, id, existing, "sequence")
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		return h.names.Index(ctx, id, &indexTypes.IPNSName{
+			FirstSeen: now,
+			LastSeen:  now,
+			Value:     e.Value,
+			CID:       cid,
+			Sequence:  e.Sequence,
+			Validity:  e.Validity,
+		})
+	}
+
+	u := &indexTypes.IPNSNameUpdate{
+		LastSeen: &now,
+	}
+
+	if e.Sequence >= existing.Sequence {
+		u.Value = e.Value
+		u.CID = cid
+		u.Sequence = &e.Sequence
+		u.Validity = &e.Validity
+	}
+
+	return h.names.Update(ctx, id, u)
+}
+
+// recordName records a name in the index, under its own timeout. Failing to record names should not hamper
+// sniffing, hence errors are only logged.
+func (h *IPNSHandler) recordName(ctx context.Context, e eventsource.EvtIPNSPut) {
+	ctx = trace.ContextWithRemoteSpanContext(ctx, e.SpanContext)
+	ctx, span := h.Tracer.Start(ctx, "handler.IPNSRecordName", trace.WithAttributes(
+		label.Stringer("name", e.Name),
+	))
+	defer span.End()
+
+	// Creating names conflicts when they were put again before creating them was flushed; record them again.
+	ctx, cancel := context.WithTimeout(ctx, flushTimeout)
+
+	index.OnWritten(ctx,
+		func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, nameTimeout)
+			defer cancel()
+
+			return h.indexName(ctx, e)
+		},
+		func(ctx context.Context, err error) error {
+			defer cancel()
+
+			if errors.Is(err, index.ErrConflict) {
+				h.enqueue(e)
+				return nil
+			}
+
+			if err != nil {
+				span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+				log.Printf("Error indexing IPNS name %s: %s", e.Name, err)
+			}
+
+			return nil
+		},
+	)
+}
+
+// RecordNames records the names handled by HandleFunc in the index, until the context is closed. Names are
+// recorded apart from handling events, as index requests may well take longer than handling is allowed to.
+func (h *IPNSHandler) RecordNames(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case e := <-h.pending:
+			h.recordName(ctx, e)
+		}
+	}
+}
+
+// enqueue queues a name for recording by RecordNames, unless too many names are pending already.
+func (h *IPNSHandler) enqueue(e eventsource.EvtIPNSPut) {
+	select {
+	case h.pending <- e:
+	default:
+		log.Printf("Too many pending IPNS names, not recording %s", e.Name)
+	}
+}
+
+// HandleFunc queues the name for every EvtIPNSPut it is called with for recording by RecordNames and writes a
+// Provider for the CID it resolves to, if any, to the IPNSHandler's providers channel.
+func (h *IPNSHandler) HandleFunc(ctx context.Context, e eventsource.EvtIPNSPut) error {
+	ctx = trace.ContextWithRemoteSpanContext(ctx, e.SpanContext)
+	ctx, span := h.Tracer.Start(ctx, "handler.IPNSHandleFunc", trace.WithAttributes(
+		label.Stringer("name", e.Name),
+		label.String("value", e.Value),
+	), trace.WithSpanKind(trace.SpanKindConsumer))
+	defer span.End()
+
+	if h.names != nil {
+		e.SpanContext = span.SpanContext()
+
+		h.enqueue(e)
+	}
+
+	if !e.CID.Defined() {
+		return nil
+	}
+
+	p := t.Provider{
+		Resource: &t.Resource{
+			Protocol: t.IPFSProtocol,
+			ID:       e.CID.String(),
+		},
+		// The peer which published the record is not known, hence there is no Provider.
+		Date:        time.Now(),
+		SpanContext: span.SpanContext(),
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case h.providers <- p:
+		return nil
+	}
+}
@@ -0,0 +1,217 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/ipfs-search/ipfs-search/components/index"
+	indexTypes "github.com/ipfs-search/ipfs-search/components/index/types"
+	"github.com/ipfs-search/ipfs-search/components/sniffer/eventsource"
+	t "github.com/ipfs-search/ipfs-search/types"
+)
+
+const (
+	testName = "12D3KooWJnoRKwJdemEEqcuaTF1nq8ZpBJuNQSwfxdttgG9Cb9RG"
+	testCID  = "QmSKboVigcD3AY4kLsob117KJcMHvMUu6vNFqk1PQzYUpp"
+)
+
+type IPNSHandlerTestSuite struct {
+	suite.Suite
+	ctx       context.Context
+	names     *index.Mock
+	providers chan t.Provider
+	h         IPNSHandler
+	e         eventsource.EvtIPNSPut
+}
+
+func (s *IPNSHandlerTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.names = &index.Mock{}
+	s.names.Test(s.T())
+	s.providers = make(chan t.Provider, 1)
+	s.h = NewIPNS(s.providers, s.names)
+
+	name, err := peer.Decode(testName)
+	s.Require().NoError(err)
+
+	c, err := cid.Decode(testCID)
+	s.Require().NoError(err)
+
+	s.e = eventsource.EvtIPNSPut{
+		Name:     name,
+		Value:    "/ipfs/" + testCID,
+		CID:      c,
+		Sequence: 5,
+		Validity: time.Now().Add(time.Hour),
+	}
+}
+
+func (s *IPNSHandlerTestSuite) assertProvider() {
+	select {
+	case p := <-s.providers:
+		s.Equal(testCID, p.ID)
+		s.Equal(t.IPFSProtocol, p.Protocol)
+		s.Empty(p.Provider)
+	default:
+		s.Fail("no provider written")
+	}
+}
+
+// handle handles the event, recording the name queued for RecordNames, if any.
+func (s *IPNSHandlerTestSuite) handle() {
+	s.NoError(s.h.HandleFunc(s.ctx, s.e))
+
+	select {
+	case e := <-s.h.pending:
+		s.h.recordName(s.ctx, e)
+	default:
+	}
+}
+
+func (s *IPNSHandlerTestSuite) TestNewName() {
+	s.names.On("Get", mock.Anything, testName, mock.Anything, []string{"sequence"}).Return(false, nil)
+	s.names.On("Index", mock.Anything, testName, mock.MatchedBy(func(n *indexTypes.IPNSName) bool {
+		return n.Value == s.e.Value && n.CID == testCID && n.Sequence == 5 &&
+			n.Validity.Equal(s.e.Validity) && !n.FirstSeen.IsZero() && n.FirstSeen.Equal(n.LastSeen)
+	})).Return(nil)
+
+	s.handle()
+
+	s.names.AssertExpectations(s.T())
+	s.assertProvider()
+}
+
+func (s *IPNSHandlerTestSuite) TestNewerSequence() {
+	s.names.On("Get", mock.Anything, testName, mock.Anything, []string{"sequence"}).
+		Run(func(args mock.Arguments) {
+			args.Get(2).(*indexTypes.IPNSName).Sequence = 4
+		}).Return(true, nil)
+	s.names.On("Update", mock.Anything, testName, mock.MatchedBy(func(u *indexTypes.IPNSNameUpdate) bool {
+		return u.LastSeen != nil && u.Value == s.e.Value && u.CID == testCID &&
+			u.Sequence != nil && *u.Sequence == 5 && u.Validity != nil
+	})).Return(nil)
+
+	s.handle()
+
+	s.names.AssertExpectations(s.T())
+	s.assertProvider()
+}
+
+func (s *IPNSHandlerTestSuite) TestOlderSequence() {
+	s.names.On("Get", mock.Anything, testName, mock.Anything, []string{"sequence"}).
+		Run(func(args mock.Arguments) {
+			args.Get(2).(*indexTypes.IPNSName).Sequence = 6
+		}).Return(true, nil)
+	s.names.On("Update", mock.Anything, testName, mock.MatchedBy(func(u *indexTypes.IPNSNameUpdate) bool {
+		// Only last-seen is updated.
+		return u.LastSeen != nil && u.Value == "" && u.CID == "" && u.Sequence == nil && u.Validity == nil
+	})).Return(nil)
+
+	s.handle()
+
+	s.names.AssertExpectations(s.T())
+	s.assertProvider()
+}
+
+func (s *IPNSHandlerTestSuite) TestIndexError() {
+	s.names.On("Get", mock.Anything, testName, mock.Anything, []string{"sequence"}).Return(false, errors.New("mock"))
+
+	// Index errors do not prevent the CID from being sniffed.
+	s.handle()
+
+	s.names.AssertExpectations(s.T())
+	s.assertProvider()
+}
+
+func (s *IPNSHandlerTestSuite) TestFlushedConflict() {
+	s.names.On("Get", mock.Anything, testName, mock.Anything, []string{"sequence"}).Return(false, nil)
+	s.names.On("Index", mock.Anything, testName, mock.Anything).Run(func(args mock.Arguments) {
+		done := index.WritesFromContext(args.Get(0).(context.Context)).Add()
+		go done(index.ErrConflict)
+	}).Return(nil)
+
+	s.handle()
+
+	// Names which turn out to have been created concurrently are recorded again.
+	select {
+	case e := <-s.h.pending:
+		s.Equal(s.e.Name, e.Name)
+	case <-time.After(time.Second):
+		s.Fail("name not queued again")
+	}
+
+	s.names.AssertExpectations(s.T())
+}
+
+func (s *IPNSHandlerTestSuite) TestNoCID() {
+	s.e.Value = "/ipns/example.com"
+	s.e.CID = cid.Undef
+
+	s.names.On("Get", mock.Anything, testName, mock.Anything, []string{"sequence"}).Return(false, nil)
+	s.names.On("Index", mock.Anything, testName, mock.MatchedBy(func(n *indexTypes.IPNSName) bool {
+		return n.Value == "/ipns/example.com" && n.CID == ""
+	})).Return(nil)
+
+	s.handle()
+
+	s.names.AssertExpectations(s.T())
+	s.Empty(s.providers)
+}
+
+func (s *IPNSHandlerTestSuite) TestHandleDoesNotIndex() {
+	// Names are recorded by RecordNames, not while handling events.
+	s.NoError(s.h.HandleFunc(s.ctx, s.e))
+
+	s.names.AssertNotCalled(s.T(), "Get", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	s.Len(s.h.pending, 1)
+	s.assertProvider()
+}
+
+func (s *IPNSHandlerTestSuite) TestPendingFull() {
+	for i := 0; i < pendingNames; i++ {
+		s.h.pending <- s.e
+	}
+
+	// Handling does not block when too many names are pending.
+	s.NoError(s.h.HandleFunc(s.ctx, s.e))
+
+	s.Len(s.h.pending, pendingNames)
+	s.assertProvider()
+}
+
+func (s *IPNSHandlerTestSuite) TestRecordNames() {
+	s.names.On("Get", mock.Anything, testName, mock.Anything, []string{"sequence"}).Return(false, nil)
+	s.names.On("Index", mock.Anything, testName, mock.Anything).Return(nil)
+
+	s.NoError(s.h.HandleFunc(s.ctx, s.e))
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	done := make(chan error)
+
+	go func() { done <- s.h.RecordNames(ctx) }()
+
+	s.Eventually(func() bool { return len(s.h.pending) == 0 }, time.Second, time.Millisecond)
+	cancel()
+
+	s.ErrorIs(<-done, context.Canceled)
+	s.names.AssertExpectations(s.T())
+}
+
+func (s *IPNSHandlerTestSuite) TestNoIndex() {
+	s.h = NewIPNS(s.providers, nil)
+
+	s.NoError(s.h.HandleFunc(s.ctx, s.e))
+
+	s.assertProvider()
+}
+
+func TestIPNSHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(IPNSHandlerTestSuite))
+}
@@ -0,0 +1,67 @@
+package sniffer
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	filters "github.com/ipfs-search/ipfs-search/components/sniffer/providerfilters"
+)
+
+// loadLastSeen restores the last-seen filter from the configured snapshot, if any.
+func (s *Sniffer) loadLastSeen(f *filters.LastSeenFilter) error {
+	path := s.cfg.LastSeenSnapshot
+	if path == "" {
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Printf("No LastSeen snapshot at %s, starting empty", path)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := f.Load(file); err != nil {
+		return fmt.Errorf("loading LastSeen snapshot %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// saveLastSeen writes the last-seen filter to the configured snapshot, if any.
+func (s *Sniffer) saveLastSeen() error {
+	path := s.cfg.LastSeenSnapshot
+	if path == "" {
+		return nil
+	}
+
+	// Write to temporary file first, so an interrupted write never clobbers an existing snapshot.
+	tmpPath := path + ".tmp"
+
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	if err := s.lastSeen.Save(file); err != nil {
+		file.Close()
+		return fmt.Errorf("saving LastSeen snapshot %s: %w", path, err)
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+
+	log.Printf("Saved %d LastSeen resources to %s", s.lastSeen.Len(), path)
+
+	return nil
+}
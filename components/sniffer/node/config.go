@@ -0,0 +1,29 @@
+package node
+
+import (
+	dht "github.com/libp2p/go-libp2p-kad-dht"
+)
+
+// Config holds configuration for a sniffing DHT Node.
+type Config struct {
+	ListenAddrs    []string // Multiaddresses to listen on
+	BootstrapPeers []string // Multiaddresses, including peer ID, of peers to bootstrap the DHT from
+	IdentityPath   string   // File to persist the node's private key in; a new identity is generated on every start when empty
+}
+
+// DefaultConfig returns the default configuration for a Node.
+func DefaultConfig() *Config {
+	var bootstrapPeers []string
+	for _, a := range dht.DefaultBootstrapPeers {
+		bootstrapPeers = append(bootstrapPeers, a.String())
+	}
+
+	return &Config{
+		ListenAddrs: []string{
+			"/ip4/0.0.0.0/tcp/4002",
+			"/ip6/::/tcp/4002",
+		},
+		BootstrapPeers: bootstrapPeers,
+		IdentityPath:   "sniffer_identity.key",
+	}
+}
@@ -0,0 +1,163 @@
+/*
+Package node provides a libp2p host running a DHT server, allowing a sniffer to observe the DHT without an IPFS node.
+
+The datastore passed to New is used for the DHT's records; it should be the datastore returned by the sniffer's
+Batching() method, for the DHT's provider records to be sniffed.
+*/
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/libp2p/go-libp2p"
+	"github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/peer"
+	dht "github.com/libp2p/go-libp2p-kad-dht"
+	"github.com/multiformats/go-multiaddr"
+)
+
+// Node is a libp2p host running a DHT server.
+type Node struct {
+	host.Host
+	DHT *dht.IpfsDHT
+
+	bootstrapPeers []peer.AddrInfo
+}
+
+// loadIdentity reads a private key from path, generating and writing a new one when it doesn't exist.
+func loadIdentity(path string) (crypto.PrivKey, error) {
+	if path == "" {
+		key, _, err := crypto.GenerateEd25519Key(nil)
+		return key, err
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err == nil {
+		return crypto.UnmarshalPrivateKey(b)
+	}
+
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	key, _, err := crypto.GenerateEd25519Key(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if b, err = crypto.MarshalPrivateKey(key); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Writing new identity to %s", path)
+
+	return key, ioutil.WriteFile(path, b, 0600)
+}
+
+func parsePeers(addrs []string) ([]peer.AddrInfo, error) {
+	maddrs := make([]multiaddr.Multiaddr, len(addrs))
+
+	for i, a := range addrs {
+		maddr, err := multiaddr.NewMultiaddr(a)
+		if err != nil {
+			return nil, fmt.Errorf("parsing peer address '%s': %w", a, err)
+		}
+
+		maddrs[i] = maddr
+	}
+
+	return peer.AddrInfosFromP2pAddrs(maddrs...)
+}
+
+// New creates a Node using ds for storing DHT records, or returns an error.
+// The Node runs until ctx is closed or Close() is called.
+func New(ctx context.Context, cfg *Config, ds datastore.Batching) (*Node, error) {
+	key, err := loadIdentity(cfg.IdentityPath)
+	if err != nil {
+		return nil, fmt.Errorf("loading identity: %w", err)
+	}
+
+	bootstrapPeers, err := parsePeers(cfg.BootstrapPeers)
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := libp2p.New(ctx,
+		libp2p.Identity(key),
+		libp2p.ListenAddrStrings(cfg.ListenAddrs...),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating host: %w", err)
+	}
+
+	d, err := dht.New(ctx, h,
+		dht.Mode(dht.ModeServer),
+		dht.Datastore(ds),
+		dht.BootstrapPeers(bootstrapPeers...),
+	)
+	if err != nil {
+		h.Close()
+		return nil, fmt.Errorf("creating DHT: %w", err)
+	}
+
+	log.Printf("Started DHT node %s on %v", h.ID(), h.Addrs())
+
+	return &Node{h, d, bootstrapPeers}, nil
+}
+
+// Bootstrap connects to the configured bootstrap peers and bootstraps the DHT's routing table.
+// It returns an error when none of the peers could be reached.
+func (n *Node) Bootstrap(ctx context.Context) error {
+	peers := n.bootstrapPeers
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		connected int
+	)
+
+	for _, p := range peers {
+		p := p // https://go.dev/doc/faq#closures_and_goroutines
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := n.Connect(ctx, p); err != nil {
+				log.Printf("Error connecting to bootstrap peer %s: %s", p.ID, err)
+				return
+			}
+
+			mu.Lock()
+			connected++
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	if len(peers) > 0 && connected == 0 {
+		return fmt.Errorf("unable to connect to any of %d bootstrap peers", len(peers))
+	}
+
+	log.Printf("Connected to %d of %d bootstrap peers", connected, len(peers))
+
+	return n.DHT.Bootstrap(ctx)
+}
+
+// Close shuts down the DHT and the host.
+func (n *Node) Close() error {
+	if err := n.DHT.Close(); err != nil {
+		return err
+	}
+
+	return n.Host.Close()
+}
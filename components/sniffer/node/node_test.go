@@ -0,0 +1,141 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/libp2p/go-eventbus"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/ipfs-search/ipfs-search/components/sniffer/eventsource"
+	"github.com/ipfs-search/ipfs-search/components/sniffer/handler"
+	t "github.com/ipfs-search/ipfs-search/types"
+)
+
+const testCID = "QmWg9o1DMjSVNSz1qHrfSQ97VsMAcr5dBz3Fe1HPbNdYB8"
+
+type NodeTestSuite struct {
+	suite.Suite
+	ctx    context.Context
+	cancel func()
+}
+
+func (s *NodeTestSuite) SetupTest() {
+	s.ctx, s.cancel = context.WithTimeout(context.Background(), 30*time.Second)
+}
+
+func (s *NodeTestSuite) TearDownTest() {
+	s.cancel()
+}
+
+func testConfig() *Config {
+	return &Config{
+		ListenAddrs: []string{"/ip4/127.0.0.1/tcp/0"},
+	}
+}
+
+// newSniffingNode returns a Node sending the providers it receives into c.
+func (s *NodeTestSuite) newSniffingNode(cfg *Config, c chan<- t.Provider) *Node {
+	es, err := eventsource.New(eventbus.NewBus(), dssync.MutexWrap(datastore.NewMapDatastore()))
+	s.Require().NoError(err)
+
+	h := handler.New(c)
+	go es.Subscribe(s.ctx, h.HandleFunc)
+
+	n, err := New(s.ctx, cfg, es.Batching())
+	s.Require().NoError(err)
+
+	return n
+}
+
+func (s *NodeTestSuite) TestIdentityPersistence() {
+	cfg := testConfig()
+	cfg.IdentityPath = filepath.Join(s.T().TempDir(), "identity.key")
+
+	n1, err := New(s.ctx, cfg, datastore.NewMapDatastore())
+	s.Require().NoError(err)
+	n1.Close()
+
+	n2, err := New(s.ctx, cfg, datastore.NewMapDatastore())
+	s.Require().NoError(err)
+	n2.Close()
+
+	s.Equal(n1.ID(), n2.ID())
+}
+
+func (s *NodeTestSuite) TestInvalidBootstrapPeer() {
+	cfg := testConfig()
+	cfg.BootstrapPeers = []string{"invalid"}
+
+	_, err := New(s.ctx, cfg, datastore.NewMapDatastore())
+	s.Error(err)
+}
+
+func (s *NodeTestSuite) TestBootstrapUnreachable() {
+	other, err := New(s.ctx, testConfig(), datastore.NewMapDatastore())
+	s.Require().NoError(err)
+	addr := fmt.Sprintf("%s/p2p/%s", other.Addrs()[0], other.ID())
+	other.Close()
+
+	cfg := testConfig()
+	cfg.BootstrapPeers = []string{addr}
+
+	n, err := New(s.ctx, cfg, datastore.NewMapDatastore())
+	s.Require().NoError(err)
+	defer n.Close()
+
+	s.Error(n.Bootstrap(s.ctx))
+}
+
+// TestSniffProviders wires several local nodes together and asserts that provider records are sniffed.
+func (s *NodeTestSuite) TestSniffProviders() {
+	sniffed := make(chan t.Provider, 100)
+
+	bootstrap := s.newSniffingNode(testConfig(), sniffed)
+
+	cfg := testConfig()
+	cfg.BootstrapPeers = []string{fmt.Sprintf("%s/p2p/%s", bootstrap.Addrs()[0], bootstrap.ID())}
+
+	nodes := []*Node{bootstrap}
+	for i := 0; i < 3; i++ {
+		n := s.newSniffingNode(cfg, sniffed)
+
+		s.Require().NoError(n.Bootstrap(s.ctx))
+		nodes = append(nodes, n)
+	}
+
+	c, err := cid.Decode(testCID)
+	s.Require().NoError(err)
+
+	// Peers are added to routing tables asynchronously, after protocol identification.
+	for _, n := range nodes {
+		n := n
+		s.Eventually(func() bool { return n.DHT.RoutingTable().Size() > 0 }, 10*time.Second, 10*time.Millisecond)
+	}
+
+	provider := nodes[len(nodes)-1]
+	s.Require().NoError(provider.DHT.Provide(s.ctx, c, true))
+
+	// Provider records are buffered by the DHT; closing flushes them to the datastore.
+	for _, n := range nodes {
+		s.NoError(n.Close())
+	}
+
+	select {
+	case p := <-sniffed:
+		s.Equal(testCID, p.ID)
+		s.Equal(provider.ID().String(), p.Provider)
+	case <-s.ctx.Done():
+		s.Fail("provider not sniffed")
+	}
+}
+
+func TestNodeTestSuite(t *testing.T) {
+	suite.Run(t, new(NodeTestSuite))
+}
@@ -0,0 +1,37 @@
+package providerfilters
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/api/metric"
+
+	"github.com/ipfs-search/ipfs-search/components/denylist"
+	"github.com/ipfs-search/ipfs-search/instr"
+	t "github.com/ipfs-search/ipfs-search/types"
+)
+
+// DenylistFilter filters out Providers of resources on a denylist.
+type DenylistFilter struct {
+	denylist *denylist.Denylist
+	dropped  metric.Int64Counter
+}
+
+// NewDenylistFilter returns a pointer to a new DenylistFilter.
+func NewDenylistFilter(d *denylist.Denylist, i *instr.Instrumentation) *DenylistFilter {
+	return &DenylistFilter{
+		denylist: d,
+		dropped: metric.Must(i.Meter).NewInt64Counter("sniffer.providerfilters.denylist.dropped",
+			metric.WithDescription("Providers dropped by the denylist.")),
+	}
+}
+
+// Filter takes a Provider and returns true when it is to be included, false
+// when not and an error when unexpected condition occur.
+func (f *DenylistFilter) Filter(p t.Provider) (bool, error) {
+	if f.denylist.DeniedResource(p.Resource) {
+		f.dropped.Add(context.Background(), 1)
+		return false, nil
+	}
+
+	return true, nil
+}
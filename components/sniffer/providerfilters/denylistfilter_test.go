@@ -0,0 +1,38 @@
+package providerfilters
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ipfs-search/ipfs-search/components/denylist"
+	"github.com/ipfs-search/ipfs-search/instr"
+	"github.com/ipfs-search/ipfs-search/types"
+)
+
+func TestDenylistFilter(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg := denylist.DefaultConfig()
+	cfg.File = filepath.Join(t.TempDir(), "denylist")
+	require.NoError(t, ioutil.WriteFile(cfg.File, []byte("/ipfs/QmSKboVigcD3AY4kLsob117KJcMHvMUu6vNFqk1PQzYUpp\n"), 0644))
+
+	d, err := denylist.New(cfg)
+	require.NoError(t, err)
+
+	f := NewDenylistFilter(d, instr.New())
+
+	result, err := f.Filter(*makeProvider(nil))
+	assert.NoError(err)
+	assert.False(result)
+
+	result, err = f.Filter(*makeProvider(&types.Resource{
+		Protocol: types.IPFSProtocol,
+		ID:       "QmbWqxBEKC3P8tqsKc98xmWNzrzDtRLMiMPL8wBuTGsMnR",
+	}))
+	assert.NoError(err)
+	assert.True(result)
+}
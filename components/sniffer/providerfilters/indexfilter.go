@@ -0,0 +1,103 @@
+package providerfilters
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/ipfs-search/ipfs-search/components/index"
+	indexTypes "github.com/ipfs-search/ipfs-search/components/index/types"
+	t "github.com/ipfs-search/ipfs-search/types"
+)
+
+// bypassFactor determines how long the index is bypassed after a failed lookup, as a multiple of the timeout.
+const bypassFactor = 10
+
+// IndexFilter filters out Providers for resources which have been seen by the crawler more recently than MinUpdateAge.
+//
+// Lookups in all indexes are performed concurrently, so that indexes which batch gets, like Elasticsearch, look them
+// up together. When the indexes fail to respond within Timeout, Providers are included (fail open) and the indexes
+// are bypassed for a while.
+type IndexFilter struct {
+	indexes      []index.Index
+	bypassUntil  int64 // Unix time in nanoseconds until which lookups are skipped.
+	MinUpdateAge time.Duration
+	Timeout      time.Duration
+}
+
+// NewIndexFilter returns a pointer to a new IndexFilter, looking up resources in indexes.
+func NewIndexFilter(indexes []index.Index, minUpdateAge time.Duration, timeout time.Duration) *IndexFilter {
+	if len(indexes) == 0 {
+		panic("NewIndexFilter indexes cannot be empty.")
+	}
+
+	return &IndexFilter{
+		indexes:      indexes,
+		MinUpdateAge: minUpdateAge,
+		Timeout:      timeout,
+	}
+}
+
+// lastSeen returns the most recent last-seen over all indexes, nil when not found.
+func (f *IndexFilter) lastSeen(ctx context.Context, id string) (*time.Time, error) {
+	updates := make([]indexTypes.Update, len(f.indexes))
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	for n, idx := range f.indexes {
+		n, idx := n, idx // https://go.dev/doc/faq#closures_and_goroutines
+
+		g.Go(func() error {
+			_, err := idx.Get(ctx, id, &updates[n], "last-seen")
+			return err
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	var lastSeen *time.Time
+
+	for _, u := range updates {
+		if u.LastSeen != nil && (lastSeen == nil || u.LastSeen.After(*lastSeen)) {
+			lastSeen = u.LastSeen
+		}
+	}
+
+	return lastSeen, nil
+}
+
+// Filter takes a Provider and returns true when it is to be included, false
+// when not and an error when unexpected condition occur.
+func (f *IndexFilter) Filter(p t.Provider) (bool, error) {
+	now := time.Now()
+
+	if now.UnixNano() < atomic.LoadInt64(&f.bypassUntil) {
+		return true, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), f.Timeout)
+	defer cancel()
+
+	lastSeen, err := f.lastSeen(ctx, p.ID)
+	if err != nil {
+		// Fail open; better to queue a resource twice than not at all.
+		bypass := f.Timeout * bypassFactor
+		log.Printf("Error getting %v from index, bypassing index for %s: %s", p, bypass, err)
+		atomic.StoreInt64(&f.bypassUntil, now.Add(bypass).UnixNano())
+
+		return true, nil
+	}
+
+	if lastSeen == nil {
+		// Not in index, index it!
+		return true, nil
+	}
+
+	// Only include when the crawler would update last-seen.
+	return p.Date.Sub(*lastSeen) > f.MinUpdateAge, nil
+}
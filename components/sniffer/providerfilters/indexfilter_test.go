@@ -0,0 +1,97 @@
+package providerfilters
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/ipfs-search/ipfs-search/components/index"
+	indexTypes "github.com/ipfs-search/ipfs-search/components/index/types"
+)
+
+type IndexFilterTestSuite struct {
+	suite.Suite
+	files       *index.Mock
+	directories *index.Mock
+	f           *IndexFilter
+}
+
+func (s *IndexFilterTestSuite) SetupTest() {
+	s.files = &index.Mock{}
+	s.files.Test(s.T())
+	s.directories = &index.Mock{}
+	s.directories.Test(s.T())
+	s.f = NewIndexFilter([]index.Index{s.files, s.directories}, time.Hour, time.Second)
+}
+
+func (s *IndexFilterTestSuite) expectGet(idx *index.Mock, lastSeen *time.Time, err error) {
+	idx.
+		On("Get", mock.Anything, mock.Anything, mock.AnythingOfType("*types.Update"), []string{"last-seen"}).
+		Run(func(args mock.Arguments) {
+			args.Get(2).(*indexTypes.Update).LastSeen = lastSeen
+		}).
+		Return(lastSeen != nil, err).
+		Once()
+}
+
+func (s *IndexFilterTestSuite) TestNotFound() {
+	s.expectGet(s.files, nil, nil)
+	s.expectGet(s.directories, nil, nil)
+
+	result, err := s.f.Filter(*makeProvider(nil))
+
+	s.NoError(err)
+	s.True(result)
+	s.files.AssertExpectations(s.T())
+	s.directories.AssertExpectations(s.T())
+}
+
+func (s *IndexFilterTestSuite) TestRecent() {
+	lastSeen := time.Now().Add(-time.Minute)
+
+	s.expectGet(s.files, nil, nil)
+	s.expectGet(s.directories, &lastSeen, nil)
+
+	result, err := s.f.Filter(*makeProvider(nil))
+
+	s.NoError(err)
+	s.False(result)
+}
+
+func (s *IndexFilterTestSuite) TestOld() {
+	lastSeen := time.Now().Add(-2 * time.Hour)
+
+	s.expectGet(s.files, &lastSeen, nil)
+	s.expectGet(s.directories, nil, nil)
+
+	result, err := s.f.Filter(*makeProvider(nil))
+
+	s.NoError(err)
+	s.True(result)
+}
+
+// TestFailOpen asserts that resources are included on errors, and that the index is subsequently bypassed.
+func (s *IndexFilterTestSuite) TestFailOpen() {
+	s.expectGet(s.files, nil, errors.New("index slow"))
+	s.expectGet(s.directories, nil, nil)
+
+	result, err := s.f.Filter(*makeProvider(nil))
+
+	s.NoError(err)
+	s.True(result)
+
+	// Bypassed; no further calls to Get.
+	result, err = s.f.Filter(*makeProvider(nil))
+
+	s.NoError(err)
+	s.True(result)
+	s.files.AssertExpectations(s.T())
+	s.directories.AssertExpectations(s.T())
+}
+
+func TestIndexFilterTestSuite(t *testing.T) {
+	suite.Run(t, new(IndexFilterTestSuite))
+}
@@ -1,90 +1,206 @@
 package providerfilters
 
 import (
+	"container/list"
+	"encoding/gob"
+	"hash/fnv"
+	"io"
 	"log"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	t "github.com/ipfs-search/ipfs-search/types"
 )
 
-const logEvery = 1000
+const (
+	logEvery = 1000
+
+	// lastSeenShards is the number of independently locked shards in a LastSeenFilter.
+	lastSeenShards = 16
+)
+
+type lastSeenEntry struct {
+	Key  string
+	Seen time.Time
+}
+
+// lastSeenShard is a fixed-size LRU of last-seen times.
+type lastSeenShard struct {
+	mu       sync.Mutex
+	capacity int
+	elements map[string]*list.Element
+	order    *list.List // Most recently used at the front.
+}
+
+func newLastSeenShard(capacity int) *lastSeenShard {
+	return &lastSeenShard{
+		capacity: capacity,
+		elements: make(map[string]*list.Element, capacity),
+		order:    list.New(),
+	}
+}
+
+// set stores seen for key as most recent entry, evicting the least recently used entry when full.
+func (s *lastSeenShard) set(key string, seen time.Time) {
+	if e, ok := s.elements[key]; ok {
+		e.Value.(*lastSeenEntry).Seen = seen
+		s.order.MoveToFront(e)
+		return
+	}
+
+	s.elements[key] = s.order.PushFront(&lastSeenEntry{key, seen})
+
+	if s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.elements, oldest.Value.(*lastSeenEntry).Key)
+	}
+}
 
 // LastSeenFilter filters out recently seen Providers.
+//
+// Memory use is bounded by Capacity; when it is exceeded, the least recently seen resources are forgotten.
+// Expiry is checked when a resource is encountered, so no scanning or pruning is required.
 type LastSeenFilter struct {
-	resources  map[string]time.Time
-	icount     uint // Iteration counter.
+	shards     [lastSeenShards]*lastSeenShard
+	icount     uint64 // Iteration counter.
 	Expiration time.Duration
-	PruneLen   int
+	Capacity   int
 }
 
-// NewLastSeenFilter initialises a new LastSeenFilter and returns a pointer to it.
-func NewLastSeenFilter(expiration time.Duration, pruneLen int) *LastSeenFilter {
-	// Allocate memory for pruneLen+1
-	r := make(map[string]time.Time, pruneLen+1)
-
-	return &LastSeenFilter{
+// NewLastSeenFilter initialises a new LastSeenFilter remembering up to capacity resources and returns a pointer to it.
+func NewLastSeenFilter(expiration time.Duration, capacity int) *LastSeenFilter {
+	f := &LastSeenFilter{
 		Expiration: expiration,
-		PruneLen:   pruneLen,
-		resources:  r,
+		Capacity:   capacity,
 	}
-}
 
-func (f *LastSeenFilter) prune() {
-	if len(f.resources) > f.PruneLen {
-		// Delete all expired items
-		now := time.Now()
-		cnt := 0
-
-		for i, t := range f.resources {
-			if now.Sub(t) > f.Expiration {
-				delete(f.resources, i)
-				cnt++
-			}
-		}
+	// Round up so that the total capacity is at least the requested capacity.
+	shardCapacity := (capacity + lastSeenShards - 1) / lastSeenShards
+	if shardCapacity < 1 {
+		shardCapacity = 1
+	}
 
-		log.Printf("Pruned %d resources, len: %d, pruneLen: %d", cnt, len(f.resources), f.PruneLen)
+	for i := range f.shards {
+		f.shards[i] = newLastSeenShard(shardCapacity)
 	}
+
+	return f
 }
 
-func (f *LastSeenFilter) shouldLog() bool {
-	return f.icount%logEvery == 0
+func (f *LastSeenFilter) shard(key string) *lastSeenShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+
+	return f.shards[h.Sum32()%lastSeenShards]
+}
+
+func (f *LastSeenFilter) shouldLog(icount uint64) bool {
+	return icount%logEvery == 0
+}
+
+// Len returns the amount of resources currently remembered.
+func (f *LastSeenFilter) Len() int {
+	l := 0
+
+	for _, s := range f.shards {
+		s.mu.Lock()
+		l += s.order.Len()
+		s.mu.Unlock()
+	}
+
+	return l
 }
 
 // Filter takes a Provider and returns true when it is to be included, false
 // when not and an error when unexpected condition occur.
 func (f *LastSeenFilter) Filter(p t.Provider) (bool, error) {
-	f.icount++
+	icount := atomic.AddUint64(&f.icount, 1)
 
-	f.prune()
+	key := p.Resource.String()
+	s := f.shard(key)
 
-	lastSeen, present := f.resources[p.Resource.String()]
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, present := s.elements[key]
 
 	if !present {
 		// Not present, add it!
-		if f.shouldLog() {
-			log.Printf("Adding LastSeen: %v, len: %d", p, len(f.resources))
+		if f.shouldLog(icount) {
+			log.Printf("Adding LastSeen: %v, len: %d", p, s.order.Len())
 		}
-		f.resources[p.Resource.String()] = p.Date
+		s.set(key, p.Date)
 
 		// Index it!
 		return true, nil
 	}
 
+	lastSeen := e.Value.(*lastSeenEntry).Seen
+
 	if p.Date.Sub(lastSeen) > f.Expiration {
 		// Last seen longer than expiration ago, update last seen.
-		if f.shouldLog() {
-			log.Printf("Updating LastSeen: %v, len: %d", p, len(f.resources))
+		if f.shouldLog(icount) {
+			log.Printf("Updating LastSeen: %v, len: %d", p, s.order.Len())
 		}
-
-		f.resources[p.Resource.String()] = p.Date
+		s.set(key, p.Date)
 
 		// Index it!
 		return true, nil
 	}
 
-	// Too recent, don't index
-	if f.shouldLog() {
+	// Too recent, don't index; but do keep it around as it is evidently popular.
+	s.order.MoveToFront(e)
+
+	if f.shouldLog(icount) {
 		log.Printf("Filtering recent %v, LastSeen %s", p, lastSeen)
 	}
 	return false, nil
 }
+
+// Save writes a snapshot of the filter's state to w.
+func (f *LastSeenFilter) Save(w io.Writer) error {
+	var entries []lastSeenEntry
+
+	for _, s := range f.shards {
+		s.mu.Lock()
+		// Oldest first, so that Load() restores the LRU order.
+		for e := s.order.Back(); e != nil; e = e.Prev() {
+			entries = append(entries, *e.Value.(*lastSeenEntry))
+		}
+		s.mu.Unlock()
+	}
+
+	return gob.NewEncoder(w).Encode(entries)
+}
+
+// Load restores a snapshot written by Save from r, skipping expired resources.
+func (f *LastSeenFilter) Load(r io.Reader) error {
+	var entries []lastSeenEntry
+
+	if err := gob.NewDecoder(r).Decode(&entries); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	cnt := 0
+
+	for _, entry := range entries {
+		if now.Sub(entry.Seen) > f.Expiration {
+			continue
+		}
+
+		s := f.shard(entry.Key)
+
+		s.mu.Lock()
+		s.set(entry.Key, entry.Seen)
+		s.mu.Unlock()
+
+		cnt++
+	}
+
+	log.Printf("Loaded %d of %d LastSeen resources from snapshot", cnt, len(entries))
+
+	return nil
+}
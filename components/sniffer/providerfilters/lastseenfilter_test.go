@@ -0,0 +1,106 @@
+package providerfilters
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfs-search/ipfs-search/types"
+)
+
+func TestLastSeenNew(t *testing.T) {
+	assert := assert.New(t)
+
+	f := NewLastSeenFilter(time.Hour, 10)
+	p := makeProvider(nil)
+
+	result, err := f.Filter(*p)
+
+	assert.NoError(err)
+	assert.True(result)
+	assert.Equal(1, f.Len())
+}
+
+func TestLastSeenRecent(t *testing.T) {
+	assert := assert.New(t)
+
+	f := NewLastSeenFilter(time.Hour, 10)
+	p := makeProvider(nil)
+
+	f.Filter(*p)
+
+	p.Date = p.Date.Add(time.Minute)
+	result, err := f.Filter(*p)
+
+	assert.NoError(err)
+	assert.False(result)
+}
+
+func TestLastSeenExpired(t *testing.T) {
+	assert := assert.New(t)
+
+	f := NewLastSeenFilter(time.Hour, 10)
+	p := makeProvider(nil)
+
+	f.Filter(*p)
+
+	p.Date = p.Date.Add(2 * time.Hour)
+	result, err := f.Filter(*p)
+
+	assert.NoError(err)
+	assert.True(result)
+	assert.Equal(1, f.Len())
+}
+
+func TestLastSeenBounded(t *testing.T) {
+	assert := assert.New(t)
+
+	capacity := 64
+	f := NewLastSeenFilter(time.Hour, capacity)
+
+	for i := 0; i < 100*capacity; i++ {
+		p := makeProvider(&types.Resource{
+			Protocol: types.IPFSProtocol,
+			ID:       fmt.Sprintf("resource-%d", i),
+		})
+
+		result, err := f.Filter(*p)
+		assert.NoError(err)
+		assert.True(result)
+	}
+
+	// Capacity is rounded up to a whole number of entries per shard.
+	assert.LessOrEqual(f.Len(), capacity+lastSeenShards)
+}
+
+func TestLastSeenSnapshot(t *testing.T) {
+	assert := assert.New(t)
+
+	f := NewLastSeenFilter(time.Hour, 10)
+
+	recent := makeProvider(nil)
+	expired := makeProvider(&types.Resource{
+		Protocol: types.IPFSProtocol,
+		ID:       "bafkreiblvqc3q73ygovlzaxz4iilm5fopppcdc3uzkrtepjsgkvyev3kgy",
+	})
+	expired.Date = expired.Date.Add(-2 * time.Hour)
+
+	f.Filter(*recent)
+	f.Filter(*expired)
+
+	var buf bytes.Buffer
+	assert.NoError(f.Save(&buf))
+
+	restored := NewLastSeenFilter(time.Hour, 10)
+	assert.NoError(restored.Load(&buf))
+
+	// Expired resources are not restored.
+	assert.Equal(1, restored.Len())
+
+	result, err := restored.Filter(*recent)
+	assert.NoError(err)
+	assert.False(result)
+}
@@ -0,0 +1,192 @@
+package providerfilters
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	"go.opentelemetry.io/otel/api/metric"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/ipfs-search/ipfs-search/instr"
+	t "github.com/ipfs-search/ipfs-search/types"
+)
+
+var errInvalidPeerList = errors.New("invalid peer list")
+
+// peerLists holds sets of allowed and denied peer ID's.
+type peerLists struct {
+	allow map[string]struct{}
+	deny  map[string]struct{}
+}
+
+// parsePeerLists reads peer lists, consisting of lines `allow <peer ID>` or `deny <peer ID>`.
+// Empty lines and lines starting with `#` are ignored.
+func parsePeerLists(r io.Reader) (*peerLists, error) {
+	l := &peerLists{
+		allow: make(map[string]struct{}),
+		deny:  make(map[string]struct{}),
+	}
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%w: line %d: expected `allow|deny <peer ID>`", errInvalidPeerList, n)
+		}
+
+		id, err := peer.Decode(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %s", errInvalidPeerList, n, err)
+		}
+
+		switch fields[0] {
+		case "allow":
+			l.allow[id.String()] = struct{}{}
+		case "deny":
+			l.deny[id.String()] = struct{}{}
+		default:
+			return nil, fmt.Errorf("%w: line %d: unknown action '%s'", errInvalidPeerList, n, fields[0])
+		}
+	}
+
+	return l, scanner.Err()
+}
+
+// PeerListFilter filters Providers by their peer ID, based on lists read from a file.
+//
+// Denied peers are always filtered out. When any peers are allowed, all other peers are filtered out as well.
+// A missing file results in empty lists; Watch() reloads the lists when the file changes.
+type PeerListFilter struct {
+	path string
+
+	mu      sync.RWMutex
+	lists   *peerLists
+	modTime time.Time
+	size    int64
+
+	dropped metric.Int64Counter
+}
+
+// NewPeerListFilter returns a pointer to a new PeerListFilter with lists from path, or an error when they are invalid.
+func NewPeerListFilter(path string, i *instr.Instrumentation) (*PeerListFilter, error) {
+	f := &PeerListFilter{
+		path:  path,
+		lists: &peerLists{},
+		dropped: metric.Must(i.Meter).NewInt64Counter("sniffer.providerfilters.peerlist.dropped",
+			metric.WithDescription("Providers dropped by the peer list, by peer and reason.")),
+	}
+
+	if _, err := f.Reload(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Reload reads the lists when the file has changed since the last load, returning whether it did.
+func (f *PeerListFilter) Reload() (bool, error) {
+	file, err := os.Open(f.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			f.set(&peerLists{}, time.Time{}, 0)
+			return false, nil
+		}
+
+		return false, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	f.mu.RLock()
+	unchanged := info.ModTime().Equal(f.modTime) && info.Size() == f.size
+	f.mu.RUnlock()
+
+	if unchanged {
+		return false, nil
+	}
+
+	lists, err := parsePeerLists(file)
+	if err != nil {
+		return false, fmt.Errorf("reading %s: %w", f.path, err)
+	}
+
+	f.set(lists, info.ModTime(), info.Size())
+
+	log.Printf("Loaded peer list %s: %d allowed, %d denied", f.path, len(lists.allow), len(lists.deny))
+
+	return true, nil
+}
+
+func (f *PeerListFilter) set(lists *peerLists, modTime time.Time, size int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.lists = lists
+	f.modTime = modTime
+	f.size = size
+}
+
+// Watch reloads the lists every interval until the context is closed. On errors, the previous lists are retained.
+func (f *PeerListFilter) Watch(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if _, err := f.Reload(); err != nil {
+				log.Printf("Error reloading peer list: %s", err)
+			}
+		}
+	}
+}
+
+// Filter takes a Provider and returns true when it is to be included, false
+// when not and an error when unexpected condition occur.
+func (f *PeerListFilter) Filter(p t.Provider) (bool, error) {
+	if p.Provider == "" {
+		// Resources without a known provider, e.g. from IPNS records, are not filtered by peer.
+		return true, nil
+	}
+
+	f.mu.RLock()
+	lists := f.lists
+	f.mu.RUnlock()
+
+	if _, denied := lists.deny[p.Provider]; denied {
+		f.dropped.Add(context.Background(), 1, label.String("peer", p.Provider), label.String("reason", "denied"))
+		return false, nil
+	}
+
+	if len(lists.allow) == 0 {
+		return true, nil
+	}
+
+	if _, allowed := lists.allow[p.Provider]; !allowed {
+		// Not labelled by peer, as these are all but the allowed peers.
+		f.dropped.Add(context.Background(), 1, label.String("reason", "not allowed"))
+		return false, nil
+	}
+
+	return true, nil
+}
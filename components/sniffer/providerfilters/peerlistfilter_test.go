@@ -0,0 +1,168 @@
+package providerfilters
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ipfs-search/ipfs-search/instr"
+)
+
+const (
+	testPeer  = "QmeTtFXm42Jb2todcKR538j6qHYxXt6suUzpF3rtT9FPSd"
+	otherPeer = "12D3KooWJnoRKwJdemEEqcuaTF1nq8ZpBJuNQSwfxdttgG9Cb9RG"
+)
+
+func writePeerList(t *testing.T, path string, lines ...string) {
+	require.NoError(t, ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644))
+}
+
+func TestParsePeerLists(t *testing.T) {
+	assert := assert.New(t)
+
+	l, err := parsePeerLists(strings.NewReader(`
+# Spammer
+deny ` + testPeer + `
+allow ` + otherPeer + `
+`))
+
+	assert.NoError(err)
+	assert.Contains(l.deny, testPeer)
+	assert.Contains(l.allow, otherPeer)
+}
+
+func TestParsePeerListsInvalid(t *testing.T) {
+	for _, list := range []string{
+		"deny",
+		"deny invalid",
+		"block " + testPeer,
+		"deny " + testPeer + " " + otherPeer,
+	} {
+		_, err := parsePeerLists(strings.NewReader(list))
+		assert.ErrorIs(t, err, errInvalidPeerList, list)
+	}
+}
+
+func TestPeerListMissingFile(t *testing.T) {
+	assert := assert.New(t)
+
+	f, err := NewPeerListFilter(filepath.Join(t.TempDir(), "missing"), instr.New())
+	require.NoError(t, err)
+
+	result, err := f.Filter(*makeProvider(nil))
+	assert.NoError(err)
+	assert.True(result)
+}
+
+func TestPeerListInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "peers")
+	writePeerList(t, path, "invalid")
+
+	_, err := NewPeerListFilter(path, instr.New())
+	assert.ErrorIs(t, err, errInvalidPeerList)
+}
+
+func TestPeerListDeny(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "peers")
+	writePeerList(t, path, "deny "+testPeer)
+
+	f, err := NewPeerListFilter(path, instr.New())
+	require.NoError(t, err)
+
+	p := makeProvider(nil)
+
+	result, err := f.Filter(*p)
+	assert.NoError(err)
+	assert.False(result)
+
+	p.Provider = otherPeer
+	result, err = f.Filter(*p)
+	assert.NoError(err)
+	assert.True(result)
+}
+
+func TestPeerListAllow(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "peers")
+	writePeerList(t, path, "allow "+otherPeer)
+
+	f, err := NewPeerListFilter(path, instr.New())
+	require.NoError(t, err)
+
+	p := makeProvider(nil)
+
+	result, err := f.Filter(*p)
+	assert.NoError(err)
+	assert.False(result)
+
+	p.Provider = otherPeer
+	result, err = f.Filter(*p)
+	assert.NoError(err)
+	assert.True(result)
+}
+
+func TestPeerListReload(t *testing.T) {
+	assert := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "peers")
+	writePeerList(t, path, "# Nothing yet")
+
+	f, err := NewPeerListFilter(path, instr.New())
+	require.NoError(t, err)
+
+	p := makeProvider(nil)
+
+	result, _ := f.Filter(*p)
+	assert.True(result)
+
+	// Unchanged file is not reloaded
+	reloaded, err := f.Reload()
+	assert.NoError(err)
+	assert.False(reloaded)
+
+	writePeerList(t, path, "deny "+testPeer)
+
+	reloaded, err = f.Reload()
+	assert.NoError(err)
+	assert.True(reloaded)
+
+	result, _ = f.Filter(*p)
+	assert.False(result)
+
+	// Invalid lists are not loaded
+	writePeerList(t, path, "invalid list")
+
+	_, err = f.Reload()
+	assert.Error(err)
+
+	result, _ = f.Filter(*p)
+	assert.False(result)
+}
+
+func TestPeerListWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "peers")
+
+	f, err := NewPeerListFilter(path, instr.New())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go f.Watch(ctx, time.Millisecond)
+
+	writePeerList(t, path, "deny "+testPeer)
+
+	assert.Eventually(t, func() bool {
+		result, _ := f.Filter(*makeProvider(nil))
+		return !result
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,165 @@
+package providerfilters
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/api/metric"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/ipfs-search/ipfs-search/instr"
+	t "github.com/ipfs-search/ipfs-search/types"
+)
+
+const (
+	// peerRateShards is the number of independently locked shards in a PeerRateFilter.
+	peerRateShards = 16
+
+	// peerRatePruneEvery is the number of announcements to a shard after which idle peers are forgotten.
+	peerRatePruneEvery = 10000
+)
+
+// peerRate approximates a sliding window count of a peer's announcements from the counts in the current and
+// previous fixed window.
+type peerRate struct {
+	start    time.Time // Start of the current window.
+	previous int
+	current  int
+	limited  bool
+}
+
+// add counts an announcement at now and returns the estimated number of announcements within the last window.
+func (r *peerRate) add(now time.Time, window time.Duration) float64 {
+	if elapsed := now.Sub(r.start); elapsed >= window {
+		windows := elapsed / window
+		if windows == 1 {
+			r.previous = r.current
+		} else {
+			r.previous = 0
+		}
+
+		r.current = 0
+		r.start = r.start.Add(windows * window)
+	}
+
+	r.current++
+
+	// Weigh the previous window by the part of it which falls within the sliding window.
+	weight := 1 - float64(now.Sub(r.start))/float64(window)
+
+	return float64(r.previous)*weight + float64(r.current)
+}
+
+type peerRateShard struct {
+	mu    sync.Mutex
+	peers map[string]*peerRate
+	count int // Announcements since last prune.
+}
+
+// prune forgets peers which have been idle for at least two windows, as their estimate would be 0.
+func (s *peerRateShard) prune(now time.Time, window time.Duration) {
+	for id, r := range s.peers {
+		if now.Sub(r.start) >= 2*window {
+			delete(s.peers, id)
+		}
+	}
+}
+
+// PeerRateFilter filters out Providers from peers announcing more than Limit resources in a sliding Window.
+// All announcements are counted, including the filtered out ones, so peers are limited for as long as they keep
+// announcing excessively.
+type PeerRateFilter struct {
+	shards [peerRateShards]*peerRateShard
+	now    func() time.Time
+
+	Window time.Duration
+	Limit  int
+
+	dropped metric.Int64Counter
+}
+
+// NewPeerRateFilter returns a pointer to a new PeerRateFilter.
+func NewPeerRateFilter(window time.Duration, limit int, i *instr.Instrumentation) *PeerRateFilter {
+	f := &PeerRateFilter{
+		now:    time.Now,
+		Window: window,
+		Limit:  limit,
+	}
+
+	for n := range f.shards {
+		f.shards[n] = &peerRateShard{
+			peers: make(map[string]*peerRate),
+		}
+	}
+
+	m := metric.Must(i.Meter)
+	f.dropped = m.NewInt64Counter("sniffer.providerfilters.peerrate.dropped",
+		metric.WithDescription("Providers dropped for exceeding the per-peer rate limit, by peer."))
+	m.NewInt64ValueObserver("sniffer.providerfilters.peerrate.limited", func(_ context.Context, r metric.Int64ObserverResult) {
+		r.Observe(int64(f.Limited()))
+	}, metric.WithDescription("Number of peers currently exceeding the per-peer rate limit."))
+
+	return f
+}
+
+func (f *PeerRateFilter) shard(id string) *peerRateShard {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return f.shards[h.Sum32()%peerRateShards]
+}
+
+// Limited returns the number of peers currently exceeding the limit.
+func (f *PeerRateFilter) Limited() int {
+	var n int
+
+	for _, s := range f.shards {
+		s.mu.Lock()
+		for _, r := range s.peers {
+			if r.limited {
+				n++
+			}
+		}
+		s.mu.Unlock()
+	}
+
+	return n
+}
+
+// Filter takes a Provider and returns true when it is to be included, false
+// when not and an error when unexpected condition occur.
+func (f *PeerRateFilter) Filter(p t.Provider) (bool, error) {
+	if p.Provider == "" {
+		// Resources without a known provider, e.g. from IPNS records, are not filtered by peer.
+		return true, nil
+	}
+
+	now := f.now()
+	s := f.shard(p.Provider)
+
+	s.mu.Lock()
+
+	if s.count++; s.count >= peerRatePruneEvery {
+		s.prune(now, f.Window)
+		s.count = 0
+	}
+
+	r, ok := s.peers[p.Provider]
+	if !ok {
+		r = &peerRate{start: now}
+		s.peers[p.Provider] = r
+	}
+
+	r.limited = r.add(now, f.Window) > float64(f.Limit)
+	limited := r.limited
+
+	s.mu.Unlock()
+
+	if limited {
+		f.dropped.Add(context.Background(), 1, label.String("peer", p.Provider))
+		return false, nil
+	}
+
+	return true, nil
+}
@@ -0,0 +1,89 @@
+package providerfilters
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ipfs-search/ipfs-search/instr"
+)
+
+func newTestPeerRateFilter(now *time.Time) *PeerRateFilter {
+	f := NewPeerRateFilter(time.Minute, 10, instr.New())
+	f.now = func() time.Time { return *now }
+	return f
+}
+
+func TestPeerRateLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Now()
+	f := newTestPeerRateFilter(&now)
+	p := makeProvider(nil)
+
+	for i := 0; i < 10; i++ {
+		result, err := f.Filter(*p)
+		assert.NoError(err)
+		assert.True(result)
+	}
+
+	result, err := f.Filter(*p)
+	assert.NoError(err)
+	assert.False(result)
+	assert.Equal(1, f.Limited())
+
+	// Other peers are unaffected
+	p.Provider = otherPeer
+	result, err = f.Filter(*p)
+	assert.NoError(err)
+	assert.True(result)
+}
+
+func TestPeerRateSlidingWindow(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Now()
+	f := newTestPeerRateFilter(&now)
+	p := makeProvider(nil)
+
+	for i := 0; i < 10; i++ {
+		f.Filter(*p)
+	}
+
+	// Halfway into the next window, half of the previous window's announcements count.
+	now = now.Add(90 * time.Second)
+
+	for i := 0; i < 5; i++ {
+		result, _ := f.Filter(*p)
+		assert.True(result, fmt.Sprintf("announcement %d", i))
+	}
+
+	result, _ := f.Filter(*p)
+	assert.False(result)
+
+	// After two idle windows, the peer is forgotten.
+	now = now.Add(2 * time.Minute)
+
+	result, _ = f.Filter(*p)
+	assert.True(result)
+	assert.Equal(0, f.Limited())
+}
+
+func TestPeerRatePrune(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Now()
+	s := &peerRateShard{
+		peers: map[string]*peerRate{
+			"idle":   {start: now.Add(-2 * time.Minute)},
+			"recent": {start: now.Add(-90 * time.Second)},
+		},
+	}
+
+	s.prune(now, time.Minute)
+
+	assert.NotContains(s.peers, "idle")
+	assert.Contains(s.peers, "recent")
+}
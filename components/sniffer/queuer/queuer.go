@@ -2,6 +2,7 @@ package queuer
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"go.opentelemetry.io/otel/api/trace"
@@ -14,33 +15,41 @@ import (
 	t "github.com/ipfs-search/ipfs-search/types"
 )
 
+var errClosed = errors.New("providers channel closed")
+
 // Queuer publishes an AnnotatedResource to a queue Publisher for Provider's it receives on a channel.
 type Queuer struct {
 	queue        queue.Publisher
 	providers    <-chan t.Provider
+	throttle     *Throttle
 	queueTimeout time.Duration
 	*instr.Instrumentation
 }
 
-// New creates a new Queuer.
-func New(q queue.Publisher, providers <-chan t.Provider) Queuer {
+// New creates a new Queuer. When throttle is nil, providers are published as fast as they arrive.
+func New(q queue.Publisher, providers <-chan t.Provider, throttle *Throttle) Queuer {
 	return Queuer{
 		queue:           q,
 		providers:       providers,
+		throttle:        throttle,
 		queueTimeout:    5 * time.Minute, // Kamikaze after 5 minutes of waiting
 		Instrumentation: instr.New(),
 	}
 }
 
-func (q *Queuer) iterate(ctx context.Context) error {
+func (q *Queuer) iterate(parentCtx context.Context) error {
 	// Never wait more than queueTimeout for a message
-	ctx, cancel := context.WithTimeout(ctx, q.queueTimeout)
+	ctx, cancel := context.WithTimeout(parentCtx, q.queueTimeout)
 	defer cancel()
 
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
-	case p := <-q.providers:
+	case p, ok := <-q.providers:
+		if !ok {
+			return errClosed
+		}
+
 		return func() error {
 			ctx = trace.ContextWithRemoteSpanContext(ctx, p.SpanContext)
 			_, span := q.Tracer.Start(ctx, "queue.Publish", trace.WithAttributes(
@@ -49,6 +58,20 @@ func (q *Queuer) iterate(ctx context.Context) error {
 			), trace.WithSpanKind(trace.SpanKindProducer))
 			defer span.End()
 
+			if q.throttle != nil {
+				// Waiting for the queue to drain is not bound by queueTimeout.
+				allow, err := q.throttle.Allow(parentCtx)
+				if err != nil {
+					span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+					return err
+				}
+
+				if !allow {
+					span.SetStatus(codes.Ok, "dropped")
+					return nil
+				}
+			}
+
 			// TODO: Queue provider here, not AnnotatedResource.
 
 			r := t.AnnotatedResource{
@@ -70,10 +93,14 @@ func (q *Queuer) iterate(ctx context.Context) error {
 	}
 }
 
-// Queue reads from the providers channel and queue's its items.
+// Queue reads from the providers channel and queue's its items, until the channel is closed.
 func (q *Queuer) Queue(ctx context.Context) error {
 	for {
 		if err := q.iterate(ctx); err != nil {
+			if errors.Is(err, errClosed) {
+				return nil
+			}
+
 			return err
 		}
 	}
@@ -43,7 +43,7 @@ func (s *QueuerTestSuite) TestQueueContextCancel() {
 	// Cancel context immediately
 	s.cancel()
 
-	pq := New(s.q, ch)
+	pq := New(s.q, ch, nil)
 
 	err := pq.Queue(s.ctx)
 
@@ -66,7 +66,7 @@ func (s *QueuerTestSuite) TestQueuePublish() {
 		s.cancel()
 	}()
 
-	pq := New(s.q, ch)
+	pq := New(s.q, ch, nil)
 	err := pq.Queue(s.ctx)
 
 	s.Equal(err, context.Canceled)
@@ -90,7 +90,7 @@ func (s *QueuerTestSuite) TestQueueError() {
 		s.cancel()
 	}()
 
-	pq := New(s.q, ch)
+	pq := New(s.q, ch, nil)
 	err := pq.Queue(s.ctx)
 
 	s.True(errors.Is(err, mockErr))
@@ -98,6 +98,22 @@ func (s *QueuerTestSuite) TestQueueError() {
 	s.q.AssertExpectations(s.T())
 }
 
+// TestQueueClosed tests whether Queue returns without error when the providers channel is closed
+func (s *QueuerTestSuite) TestQueueClosed() {
+	s.q.On("Publish", mock.Anything, s.r, uint8(9)).Return(nil)
+
+	ch := make(chan t.Provider, 1)
+	ch <- s.p
+	close(ch)
+
+	pq := New(s.q, ch, nil)
+	err := pq.Queue(s.ctx)
+
+	s.NoError(err)
+
+	s.q.AssertExpectations(s.T())
+}
+
 func TestQueuerTestSuite(t *testing.T) {
 	suite.Run(t, new(QueuerTestSuite))
 }
@@ -0,0 +1,276 @@
+package queuer
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/api/metric"
+	"go.opentelemetry.io/otel/label"
+	"golang.org/x/time/rate"
+
+	"github.com/ipfs-search/ipfs-search/components/queue"
+	"github.com/ipfs-search/ipfs-search/instr"
+)
+
+// State represents the state of a Throttle.
+type State int32
+
+const (
+	// NormalState means providers are published as they arrive.
+	NormalState State = iota
+	// ThrottledState means publishing is slowed down by the rate limit.
+	ThrottledState
+	// PausedState means the queue is above its high-water mark; publishing blocks until it has drained.
+	PausedState
+	// SamplingState means overload has been sustained; only a fraction of providers is considered for publishing.
+	SamplingState
+)
+
+func (s State) String() string {
+	switch s {
+	case NormalState:
+		return "normal"
+	case ThrottledState:
+		return "throttled"
+	case PausedState:
+		return "paused"
+	case SamplingState:
+		return "sampling"
+	default:
+		panic("Invalid value for State.")
+	}
+}
+
+// ThrottleConfig configures a Throttle.
+type ThrottleConfig struct {
+	Rate           float64       // Maximum sustained publishing rate per second.
+	Burst          int           // Maximum burst of publishes.
+	HighWater      int           // Pause publishing when the queue holds more messages than this.
+	ProbeInterval  time.Duration // Interval between queue depth probes.
+	OverloadWindow time.Duration // Start sampling after being overloaded for this long, stop after not being overloaded for this long.
+	SampleRatio    float64       // Fraction of providers considered for publishing while sampling.
+}
+
+// Throttle provides rate limiting and backpressure for publishing to a queue.
+type Throttle struct {
+	cfg     *ThrottleConfig
+	limiter *rate.Limiter
+
+	mu            sync.Mutex
+	sizer         queue.Sizer
+	state         State
+	depth         int       // Last probed queue depth.
+	probed        time.Time // Time of last queue depth probe.
+	overloaded    time.Time // Start of current overload, zero when not overloaded.
+	notOverloaded time.Time // Start of current non-overload, zero when overloaded.
+	sampling      bool
+
+	allowed metric.Int64Counter
+	dropped metric.Int64Counter
+
+	*instr.Instrumentation
+}
+
+// NewThrottle returns a new Throttle, registering its metrics. When sizer is nil, the queue depth is not probed.
+// As metrics are only registered once, a Throttle should outlive the publishers it throttles; see SetSizer.
+func NewThrottle(cfg *ThrottleConfig, sizer queue.Sizer, i *instr.Instrumentation) *Throttle {
+	t := &Throttle{
+		cfg:             cfg,
+		limiter:         rate.NewLimiter(rate.Limit(cfg.Rate), cfg.Burst),
+		sizer:           sizer,
+		Instrumentation: i,
+	}
+
+	m := metric.Must(i.Meter)
+	t.allowed = m.NewInt64Counter("sniffer.queuer.allowed",
+		metric.WithDescription("Providers allowed to be published."))
+	t.dropped = m.NewInt64Counter("sniffer.queuer.dropped",
+		metric.WithDescription("Providers dropped due to sustained overload."))
+	m.NewInt64ValueObserver("sniffer.queuer.state", func(_ context.Context, r metric.Int64ObserverResult) {
+		r.Observe(int64(t.State()))
+	}, metric.WithDescription("Throttle state: 0 normal, 1 throttled, 2 paused, 3 sampling."))
+	m.NewInt64ValueObserver("sniffer.queuer.queue_depth", func(_ context.Context, r metric.Int64ObserverResult) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		r.Observe(int64(t.depth))
+	}, metric.WithDescription("Last probed depth of the queue."))
+
+	return t
+}
+
+// SetSizer sets the sizer probing the depth of the queue, e.g. when publishing to a new publisher, after which the
+// depth is probed right away. When sizer is nil, the queue depth is not probed.
+func (t *Throttle) SetSizer(sizer queue.Sizer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sizer = sizer
+	t.depth = 0
+	t.probed = time.Time{}
+}
+
+// State returns the current state of the Throttle.
+func (t *Throttle) State() State {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.state
+}
+
+func (t *Throttle) setState(s State) {
+	if s != t.state {
+		log.Printf("Queuer throttle state changed from %s to %s", t.state, s)
+		t.state = s
+	}
+}
+
+// probe updates the queue depth when it is stale, returning whether the queue is above its high-water mark. The lock
+// is not held while probing, concurrent callers use the last probed depth meanwhile.
+func (t *Throttle) probe(ctx context.Context, now time.Time) bool {
+	t.mu.Lock()
+	sizer := t.sizer
+	stale := sizer != nil && now.Sub(t.probed) >= t.cfg.ProbeInterval
+	if stale {
+		t.probed = now
+	}
+	t.mu.Unlock()
+
+	if sizer == nil {
+		return false
+	}
+
+	if stale {
+		depth, err := sizer.Size(ctx)
+
+		t.mu.Lock()
+		if err != nil {
+			// Don't pause on probe errors; the rate limit still applies.
+			log.Printf("Error probing queue depth: %s", err)
+		} else {
+			t.depth = depth
+		}
+		t.mu.Unlock()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.depth > t.cfg.HighWater
+}
+
+// waitDrained blocks while the queue is above its high-water mark, probing its depth every ProbeInterval.
+// An error is returned when the context is closed.
+func (t *Throttle) waitDrained(ctx context.Context) error {
+	for t.probe(ctx, time.Now()) {
+		t.mu.Lock()
+		t.updateState(time.Now(), true, false)
+		t.mu.Unlock()
+
+		timer := time.NewTimer(t.cfg.ProbeInterval)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	return nil
+}
+
+// updateOverload keeps track of sustained overload, switching sampling on and off after OverloadWindow.
+func (t *Throttle) updateOverload(now time.Time, overloaded bool) {
+	if overloaded {
+		t.notOverloaded = time.Time{}
+		if t.overloaded.IsZero() {
+			t.overloaded = now
+		}
+
+		if !t.sampling && now.Sub(t.overloaded) >= t.cfg.OverloadWindow {
+			log.Printf("Overloaded for %s, sampling %f of providers", now.Sub(t.overloaded), t.cfg.SampleRatio)
+			t.sampling = true
+		}
+
+		return
+	}
+
+	t.overloaded = time.Time{}
+	if t.notOverloaded.IsZero() {
+		t.notOverloaded = now
+	}
+
+	if t.sampling && now.Sub(t.notOverloaded) >= t.cfg.OverloadWindow {
+		log.Printf("Overload over, stop sampling")
+		t.sampling = false
+	}
+}
+
+func (t *Throttle) drop(ctx context.Context, reason State) (bool, error) {
+	t.dropped.Add(ctx, 1, label.Stringer("reason", reason))
+	return false, nil
+}
+
+// updateState sets the state based on current conditions.
+func (t *Throttle) updateState(now time.Time, paused bool, throttled bool) {
+	t.updateOverload(now, paused || throttled)
+
+	switch {
+	case paused:
+		t.setState(PausedState)
+	case t.sampling:
+		t.setState(SamplingState)
+	case throttled:
+		t.setState(ThrottledState)
+	default:
+		t.setState(NormalState)
+	}
+}
+
+// Allow returns whether a provider should be published, waiting for the queue to drain below its high-water mark and
+// for the rate limit when necessary.
+// An error is returned when the context is closed.
+func (t *Throttle) Allow(ctx context.Context) (bool, error) {
+	if err := t.waitDrained(ctx); err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+
+	t.mu.Lock()
+	sampling := t.sampling
+	t.mu.Unlock()
+
+	if sampling && rand.Float64() >= t.cfg.SampleRatio {
+		return t.drop(ctx, SamplingState)
+	}
+
+	r := t.limiter.ReserveN(now, 1)
+	if !r.OK() {
+		panic("rate limit burst should be at least 1")
+	}
+	delay := r.DelayFrom(now)
+
+	t.mu.Lock()
+	t.updateState(now, false, delay > 0)
+	t.mu.Unlock()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			r.Cancel()
+			return false, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	t.allowed.Add(ctx, 1)
+
+	return true, nil
+}
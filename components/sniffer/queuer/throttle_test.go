@@ -0,0 +1,170 @@
+package queuer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/ipfs-search/ipfs-search/instr"
+)
+
+// stubSizer returns sizes in turn, repeating the last one.
+type stubSizer struct {
+	sizes []int
+	calls int
+}
+
+func (s *stubSizer) Size(context.Context) (int, error) {
+	s.calls++
+
+	if len(s.sizes) == 0 {
+		return 0, nil
+	}
+
+	if s.calls > len(s.sizes) {
+		return s.sizes[len(s.sizes)-1], nil
+	}
+
+	return s.sizes[s.calls-1], nil
+}
+
+type ThrottleTestSuite struct {
+	suite.Suite
+	ctx   context.Context
+	cfg   *ThrottleConfig
+	sizer *stubSizer
+}
+
+func (s *ThrottleTestSuite) SetupTest() {
+	s.ctx = context.Background()
+	s.cfg = &ThrottleConfig{
+		Rate:           1000,
+		Burst:          10,
+		HighWater:      100,
+		ProbeInterval:  time.Hour,
+		OverloadWindow: time.Hour,
+		SampleRatio:    0,
+	}
+	s.sizer = &stubSizer{}
+}
+
+func (s *ThrottleTestSuite) TestNormal() {
+	t := NewThrottle(s.cfg, s.sizer, instr.New())
+
+	allow, err := t.Allow(s.ctx)
+
+	s.NoError(err)
+	s.True(allow)
+	s.Equal(NormalState, t.State())
+}
+
+func (s *ThrottleTestSuite) TestNilSizer() {
+	t := NewThrottle(s.cfg, nil, instr.New())
+
+	allow, err := t.Allow(s.ctx)
+
+	s.NoError(err)
+	s.True(allow)
+}
+
+func (s *ThrottleTestSuite) TestPaused() {
+	s.cfg.ProbeInterval = time.Millisecond
+	s.sizer.sizes = []int{s.cfg.HighWater + 1, s.cfg.HighWater + 1, s.cfg.HighWater}
+	t := NewThrottle(s.cfg, s.sizer, instr.New())
+
+	// Blocks until the queue has drained below the high-water mark.
+	allow, err := t.Allow(s.ctx)
+
+	s.NoError(err)
+	s.True(allow)
+	s.Equal(3, s.sizer.calls)
+	s.Equal(NormalState, t.State())
+}
+
+func (s *ThrottleTestSuite) TestPausedProbeInterval() {
+	s.sizer.sizes = []int{0}
+	t := NewThrottle(s.cfg, s.sizer, instr.New())
+
+	t.Allow(s.ctx)
+	t.Allow(s.ctx)
+
+	// Queue depth is not probed more often than ProbeInterval.
+	s.Equal(1, s.sizer.calls)
+}
+
+func (s *ThrottleTestSuite) TestSetSizer() {
+	s.sizer.sizes = []int{0}
+	t := NewThrottle(s.cfg, nil, instr.New())
+
+	t.Allow(s.ctx)
+	t.SetSizer(s.sizer)
+	t.Allow(s.ctx)
+
+	// The new sizer is probed right away.
+	s.Equal(1, s.sizer.calls)
+}
+
+func (s *ThrottleTestSuite) TestPausedContextCancel() {
+	s.cfg.ProbeInterval = time.Millisecond
+	s.sizer.sizes = []int{s.cfg.HighWater + 1}
+	t := NewThrottle(s.cfg, s.sizer, instr.New())
+
+	ctx, cancel := context.WithTimeout(s.ctx, 10*time.Millisecond)
+	defer cancel()
+
+	allow, err := t.Allow(ctx)
+
+	s.ErrorIs(err, context.DeadlineExceeded)
+	s.False(allow)
+	s.Equal(PausedState, t.State())
+}
+
+func (s *ThrottleTestSuite) TestThrottled() {
+	s.cfg.Burst = 1
+	t := NewThrottle(s.cfg, s.sizer, instr.New())
+
+	t.Allow(s.ctx)
+	allow, err := t.Allow(s.ctx)
+
+	s.NoError(err)
+	s.True(allow)
+	s.Equal(ThrottledState, t.State())
+}
+
+func (s *ThrottleTestSuite) TestSampling() {
+	s.cfg.Burst = 1
+	s.cfg.OverloadWindow = 0
+	t := NewThrottle(s.cfg, s.sizer, instr.New())
+
+	// Exhaust burst, then get throttled, causing immediate sampling.
+	t.Allow(s.ctx)
+	t.Allow(s.ctx)
+	s.Equal(SamplingState, t.State())
+
+	// SampleRatio is 0; everything is dropped.
+	allow, err := t.Allow(s.ctx)
+
+	s.NoError(err)
+	s.False(allow)
+}
+
+func (s *ThrottleTestSuite) TestContextCancel() {
+	s.cfg.Rate = 0.001
+	s.cfg.Burst = 1
+	t := NewThrottle(s.cfg, s.sizer, instr.New())
+
+	t.Allow(s.ctx)
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	cancel()
+
+	_, err := t.Allow(ctx)
+
+	s.ErrorIs(err, context.Canceled)
+}
+
+func TestThrottleTestSuite(t *testing.T) {
+	suite.Run(t, new(ThrottleTestSuite))
+}
@@ -0,0 +1,12 @@
+package recorder
+
+import (
+	"time"
+)
+
+// Config holds configuration for a Recorder.
+type Config struct {
+	Dir             string        // Directory to write segments to
+	SegmentDuration time.Duration // Start a new segment after this long
+	Retention       time.Duration // Remove segments with entries older than this
+}
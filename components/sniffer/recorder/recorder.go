@@ -0,0 +1,154 @@
+/*
+Package recorder records sniffed Provider's to rotating, compressed JSONL segments and replays them.
+*/
+package recorder
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	t "github.com/ipfs-search/ipfs-search/types"
+)
+
+// Recorder appends Provider's to rotating segments.
+type Recorder struct {
+	cfg *Config
+
+	mu    sync.Mutex
+	f     *os.File
+	gz    *gzip.Writer
+	enc   *json.Encoder
+	start time.Time // Start of the current segment; zero when none is open.
+}
+
+// New creates a new Recorder, creating the segment directory when it does not exist.
+func New(cfg *Config) (*Recorder, error) {
+	if cfg == nil {
+		panic("Recorder.New Config cannot be nil.")
+	}
+
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &Recorder{cfg: cfg}, nil
+}
+
+// closeSegment closes the current segment, if any.
+func (r *Recorder) closeSegment() error {
+	if r.f == nil {
+		return nil
+	}
+
+	gzErr := r.gz.Close()
+	fErr := r.f.Close()
+
+	r.f, r.gz, r.enc, r.start = nil, nil, nil, time.Time{}
+
+	if gzErr != nil {
+		return gzErr
+	}
+
+	return fErr
+}
+
+// prune removes segments which only contain entries older than Retention.
+func (r *Recorder) prune(now time.Time) error {
+	segments, err := listSegments(r.cfg.Dir)
+	if err != nil {
+		return err
+	}
+
+	// A segment's entries are older than the start of the next segment.
+	for i := 0; i < len(segments)-1; i++ {
+		if now.Sub(segments[i+1].start) <= r.cfg.Retention {
+			break
+		}
+
+		log.Printf("Removing expired sniff log segment %s", segments[i].path)
+
+		if err := os.Remove(segments[i].path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rotate starts a new segment when there is none or the current one has lasted SegmentDuration.
+func (r *Recorder) rotate(now time.Time) error {
+	if r.f != nil && now.Sub(r.start) < r.cfg.SegmentDuration {
+		return nil
+	}
+
+	if err := r.closeSegment(); err != nil {
+		return fmt.Errorf("closing segment: %w", err)
+	}
+
+	path := filepath.Join(r.cfg.Dir, segmentName(now))
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("creating segment: %w", err)
+	}
+
+	r.f = f
+	r.gz = gzip.NewWriter(f)
+	r.enc = json.NewEncoder(r.gz)
+	r.start = now
+
+	if err := r.prune(now); err != nil {
+		// Failing to prune should not hamper recording.
+		log.Printf("Error pruning sniff log: %s", err)
+	}
+
+	return nil
+}
+
+// Write appends p to the current segment, rotating segments as required.
+func (r *Recorder) Write(p *t.Provider) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.rotate(time.Now()); err != nil {
+		return err
+	}
+
+	return r.enc.Encode(newEntry(p))
+}
+
+// Close closes the current segment, flushing it to disk.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.closeSegment()
+}
+
+// Record writes Provider's from in to out, recording them on the way, until the context is closed.
+// Failing to record does not hamper the flow of Provider's.
+func (r *Recorder) Record(ctx context.Context, in <-chan t.Provider, out chan<- t.Provider) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case p := <-in:
+			if err := r.Write(&p); err != nil {
+				log.Printf("Error recording %s: %s", &p, err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case out <- p:
+			}
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package recorder
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	t "github.com/ipfs-search/ipfs-search/types"
+)
+
+type RecorderTestSuite struct {
+	suite.Suite
+	ctx    context.Context
+	cancel func()
+	cfg    *Config
+}
+
+func (s *RecorderTestSuite) SetupTest() {
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.cfg = &Config{
+		Dir:             s.T().TempDir(),
+		SegmentDuration: time.Hour,
+		Retention:       24 * time.Hour,
+	}
+}
+
+func (s *RecorderTestSuite) TearDownTest() {
+	s.cancel()
+}
+
+func (s *RecorderTestSuite) replay(from, to time.Time) []t.Provider {
+	out := make(chan t.Provider, 100)
+	s.Require().NoError(Replay(context.Background(), s.cfg.Dir, from, to, out))
+	close(out)
+
+	var providers []t.Provider
+	for p := range out {
+		providers = append(providers, p)
+	}
+
+	return providers
+}
+
+// writeSegment writes a closed segment starting at start, containing providers.
+func (s *RecorderTestSuite) writeSegment(start time.Time, providers ...t.Provider) {
+	r, err := New(s.cfg)
+	s.Require().NoError(err)
+
+	r.mu.Lock()
+	s.Require().NoError(r.rotate(start))
+	for _, p := range providers {
+		s.Require().NoError(r.enc.Encode(newEntry(&p)))
+	}
+	r.mu.Unlock()
+
+	s.Require().NoError(r.Close())
+}
+
+func mockProvider(id string, date time.Time) t.Provider {
+	p := t.MockProvider()
+	p.ID = id
+	p.Date = date
+	return p
+}
+
+func (s *RecorderTestSuite) TestRecord() {
+	r, err := New(s.cfg)
+	s.Require().NoError(err)
+
+	in := make(chan t.Provider)
+	out := make(chan t.Provider)
+
+	go r.Record(s.ctx, in, out)
+
+	p := t.MockProvider()
+	in <- p
+	s.Equal(p, <-out)
+
+	s.cancel()
+	s.NoError(r.Close())
+
+	providers := s.replay(p.Date.Add(-time.Second), p.Date.Add(time.Second))
+	s.Require().Len(providers, 1)
+	s.Equal(p.ID, providers[0].ID)
+	s.Equal(p.Provider, providers[0].Provider)
+	s.Equal(t.IPFSProtocol, providers[0].Protocol)
+	s.True(p.Date.Equal(providers[0].Date))
+}
+
+func (s *RecorderTestSuite) TestRotate() {
+	r, err := New(s.cfg)
+	s.Require().NoError(err)
+
+	now := time.Now()
+
+	r.mu.Lock()
+	s.NoError(r.rotate(now))
+	s.NoError(r.rotate(now.Add(time.Minute)))
+	s.NoError(r.rotate(now.Add(time.Hour)))
+	r.mu.Unlock()
+
+	s.NoError(r.Close())
+
+	segments, err := listSegments(s.cfg.Dir)
+	s.NoError(err)
+	s.Len(segments, 2)
+}
+
+func (s *RecorderTestSuite) TestPrune() {
+	now := time.Now()
+
+	s.writeSegment(now.Add(-72 * time.Hour))
+	s.writeSegment(now.Add(-48 * time.Hour))
+	s.writeSegment(now.Add(-12 * time.Hour))
+
+	r, err := New(s.cfg)
+	s.Require().NoError(err)
+	s.Require().NoError(r.prune(now))
+
+	segments, err := listSegments(s.cfg.Dir)
+	s.NoError(err)
+
+	// The segment started 48 hours ago may contain entries up to 12 hours ago.
+	s.Require().Len(segments, 2)
+	s.True(segments[0].start.Equal(now.Add(-48 * time.Hour)))
+}
+
+func (s *RecorderTestSuite) TestReplayRange() {
+	start := time.Now().Add(-3 * time.Hour).Truncate(time.Hour)
+
+	s.writeSegment(start,
+		mockProvider("a", start.Add(10*time.Minute)),
+		mockProvider("b", start.Add(50*time.Minute)),
+	)
+	s.writeSegment(start.Add(time.Hour),
+		mockProvider("c", start.Add(70*time.Minute)),
+		mockProvider("d", start.Add(110*time.Minute)),
+	)
+	s.writeSegment(start.Add(2*time.Hour),
+		mockProvider("e", start.Add(130*time.Minute)),
+	)
+
+	providers := s.replay(start.Add(50*time.Minute), start.Add(110*time.Minute))
+
+	var ids []string
+	for _, p := range providers {
+		ids = append(ids, p.ID)
+	}
+
+	s.Equal([]string{"b", "c"}, ids)
+}
+
+func (s *RecorderTestSuite) TestReplayTruncated() {
+	start := time.Now().Add(-time.Hour)
+
+	s.writeSegment(start,
+		mockProvider("a", start.Add(time.Minute)),
+		mockProvider("b", start.Add(2*time.Minute)),
+	)
+
+	// Cut the segment short, as if the recorder crashed.
+	path := filepath.Join(s.cfg.Dir, segmentName(start))
+	b, err := ioutil.ReadFile(path)
+	s.Require().NoError(err)
+	s.Require().NoError(ioutil.WriteFile(path, b[:len(b)-10], 0644))
+
+	providers := s.replay(start, time.Now())
+	s.NotEmpty(providers)
+	s.Equal("a", providers[0].ID)
+}
+
+func (s *RecorderTestSuite) TestReplayIgnoresOtherFiles() {
+	s.Require().NoError(ioutil.WriteFile(filepath.Join(s.cfg.Dir, "README"), []byte("hi"), 0644))
+	s.Require().NoError(os.Mkdir(filepath.Join(s.cfg.Dir, "sub"), 0755))
+
+	s.Empty(s.replay(time.Time{}, time.Now()))
+}
+
+func TestRecorderTestSuite(t *testing.T) {
+	suite.Run(t, new(RecorderTestSuite))
+}
@@ -0,0 +1,86 @@
+package recorder
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	t "github.com/ipfs-search/ipfs-search/types"
+)
+
+// replaySegment writes the Provider's in a segment, recorded from `from` until `to`, to out.
+func replaySegment(ctx context.Context, path string, from, to time.Time, out chan<- t.Provider) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(bufio.NewReader(f))
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	dec := json.NewDecoder(gz)
+
+	for {
+		var e Entry
+
+		if err := dec.Decode(&e); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				// Segment was not closed properly, e.g. because of a crash.
+				log.Printf("Truncated sniff log segment %s", path)
+				return nil
+			}
+
+			return err
+		}
+
+		if e.Date.Before(from) || !e.Date.Before(to) {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case out <- e.Provider():
+		}
+	}
+}
+
+// Replay writes the Provider's recorded in dir from `from` until `to`, ordered by time, to out.
+func Replay(ctx context.Context, dir string, from, to time.Time, out chan<- t.Provider) error {
+	segments, err := listSegments(dir)
+	if err != nil {
+		return err
+	}
+
+	for i, s := range segments {
+		if !s.start.Before(to) {
+			break
+		}
+
+		// A segment's entries are older than the start of the next segment.
+		if i+1 < len(segments) && !segments[i+1].start.After(from) {
+			continue
+		}
+
+		if err := replaySegment(ctx, s.path, from, to, out); err != nil {
+			return fmt.Errorf("replaying %s: %w", s.path, err)
+		}
+	}
+
+	return nil
+}
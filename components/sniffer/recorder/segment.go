@@ -0,0 +1,96 @@
+package recorder
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	t "github.com/ipfs-search/ipfs-search/types"
+)
+
+const (
+	segmentPrefix     = "sniff-"
+	segmentSuffix     = ".jsonl.gz"
+	segmentTimeFormat = "20060102T150405.000000000Z"
+)
+
+// Entry is the representation of a Provider in a segment.
+type Entry struct {
+	Date   time.Time `json:"date"`
+	CID    string    `json:"cid"`
+	PeerID string    `json:"provider"`
+}
+
+func newEntry(p *t.Provider) *Entry {
+	return &Entry{
+		Date:   p.Date,
+		CID:    p.ID,
+		PeerID: p.Provider,
+	}
+}
+
+// Provider returns the Provider represented by the Entry.
+func (e *Entry) Provider() t.Provider {
+	return t.Provider{
+		Resource: &t.Resource{
+			Protocol: t.IPFSProtocol,
+			ID:       e.CID,
+		},
+		Date:     e.Date,
+		Provider: e.PeerID,
+	}
+}
+
+// segment is a file containing the gzip-compressed JSONL Entries recorded from its start time on.
+type segment struct {
+	path  string
+	start time.Time
+}
+
+func segmentName(start time.Time) string {
+	return segmentPrefix + start.UTC().Format(segmentTimeFormat) + segmentSuffix
+}
+
+func parseSegmentName(name string) (time.Time, error) {
+	if !strings.HasPrefix(name, segmentPrefix) || !strings.HasSuffix(name, segmentSuffix) {
+		return time.Time{}, fmt.Errorf("not a segment: %s", name)
+	}
+
+	return time.Parse(segmentTimeFormat, name[len(segmentPrefix):len(name)-len(segmentSuffix)])
+}
+
+// listSegments returns the segments in dir, ordered by start time.
+func listSegments(dir string) ([]segment, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []segment
+
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+
+		start, err := parseSegmentName(f.Name())
+		if err != nil {
+			// Ignore unrelated files
+			continue
+		}
+
+		segments = append(segments, segment{
+			path:  filepath.Join(dir, f.Name()),
+			start: start,
+		})
+	}
+
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].start.Before(segments[j].start)
+	})
+
+	return segments, nil
+}
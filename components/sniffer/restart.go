@@ -0,0 +1,115 @@
+package sniffer
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jpillora/backoff"
+)
+
+// ErrCircuitOpen is returned by Sniff when sniffing failed too often in a row to be worth restarting.
+var ErrCircuitOpen = errors.New("sniffer circuit breaker open")
+
+// BreakerState is an enum of the states of the Sniffer's circuit breaker.
+type BreakerState uint8
+
+const (
+	// ClosedState means the Sniffer is running normally.
+	ClosedState BreakerState = iota
+	// RestartingState means the Sniffer failed and is waiting to be restarted.
+	RestartingState
+	// OpenState means the Sniffer failed too often in a row and has given up.
+	OpenState
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case ClosedState:
+		return "closed"
+	case RestartingState:
+		return "restarting"
+	case OpenState:
+		return "open"
+	default:
+		panic("Invalid value for BreakerState.")
+	}
+}
+
+// Status represents the restart state of a Sniffer.
+type Status struct {
+	State       BreakerState
+	Failures    int       // Consecutive failures.
+	Restarts    int       // Total restarts.
+	LastError   error     // Reason for the last restart.
+	NextRestart time.Time // Time of the next restart, when restarting.
+}
+
+// restarter implements a restart policy with exponential backoff and a circuit breaker.
+type restarter struct {
+	cfg     *Config
+	backoff backoff.Backoff
+
+	mu     sync.Mutex
+	status Status
+}
+
+func newRestarter(cfg *Config) *restarter {
+	return &restarter{
+		cfg: cfg,
+		backoff: backoff.Backoff{
+			Min:    cfg.RestartMinBackoff,
+			Max:    cfg.RestartMaxBackoff,
+			Factor: 2.0,
+			Jitter: true,
+		},
+	}
+}
+
+// fail registers a failure of a run which started at start, returning the time to wait before restarting or
+// ErrCircuitOpen when the breaker opens.
+func (r *restarter) fail(start time.Time, err error) (time.Duration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+
+	// Runs which lasted long enough are considered healthy; start counting again.
+	if now.Sub(start) >= r.cfg.BreakerResetAfter {
+		r.status.Failures = 0
+		r.backoff.Reset()
+	}
+
+	r.status.Failures++
+	r.status.LastError = err
+
+	if r.status.Failures >= r.cfg.BreakerThreshold {
+		r.status.State = OpenState
+		return 0, fmt.Errorf("%w after %d consecutive failures: %v", ErrCircuitOpen, r.status.Failures, err)
+	}
+
+	wait := r.backoff.Duration()
+
+	r.status.State = RestartingState
+	r.status.Restarts++
+	r.status.NextRestart = now.Add(wait)
+
+	return wait, nil
+}
+
+// started registers the start of a run.
+func (r *restarter) started() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.status.State = ClosedState
+	r.status.NextRestart = time.Time{}
+}
+
+func (r *restarter) getStatus() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.status
+}
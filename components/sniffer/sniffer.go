@@ -18,11 +18,13 @@ import (
 	"github.com/ipfs/go-datastore"
 	"github.com/libp2p/go-eventbus"
 
+	"github.com/ipfs-search/ipfs-search/components/index"
 	"github.com/ipfs-search/ipfs-search/components/queue"
 	"github.com/ipfs-search/ipfs-search/components/sniffer/eventsource"
 	"github.com/ipfs-search/ipfs-search/components/sniffer/handler"
 	filters "github.com/ipfs-search/ipfs-search/components/sniffer/providerfilters"
 	"github.com/ipfs-search/ipfs-search/components/sniffer/queuer"
+	"github.com/ipfs-search/ipfs-search/components/sniffer/recorder"
 	filter "github.com/ipfs-search/ipfs-search/components/sniffer/streamfilter"
 
 	"github.com/ipfs-search/ipfs-search/instr"
@@ -32,15 +34,26 @@ import (
 // Sniffer allows sniffing Batching datastore's events, effectively allowing sniffing of the IPFS DHT.
 // To effectively use the Sniffer, the proxied datastore needs to be acquired by calling `Batching()` on the Sniffer.
 type Sniffer struct {
-	cfg *Config
-	es  eventsource.EventSource
-	pub queue.PublisherFactory
+	cfg      *Config
+	es       eventsource.EventSource
+	pub      queue.PublisherFactory
+	names    index.Index
+	peerList *filters.PeerListFilter
+	peerRate *filters.PeerRateFilter
+	lastSeen *filters.LastSeenFilter
+	filters  []filters.Filter
+	throttle *queuer.Throttle // Outlives restarts, as its metrics are only registered once.
+
+	recorder  *recorder.Recorder
+	restarter *restarter
 
 	*instr.Instrumentation
 }
 
 // New creates a new Sniffer based on a datastore, or returns an error.
-func New(cfg *Config, ds datastore.Batching, pub queue.PublisherFactory, i *instr.Instrumentation) (*Sniffer, error) {
+// Sniffed IPNS names are recorded in names, unless it is nil.
+// Optional extra filters are applied after the built-in peer, last-seen and CID filters.
+func New(cfg *Config, ds datastore.Batching, pub queue.PublisherFactory, names index.Index, i *instr.Instrumentation, extraFilters ...filters.Filter) (*Sniffer, error) {
 	bus := eventbus.NewBus()
 
 	es, err := eventsource.New(bus, ds)
@@ -48,13 +61,40 @@ func New(cfg *Config, ds datastore.Batching, pub queue.PublisherFactory, i *inst
 		return nil, fmt.Errorf("failed to get eventsource: %w", err)
 	}
 
+	peerList, err := filters.NewPeerListFilter(cfg.PeerListFile, i)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get peer list: %w", err)
+	}
+
 	s := Sniffer{
 		cfg:             cfg,
 		es:              es,
 		pub:             pub,
+		names:           names,
+		peerList:        peerList,
+		peerRate:        filters.NewPeerRateFilter(cfg.PeerRateWindow, cfg.PeerRateLimit, i),
+		lastSeen:        filters.NewLastSeenFilter(cfg.LastSeenExpiration, cfg.LastSeenPruneLen),
+		filters:         extraFilters,
+		throttle:        queuer.NewThrottle(cfg.ThrottleConfig(), nil, i),
+		restarter:       newRestarter(cfg),
 		Instrumentation: i,
 	}
 
+	switch cfg.RecordMode {
+	case RecordNone:
+	case RecordRaw, RecordFiltered:
+		if s.recorder, err = recorder.New(cfg.RecorderConfig()); err != nil {
+			return nil, fmt.Errorf("failed to create recorder: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("invalid record mode: %s", cfg.RecordMode)
+	}
+
+	// The last-seen filter outlives restarts of the sniffer, so that it does not re-queue recent sightings.
+	if err := s.loadLastSeen(s.lastSeen); err != nil {
+		log.Printf("Ignoring error restoring LastSeen: %s", err)
+	}
+
 	return &s, nil
 }
 
@@ -68,8 +108,17 @@ func (s *Sniffer) subscribe(ctx context.Context, c chan<- t.Provider) error {
 	// defer span.End()
 
 	h := handler.New(c)
+	ih := handler.NewIPNS(c, s.names)
+
+	errg, ctx := errgroup.WithContext(ctx)
+	errg.Go(func() error { return s.es.Subscribe(ctx, h.HandleFunc) })
+	errg.Go(func() error { return s.es.SubscribeIPNS(ctx, ih.HandleFunc) })
+
+	if s.names != nil {
+		errg.Go(func() error { return ih.RecordNames(ctx) })
+	}
 
-	err := s.es.Subscribe(ctx, h.HandleFunc)
+	err := errg.Wait()
 	// span.RecordError(ctx, err)
 	// span.SetStatus(codes.Internal, err.Error())
 	return err
@@ -79,12 +128,19 @@ func (s *Sniffer) filter(ctx context.Context, in <-chan t.Provider, out chan<- t
 	// ctx, span := s.Tracer.Start(ctx, "sniffer.filter")
 	// defer span.End()
 
-	lastSeenFilter := filters.NewLastSeenFilter(s.cfg.LastSeenExpiration, s.cfg.LastSeenPruneLen)
 	cidFilter := filters.NewCidFilter()
-	mutliFilter := filters.NewMultiFilter(lastSeenFilter, cidFilter)
+	// Peer filters go first, so that junk does not push out other resources from the last-seen filter.
+	builtin := []filters.Filter{s.peerList, s.peerRate, s.lastSeen, cidFilter}
+	mutliFilter := filters.NewMultiFilter(append(builtin, s.filters...)...)
 	f := filter.New(mutliFilter, in, out)
 
-	err := f.Filter(ctx)
+	// Run filters concurrently, so that slow filters (e.g. index lookups) are batched rather than serialized.
+	errg, ctx := errgroup.WithContext(ctx)
+	for i := 0; i < s.cfg.FilterWorkers; i++ {
+		errg.Go(func() error { return f.Filter(ctx) })
+	}
+
+	err := errg.Wait()
 	// span.RecordError(ctx, err)
 	// span.SetStatus(codes.Internal, err.Error())
 	return err
@@ -99,7 +155,11 @@ func (s *Sniffer) queue(ctx context.Context, c <-chan t.Provider) error {
 		return err
 	}
 
-	q := queuer.New(publisher, c)
+	// Probe queue depth when supported by the publisher.
+	sizer, _ := publisher.(queue.Sizer)
+	s.throttle.SetSizer(sizer)
+
+	q := queuer.New(publisher, c, s.throttle)
 
 	err = q.Queue(ctx)
 	// span.RecordError(ctx, err)
@@ -107,15 +167,28 @@ func (s *Sniffer) queue(ctx context.Context, c <-chan t.Provider) error {
 	return err
 }
 
-func (s *Sniffer) iterate(ctx context.Context, sniffed, filtered chan t.Provider) error {
+func (s *Sniffer) iterate(ctx context.Context, sniffed, filtered, recorded chan t.Provider) error {
 	// ctx, span := s.Tracer.Start(ctx, "sniffer.iterate")
 	// defer span.End()
 
 	// Create error group and context
 	errg, ctx := errgroup.WithContext(ctx)
-	errg.Go(func() error { return s.subscribe(ctx, sniffed) })
+
+	// The recorder is inserted before or after filtering.
+	subscribed, queued := sniffed, filtered
+
+	switch s.cfg.RecordMode {
+	case RecordRaw:
+		subscribed = recorded
+		errg.Go(func() error { return s.recorder.Record(ctx, recorded, sniffed) })
+	case RecordFiltered:
+		queued = recorded
+		errg.Go(func() error { return s.recorder.Record(ctx, filtered, recorded) })
+	}
+
+	errg.Go(func() error { return s.subscribe(ctx, subscribed) })
 	errg.Go(func() error { return s.filter(ctx, sniffed, filtered) })
-	errg.Go(func() error { return s.queue(ctx, filtered) })
+	errg.Go(func() error { return s.queue(ctx, queued) })
 
 	// Wait until all contexts are closed, then return *first* error
 	err := errg.Wait()
@@ -126,29 +199,67 @@ func (s *Sniffer) iterate(ctx context.Context, sniffed, filtered chan t.Provider
 	return err
 }
 
-// Sniff starts sniffing until the context is closed - it restarts itself on intermittant errors.
+// Status returns the restart state of the Sniffer, including the reason for the last restart.
+func (s *Sniffer) Status() Status {
+	return s.restarter.getStatus()
+}
+
+// Sniff starts sniffing until the context is closed - it restarts itself on intermittant errors, with exponential
+// backoff, and returns ErrCircuitOpen when failing repeatedly.
 func (s *Sniffer) Sniff(ctx context.Context) error {
 	// ctx, span := s.Tracer.Start(ctx, "sniffer.Sniff")
 	// defer span.End()
 
+	defer func() {
+		if err := s.saveLastSeen(); err != nil {
+			log.Printf("Error saving LastSeen: %s", err)
+		}
+	}()
+
+	if s.recorder != nil {
+		defer func() {
+			if err := s.recorder.Close(); err != nil {
+				log.Printf("Error closing recorder: %s", err)
+			}
+		}()
+	}
+
+	// Reload peer lists for as long as we're sniffing.
+	go s.peerList.Watch(ctx, s.cfg.PeerListReload)
+
 	sniffed := make(chan t.Provider, s.cfg.BufferSize)
 	filtered := make(chan t.Provider, s.cfg.BufferSize)
+	recorded := make(chan t.Provider, s.cfg.BufferSize)
 
 	for {
-		err := s.iterate(ctx, sniffed, filtered)
+		start := time.Now()
+		s.restarter.started()
+
+		err := s.iterate(ctx, sniffed, filtered, recorded)
 
 		// Closing the parent context should cause a return, other errors cause a restart
 		if err := ctx.Err(); err != nil {
 			log.Printf("Parent context closed with error '%s', returning error", err)
+
 			// span.RecordError(ctx, err)
 			// span.SetStatus(codes.Internal, err.Error())
 			return err
 		}
 
-		log.Printf("Wait group exited with error '%s', restarting", err)
+		log.Printf("Wait group exited with error '%s'", err)
+
+		wait, err := s.restarter.fail(start, err)
+		if err != nil {
+			log.Printf("Not restarting: %s", err)
+			return err
+		}
+
+		log.Printf("Restarting in %s", wait)
 
-		// TODO: Add circuit breaker here
-		log.Printf("Stubbornly restarting in 1s")
-		time.Sleep(time.Second)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
 	}
 }
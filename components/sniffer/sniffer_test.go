@@ -3,6 +3,7 @@ package sniffer
 import (
 	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"sync"
 	"testing"
@@ -17,6 +18,7 @@ import (
 	"github.com/stretchr/testify/suite"
 
 	"github.com/ipfs-search/ipfs-search/components/queue"
+	"github.com/ipfs-search/ipfs-search/components/sniffer/recorder"
 	"github.com/ipfs-search/ipfs-search/instr"
 	t "github.com/ipfs-search/ipfs-search/types"
 )
@@ -44,7 +46,7 @@ func (s *SnifferTestSuite) TearDownTest() {
 // TestNew does a burn test for New()
 func (s *SnifferTestSuite) TestNew() {
 	cfg := DefaultConfig()
-	sniffy, e := New(cfg, s.ds, s.f, instr.New())
+	sniffy, e := New(cfg, s.ds, s.f, nil, instr.New())
 
 	s.NotEmpty(sniffy)
 	s.NoError(e)
@@ -53,7 +55,7 @@ func (s *SnifferTestSuite) TestNew() {
 // TestSniffCancel tests whether running Sniff() with a cancelled context returns with a context error.
 func (s *SnifferTestSuite) TestSniffCancel() {
 	cfg := DefaultConfig()
-	sniffy, e := New(cfg, s.ds, s.f, instr.New())
+	sniffy, e := New(cfg, s.ds, s.f, nil, instr.New())
 	s.NoError(e)
 
 	// Cancel context
@@ -69,6 +71,32 @@ func (s *SnifferTestSuite) TestSniffCancel() {
 	s.f.AssertExpectations(s.T())
 }
 
+// TestSniffCircuitOpen tests whether Sniff() gives up with ErrCircuitOpen after repeated failures.
+func (s *SnifferTestSuite) TestSniffCircuitOpen() {
+	cfg := DefaultConfig()
+	cfg.RestartMinBackoff = time.Millisecond
+	cfg.RestartMaxBackoff = time.Millisecond
+	cfg.BreakerThreshold = 3
+
+	sniffy, e := New(cfg, s.ds, s.f, nil, instr.New())
+	s.NoError(e)
+
+	// Publisher can't be created, e.g. due to misconfiguration
+	mockErr := errors.New("mock")
+	s.f.On("NewPublisher", mock.Anything).Return(&queue.Mock{}, mockErr)
+
+	err := sniffy.Sniff(s.ctx)
+	s.ErrorIs(err, ErrCircuitOpen)
+
+	status := sniffy.Status()
+	s.Equal(OpenState, status.State)
+	s.Equal(3, status.Failures)
+	s.Equal(2, status.Restarts)
+	s.ErrorIs(status.LastError, mockErr)
+
+	s.f.AssertNumberOfCalls(s.T(), "NewPublisher", 3)
+}
+
 func timeToVal(t time.Time) []byte {
 	// Ref: https://github.com/libp2p/go-libp2p-kad-dht/blob/master/providers/providers_manager.go#L239
 
@@ -106,8 +134,8 @@ func makeKey(cidStr, provStr string) (datastore.Key, error) {
 	return datastore.NewKey(fmt.Sprintf("%s%s/%s", providers.ProvidersKeyPrefix, encodedCid, encodedProv)), nil
 }
 
-// TestHandleToPublish tests the full chain from a yielded event to a publish.
-func (s *SnifferTestSuite) TestHandleToPublish() {
+// handleToPublish tests the full chain from a yielded event to a publish.
+func (s *SnifferTestSuite) handleToPublish(cfg *Config) {
 	// Prepare provider key and value
 	cidStr := "QmSKboVigcD3AY4kLsob117KJcMHvMUu6vNFqk1PQzYUpp"
 	provStr := "QmeTtFXm42Jb2todcKR538j6qHYxXt6suUzpF3rtT9FPSd"
@@ -119,8 +147,7 @@ func (s *SnifferTestSuite) TestHandleToPublish() {
 	value := timeToVal(now)
 
 	// Create sniffer
-	cfg := DefaultConfig()
-	sniffy, e := New(cfg, s.ds, s.f, instr.New())
+	sniffy, e := New(cfg, s.ds, s.f, nil, instr.New())
 	s.NoError(e)
 
 	// Get wrapped Datastore
@@ -172,6 +199,50 @@ func (s *SnifferTestSuite) TestHandleToPublish() {
 	qMock.AssertExpectations(s.T())
 }
 
+// TestHandleToPublish tests the full chain from a yielded event to a publish.
+func (s *SnifferTestSuite) TestHandleToPublish() {
+	s.handleToPublish(DefaultConfig())
+}
+
+// assertRecorded asserts the published CID was recorded in the sniff log.
+func (s *SnifferTestSuite) assertRecorded(cfg *Config) {
+	out := make(chan t.Provider, 10)
+	err := recorder.Replay(context.Background(), cfg.RecordDir, time.Now().Add(-time.Minute), time.Now(), out)
+	s.NoError(err)
+
+	s.Require().Len(out, 1)
+	s.Equal("QmSKboVigcD3AY4kLsob117KJcMHvMUu6vNFqk1PQzYUpp", (<-out).ID)
+}
+
+// TestHandleToPublishRecordRaw tests the full chain, recording before filtering.
+func (s *SnifferTestSuite) TestHandleToPublishRecordRaw() {
+	cfg := DefaultConfig()
+	cfg.RecordMode = RecordRaw
+	cfg.RecordDir = s.T().TempDir()
+
+	s.handleToPublish(cfg)
+	s.assertRecorded(cfg)
+}
+
+// TestHandleToPublishRecordFiltered tests the full chain, recording after filtering.
+func (s *SnifferTestSuite) TestHandleToPublishRecordFiltered() {
+	cfg := DefaultConfig()
+	cfg.RecordMode = RecordFiltered
+	cfg.RecordDir = s.T().TempDir()
+
+	s.handleToPublish(cfg)
+	s.assertRecorded(cfg)
+}
+
+// TestNewInvalidRecordMode tests whether New() rejects invalid record modes.
+func (s *SnifferTestSuite) TestNewInvalidRecordMode() {
+	cfg := DefaultConfig()
+	cfg.RecordMode = "invalid"
+
+	_, err := New(cfg, s.ds, s.f, nil, instr.New())
+	s.Error(err)
+}
+
 // // TestLogToPublish tests the full chain from a log to a publish
 // func (s *SnifferTestSuite) TestLogToPublish() {
 // 	// Create queue and channels to retreive published messages and priorities
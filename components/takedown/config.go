@@ -0,0 +1,13 @@
+package takedown
+
+// Config holds configuration for a Takedown.
+type Config struct {
+	AuditLog string // File to append a JSON record of every takedown to
+}
+
+// DefaultConfig returns the default configuration for a Takedown.
+func DefaultConfig() *Config {
+	return &Config{
+		AuditLog: "takedown_audit.jsonl",
+	}
+}
@@ -0,0 +1,345 @@
+/*
+Package takedown removes content from the indexes on request, and keeps it from being crawled again.
+
+A takedown deletes a document from whichever index contains it and prunes references to it from the documents it links
+to. When recursive, linked documents which are referenced exclusively by a taken down directory are taken down as well.
+Taken down CID's are added to the denylist and an audit record is appended to the audit log.
+*/
+package takedown
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/label"
+
+	"github.com/ipfs-search/ipfs-search/components/denylist"
+	"github.com/ipfs-search/ipfs-search/components/index"
+	indexTypes "github.com/ipfs-search/ipfs-search/components/index/types"
+	"github.com/ipfs-search/ipfs-search/instr"
+)
+
+// Options determine the scope of a takedown.
+type Options struct {
+	Recursive bool   // Take down documents exclusively referenced by taken down directories.
+	DryRun    bool   // Determine what would be taken down, without changing indexes, denylist or audit log.
+	Reason    string // Reason for the takedown, for the audit log.
+}
+
+// Deletion represents a document deleted by a takedown.
+type Deletion struct {
+	CID   string `json:"cid"`
+	Index string `json:"index"`
+}
+
+// Record is the audit record of a takedown.
+type Record struct {
+	Time       time.Time  `json:"time"`
+	CID        string     `json:"cid"`
+	Reason     string     `json:"reason,omitempty"`
+	Recursive  bool       `json:"recursive"`
+	DryRun     bool       `json:"dry_run"`
+	Deleted    []Deletion `json:"deleted"`    // Documents deleted.
+	Pruned     []string   `json:"pruned"`     // Documents from which references to deleted documents were removed.
+	Denylisted []string   `json:"denylisted"` // CID's added to the denylist.
+}
+
+// ErrInvalidCID is returned when taking down an invalid CID, which can not be denylisted.
+var ErrInvalidCID = errors.New("invalid CID")
+
+// maxConflictRetries is the number of times references are re-read and pruned when concurrently modified.
+const maxConflictRetries = 3
+
+// document holds the fields of indexed documents relevant to takedowns.
+type document struct {
+	indexTypes.Version `json:"-"`
+
+	References indexTypes.References `json:"references"`
+	Links      indexTypes.Links      `json:"links"`
+}
+
+// referencesUpdate replaces a document's references, unlike indexTypes.Update, also when they are empty.
+type referencesUpdate struct {
+	indexTypes.Version `json:"-"`
+
+	References indexTypes.References `json:"references"`
+}
+
+// withoutParent returns references without those from parent.
+func withoutParent(refs indexTypes.References, parent string) indexTypes.References {
+	references := indexTypes.References{}
+	for _, ref := range refs {
+		if ref.ParentHash != parent {
+			references = append(references, ref)
+		}
+	}
+
+	return references
+}
+
+// Takedown removes documents from indexes.
+type Takedown struct {
+	cfg          *Config
+	indexes      []index.Index
+	denylistFile string
+
+	*instr.Instrumentation
+}
+
+// New returns a Takedown for documents in indexes, adding taken down CID's to denylistFile.
+func New(cfg *Config, indexes []index.Index, denylistFile string, i *instr.Instrumentation) *Takedown {
+	if cfg == nil {
+		panic("takedown.New Config cannot be nil.")
+	}
+
+	return &Takedown{
+		cfg:             cfg,
+		indexes:         indexes,
+		denylistFile:    denylistFile,
+		Instrumentation: i,
+	}
+}
+
+// run holds the state of a single takedown.
+type run struct {
+	*Takedown
+	opts    Options
+	record  *Record
+	seen    map[string]struct{}
+	writes  *index.Writes       // Tracks asynchronous deletes and updates.
+	written map[string]struct{} // Documents written to, which are read again only after writes completed.
+}
+
+// Takedown takes down the document with CID id, returning the audit record or an error.
+// Documents deleted before an error occurred remain deleted, but are not added to the denylist.
+func (t *Takedown) Takedown(ctx context.Context, id string, opts Options) (*Record, error) {
+	ctx, span := t.Tracer.Start(ctx, "takedown.Takedown", trace.WithAttributes(label.String("cid", id)))
+	defer span.End()
+
+	ctx, writes := index.WithWrites(ctx)
+
+	r := run{
+		Takedown: t,
+		opts:     opts,
+		record: &Record{
+			Time:       time.Now().UTC(),
+			CID:        id,
+			Reason:     opts.Reason,
+			Recursive:  opts.Recursive,
+			DryRun:     opts.DryRun,
+			Deleted:    []Deletion{},
+			Pruned:     []string{},
+			Denylisted: []string{},
+		},
+		seen:    make(map[string]struct{}),
+		writes:  writes,
+		written: make(map[string]struct{}),
+	}
+
+	// The CID itself is denied, even when it has not been indexed.
+	if err := r.remove(ctx, id); err != nil {
+		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+		return nil, err
+	}
+
+	if opts.DryRun {
+		return r.record, nil
+	}
+
+	// Only denylist after all deletes and updates succeeded.
+	if err := writes.Wait(ctx); err != nil {
+		err = fmt.Errorf("writing to indexes: %w", err)
+		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+		return nil, err
+	}
+
+	comment := fmt.Sprintf("Takedown of %s on %s", id, r.record.Time.Format(time.RFC3339))
+	if opts.Reason != "" {
+		comment += ": " + opts.Reason
+	}
+
+	if err := denylist.Append(t.denylistFile, comment, r.record.Denylisted...); err != nil {
+		err = fmt.Errorf("adding to denylist: %w", err)
+		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+		return nil, err
+	}
+
+	if err := t.audit(r.record); err != nil {
+		err = fmt.Errorf("writing audit record: %w", err)
+		span.RecordError(ctx, err, trace.WithErrorStatus(codes.Error))
+		return nil, err
+	}
+
+	return r.record, nil
+}
+
+// remove deletes the document with id and handles the documents it links to.
+func (r *run) remove(ctx context.Context, id string) error {
+	if _, seen := r.seen[id]; seen {
+		return nil
+	}
+	r.seen[id] = struct{}{}
+
+	if _, err := cid.Decode(id); err != nil {
+		return fmt.Errorf("%w %s: %v", ErrInvalidCID, id, err)
+	}
+
+	r.record.Denylisted = append(r.record.Denylisted, id)
+
+	doc := new(document)
+
+	found, err := r.find(ctx, id, doc, "references", "links")
+	if err != nil {
+		return err
+	}
+
+	if len(found) == 0 {
+		log.Printf("%s not found in any index", id)
+		return nil
+	}
+
+	for _, idx := range found {
+		r.record.Deleted = append(r.record.Deleted, Deletion{id, fmt.Sprint(idx)})
+
+		if r.opts.DryRun {
+			continue
+		}
+
+		r.written[id] = struct{}{}
+
+		if err := idx.Delete(ctx, id); err != nil {
+			return fmt.Errorf("deleting %s: %w", id, err)
+		}
+	}
+
+	children := make(map[string]struct{}, len(doc.Links))
+	for _, l := range doc.Links {
+		if _, seen := children[l.Hash]; seen {
+			continue
+		}
+		children[l.Hash] = struct{}{}
+
+		if err := r.unlink(ctx, l.Hash, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// unlink removes references from parent to the document with id, removing the document when recursive and parent
+// was its only referrer.
+func (r *run) unlink(ctx context.Context, id, parent string) error {
+	if _, seen := r.seen[id]; seen {
+		return nil
+	}
+
+	doc := new(document)
+
+	found, err := r.find(ctx, id, doc, "references")
+	if err != nil {
+		return err
+	}
+
+	references := withoutParent(doc.References, parent)
+
+	if len(found) == 0 || len(references) == len(doc.References) {
+		// Not referenced by parent.
+		return nil
+	}
+
+	if len(references) == 0 && r.opts.Recursive {
+		return r.remove(ctx, id)
+	}
+
+	r.record.Pruned = append(r.record.Pruned, id)
+
+	if r.opts.DryRun {
+		return nil
+	}
+
+	r.written[id] = struct{}{}
+
+	for _, idx := range found {
+		if err := r.prune(ctx, idx, id, parent, doc); err != nil {
+			return fmt.Errorf("pruning references of %s: %w", id, err)
+		}
+	}
+
+	return nil
+}
+
+// prune removes references from parent to the document with id in idx, given the document as read before. When the
+// document was modified since, it is read again and pruning is retried.
+func (r *run) prune(ctx context.Context, idx index.Index, id, parent string, doc *document) error {
+	for attempt := 0; ; attempt++ {
+		update := &referencesUpdate{
+			Version:    doc.Version,
+			References: withoutParent(doc.References, parent),
+		}
+
+		err := idx.Update(ctx, id, update)
+		if !errors.Is(err, index.ErrConflict) || attempt == maxConflictRetries {
+			return err
+		}
+
+		if err := r.writes.Wait(ctx); err != nil {
+			return err
+		}
+
+		doc = new(document)
+
+		found, err := idx.Get(ctx, id, doc, "references")
+		if err != nil || !found {
+			return err
+		}
+	}
+}
+
+// find returns the indexes containing the document with id, reading fields into dst. Documents written to before are
+// read after pending writes completed, as their references would be stale otherwise.
+func (r *run) find(ctx context.Context, id string, dst interface{}, fields ...string) ([]index.Index, error) {
+	if _, written := r.written[id]; written {
+		if err := r.writes.Wait(ctx); err != nil {
+			return nil, fmt.Errorf("writing to indexes: %w", err)
+		}
+	}
+
+	var found []index.Index
+
+	for _, idx := range r.indexes {
+		ok, err := idx.Get(ctx, id, dst, fields...)
+		if err != nil {
+			return nil, fmt.Errorf("getting %s from %s: %w", id, idx, err)
+		}
+
+		if ok {
+			found = append(found, idx)
+		}
+	}
+
+	return found, nil
+}
+
+// audit appends a record to the audit log.
+func (t *Takedown) audit(record *Record) error {
+	file, err := os.OpenFile(t.cfg.AuditLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	if err := json.NewEncoder(file).Encode(record); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,331 @@
+package takedown
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/ipfs-search/ipfs-search/components/denylist"
+	"github.com/ipfs-search/ipfs-search/components/index"
+	indexTypes "github.com/ipfs-search/ipfs-search/components/index/types"
+	"github.com/ipfs-search/ipfs-search/instr"
+	t "github.com/ipfs-search/ipfs-search/types"
+)
+
+const (
+	dirCID       = "QmSKboVigcD3AY4kLsob117KJcMHvMUu6vNFqk1PQzYUpp"
+	exclusiveCID = "QmafrLBfzRLV4XSH1XcaMMeaXEUhDJjmtDfsYU95TrWG87"
+	sharedCID    = "QmS4ustL54uo8FzR9455qaxZwuMiUhyvMcX9Ba8nUH4uVv"
+	otherCID     = "QmbWqxBEKC3P8tqsKc98xmWNzrzDtRLMiMPL8wBuTGsMnR"
+)
+
+// namedIndex gives mocked indexes a readable name in records.
+type namedIndex struct {
+	*index.Mock
+	name string
+}
+
+func (i namedIndex) String() string {
+	return i.name
+}
+
+type TakedownTestSuite struct {
+	suite.Suite
+
+	ctx context.Context
+	cfg *Config
+
+	denylistFile string
+
+	files, dirs, invalids *index.Mock
+
+	td *Takedown
+}
+
+func (s *TakedownTestSuite) SetupTest() {
+	s.ctx = context.Background()
+
+	dir := s.T().TempDir()
+	s.cfg = &Config{AuditLog: filepath.Join(dir, "audit.jsonl")}
+	s.denylistFile = filepath.Join(dir, "denylist")
+
+	s.files, s.dirs, s.invalids = &index.Mock{}, &index.Mock{}, &index.Mock{}
+	s.files.Test(s.T())
+	s.dirs.Test(s.T())
+	s.invalids.Test(s.T())
+
+	indexes := []index.Index{
+		namedIndex{s.files, "files"},
+		namedIndex{s.dirs, "directories"},
+		namedIndex{s.invalids, "invalids"},
+	}
+
+	s.td = New(s.cfg, indexes, s.denylistFile, instr.New())
+}
+
+func (s *TakedownTestSuite) assertExpectations() {
+	mock.AssertExpectationsForObjects(s.T(), s.files, s.dirs, s.invalids)
+}
+
+// expectGet sets up a Get of id on idx, returning doc when it is not nil.
+func (s *TakedownTestSuite) expectGet(idx *index.Mock, id string, doc *document, fields ...string) {
+	idx.
+		On("Get", mock.Anything, id, mock.Anything, fields).
+		Run(func(args mock.Arguments) {
+			if doc != nil {
+				*args.Get(2).(*document) = *doc
+			}
+		}).
+		Return(doc != nil, nil).
+		Once()
+}
+
+// expectNotFound sets up a Get of id on indexes other than idx.
+func (s *TakedownTestSuite) expectNotFound(id string, fields []string, except *index.Mock) {
+	for _, idx := range []*index.Mock{s.files, s.dirs, s.invalids} {
+		if idx != except {
+			s.expectGet(idx, id, nil, fields...)
+		}
+	}
+}
+
+func (s *TakedownTestSuite) assertDenied(ids ...string) {
+	d, err := denylist.New(&denylist.Config{File: s.denylistFile})
+	s.Require().NoError(err)
+
+	for _, id := range ids {
+		s.True(d.DeniedResource(&t.Resource{Protocol: t.IPFSProtocol, ID: id}), id)
+	}
+}
+
+func (s *TakedownTestSuite) readAudit() *Record {
+	b, err := ioutil.ReadFile(s.cfg.AuditLog)
+	s.Require().NoError(err)
+
+	r := new(Record)
+	s.Require().NoError(json.Unmarshal(b, r))
+
+	return r
+}
+
+func (s *TakedownTestSuite) TestFile() {
+	fields := []string{"references", "links"}
+
+	s.expectGet(s.files, otherCID, &document{}, fields...)
+	s.expectNotFound(otherCID, fields, s.files)
+	s.files.On("Delete", mock.Anything, otherCID).Return(nil).Once()
+
+	record, err := s.td.Takedown(s.ctx, otherCID, Options{Reason: "test"})
+	s.NoError(err)
+
+	s.Equal([]Deletion{{otherCID, "files"}}, record.Deleted)
+	s.Empty(record.Pruned)
+	s.Equal([]string{otherCID}, record.Denylisted)
+
+	s.assertDenied(otherCID)
+
+	audit := s.readAudit()
+	s.Equal(otherCID, audit.CID)
+	s.Equal("test", audit.Reason)
+
+	s.assertExpectations()
+}
+
+func (s *TakedownTestSuite) TestNotIndexed() {
+	s.expectNotFound(otherCID, []string{"references", "links"}, nil)
+
+	record, err := s.td.Takedown(s.ctx, otherCID, Options{})
+	s.NoError(err)
+
+	s.Empty(record.Deleted)
+	s.Equal([]string{otherCID}, record.Denylisted)
+
+	s.assertDenied(otherCID)
+	s.assertExpectations()
+}
+
+func (s *TakedownTestSuite) TestInvalidCID() {
+	_, err := s.td.Takedown(s.ctx, "invalid", Options{})
+	s.ErrorIs(err, ErrInvalidCID)
+
+	s.NoFileExists(s.denylistFile)
+	s.assertExpectations()
+}
+
+func (s *TakedownTestSuite) TestWriteFailed() {
+	fields := []string{"references", "links"}
+
+	s.expectGet(s.files, otherCID, &document{}, fields...)
+	s.expectNotFound(otherCID, fields, s.files)
+	s.files.On("Delete", mock.Anything, otherCID).
+		Run(func(args mock.Arguments) {
+			// Asynchronous delete, failing after Delete returned.
+			done := index.WritesFromContext(args.Get(0).(context.Context)).Add()
+			go done(errors.New("mock"))
+		}).
+		Return(nil).
+		Once()
+
+	_, err := s.td.Takedown(s.ctx, otherCID, Options{})
+	s.Error(err)
+
+	// Not denylisted, as the document was not deleted.
+	s.NoFileExists(s.denylistFile)
+	s.assertExpectations()
+}
+
+// expectDirectory sets up a directory with an exclusively referenced and a shared entry, linked twice.
+func (s *TakedownTestSuite) expectDirectory() {
+	rootFields := []string{"references", "links"}
+	childFields := []string{"references"}
+
+	s.expectGet(s.dirs, dirCID, &document{
+		Links: indexTypes.Links{
+			{Hash: exclusiveCID, Name: "exclusive"},
+			{Hash: sharedCID, Name: "shared"},
+			{Hash: exclusiveCID, Name: "again"},
+		},
+	}, rootFields...)
+	s.expectNotFound(dirCID, rootFields, s.dirs)
+
+	s.expectGet(s.files, sharedCID, &document{
+		References: indexTypes.References{
+			{ParentHash: dirCID, Name: "shared"},
+			{ParentHash: otherCID, Name: "elsewhere"},
+		},
+	}, childFields...)
+	s.expectNotFound(sharedCID, childFields, s.files)
+}
+
+func (s *TakedownTestSuite) TestDirectory() {
+	childFields := []string{"references"}
+
+	s.expectDirectory()
+
+	// Without recursion, exclusively referenced entries only lose their reference.
+	s.expectGet(s.files, exclusiveCID, &document{
+		References: indexTypes.References{{ParentHash: dirCID, Name: "exclusive"}},
+	}, childFields...)
+	s.expectNotFound(exclusiveCID, childFields, s.files)
+
+	s.dirs.On("Delete", mock.Anything, dirCID).Return(nil).Once()
+	s.files.On("Update", mock.Anything, exclusiveCID, &referencesUpdate{References: indexTypes.References{}}).Return(nil).Once()
+	s.files.On("Update", mock.Anything, sharedCID, &referencesUpdate{References: indexTypes.References{
+		{ParentHash: otherCID, Name: "elsewhere"},
+	}}).Return(nil).Once()
+
+	record, err := s.td.Takedown(s.ctx, dirCID, Options{})
+	s.NoError(err)
+
+	s.Equal([]Deletion{{dirCID, "directories"}}, record.Deleted)
+	s.Equal([]string{exclusiveCID, sharedCID}, record.Pruned)
+	s.Equal([]string{dirCID}, record.Denylisted)
+
+	s.assertExpectations()
+}
+
+func (s *TakedownTestSuite) TestDirectoryRecursive() {
+	s.expectDirectory()
+
+	// Exclusively referenced entries are taken down, fetching their links.
+	fields := []string{"references"}
+	s.expectGet(s.files, exclusiveCID, &document{
+		References: indexTypes.References{{ParentHash: dirCID, Name: "exclusive"}},
+	}, fields...)
+	s.expectNotFound(exclusiveCID, fields, s.files)
+
+	fields = []string{"references", "links"}
+	s.expectGet(s.files, exclusiveCID, &document{}, fields...)
+	s.expectNotFound(exclusiveCID, fields, s.files)
+
+	s.dirs.On("Delete", mock.Anything, dirCID).Return(nil).Once()
+	s.files.On("Delete", mock.Anything, exclusiveCID).Return(nil).Once()
+	s.files.On("Update", mock.Anything, sharedCID, &referencesUpdate{References: indexTypes.References{
+		{ParentHash: otherCID, Name: "elsewhere"},
+	}}).Return(nil).Once()
+
+	record, err := s.td.Takedown(s.ctx, dirCID, Options{Recursive: true})
+	s.NoError(err)
+
+	s.Equal([]Deletion{{dirCID, "directories"}, {exclusiveCID, "files"}}, record.Deleted)
+	s.Equal([]string{sharedCID}, record.Pruned)
+	s.Equal([]string{dirCID, exclusiveCID}, record.Denylisted)
+
+	s.assertDenied(dirCID, exclusiveCID)
+	s.assertExpectations()
+}
+
+func (s *TakedownTestSuite) TestDirectoryConflict() {
+	childFields := []string{"references"}
+
+	s.expectDirectory()
+
+	s.expectGet(s.files, exclusiveCID, &document{
+		Version:    indexTypes.Version{SeqNo: 1, PrimaryTerm: 1},
+		References: indexTypes.References{{ParentHash: dirCID, Name: "exclusive"}},
+	}, childFields...)
+	s.expectNotFound(exclusiveCID, childFields, s.files)
+
+	s.dirs.On("Delete", mock.Anything, dirCID).Return(nil).Once()
+	s.files.On("Update", mock.Anything, sharedCID, mock.Anything).Return(nil).Once()
+
+	// A reference is concurrently added; pruning is retried after reading it again.
+	s.files.On("Update", mock.Anything, exclusiveCID, &referencesUpdate{
+		Version:    indexTypes.Version{SeqNo: 1, PrimaryTerm: 1},
+		References: indexTypes.References{},
+	}).Return(index.ErrConflict).Once()
+
+	s.expectGet(s.files, exclusiveCID, &document{
+		Version: indexTypes.Version{SeqNo: 2, PrimaryTerm: 1},
+		References: indexTypes.References{
+			{ParentHash: dirCID, Name: "exclusive"},
+			{ParentHash: otherCID, Name: "new"},
+		},
+	}, childFields...)
+
+	s.files.On("Update", mock.Anything, exclusiveCID, &referencesUpdate{
+		Version:    indexTypes.Version{SeqNo: 2, PrimaryTerm: 1},
+		References: indexTypes.References{{ParentHash: otherCID, Name: "new"}},
+	}).Return(nil).Once()
+
+	_, err := s.td.Takedown(s.ctx, dirCID, Options{})
+	s.NoError(err)
+
+	s.assertExpectations()
+}
+
+func (s *TakedownTestSuite) TestDryRun() {
+	fields := []string{"references"}
+
+	s.expectDirectory()
+	s.expectGet(s.files, exclusiveCID, &document{
+		References: indexTypes.References{{ParentHash: dirCID, Name: "exclusive"}},
+	}, fields...)
+	s.expectNotFound(exclusiveCID, fields, s.files)
+
+	fields = []string{"references", "links"}
+	s.expectGet(s.files, exclusiveCID, &document{}, fields...)
+	s.expectNotFound(exclusiveCID, fields, s.files)
+
+	record, err := s.td.Takedown(s.ctx, dirCID, Options{Recursive: true, DryRun: true})
+	s.NoError(err)
+
+	s.True(record.DryRun)
+	s.Len(record.Deleted, 2)
+
+	// Nothing is deleted, updated or written.
+	s.NoFileExists(s.denylistFile)
+	s.NoFileExists(s.cfg.AuditLog)
+
+	s.assertExpectations()
+}
+
+func TestTakedownTestSuite(t *testing.T) {
+	suite.Run(t, new(TakedownTestSuite))
+}
@@ -0,0 +1,24 @@
+package config
+
+import (
+	"github.com/ipfs-search/ipfs-search/components/api"
+)
+
+// API is configuration pertaining to the search API.
+type API struct {
+	Addr        string `yaml:"addr" env:"API_ADDR"` // Address to listen on, e.g. `:9615`.
+	PageSize    int    `yaml:"page_size"`           // Number of hits per page, unless requested otherwise.
+	MaxPageSize int    `yaml:"max_page_size"`       // Maximum number of hits per page.
+	MaxPage     int    `yaml:"max_page"`            // Maximum page number.
+}
+
+// APIConfig returns component-specific configuration from the canonical central configuration.
+func (c *Config) APIConfig() *api.Config {
+	cfg := api.Config(c.API)
+	return &cfg
+}
+
+// APIDefaults returns the defaults for component configuration, based on the component-specific configuration.
+func APIDefaults() API {
+	return API(*api.DefaultConfig())
+}
@@ -0,0 +1,21 @@
+package config
+
+import (
+	"github.com/ipfs-search/ipfs-search/components/index/bleve"
+)
+
+// Bleve is configuration pertaining to embedded indexes, for indexes with `backend: bleve`.
+type Bleve struct {
+	Dir string `yaml:"dir" env:"BLEVE_DIR"` // Directory holding a subdirectory per index.
+}
+
+// BleveConfig returns component-specific configuration from the canonical central configuration.
+func (c *Config) BleveConfig() *bleve.ClientConfig {
+	cfg := bleve.ClientConfig(c.Bleve)
+	return &cfg
+}
+
+// BleveDefaults returns the defaults for component configuration, based on the component-specific configuration.
+func BleveDefaults() Bleve {
+	return Bleve(*bleve.DefaultClientConfig())
+}
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"time"
+
+	"github.com/ipfs-search/ipfs-search/components/index/cache"
+)
+
+// Cache is configuration pertaining to caching of indexes, for indexes with `cache: memory`.
+type Cache struct {
+	CachingFields []string      `yaml:"fields"`       // Fields to cache.
+	Capacity      int           `yaml:"capacity"`     // Maximum number of documents to cache per index.
+	TTL           time.Duration `yaml:"ttl"`          // Time to cache documents; writes by other processes are not seen meanwhile.
+	NegativeTTL   time.Duration `yaml:"negative_ttl"` // Time to cache that documents were not found.
+}
+
+// CacheConfig returns component-specific configuration from the canonical central configuration.
+func (c *Config) CacheConfig() *cache.Config {
+	cfg := cache.Config(c.Cache)
+	return &cfg
+}
+
+// CacheDefaults returns the defaults for component configuration, based on the component-specific configuration.
+func CacheDefaults() Cache {
+	return Cache(*cache.DefaultConfig())
+}
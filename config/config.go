@@ -37,17 +37,25 @@ import (
 
 // Config contains the configuration for all components.
 type Config struct {
-	IPFS          `yaml:"ipfs"`
-	ElasticSearch `yaml:"elasticsearch"`
-	AMQP          `yaml:"amqp"`
-	Tika          `yaml:"tika"`
-
-	Instr   `yaml:"instrumentation"`
-	Crawler `yaml:"crawler"`
-	Sniffer `yaml:"sniffer"`
-	Indexes `yaml:"indexes"`
-	Queues  `yaml:"queues"`
-	Workers `yaml:"workers"`
+	IPFS            `yaml:"ipfs"`
+	ElasticSearch   `yaml:"elasticsearch"`
+	Spool           `yaml:"spool"`
+	Bleve           `yaml:"bleve"`
+	AMQP            `yaml:"amqp"`
+	Tika            `yaml:"tika"`
+	NativeExtractor `yaml:"native_extractor"`
+
+	Instr       `yaml:"instrumentation"`
+	Crawler     `yaml:"crawler"`
+	Sniffer     `yaml:"sniffer"`
+	SnifferNode `yaml:"sniffer_node"`
+	Denylist    `yaml:"denylist"`
+	Takedown    `yaml:"takedown"`
+	API         `yaml:"api"`
+	Cache       `yaml:"cache"`
+	Indexes     `yaml:"indexes"`
+	Queues      `yaml:"queues"`
+	Workers     `yaml:"workers"`
 }
 
 // String renders config as YAML
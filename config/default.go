@@ -5,11 +5,19 @@ func Default() *Config {
     return &Config{
         IPFSDefaults(),
         ElasticSearchDefaults(),
+        SpoolDefaults(),
+        BleveDefaults(),
         AMQPDefaults(),
         TikaDefaults(),
+        NativeExtractorDefaults(),
         InstrDefaults(),
         CrawlerDefaults(),
         SnifferDefaults(),
+        SnifferNodeDefaults(),
+        DenylistDefaults(),
+        TakedownDefaults(),
+        APIDefaults(),
+        CacheDefaults(),
         IndexesDefaults(),
         QueuesDefaults(),
         WorkersDefaults(),
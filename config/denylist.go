@@ -0,0 +1,24 @@
+package config
+
+import (
+	"time"
+
+	"github.com/ipfs-search/ipfs-search/components/denylist"
+)
+
+// Denylist is configuration pertaining to the denylist of content kept out of the index.
+type Denylist struct {
+	File   string        `yaml:"file" env:"DENYLIST_FILE"` // File with badbits, CID or path entries.
+	Reload time.Duration `yaml:"reload"`                   // Interval between checks for changes of the file.
+}
+
+// DenylistConfig returns component-specific configuration from the canonical central configuration.
+func (c *Config) DenylistConfig() *denylist.Config {
+	cfg := denylist.Config(c.Denylist)
+	return &cfg
+}
+
+// DenylistDefaults returns the defaults for component configuration, based on the component-specific configuration.
+func DenylistDefaults() Denylist {
+	return Denylist(*denylist.DefaultConfig())
+}
@@ -1,28 +1,50 @@
 package config
 
 import (
+	"net/http"
 	"runtime"
 	"time"
 
 	"github.com/c2h5oh/datasize"
+
+	"github.com/ipfs-search/ipfs-search/components/index/elasticsearch"
 )
 
 // ElasticSearch holds configuration for ElasticSearch.
 type ElasticSearch struct {
-	URL                    string            `yaml:"url" env:"ELASTICSEARCH_URL"`
-	BulkIndexerWorkers     int               `yaml:"bulk_indexer_workers"`      // Amount of workers to user for indexer.
-	BulkIndexerFlushBytes  datasize.ByteSize `yaml:"bulk_flush_bytes"`          // Flush index buffer after this many bytes.
-	BulkGetterBatchSize    int               `yaml:"bulk_getter_batch_size"`    // Maximum batch size for bulk gets.
-	BulkGetterBatchTimeout time.Duration     `yaml:"bulk_getter_batch_timeout"` // Maximum time to wait until executing batch.
+	URL                      string            `yaml:"url" env:"ELASTICSEARCH_URL"`
+	BulkIndexerWorkers       int               `yaml:"bulk_indexer_workers"`        // Amount of workers to user for indexer.
+	BulkIndexerFlushBytes    datasize.ByteSize `yaml:"bulk_flush_bytes"`            // Flush index buffer after this many bytes.
+	BulkGetterBatchSize      int               `yaml:"bulk_getter_batch_size"`      // Maximum batch size for bulk gets.
+	BulkGetterBatchTimeout   time.Duration     `yaml:"bulk_getter_batch_timeout"`   // Maximum time to wait until executing batch.
+	BulkGetterRequestTimeout time.Duration     `yaml:"bulk_getter_request_timeout"` // Maximum time to wait for a single get.
+	BulkGetterAliasTTL       time.Duration     `yaml:"bulk_getter_alias_ttl"`       // Time after which indexes behind aliases are resolved again.
 }
 
 // ElasticSearchDefaults returns the defaults for ElasticSearch.
 func ElasticSearchDefaults() ElasticSearch {
 	return ElasticSearch{
-		URL:                    "http://localhost:9200",
-		BulkIndexerWorkers:     runtime.NumCPU(),
-		BulkIndexerFlushBytes:  5e+6, // 5MB
-		BulkGetterBatchSize:    48,
-		BulkGetterBatchTimeout: 150 * time.Millisecond,
+		URL:                      "http://localhost:9200",
+		BulkIndexerWorkers:       runtime.NumCPU(),
+		BulkIndexerFlushBytes:    5e+6, // 5MB
+		BulkGetterBatchSize:      48,
+		BulkGetterBatchTimeout:   150 * time.Millisecond,
+		BulkGetterRequestTimeout: 30 * time.Second,
+		BulkGetterAliasTTL:       time.Minute,
+	}
+}
+
+// ElasticSearchClientConfig returns component-specific configuration from the canonical central configuration, for
+// clients connecting through transport. Writes are not spooled; set Spool to spool them.
+func (c *Config) ElasticSearchClientConfig(transport http.RoundTripper) *elasticsearch.ClientConfig {
+	return &elasticsearch.ClientConfig{
+		URL:                      c.ElasticSearch.URL,
+		Transport:                transport,
+		BulkIndexerWorkers:       c.ElasticSearch.BulkIndexerWorkers,
+		BulkIndexerFlushBytes:    int(c.ElasticSearch.BulkIndexerFlushBytes),
+		BulkGetterBatchSize:      c.ElasticSearch.BulkGetterBatchSize,
+		BulkGetterBatchTimeout:   c.ElasticSearch.BulkGetterBatchTimeout,
+		BulkGetterRequestTimeout: c.ElasticSearch.BulkGetterRequestTimeout,
+		BulkGetterAliasTTL:       c.ElasticSearch.BulkGetterAliasTTL,
 	}
 }
@@ -1,8 +1,52 @@
 package config
 
+import (
+	"github.com/ipfs-search/ipfs-search/components/index/cache"
+	"github.com/ipfs-search/ipfs-search/components/index/factory"
+)
+
 // Index represents the configuration for a single Index.
 type Index struct {
-	Name string
+	Name    string
+	Backend string // Store the index in "elasticsearch" or in an embedded "bleve" index.
+	Cache   string // Cache documents in "memory" or "none".
+	Tee     *Tee   `yaml:"tee,omitempty"` // Write to secondary indexes as well; optional.
+}
+
+// Tee represents writing an index to secondary indexes as well, e.g. while migrating to another cluster.
+type Tee struct {
+	Read        string      `yaml:"read"` // Read from the "primary" index only, or "fallback" to secondary indexes.
+	Secondaries []Secondary `yaml:"secondaries"`
+}
+
+// Secondary represents the configuration for a secondary index.
+type Secondary struct {
+	Name    string `yaml:"name"`
+	Backend string `yaml:"backend"`
+	URL     string `yaml:"url,omitempty"` // Elasticsearch cluster, when other than the configured one.
+	OnError string `yaml:"on_error"`      // Policy for failed writes: "fail", "log" or "spool".
+}
+
+// IndexConfig returns component-specific configuration for the index.
+func (i Index) IndexConfig() *factory.Config {
+	cfg := &factory.Config{
+		Name:    i.Name,
+		Backend: i.Backend,
+		Cache:   i.Cache,
+	}
+
+	if i.Tee != nil {
+		cfg.Tee = &factory.TeeConfig{
+			Read:        i.Tee.Read,
+			Secondaries: make([]factory.SecondaryConfig, len(i.Tee.Secondaries)),
+		}
+
+		for n, s := range i.Tee.Secondaries {
+			cfg.Tee.Secondaries[n] = factory.SecondaryConfig(s)
+		}
+	}
+
+	return cfg
 }
 
 // Indexes represents the various indexes we're using
@@ -11,22 +55,36 @@ type Indexes struct {
 	Directories Index `yaml:"directories"`
 	Invalids    Index `yaml:"invalids"`
 	Partials    Index `yaml:"partials"`
+	IPNSNames   Index `yaml:"ipns_names"`
 }
 
 // IndexesDefaults returns the default indexes.
 func IndexesDefaults() Indexes {
 	return Indexes{
 		Files: Index{
-			Name: "ipfs_files",
+			Name:    "ipfs_files",
+			Backend: factory.Elasticsearch,
+			Cache:   cache.None,
 		},
 		Directories: Index{
-			Name: "ipfs_directories",
+			Name:    "ipfs_directories",
+			Backend: factory.Elasticsearch,
+			Cache:   cache.None,
 		},
 		Invalids: Index{
-			Name: "ipfs_invalids",
+			Name:    "ipfs_invalids",
+			Backend: factory.Elasticsearch,
+			Cache:   cache.None,
 		},
 		Partials: Index{
-			Name: "ipfs_partials",
+			Name:    "ipfs_partials",
+			Backend: factory.Elasticsearch,
+			Cache:   cache.None,
+		},
+		IPNSNames: Index{
+			Name:    "ipfs_ipns_names",
+			Backend: factory.Elasticsearch,
+			Cache:   cache.None,
 		},
 	}
 }
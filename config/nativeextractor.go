@@ -0,0 +1,26 @@
+package config
+
+import (
+	"time"
+
+	"github.com/c2h5oh/datasize"
+
+	"github.com/ipfs-search/ipfs-search/components/extractor/native"
+)
+
+// NativeExtractor is configuration pertaining to the native extractor, falling back to Tika.
+type NativeExtractor struct {
+	RequestTimeout time.Duration     `yaml:"timeout"`
+	MaxFileSize    datasize.ByteSize `yaml:"max_file_size"`
+}
+
+// NativeExtractorConfig returns component-specific configuration from the canonical central configuration.
+func (c *Config) NativeExtractorConfig() *native.Config {
+	cfg := native.Config(c.NativeExtractor)
+	return &cfg
+}
+
+// NativeExtractorDefaults returns the defaults for component configuration, based on the component-specific configuration.
+func NativeExtractorDefaults() NativeExtractor {
+	return NativeExtractor(*native.DefaultConfig())
+}
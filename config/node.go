@@ -0,0 +1,23 @@
+package config
+
+import (
+	"github.com/ipfs-search/ipfs-search/components/sniffer/node"
+)
+
+// SnifferNode is configuration pertaining to the DHT node used by the sniff command.
+type SnifferNode struct {
+	ListenAddrs    []string `yaml:"listen_addrs"`
+	BootstrapPeers []string `yaml:"bootstrap_peers"`
+	IdentityPath   string   `yaml:"identity_path" env:"SNIFFER_NODE_IDENTITY_PATH"`
+}
+
+// SnifferNodeConfig returns component-specific configuration from the canonical central configuration.
+func (c *Config) SnifferNodeConfig() *node.Config {
+	cfg := node.Config(c.SnifferNode)
+	return &cfg
+}
+
+// SnifferNodeDefaults returns the defaults for component configuration, based on the component-specific configuration.
+func SnifferNodeDefaults() SnifferNode {
+	return SnifferNode(*node.DefaultConfig())
+}
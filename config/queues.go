@@ -10,6 +10,7 @@ type Queues struct {
 	Files       Queue `yaml:"files"`       // Resources known to be files.
 	Directories Queue `yaml:"directories"` // Resources known to be directories.
 	Hashes      Queue `yaml:"hashes"`      // Resources with unknown type.
+	Failed      Queue `yaml:"failed"`      // Resources for which index writes failed, for inspection or requeueing.
 }
 
 // QueuesDefaults returns the default queues.
@@ -24,5 +25,8 @@ func QueuesDefaults() Queues {
 		Hashes: Queue{
 			Name: "hashes",
 		},
+		Failed: Queue{
+			Name: "failed",
+		},
 	}
 }
@@ -2,15 +2,38 @@ package config
 
 import (
 	"github.com/ipfs-search/ipfs-search/components/sniffer"
+	"os"
+	"path/filepath"
 	"time"
 )
 
 // Sniffer is configuration pertaining to the sniffer
 type Sniffer struct {
-	LastSeenExpiration time.Duration `yaml:"lastseen_expiration" env:"SNIFFER_LASTSEEN_EXPIRATION"`
-	LastSeenPruneLen   int           `yaml:"lastseen_prunelen" env:"SNIFFER_LASTSEEN_PRUNELEN"`
-	LoggerTimeout      time.Duration `yaml:"logger_timeout"`
-	BufferSize         uint          `yaml:"buffer_size" env:"SNIFFER_BUFFER_SIZE"`
+	LastSeenExpiration time.Duration      `yaml:"lastseen_expiration" env:"SNIFFER_LASTSEEN_EXPIRATION"`
+	LastSeenPruneLen   int                `yaml:"lastseen_prunelen" env:"SNIFFER_LASTSEEN_PRUNELEN"`
+	LastSeenSnapshot   string             `yaml:"lastseen_snapshot" env:"SNIFFER_LASTSEEN_SNAPSHOT"`
+	LoggerTimeout      time.Duration      `yaml:"logger_timeout"`
+	BufferSize         uint               `yaml:"buffer_size" env:"SNIFFER_BUFFER_SIZE"`
+	FilterWorkers      int                `yaml:"filter_workers" env:"SNIFFER_FILTER_WORKERS"`
+	IndexFilterTimeout time.Duration      `yaml:"index_filter_timeout"`
+	PublishRate        float64            `yaml:"publish_rate" env:"SNIFFER_PUBLISH_RATE"`
+	PublishBurst       int                `yaml:"publish_burst"`
+	QueueHighWater     int                `yaml:"queue_high_water" env:"SNIFFER_QUEUE_HIGH_WATER"`
+	QueueProbeInterval time.Duration      `yaml:"queue_probe_interval"`
+	OverloadWindow     time.Duration      `yaml:"overload_window"`
+	OverloadSampling   float64            `yaml:"overload_sampling"`
+	RestartMinBackoff  time.Duration      `yaml:"restart_min_backoff"`
+	RestartMaxBackoff  time.Duration      `yaml:"restart_max_backoff"`
+	BreakerThreshold   int                `yaml:"breaker_threshold"`
+	BreakerResetAfter  time.Duration      `yaml:"breaker_reset_after"`
+	RecordMode         sniffer.RecordMode `yaml:"record_mode" env:"SNIFFER_RECORD_MODE"`
+	RecordDir          string             `yaml:"record_dir" env:"SNIFFER_RECORD_DIR"`
+	RecordSegment      time.Duration      `yaml:"record_segment"`
+	RecordRetention    time.Duration      `yaml:"record_retention"`
+	PeerListFile       string             `yaml:"peerlist_file" env:"SNIFFER_PEERLIST_FILE"`
+	PeerListReload     time.Duration      `yaml:"peerlist_reload"`
+	PeerRateWindow     time.Duration      `yaml:"peer_rate_window"`
+	PeerRateLimit      int                `yaml:"peer_rate_limit" env:"SNIFFER_PEER_RATE_LIMIT"`
 }
 
 // SnifferConfig returns component-specific configuration from the canonical central configuration.
@@ -21,5 +44,10 @@ func (c *Config) SnifferConfig() *sniffer.Config {
 
 // SnifferDefaults returns the defaults for component configuration, based on the component-specific configuration.
 func SnifferDefaults() Sniffer {
-	return Sniffer(*sniffer.DefaultConfig())
+	cfg := Sniffer(*sniffer.DefaultConfig())
+
+	// Snapshots are disabled for the component by default, but enabled for the sniffer command.
+	cfg.LastSeenSnapshot = filepath.Join(os.TempDir(), "ipfs-sniffer-lastseen.gob")
+
+	return cfg
 }
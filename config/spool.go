@@ -0,0 +1,29 @@
+package config
+
+import (
+	"time"
+
+	"github.com/c2h5oh/datasize"
+
+	"github.com/ipfs-search/ipfs-search/components/index/elasticsearch"
+)
+
+// Spool is configuration pertaining to spooling of index writes while OpenSearch is unavailable.
+type Spool struct {
+	Mode          string            `yaml:"mode" env:"SPOOL_MODE"` // Spool writes to "disk", or "none".
+	Dir           string            `yaml:"dir" env:"SPOOL_DIR"`   // Directory for spooled writes.
+	MaxSize       datasize.ByteSize `yaml:"max_size"`              // Maximum size of spooled writes.
+	RetryInterval time.Duration     `yaml:"retry_interval"`        // Interval for checking whether OpenSearch is available.
+	MaxReplays    int               `yaml:"max_replays"`           // Failed replays after which spooled writes are set aside.
+}
+
+// SpoolConfig returns component-specific configuration from the canonical central configuration.
+func (c *Config) SpoolConfig() *elasticsearch.SpoolConfig {
+	cfg := elasticsearch.SpoolConfig(c.Spool)
+	return &cfg
+}
+
+// SpoolDefaults returns the defaults for component configuration, based on the component-specific configuration.
+func SpoolDefaults() Spool {
+	return Spool(*elasticsearch.DefaultSpoolConfig())
+}
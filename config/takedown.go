@@ -0,0 +1,21 @@
+package config
+
+import (
+	"github.com/ipfs-search/ipfs-search/components/takedown"
+)
+
+// Takedown is configuration pertaining to the takedown command.
+type Takedown struct {
+	AuditLog string `yaml:"audit_log" env:"TAKEDOWN_AUDIT_LOG"` // File to append takedown records to.
+}
+
+// TakedownConfig returns component-specific configuration from the canonical central configuration.
+func (c *Config) TakedownConfig() *takedown.Config {
+	cfg := takedown.Config(c.Takedown)
+	return &cfg
+}
+
+// TakedownDefaults returns the defaults for component configuration, based on the component-specific configuration.
+func TakedownDefaults() Takedown {
+	return Takedown(*takedown.DefaultConfig())
+}
@@ -3,9 +3,11 @@ package config
 import (
 	"fmt"
 	"reflect"
+	"strings"
 )
 
-// findZeroElements returns a slice of all (nested) struct fields with a zero value.
+// findZeroElements returns a slice of all (nested) struct fields with a zero value. Optional fields, tagged with
+// `omitempty`, are skipped.
 func findZeroElements(s interface{}) []string {
 	var output []string
 
@@ -14,7 +16,12 @@ func findZeroElements(s interface{}) []string {
 	// Iterate over fields
 	for i := 0; i < v.NumField(); i++ {
 		f := v.Field(i)
-		name := v.Type().Field(i).Tag.Get("yaml")
+		tag := strings.Split(v.Type().Field(i).Tag.Get("yaml"), ",")
+		name := tag[0]
+
+		if len(tag) > 1 && tag[1] == "omitempty" {
+			continue
+		}
 
 		switch f.Kind() {
 		case reflect.Struct:
@@ -22,6 +29,11 @@ func findZeroElements(s interface{}) []string {
 			for _, newE := range findZeroElements(f.Interface()) {
 				output = append(output, fmt.Sprintf("%s.%s", name, newE))
 			}
+		case reflect.Slice:
+			// Slice type, require non-zero length
+			if f.Len() == 0 {
+				output = append(output, name)
+			}
 		case reflect.Map:
 			// Map type, require non-zero length
 			if f.Len() == 0 {
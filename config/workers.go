@@ -1,14 +1,27 @@
 package config
 
+import (
+	"time"
+)
+
+// Values for AckMode.
+const (
+	AckCrawled = "crawled" // Acknowledge deliveries when crawled, before index writes are flushed.
+	AckFlushed = "flushed" // Acknowledge deliveries when their index writes are confirmed.
+)
+
 /*
 Workers contains the configuration for the worker pool.
 
 It is fully contained here in order to avoid cyclic imports as the worker package uses the central Config struct.
 */
 type Workers struct {
-	HashWorkers      int `yaml:"hash_workers" env:"HASH_WORKERS"`
-	FileWorkers      int `yaml:"file_workers" env:"FILE_WORKERS"`
-	DirectoryWorkers int `yaml:"directory_workers" env:"DIRECTORY_WORKERS"`
+	HashWorkers      int           `yaml:"hash_workers" env:"HASH_WORKERS"`
+	FileWorkers      int           `yaml:"file_workers" env:"FILE_WORKERS"`
+	DirectoryWorkers int           `yaml:"directory_workers" env:"DIRECTORY_WORKERS"`
+	AckMode          string        `yaml:"ack_mode" env:"ACK_MODE"` // Acknowledge deliveries when "crawled" or "flushed".
+	AckTimeout       time.Duration `yaml:"ack_timeout"`             // Time to wait for index writes to be confirmed.
+	MaxPending       int           `yaml:"max_pending"`             // Deliveries per queue awaiting flushes, when "flushed".
 }
 
 // WorkersDefaults returns the default configuration for the workerpool.
@@ -17,5 +30,8 @@ func WorkersDefaults() Workers {
 		HashWorkers:      70,
 		FileWorkers:      120,
 		DirectoryWorkers: 70,
+		AckMode:          AckCrawled,
+		AckTimeout:       5 * time.Minute,
+		MaxPending:       1000,
 	}
 }
@@ -1,22 +1,31 @@
 module github.com/ipfs-search/ipfs-search
 
 require (
-	github.com/libp2p/go-buffer-pool v0.0.2
-	github.com/libp2p/go-msgio v0.2.0
 	github.com/Netflix/go-env v0.0.0-20210116210345-8f74e74141f7
 	github.com/alanshaw/ipfs-hookds v0.3.0
+	github.com/blevesearch/bleve/v2 v2.3.4
 	github.com/c2h5oh/datasize v0.0.0-20200112174442-28bbd4740fee
 	github.com/dankinder/httpmock v1.0.1
+	github.com/gogo/protobuf v1.3.2
 	github.com/ipfs/go-cid v0.0.7
 	github.com/ipfs/go-datastore v0.4.5
 	github.com/ipfs/go-ipfs-api v0.3.0
+	github.com/ipfs/go-ipld-format v0.0.2
+	github.com/ipfs/go-ipns v0.0.2
+	github.com/ipfs/go-merkledag v0.2.3
 	github.com/ipfs/go-unixfs v0.2.4
 	github.com/jpillora/backoff v1.0.0
 	github.com/kr/text v0.2.0 // indirect
+	github.com/libp2p/go-buffer-pool v0.0.2
 	github.com/libp2p/go-eventbus v0.2.1
+	github.com/libp2p/go-libp2p v0.11.0
 	github.com/libp2p/go-libp2p-core v0.6.1
 	github.com/libp2p/go-libp2p-kad-dht v0.10.0
+	github.com/libp2p/go-libp2p-record v0.1.3
+	github.com/libp2p/go-msgio v0.2.0
 	github.com/multiformats/go-base32 v0.0.3
+	github.com/multiformats/go-multiaddr v0.3.1
+	github.com/multiformats/go-multihash v0.0.14
 	github.com/niemeyer/pretty v0.0.0-20200227124842-a10e7caefd8e // indirect
 	github.com/opensearch-project/opensearch-go/v2 v2.0.0
 	github.com/rabbitmq/amqp091-go v1.3.4
@@ -25,11 +34,12 @@ require (
 	go.opentelemetry.io/otel v0.13.0
 	go.opentelemetry.io/otel/exporters/trace/jaeger v0.13.0
 	go.opentelemetry.io/otel/sdk v0.13.0
-	golang.org/x/sync v0.0.0-20210220032951-036812b2e83c
+	golang.org/x/net v0.1.0
+	golang.org/x/sync v0.0.0-20220722155255-886fb9371eb4
+	golang.org/x/time v0.0.0-20191024005414-555d28b269f0
 	gopkg.in/check.v1 v1.0.0-20200227125254-8fa46927fb4f // indirect
 	gopkg.in/urfave/cli.v1 v1.20.0
 	gopkg.in/yaml.v3 v3.0.1
-
 )
 
 replace github.com/stretchr/testify => github.com/ipfs-search/testify v1.8.1-0.20220714120938-9ebebef47942
@@ -5,12 +5,16 @@ import (
 	"context"
 	"fmt"
 	"github.com/ipfs-search/ipfs-search/commands"
+	"github.com/ipfs-search/ipfs-search/components/export"
+	"github.com/ipfs-search/ipfs-search/components/index/elasticsearch"
+	td "github.com/ipfs-search/ipfs-search/components/takedown"
 	"github.com/ipfs-search/ipfs-search/config"
 	"gopkg.in/urfave/cli.v1"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 )
 
 func main() {
@@ -37,6 +41,138 @@ func main() {
 			Usage:   "start crawler",
 			Action:  crawl,
 		},
+		{
+			Name:    "sniff",
+			Aliases: []string{"s"},
+			Usage:   "start DHT node and sniff hashes into crawler queue",
+			Action:  sniff,
+		},
+		{
+			Name:   "serve",
+			Usage:  "serve search API",
+			Action: serve,
+		},
+		{
+			Name:   "run",
+			Usage:  "crawl, sniff and serve search API in a single process, as required for bleve indexes",
+			Action: run,
+		},
+		{
+			Name:   "replay",
+			Usage:  "replay sniffed hashes from the sniff log into crawler queue",
+			Action: replay,
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "from",
+					Usage: "replay hashes sniffed from `TIME` (RFC 3339), defaults to the start of the log",
+				},
+				cli.StringFlag{
+					Name:  "to",
+					Usage: "replay hashes sniffed until `TIME` (RFC 3339), defaults to now",
+				},
+				cli.Float64Flag{
+					Name:  "rate",
+					Usage: "replay at most `RATE` hashes per second, defaults to the sniffer's publish rate",
+				},
+			},
+		},
+		{
+			Name:   "takedown",
+			Usage:  "remove `HASH` from all indexes and add it to the denylist",
+			Action: takedown,
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "recursive, r",
+					Usage: "also take down directory entries which are not referenced elsewhere",
+				},
+				cli.BoolFlag{
+					Name:  "dry-run, n",
+					Usage: "show what would be taken down, without changing anything",
+				},
+				cli.StringFlag{
+					Name:  "reason",
+					Usage: "record `REASON` in the audit log and denylist",
+				},
+			},
+		},
+		{
+			Name:      "export",
+			Usage:     "export documents to a dump of compressed JSONL shards in a directory",
+			ArgsUsage: "DIR",
+			Action:    exportIndexes,
+			Flags: []cli.Flag{
+				cli.StringSliceFlag{
+					Name:  "kind, k",
+					Usage: "export `KIND` of index, e.g. files; may be repeated, defaults to all",
+				},
+				cli.StringFlag{
+					Name:  "last-seen-after",
+					Usage: "only export documents seen at or after `TIME` (RFC 3339)",
+				},
+				cli.StringFlag{
+					Name:  "last-seen-before",
+					Usage: "only export documents seen before `TIME` (RFC 3339)",
+				},
+				cli.StringFlag{
+					Name:  "mime",
+					Usage: "only export files with a MIME type starting with `PREFIX`, e.g. text/",
+				},
+				cli.IntFlag{
+					Name:  "shard-size",
+					Usage: "maximum number of documents per shard",
+					Value: 100000,
+				},
+				cli.StringFlag{
+					Name:  "car",
+					Usage: "pack the dump into a CAR `FILE`, for importing into IPFS",
+				},
+			},
+		},
+		{
+			Name:      "import",
+			Usage:     "import documents from dumps or shards into the configured indexes",
+			ArgsUsage: "PATH...",
+			Action:    importIndexes,
+			Flags: []cli.Flag{
+				cli.IntFlag{
+					Name:  "workers",
+					Usage: "number of documents to write concurrently",
+					Value: 8,
+				},
+			},
+		},
+		{
+			Name:  "index",
+			Usage: "manage indexes",
+			Subcommands: []cli.Command{
+				{
+					Name:   "init",
+					Usage:  "create indexes which do not exist",
+					Action: indexInit,
+				},
+				{
+					Name:   "status",
+					Usage:  "show index status and mapping differences from their definitions",
+					Action: indexStatus,
+				},
+				{
+					Name:      "migrate",
+					Usage:     "reindex a kind of index into a new version and swap its alias",
+					ArgsUsage: "KIND",
+					Action:    indexMigrate,
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "transform",
+							Usage: "apply painless `SCRIPT` to documents while reindexing",
+						},
+						cli.BoolFlag{
+							Name:  "replace-index",
+							Usage: "delete the index when it is not an alias, replacing it by one",
+						},
+					},
+				},
+			},
+		},
 		{
 			Name:    "config",
 			Aliases: []string{},
@@ -189,3 +325,263 @@ func crawl(c *cli.Context) error {
 
 	return nil
 }
+
+func sniff(c *cli.Context) error {
+	fmt.Println("Starting sniffer")
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Allow SIGTERM / Control-C quit through context
+	onSigTerm(cancel)
+
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	err = commands.Sniff(ctx, cfg)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+func serve(c *cli.Context) error {
+	fmt.Println("Starting search API")
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Allow SIGTERM / Control-C quit through context
+	onSigTerm(cancel)
+
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	err = commands.Serve(ctx, cfg)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+func run(c *cli.Context) error {
+	fmt.Println("Starting crawler, sniffer and search API")
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Allow SIGTERM / Control-C quit through context
+	onSigTerm(cancel)
+
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	err = commands.Run(ctx, cfg)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+func parseTimeFlag(c *cli.Context, name string, def time.Time) (time.Time, error) {
+	v := c.String(name)
+	if v == "" {
+		return def, nil
+	}
+
+	return time.Parse(time.RFC3339, v)
+}
+
+func replay(c *cli.Context) error {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Allow SIGTERM / Control-C quit through context
+	onSigTerm(cancel)
+
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	from, err := parseTimeFlag(c, "from", time.Time{})
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	to, err := parseTimeFlag(c, "to", time.Now())
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	rate := c.Float64("rate")
+	if rate == 0 {
+		rate = cfg.Sniffer.PublishRate
+	}
+
+	err = commands.Replay(ctx, cfg, from, to, rate)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+func takedown(c *cli.Context) error {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Allow SIGTERM / Control-C quit through context
+	onSigTerm(cancel)
+
+	if c.NArg() != 1 {
+		return cli.NewExitError("Please supply one hash as argument.", 1)
+	}
+	hash := c.Args().Get(0)
+
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	opts := td.Options{
+		Recursive: c.Bool("recursive"),
+		DryRun:    c.Bool("dry-run"),
+		Reason:    c.String("reason"),
+	}
+
+	if opts.DryRun {
+		fmt.Printf("Dry run of taking down '%s'\n", hash)
+	} else {
+		fmt.Printf("Taking down '%s'\n", hash)
+	}
+
+	err = commands.Takedown(ctx, cfg, hash, opts)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+func exportIndexes(c *cli.Context) error {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Allow SIGTERM / Control-C to cancel exporting through context
+	onSigTerm(cancel)
+
+	if c.NArg() != 1 {
+		return cli.NewExitError("Please supply one directory as argument.", 1)
+	}
+	dir := c.Args().Get(0)
+
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	opts := &export.Options{
+		ShardSize: c.Int("shard-size"),
+	}
+
+	opts.Filter.MIMEType = c.String("mime")
+
+	if opts.Filter.LastSeenAfter, err = parseTimeFlag(c, "last-seen-after", time.Time{}); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	if opts.Filter.LastSeenBefore, err = parseTimeFlag(c, "last-seen-before", time.Time{}); err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	err = commands.Export(ctx, cfg, dir, c.StringSlice("kind"), opts, c.String("car"))
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+func importIndexes(c *cli.Context) error {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Allow SIGTERM / Control-C to cancel importing through context
+	onSigTerm(cancel)
+
+	if c.NArg() == 0 {
+		return cli.NewExitError("Please supply dumps or shards as arguments.", 1)
+	}
+
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	err = commands.Import(ctx, cfg, c.Args(), c.Int("workers"))
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+func indexInit(c *cli.Context) error {
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	err = commands.IndexInit(context.Background(), cfg)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+func indexStatus(c *cli.Context) error {
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	err = commands.IndexStatus(context.Background(), cfg)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}
+
+func indexMigrate(c *cli.Context) error {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Allow SIGTERM / Control-C to cancel reindexing through context
+	onSigTerm(cancel)
+
+	if c.NArg() != 1 {
+		return cli.NewExitError("Please supply one kind of index as argument.", 1)
+	}
+	kind := c.Args().Get(0)
+
+	cfg, err := getConfig(c)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	opts := &elasticsearch.MigrateOptions{
+		Transform:    c.String("transform"),
+		ReplaceIndex: c.Bool("replace-index"),
+	}
+
+	err = commands.IndexMigrate(ctx, cfg, kind, opts)
+	if err != nil {
+		return cli.NewExitError(err.Error(), 1)
+	}
+
+	return nil
+}